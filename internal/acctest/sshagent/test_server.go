@@ -50,7 +50,7 @@ func (s *TestServer) Use(t *testing.T, scopeFunc func(t *testing.T)) {
 	go func() {
 		err = s.server.Serve(ctx, listener)
 		if err != nil {
-			t.Fatal(err)
+			t.Error(err)
 		}
 	}()
 
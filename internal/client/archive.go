@@ -0,0 +1,521 @@
+package client
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/neuspaces/terraform-provider-system/internal/system"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Archive extracts an archive into a directory on the system
+type Archive struct {
+	// Path is the directory on the system
+	Path string
+
+	// StripComponents is the number of leading path elements which are removed from the entries of the archive.
+	// Entries with fewer path elements are skipped.
+	StripComponents int
+
+	// FileMode and DirMode override the permissions of the entries of the archive unless they are 0
+	FileMode fs.FileMode
+	DirMode  fs.FileMode
+
+	// User and Group optionally own all extracted entries
+	User  string
+	Group string
+}
+
+// ArchiveManifest contains the sorted slash-separated paths of the extracted entries relative to the directory. Paths
+// of directories have a trailing slash.
+type ArchiveManifest []string
+
+type ArchiveClient interface {
+	// Get returns the entries of m which exist in the directory on the system
+	Get(ctx context.Context, a Archive, m ArchiveManifest) (ArchiveManifest, error)
+
+	// Extract extracts the tar, gzip compressed tar or zip archive read from content into the directory on the system
+	// and returns the extracted entries. The format is detected from the content. The directory is created if it does
+	// not exist.
+	Extract(ctx context.Context, a Archive, content io.Reader) (ArchiveManifest, error)
+
+	// Delete removes the entries in m from the directory. Directories which are not empty are retained.
+	Delete(ctx context.Context, a Archive, m ArchiveManifest) error
+}
+
+func NewArchiveClient(s system.System) ArchiveClient {
+	return &archiveClient{
+		s: s,
+	}
+}
+
+var (
+	ErrArchive = errors.New("archive resource")
+
+	ErrArchiveNotFound = errors.Join(ErrArchive, errors.New("folder not found"))
+
+	ErrArchiveInvalid = errors.Join(ErrArchive, errors.New("invalid archive"))
+
+	ErrArchiveUnexpected = errors.Join(ErrArchive, errors.New("unexpected error"))
+)
+
+const (
+	codeArchiveNotFound = 17
+)
+
+type archiveClient struct {
+	s system.System
+}
+
+func (c *archiveClient) Get(ctx context.Context, a Archive, m ArchiveManifest) (ArchiveManifest, error) {
+	stdin := &bytes.Buffer{}
+	for _, name := range m {
+		stdin.WriteString(name)
+		stdin.WriteByte(0)
+	}
+
+	cmd := NewInputCommand(fmt.Sprintf(`_do() { path=$1; [ -d "${path}" ] || return %[2]d; cd "${path}" && xargs -0 sh -c 'for p in "$@"; do if [ -e "${p}" ] || [ -L "${p}" ]; then printf "%%s\n" "${p}"; fi; done' _; }; _do '%[1]s';`, a.Path, codeArchiveNotFound), stdin)
+	res, err := ExecuteCommand(ctx, c.s, cmd)
+	if err != nil {
+		return nil, errors.Join(ErrArchiveUnexpected, err)
+	}
+
+	switch res.ExitCode {
+	case codeArchiveNotFound:
+		return nil, ErrArchiveNotFound
+	}
+
+	if err := res.Error(); err != nil {
+		return nil, errors.Join(ErrArchiveUnexpected, err, errors.New(res.StderrString()))
+	}
+
+	existing := ArchiveManifest{}
+	for _, line := range strings.Split(res.StdoutString(), "\n") {
+		if line != "" {
+			existing = append(existing, line)
+		}
+	}
+	sort.Strings(existing)
+
+	return existing, nil
+}
+
+func (c *archiveClient) Extract(ctx context.Context, a Archive, content io.Reader) (ArchiveManifest, error) {
+	// The archive is stored in a temporary file because the format is detected from the content and zip archives
+	// require random access
+	file, err := os.CreateTemp("", "terraform-provider-system-archive-*")
+	if err != nil {
+		return nil, errors.Join(ErrArchive, err)
+	}
+	defer func() {
+		_ = file.Close()
+		_ = os.Remove(file.Name())
+	}()
+
+	size, err := io.Copy(file, content)
+	if err != nil {
+		return nil, errors.Join(ErrArchive, err)
+	}
+
+	w := &archiveWriter{
+		a:        a,
+		dirs:     map[string]bool{},
+		links:    map[string]bool{},
+		files:    map[string]bool{},
+		manifest: map[string]bool{},
+	}
+
+	// Validate the archive before the extraction
+	err = readArchive(file, size, w.validate)
+	if err != nil {
+		return nil, errors.Join(ErrArchiveInvalid, err)
+	}
+
+	err = extractTar(ctx, c.s, a.Path, func(tarWriter *tar.Writer) error {
+		w.tarWriter = tarWriter
+		return readArchive(file, size, w.write)
+	})
+	if err != nil {
+		return nil, errors.Join(ErrArchive, fmt.Errorf("failed to extract to %q", a.Path), err)
+	}
+
+	m := w.Manifest()
+
+	var files, dirs []string
+	for _, name := range m {
+		if strings.HasSuffix(name, "/") {
+			dirs = append(dirs, name)
+		} else if w.files[name] {
+			files = append(files, name)
+		}
+	}
+
+	// Apply overrides to existing entries which are not replaced by the extraction
+	if a.FileMode != 0 {
+		err = xargs(ctx, c.s, a.Path, fmt.Sprintf(`chmod %o --`, a.FileMode.Perm()), files)
+		if err != nil {
+			return nil, errors.Join(ErrArchive, err)
+		}
+	}
+
+	if a.DirMode != 0 {
+		err = xargs(ctx, c.s, a.Path, fmt.Sprintf(`chmod %o --`, a.DirMode.Perm()), dirs)
+		if err != nil {
+			return nil, errors.Join(ErrArchive, err)
+		}
+	}
+
+	if a.User != "" || a.Group != "" {
+		err = chownPaths(ctx, c.s, a.Path, a.User, a.Group, m)
+		if err != nil {
+			return nil, errors.Join(ErrArchive, err)
+		}
+	}
+
+	return m, nil
+}
+
+func (c *archiveClient) Delete(ctx context.Context, a Archive, m ArchiveManifest) error {
+	var files, dirs []string
+	for _, name := range m {
+		if strings.HasSuffix(name, "/") {
+			dirs = append(dirs, name)
+		} else {
+			files = append(files, name)
+		}
+	}
+
+	err := removePaths(ctx, c.s, a.Path, files, dirs)
+	if err != nil {
+		// The directory may have been removed
+		_, getErr := c.Get(ctx, a, nil)
+		if errors.Is(getErr, ErrArchiveNotFound) {
+			return nil
+		}
+
+		return errors.Join(ErrArchive, err)
+	}
+
+	return nil
+}
+
+// archiveEntryFunc is called by readArchive for each entry of an archive. Content reads the content of regular files.
+type archiveEntryFunc func(h *tar.Header, content io.Reader) error
+
+// readArchive calls fn for each entry of the tar, gzip compressed tar or zip archive in file
+func readArchive(file *os.File, size int64, fn archiveEntryFunc) error {
+	_, err := file.Seek(0, io.SeekStart)
+	if err != nil {
+		return err
+	}
+
+	magic, err := bufio.NewReader(file).Peek(4)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+
+	_, err = file.Seek(0, io.SeekStart)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case bytes.HasPrefix(magic, []byte{0x1f, 0x8b}):
+		gzipReader, err := gzip.NewReader(file)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			_ = gzipReader.Close()
+		}()
+
+		return readTar(gzipReader, fn)
+	case bytes.HasPrefix(magic, []byte("PK\x03\x04")), bytes.HasPrefix(magic, []byte("PK\x05\x06")):
+		return readZip(file, size, fn)
+	default:
+		return readTar(file, fn)
+	}
+}
+
+func readTar(r io.Reader, fn archiveEntryFunc) error {
+	tarReader := tar.NewReader(r)
+
+	for {
+		h, err := tarReader.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		err = fn(h, tarReader)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func readZip(r io.ReaderAt, size int64, fn archiveEntryFunc) error {
+	zipReader, err := zip.NewReader(r, size)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range zipReader.File {
+		err = readZipFile(f, fn)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func readZipFile(f *zip.File, fn archiveEntryFunc) error {
+	info := f.FileInfo()
+
+	h := &tar.Header{
+		Name:    f.Name,
+		Mode:    int64(info.Mode().Perm()),
+		ModTime: f.Modified,
+	}
+
+	var content io.Reader
+
+	switch {
+	case info.IsDir():
+		h.Typeflag = tar.TypeDir
+	case info.Mode()&fs.ModeSymlink != 0:
+		r, err := f.Open()
+		if err != nil {
+			return err
+		}
+		target, err := io.ReadAll(r)
+		_ = r.Close()
+		if err != nil {
+			return err
+		}
+
+		h.Typeflag = tar.TypeSymlink
+		h.Linkname = string(target)
+	case info.Mode().IsRegular():
+		r, err := f.Open()
+		if err != nil {
+			return err
+		}
+		defer func() {
+			_ = r.Close()
+		}()
+
+		h.Typeflag = tar.TypeReg
+		h.Size = int64(f.UncompressedSize64)
+		content = r
+	default:
+		return nil
+	}
+
+	// Zip archives created on systems without permissions do not define a mode
+	if h.Mode == 0 {
+		h.Mode = 0644
+		if info.IsDir() {
+			h.Mode = 0755
+		}
+	}
+
+	return fn(h, content)
+}
+
+// archiveWriter writes the entries of an archive to a tar archive which is extracted on the system
+type archiveWriter struct {
+	a         Archive
+	tarWriter *tar.Writer
+
+	// dirs contains the written directories
+	dirs map[string]bool
+
+	// links contains the symbolic links which must not be traversed by other entries
+	links map[string]bool
+
+	// files contains the regular files and hard links
+	files map[string]bool
+
+	manifest map[string]bool
+}
+
+// Manifest returns the sorted paths of all written entries
+func (w *archiveWriter) Manifest() ArchiveManifest {
+	m := ArchiveManifest{}
+	for name := range w.manifest {
+		m = append(m, name)
+	}
+	sort.Strings(m)
+
+	return m
+}
+
+// name returns the path of an entry after StripComponents path elements are removed. The returned path is empty if
+// the entry is skipped.
+func (w *archiveWriter) name(name string) (string, error) {
+	name = path.Clean(strings.TrimLeft(name, "/"))
+	if name == ".." || strings.HasPrefix(name, "../") {
+		return "", fmt.Errorf("entry %q is outside of the folder", name)
+	}
+
+	if strings.ContainsAny(name, "\n\x00") {
+		return "", fmt.Errorf("unsupported entry name %q", name)
+	}
+
+	elems := strings.Split(name, "/")
+	if name == "." || len(elems) <= w.a.StripComponents {
+		return "", nil
+	}
+
+	return strings.Join(elems[w.a.StripComponents:], "/"), nil
+}
+
+// validate validates an entry and records symbolic links
+func (w *archiveWriter) validate(h *tar.Header, _ io.Reader) error {
+	name, err := w.name(h.Name)
+	if err != nil || name == "" {
+		return err
+	}
+
+	// Entries must not be extracted through a symbolic link of the archive
+	for p := path.Dir(name); p != "."; p = path.Dir(p) {
+		if w.links[p] {
+			return fmt.Errorf("entry %q is extracted through the symbolic link %q", h.Name, p)
+		}
+	}
+
+	switch h.Typeflag {
+	case tar.TypeSymlink:
+		w.links[name] = true
+	case tar.TypeLink:
+		linkname, err := w.name(h.Linkname)
+		if err != nil {
+			return err
+		}
+		if linkname == "" {
+			return fmt.Errorf("target of the hard link %q is stripped", h.Name)
+		}
+	}
+
+	return nil
+}
+
+// write writes an entry and its parent directories
+func (w *archiveWriter) write(h *tar.Header, content io.Reader) error {
+	name, err := w.name(h.Name)
+	if err != nil || name == "" {
+		return err
+	}
+
+	if parent := path.Dir(name); parent != "." {
+		err = w.writeDir(parent, 0755)
+		if err != nil {
+			return err
+		}
+	}
+
+	switch h.Typeflag {
+	case tar.TypeDir:
+		return w.writeDir(name, fs.FileMode(h.Mode).Perm())
+	case tar.TypeReg:
+		mode := fs.FileMode(h.Mode).Perm()
+		if w.a.FileMode != 0 {
+			mode = w.a.FileMode.Perm()
+		}
+
+		err = w.tarWriter.WriteHeader(&tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     name,
+			Mode:     int64(mode),
+			Size:     h.Size,
+			ModTime:  h.ModTime,
+		})
+		if err != nil {
+			return err
+		}
+
+		_, err = io.Copy(w.tarWriter, content)
+		if err != nil {
+			return err
+		}
+
+		w.files[name] = true
+	case tar.TypeSymlink:
+		err = w.tarWriter.WriteHeader(&tar.Header{
+			Typeflag: tar.TypeSymlink,
+			Name:     name,
+			Linkname: h.Linkname,
+			Mode:     0777,
+			ModTime:  h.ModTime,
+		})
+		if err != nil {
+			return err
+		}
+	case tar.TypeLink:
+		linkname, _ := w.name(h.Linkname)
+
+		err = w.tarWriter.WriteHeader(&tar.Header{
+			Typeflag: tar.TypeLink,
+			Name:     name,
+			Linkname: linkname,
+			ModTime:  h.ModTime,
+		})
+		if err != nil {
+			return err
+		}
+
+		w.files[name] = true
+	default:
+		// Special files are skipped
+		return nil
+	}
+
+	w.manifest[name] = true
+
+	return nil
+}
+
+// writeDir writes the directory name and its parent directories unless they have been written before
+func (w *archiveWriter) writeDir(name string, mode fs.FileMode) error {
+	if w.dirs[name] {
+		return nil
+	}
+
+	if parent := path.Dir(name); parent != "." {
+		err := w.writeDir(parent, 0755)
+		if err != nil {
+			return err
+		}
+	}
+
+	if w.a.DirMode != 0 {
+		mode = w.a.DirMode.Perm()
+	}
+
+	err := w.tarWriter.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeDir,
+		Name:     name + "/",
+		Mode:     int64(mode),
+	})
+	if err != nil {
+		return err
+	}
+
+	w.dirs[name] = true
+	w.manifest[name+"/"] = true
+
+	return nil
+}
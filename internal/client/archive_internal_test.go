@@ -0,0 +1,359 @@
+package client
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type testArchiveEntry struct {
+	Name     string
+	Typeflag byte
+	Linkname string
+	Content  string
+}
+
+type testArchiveFormat string
+
+const (
+	testArchiveTar   testArchiveFormat = "tar"
+	testArchiveTarGz testArchiveFormat = "tar.gz"
+	testArchiveZip   testArchiveFormat = "zip"
+)
+
+// newTestArchive writes entries to a temporary archive file in format
+func newTestArchive(t *testing.T, format testArchiveFormat, entries []testArchiveEntry) (*os.File, int64) {
+	file, err := os.Create(filepath.Join(t.TempDir(), "archive."+string(format)))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = file.Close()
+	})
+
+	switch format {
+	case testArchiveTar, testArchiveTarGz:
+		var w io.Writer = file
+		var gzipWriter *gzip.Writer
+		if format == testArchiveTarGz {
+			gzipWriter = gzip.NewWriter(file)
+			w = gzipWriter
+		}
+
+		tarWriter := tar.NewWriter(w)
+		for _, e := range entries {
+			h := &tar.Header{Name: e.Name, Typeflag: e.Typeflag, Linkname: e.Linkname, Mode: 0644}
+			if e.Typeflag == tar.TypeReg {
+				h.Size = int64(len(e.Content))
+			}
+			require.NoError(t, tarWriter.WriteHeader(h))
+			_, err = io.WriteString(tarWriter, e.Content)
+			require.NoError(t, err)
+		}
+		require.NoError(t, tarWriter.Close())
+
+		if gzipWriter != nil {
+			require.NoError(t, gzipWriter.Close())
+		}
+	case testArchiveZip:
+		zipWriter := zip.NewWriter(file)
+		for _, e := range entries {
+			h := &zip.FileHeader{Name: e.Name, Method: zip.Deflate}
+			content := e.Content
+
+			switch e.Typeflag {
+			case tar.TypeDir:
+				h.SetMode(fs.ModeDir | 0755)
+			case tar.TypeSymlink:
+				h.SetMode(fs.ModeSymlink | 0777)
+				content = e.Linkname
+			default:
+				h.SetMode(0644)
+			}
+
+			w, err := zipWriter.CreateHeader(h)
+			require.NoError(t, err)
+			_, err = io.WriteString(w, content)
+			require.NoError(t, err)
+		}
+		require.NoError(t, zipWriter.Close())
+	}
+
+	size, err := file.Seek(0, io.SeekEnd)
+	require.NoError(t, err)
+
+	return file, size
+}
+
+func newTestArchiveWriter(a Archive) *archiveWriter {
+	return &archiveWriter{
+		a:        a,
+		dirs:     map[string]bool{},
+		links:    map[string]bool{},
+		files:    map[string]bool{},
+		manifest: map[string]bool{},
+	}
+}
+
+func TestArchiveWriter_name(t *testing.T) {
+	t.Parallel()
+
+	type testCase struct {
+		Desc            string
+		Name            string
+		StripComponents int
+		Expect          string
+		ExpectErr       bool
+	}
+
+	tcs := []testCase{
+		{
+			Desc:   "relative name",
+			Name:   "dir/file",
+			Expect: "dir/file",
+		},
+		{
+			Desc:   "absolute name is relative to the folder",
+			Name:   "/etc/passwd",
+			Expect: "etc/passwd",
+		},
+		{
+			Desc:   "directory with trailing slash",
+			Name:   "./dir/",
+			Expect: "dir",
+		},
+		{
+			Desc:   "parent element within the folder",
+			Name:   "dir/../file",
+			Expect: "file",
+		},
+		{
+			Desc:      "parent of the folder",
+			Name:      "..",
+			ExpectErr: true,
+		},
+		{
+			Desc:      "outside of the folder",
+			Name:      "../file",
+			ExpectErr: true,
+		},
+		{
+			Desc:      "outside of the folder after clean",
+			Name:      "dir/../../file",
+			ExpectErr: true,
+		},
+		{
+			Desc:      "absolute name outside of the folder",
+			Name:      "/../file",
+			ExpectErr: true,
+		},
+		{
+			Desc:      "newline",
+			Name:      "dir/fi\nle",
+			ExpectErr: true,
+		},
+		{
+			Desc:   "current directory is skipped",
+			Name:   "./",
+			Expect: "",
+		},
+		{
+			Desc:            "strip components",
+			Name:            "top/dir/file",
+			StripComponents: 1,
+			Expect:          "dir/file",
+		},
+		{
+			Desc:            "strip all components",
+			Name:            "top/dir/file",
+			StripComponents: 3,
+			Expect:          "",
+		},
+		{
+			Desc:            "strip more components than elements",
+			Name:            "top",
+			StripComponents: 2,
+			Expect:          "",
+		},
+		{
+			Desc:            "strip components after clean",
+			Name:            "top/../dir/file",
+			StripComponents: 1,
+			Expect:          "file",
+		},
+		{
+			Desc:            "strip components of the parent of the folder",
+			Name:            "top/../../file",
+			StripComponents: 1,
+			ExpectErr:       true,
+		},
+	}
+
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.Desc, func(t *testing.T) {
+			t.Parallel()
+
+			w := newTestArchiveWriter(Archive{StripComponents: tc.StripComponents})
+
+			name, err := w.name(tc.Name)
+			if tc.ExpectErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.Expect, name)
+		})
+	}
+}
+
+func TestArchiveWriter_validate(t *testing.T) {
+	t.Parallel()
+
+	type testCase struct {
+		Desc            string
+		Entries         []testArchiveEntry
+		StripComponents int
+		ExpectErr       string
+
+		// TarOnly is true if the entries cannot be represented in a zip archive
+		TarOnly bool
+	}
+
+	tcs := []testCase{
+		{
+			Desc: "valid entries",
+			Entries: []testArchiveEntry{
+				{Name: "dir/", Typeflag: tar.TypeDir},
+				{Name: "dir/file", Typeflag: tar.TypeReg, Content: "content"},
+				{Name: "dir/link", Typeflag: tar.TypeSymlink, Linkname: "file"},
+			},
+		},
+		{
+			Desc: "absolute names",
+			Entries: []testArchiveEntry{
+				{Name: "/etc/", Typeflag: tar.TypeDir},
+				{Name: "/etc/passwd", Typeflag: tar.TypeReg, Content: "root"},
+			},
+		},
+		{
+			Desc: "entry outside of the folder",
+			Entries: []testArchiveEntry{
+				{Name: "../file", Typeflag: tar.TypeReg, Content: "content"},
+			},
+			ExpectErr: `entry "../file" is outside of the folder`,
+		},
+		{
+			Desc: "entry outside of the folder after clean",
+			Entries: []testArchiveEntry{
+				{Name: "dir/../../file", Typeflag: tar.TypeReg, Content: "content"},
+			},
+			ExpectErr: `entry "../file" is outside of the folder`,
+		},
+		{
+			Desc: "entry through a symbolic link",
+			Entries: []testArchiveEntry{
+				{Name: "link", Typeflag: tar.TypeSymlink, Linkname: "/etc"},
+				{Name: "link/passwd", Typeflag: tar.TypeReg, Content: "root"},
+			},
+			ExpectErr: `entry "link/passwd" is extracted through the symbolic link "link"`,
+		},
+		{
+			Desc: "entry through a nested symbolic link",
+			Entries: []testArchiveEntry{
+				{Name: "dir/link", Typeflag: tar.TypeSymlink, Linkname: "../.."},
+				{Name: "dir/link/sub/file", Typeflag: tar.TypeReg, Content: "content"},
+			},
+			ExpectErr: `entry "dir/link/sub/file" is extracted through the symbolic link "dir/link"`,
+		},
+		{
+			Desc: "entry through a symbolic link after strip components",
+			Entries: []testArchiveEntry{
+				{Name: "top/link", Typeflag: tar.TypeSymlink, Linkname: "/etc"},
+				{Name: "other/link/passwd", Typeflag: tar.TypeReg, Content: "root"},
+			},
+			StripComponents: 1,
+			ExpectErr:       `entry "other/link/passwd" is extracted through the symbolic link "link"`,
+		},
+		{
+			Desc: "symbolic link which is not traversed",
+			Entries: []testArchiveEntry{
+				{Name: "link", Typeflag: tar.TypeSymlink, Linkname: "/etc"},
+				{Name: "linked/file", Typeflag: tar.TypeReg, Content: "content"},
+			},
+		},
+		{
+			Desc: "hard link",
+			Entries: []testArchiveEntry{
+				{Name: "top/file", Typeflag: tar.TypeReg, Content: "content"},
+				{Name: "top/link", Typeflag: tar.TypeLink, Linkname: "top/file"},
+			},
+			StripComponents: 1,
+			TarOnly:         true,
+		},
+		{
+			Desc: "hard link to a stripped target",
+			Entries: []testArchiveEntry{
+				{Name: "file", Typeflag: tar.TypeReg, Content: "content"},
+				{Name: "top/link", Typeflag: tar.TypeLink, Linkname: "file"},
+			},
+			StripComponents: 1,
+			ExpectErr:       `target of the hard link "top/link" is stripped`,
+			TarOnly:         true,
+		},
+		{
+			Desc: "hard link outside of the folder",
+			Entries: []testArchiveEntry{
+				{Name: "link", Typeflag: tar.TypeLink, Linkname: "../etc/passwd"},
+			},
+			ExpectErr: `entry "../etc/passwd" is outside of the folder`,
+			TarOnly:   true,
+		},
+		{
+			Desc: "entries with fewer elements than strip components are skipped",
+			Entries: []testArchiveEntry{
+				{Name: "top/", Typeflag: tar.TypeDir},
+				{Name: "file", Typeflag: tar.TypeReg, Content: "content"},
+				{Name: "link", Typeflag: tar.TypeSymlink, Linkname: "/etc"},
+				{Name: "top/file", Typeflag: tar.TypeReg, Content: "content"},
+			},
+			StripComponents: 1,
+		},
+		{
+			Desc: "all entries are stripped",
+			Entries: []testArchiveEntry{
+				{Name: "top/file", Typeflag: tar.TypeReg, Content: "content"},
+			},
+			StripComponents: 5,
+		},
+	}
+
+	for _, tc := range tcs {
+		tc := tc
+		for _, format := range []testArchiveFormat{testArchiveTar, testArchiveTarGz, testArchiveZip} {
+			format := format
+			if tc.TarOnly && format == testArchiveZip {
+				continue
+			}
+
+			t.Run(tc.Desc+" "+string(format), func(t *testing.T) {
+				t.Parallel()
+
+				file, size := newTestArchive(t, format, tc.Entries)
+				w := newTestArchiveWriter(Archive{StripComponents: tc.StripComponents})
+
+				err := readArchive(file, size, w.validate)
+				if tc.ExpectErr != "" {
+					assert.EqualError(t, err, tc.ExpectErr)
+					return
+				}
+				assert.NoError(t, err)
+			})
+		}
+	}
+}
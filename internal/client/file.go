@@ -4,6 +4,9 @@ import (
 	"bytes"
 	"compress/gzip"
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
 	"encoding/base64"
 	"encoding/hex"
 	"errors"
@@ -12,6 +15,7 @@ import (
 	"github.com/neuspaces/terraform-provider-system/internal/system"
 	"io"
 	"io/fs"
+	"regexp"
 	"strconv"
 	"strings"
 )
@@ -27,6 +31,18 @@ type File struct {
 	// Content optionally contains the file contents when enabled with FileClientIncludeContent
 	Content io.Reader
 	Md5Sum  string
+
+	// CopyFrom optionally contains the path to a file on the system which is copied as the content of the file
+	CopyFrom string
+
+	// CopyFromSha256Sum optionally contains the expected SHA-256 checksum in hexadecimal encoding of the file at
+	// CopyFrom. The checksum is verified on the system before the file is copied.
+	CopyFromSha256Sum string
+
+	// Sha256Sum and Sha512Sum are the checksums of the content in hexadecimal encoding. The checksums are empty if the
+	// system does not provide a tool to compute them.
+	Sha256Sum string
+	Sha512Sum string
 }
 
 func newFileFromStat(s *stat.Stat) *File {
@@ -45,10 +61,73 @@ type FileClient interface {
 	Create(ctx context.Context, f File) error
 	Update(ctx context.Context, f File) error
 	Delete(ctx context.Context, path string) error
+
+	// Backup copies the file at path to a new backup and returns the path of the backup
+	Backup(ctx context.Context, path string, b FileBackup) (string, error)
+}
+
+// FileBackup defines the backups of a file
+type FileBackup struct {
+	// Dir is the directory of the backups. Defaults to the directory of the file.
+	Dir string
+
+	// Suffix is appended to the name of the file to name a backup. Suffix is a format of `date` which is evaluated in
+	// UTC. Conversion specifications like %Y are replaced by `date`; all other characters are retained.
+	Suffix string
+
+	// Retention is the number of most recent backups which are retained. Backups are ordered by name. A Retention of 0
+	// retains all backups. Only names whose remainder after the name of the file matches Suffix with digits in place of
+	// each conversion specification are backups of the file.
+	Retention int
+}
+
+// FileBackupDefaultSuffix is a suffix of backups which orders backups by name chronologically. The suffix contains
+// nanoseconds, so that backups which are created within the same second have distinct names.
+const FileBackupDefaultSuffix = ".%Y%m%dT%H%M%S.%NZ.bak"
+
+// glob returns a shell pattern which matches the suffix of all backups
+func (b FileBackup) glob() string {
+	var sb strings.Builder
+
+	for i := 0; i < len(b.Suffix); i++ {
+		if b.Suffix[i] == '%' && i+1 < len(b.Suffix) {
+			if !strings.HasSuffix(sb.String(), "*") {
+				sb.WriteString("*")
+			}
+			i++
+			continue
+		}
+		sb.WriteByte(b.Suffix[i])
+	}
+
+	return sb.String()
+}
+
+// pattern returns an extended regular expression which matches the entire suffix of all backups. Each conversion
+// specification matches digits, so that backups of files whose names start with the name of the file do not match.
+func (b FileBackup) pattern() string {
+	var sb strings.Builder
+
+	for i := 0; i < len(b.Suffix); i++ {
+		if b.Suffix[i] == '%' && i+1 < len(b.Suffix) {
+			i++
+			if b.Suffix[i] == '%' {
+				sb.WriteString("%")
+			} else if !strings.HasSuffix(sb.String(), "[0-9]+") {
+				sb.WriteString("[0-9]+")
+			}
+			continue
+		}
+		sb.WriteString(regexp.QuoteMeta(b.Suffix[i : i+1]))
+	}
+
+	return sb.String()
 }
 
 type FileClientOpt func(c *fileClient)
 
+// FileClientCompression enables compression of the content which is uploaded by executing commands. Compression does
+// not apply if the system accesses files directly.
 func FileClientCompression(enabled bool) FileClientOpt {
 	return func(c *fileClient) {
 		c.compress = enabled
@@ -61,9 +140,48 @@ func FileClientIncludeContent(include bool) FileClientOpt {
 	}
 }
 
+// FileClientContentRange limits the content which is included by FileClientIncludeContent to length bytes starting at
+// offset. A negative length includes the content until the end of the file.
+func FileClientContentRange(offset int64, length int64) FileClientOpt {
+	return func(c *fileClient) {
+		c.contentOffset = offset
+		c.contentLength = length
+	}
+}
+
+// FileClientContentLimit limits the size of the content which is included by FileClientIncludeContent to limit bytes.
+// Get returns ErrFileContentLimit if the content exceeds limit. A negative limit includes content of any size.
+func FileClientContentLimit(limit int64) FileClientOpt {
+	return func(c *fileClient) {
+		c.contentLimit = limit
+	}
+}
+
+// FileClientAtomic enables atomic writes of the content. The content is written to a temporary file in the directory of
+// the file which receives the mode and the owner of the file, is flushed to disk and is renamed to the file. Readers
+// never observe partially written content. The temporary file is removed if writing fails. If the path is a symbolic
+// link, the target of the link is replaced and the link is retained. Files with multiple hard links are not written and
+// ErrFileHardLinks is returned because the rename would detach the file from its other links.
+func FileClientAtomic(enabled bool) FileClientOpt {
+	return func(c *fileClient) {
+		c.atomic = enabled
+	}
+}
+
+// FileClientValidateCommand validates the content before it replaces the file. Each `%s` in command is replaced by the
+// path of the temporary file. If command exits with a non-zero exit code, the temporary file is removed and the file
+// remains unchanged. FileClientValidateCommand requires FileClientAtomic.
+func FileClientValidateCommand(command string) FileClientOpt {
+	return func(c *fileClient) {
+		c.validateCommand = command
+	}
+}
+
 func NewFileClient(s system.System, opts ...FileClientOpt) FileClient {
 	fc := &fileClient{
-		s: s,
+		s:             s,
+		contentLength: -1,
+		contentLimit:  -1,
 	}
 
 	for _, opt := range opts {
@@ -81,14 +199,45 @@ var (
 	ErrFileNotFound = errors.Join(ErrFile, errors.New("file not found"))
 
 	ErrFileUnexpected = errors.Join(ErrFile, errors.New("unexpected error"))
+
+	ErrFileValidation = errors.Join(ErrFile, errors.New("validation failed"))
+
+	ErrFileContentLimit = errors.Join(ErrFile, errors.New("content exceeds limit"))
+
+	ErrFileChecksumMismatch = errors.Join(ErrFile, errors.New("checksum mismatch"))
+
+	ErrFileHardLinks = errors.Join(ErrFile, errors.New("file has multiple hard links"))
 )
 
+// FileValidationError is returned if the validate command rejected the content of a file
+type FileValidationError struct {
+	// Output is the standard output and the standard error of the validate command
+	Output string
+}
+
+func (e *FileValidationError) Error() string {
+	if e.Output == "" {
+		return "validate command rejected the content"
+	}
+	return fmt.Sprintf("validate command rejected the content: %s", e.Output)
+}
+
+func (e *FileValidationError) Unwrap() error {
+	return ErrFileValidation
+}
+
 const (
 	codeFileUnexpected = 1
 
 	codeFilePathExists = 16
 
 	codeFileNotFound = 17
+
+	codeFileValidation = 18
+
+	codeFileChecksumMismatch = 19
+
+	codeFileHardLinks = 20
 )
 
 type fileClient struct {
@@ -96,10 +245,22 @@ type fileClient struct {
 
 	compress       bool
 	includeContent bool
+	atomic         bool
+
+	validateCommand string
+
+	contentOffset int64
+	contentLength int64
+	contentLimit  int64
 }
 
+// errFileValidationNotAtomic is returned if the content cannot be validated because atomic writes are disabled
+var errFileValidationNotAtomic = errors.Join(ErrFile, errors.New("validate command requires atomic writes"))
+
+// Get returns the File at path. The metadata and the checksums are computed on the system by a single command. Only
+// the content is transferred by the file system of the system.
 func (c *fileClient) Get(ctx context.Context, path string) (*File, error) {
-	cmd := NewCommand(fmt.Sprintf(`_do() { path='%[1]s'; [ -f "${path}" ] || return %[2]d; { stat -c '%[3]s' "${path}" && md5sum "${path}"; } || return 1; }; _do;`, path, codeFileNotFound, stat.FormatJsonGnu))
+	cmd := NewCommand(fmt.Sprintf(`_do() { path='%[1]s'; [ -f "${path}" ] || return %[2]d; { stat -c '%[3]s' "${path}" && md5sum "${path}" && echo "$(%[4]s)" && echo "$(%[5]s)"; } || return 1; }; _do;`, path, codeFileNotFound, stat.FormatJsonGnu, checksumCommand(256, `"${path}"`), checksumCommand(512, `"${path}"`)))
 	res, err := ExecuteCommand(ctx, c.s, cmd)
 	if err != nil {
 		return nil, errors.Join(ErrFileUnexpected, err)
@@ -110,9 +271,10 @@ func (c *fileClient) Get(ctx context.Context, path string) (*File, error) {
 		return nil, ErrFileNotFound
 	}
 
-	stdoutLines := strings.Split(strings.TrimSpace(string(res.Stdout)), "\n")
+	// The lines of the SHA-2 checksums are empty if the system does not provide a tool to compute them
+	stdoutLines := strings.Split(strings.TrimSuffix(string(res.Stdout), "\n"), "\n")
 
-	if res.ExitCode != 0 || len(res.Stdout) == 0 || len(stdoutLines) != 2 {
+	if res.ExitCode != 0 || len(res.Stdout) == 0 || len(stdoutLines) != 4 {
 		return nil, ErrFileUnexpected
 	}
 
@@ -137,57 +299,217 @@ func (c *fileClient) Get(ctx context.Context, path string) (*File, error) {
 
 	file.Md5Sum = base64.StdEncoding.EncodeToString(md5Hex)
 
-	// Get content if requested
+	file.Sha256Sum, err = parseChecksum(stdoutLines[2], sha256.Size)
+	if err != nil {
+		return nil, errors.Join(ErrFileUnexpected, err)
+	}
+
+	file.Sha512Sum, err = parseChecksum(stdoutLines[3], sha512.Size)
+	if err != nil {
+		return nil, errors.Join(ErrFileUnexpected, err)
+	}
+
+	// Stream content if requested. The content is read by a command which is started by the file system of the system.
 	if c.includeContent {
-		catCmd := NewCommand(fmt.Sprintf(`cat '%s'`, path))
-		catRes, err := ExecuteCommand(ctx, c.s, catCmd)
+		err = c.checkContentSize(parsedStat.Size)
 		if err != nil {
-			return nil, errors.Join(ErrFileUnexpected, err)
+			return nil, err
 		}
 
-		if err := catRes.Error(); err != nil {
+		f, err := c.s.Open(ctx, path)
+		if err != nil {
 			return nil, errors.Join(ErrFileUnexpected, err)
 		}
+		defer func() {
+			_ = f.Close()
+		}()
 
-		file.Content = bytes.NewReader(catRes.Stdout)
+		file.Content, err = c.readContent(path, f)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	return file, nil
 }
 
+// checkContentSize returns ErrFileContentLimit if the content in the range of FileClientContentRange of a file with
+// size bytes exceeds the limit of FileClientContentLimit
+func (c *fileClient) checkContentSize(size int64) error {
+	if c.contentLimit < 0 {
+		return nil
+	}
+
+	n := max(size-c.contentOffset, 0)
+	if c.contentLength >= 0 {
+		n = min(n, c.contentLength)
+	}
+
+	if n > c.contentLimit {
+		return ErrFileContentLimit
+	}
+
+	return nil
+}
+
+// readContent seeks f and reads the content in the range of FileClientContentRange. At most one byte more than the limit
+// of FileClientContentLimit is read to detect content which exceeds the limit.
+func (c *fileClient) readContent(path string, f fs.File) (io.Reader, error) {
+	seeker, ok := f.(io.Seeker)
+	if !ok {
+		return nil, errors.Join(ErrFileUnexpected, fmt.Errorf("partial read of %q is not supported", path))
+	}
+
+	_, err := seeker.Seek(c.contentOffset, io.SeekStart)
+	if err != nil {
+		return nil, errors.Join(ErrFileUnexpected, err)
+	}
+
+	var r io.Reader = f
+	if c.contentLength >= 0 {
+		r = io.LimitReader(r, c.contentLength)
+	}
+	if c.contentLimit >= 0 {
+		r = io.LimitReader(r, c.contentLimit+1)
+	}
+
+	content := &contentBuffer{limit: c.contentLimit}
+	_, err = io.Copy(content, r)
+	if err != nil {
+		return nil, errors.Join(ErrFileUnexpected, err)
+	}
+
+	if content.exceeded {
+		return nil, ErrFileContentLimit
+	}
+
+	return &content.Buffer, nil
+}
+
+// contentBuffer retains content up to limit bytes and discards the remaining content. A negative limit retains the
+// complete content.
+type contentBuffer struct {
+	bytes.Buffer
+
+	limit int64
+
+	// exceeded is true if content has been discarded
+	exceeded bool
+}
+
+func (b *contentBuffer) Write(p []byte) (int, error) {
+	if b.exceeded || (b.limit >= 0 && int64(b.Len()+len(p)) > b.limit) {
+		b.exceeded = true
+		return len(p), nil
+	}
+
+	return b.Buffer.Write(p)
+}
+
+// checksumCommand returns a command which prints the SHA-2 checksum with the number of bits of the file at pathSub in
+// hexadecimal encoding. The command uses `sha256sum` or `sha512sum` and falls back to `shasum` and `openssl` on systems
+// like BusyBox where the applets may be unavailable. The command prints nothing if none of the tools is available.
+func checksumCommand(bits int, pathSub string) string {
+	return fmt.Sprintf(`{ if command -v sha%[1]dsum >/dev/null 2>&1; then sha%[1]dsum %[2]s; elif command -v shasum >/dev/null 2>&1; then shasum -a %[1]d %[2]s; elif command -v openssl >/dev/null 2>&1; then openssl dgst -sha%[1]d -r %[2]s; fi; } | cut -d ' ' -f 1`, bits, pathSub)
+}
+
+// parseChecksum validates a checksum in hexadecimal encoding with the expected size in bytes. An empty line results
+// in an empty checksum.
+func parseChecksum(line string, size int) (string, error) {
+	sum := strings.ToLower(strings.TrimSpace(line))
+	if sum == "" {
+		return "", nil
+	}
+
+	decoded, err := hex.DecodeString(sum)
+	if err != nil {
+		return "", err
+	}
+
+	if len(decoded) != size {
+		return "", fmt.Errorf("unexpected checksum %q", sum)
+	}
+
+	return sum, nil
+}
+
+// Create creates the File. If the system accesses files directly, the content is uploaded without executing commands.
 func (c *fileClient) Create(ctx context.Context, f File) error {
+	if c.validateCommand != "" && !c.atomic {
+		return errFileValidationNotAtomic
+	}
+
+	// Files are copied on the system by commands
+	if system.IsDirectFS(ctx, c.s) && f.CopyFrom == "" {
+		return c.createDirect(ctx, f)
+	}
+
+	return c.createShell(ctx, f)
+}
+
+func (c *fileClient) createDirect(ctx context.Context, f File) error {
+	_, err := c.s.Stat(ctx, f.Path)
+	if err == nil {
+		return ErrFileExists
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return errors.Join(ErrFile, err)
+	}
+
+	if !c.atomic {
+		err = c.upload(ctx, f.Path, f.Mode, f.Content)
+		if err != nil {
+			return errors.Join(ErrFile, err)
+		}
+
+		createCmds := fileOwnerCommands(f, `"${path}"`)
+		if len(createCmds) == 0 {
+			return nil
+		}
+
+		return c.executeFileScript(ctx, fileScript(f.Path, "", "", 0, createCmds), nil)
+	}
+
+	tmp := newFileTempPath(f.Path)
+
+	err = c.upload(ctx, tmp, f.Mode, f.Content)
+	if err != nil {
+		c.removeTemp(ctx, tmp)
+		return errors.Join(ErrFile, err)
+	}
+
+	createCmds := fileOwnerCommands(f, `"${tmp}"`)
+	createCmds = append(createCmds, c.commitCommands()...)
+
+	return c.executeFileScript(ctx, fileScript(f.Path, tmp, `[ ! -e "${path}" ]`, codeFilePathExists, createCmds), nil)
+}
+
+func (c *fileClient) createShell(ctx context.Context, f File) error {
 	pathSub := `"${path}"`
 
+	// Write to a temporary file if atomic writes are enabled
+	var tmp string
+	if c.atomic {
+		tmp = newFileTempPath(f.Path)
+		pathSub = `"${tmp}"`
+	}
+
 	var createCmds []Command
 	var createCmdIn io.Reader
 
-	if f.Content != nil {
+	if f.CopyFrom != "" {
+		// File content is copied from a file on the system
+		createCmds = append(createCmds, copyFromCommand(f.CopyFrom, f.CopyFromSha256Sum, pathSub))
+	} else if f.Content != nil {
 		// File content is provided from io.Reader
+		var contentCmd Command
+		var err error
 
-		if !c.compress {
-			// Without transport compression
-			createCmds = append(createCmds, NewCommand(fmt.Sprintf(`cat - > %s`, pathSub)))
-			createCmdIn = f.Content
-		} else {
-			// With transport compression
-			createCmds = append(createCmds, NewCommand(fmt.Sprintf(`gzip -d > %s`, pathSub)))
-
-			// Setup pipe to compress source
-			pipeReader, pipeWriter := io.Pipe()
-			gzipWriter, err := gzip.NewWriterLevel(pipeWriter, gzip.BestCompression)
-			if err != nil {
-				return errors.Join(ErrFile, err)
-			}
-
-			go func() {
-				_, _ = io.Copy(gzipWriter, f.Content)
-				_ = gzipWriter.Close()
-				_ = pipeWriter.Close()
-			}()
-
-			// Remote command stdin is the pipe output
-			createCmdIn = pipeReader
+		contentCmd, createCmdIn, err = c.contentCommand(f.Content, pathSub)
+		if err != nil {
+			return errors.Join(ErrFile, err)
 		}
+
+		createCmds = append(createCmds, contentCmd)
 	} else {
 		// Create file without content
 		createCmds = append(createCmds, NewCommand(fmt.Sprintf(`touch %s`, pathSub)))
@@ -197,20 +519,203 @@ func (c *fileClient) Create(ctx context.Context, f File) error {
 		createCmds = append(createCmds, &ChmodCommand{Path: pathSub, Mode: f.Mode})
 	}
 
-	if f.Uid != -1 {
-		createCmds = append(createCmds, &ChownCommand{Path: pathSub, User: strconv.Itoa(f.Uid)})
-	} else if f.User != "" {
-		createCmds = append(createCmds, &ChownCommand{Path: pathSub, User: f.User})
+	createCmds = append(createCmds, fileOwnerCommands(f, pathSub)...)
+
+	if tmp != "" {
+		createCmds = append(createCmds, c.commitCommands()...)
 	}
 
-	if f.Gid != -1 {
-		createCmds = append(createCmds, &ChgrpCommand{Path: pathSub, Group: strconv.Itoa(f.Gid)})
-	} else if f.Group != "" {
-		createCmds = append(createCmds, &ChgrpCommand{Path: pathSub, Group: f.Group})
+	return c.executeFileScript(ctx, fileScript(f.Path, tmp, `[ ! -e "${path}" ]`, codeFilePathExists, createCmds), createCmdIn)
+}
+
+// Update updates the File. If the system accesses files directly, the content is uploaded without executing commands.
+func (c *fileClient) Update(ctx context.Context, f File) error {
+	if c.validateCommand != "" && !c.atomic {
+		return errFileValidationNotAtomic
 	}
 
-	cmd := NewInputCommand(fmt.Sprintf(`_do() { path=$1; [ ! -e "${path}" ] || return %[2]d; { %[3]s; } || return 1; }; _do '%[1]s';`, f.Path, codeFilePathExists, CompositeCommand(createCmds).Command()), createCmdIn)
-	res, err := ExecuteCommand(ctx, c.s, cmd)
+	// Files are copied on the system by commands
+	if system.IsDirectFS(ctx, c.s) && f.CopyFrom == "" {
+		return c.updateDirect(ctx, f)
+	}
+
+	return c.updateShell(ctx, f)
+}
+
+func (c *fileClient) updateDirect(ctx context.Context, f File) error {
+	fileInfo, err := c.s.Stat(ctx, f.Path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return ErrFileNotFound
+	} else if err != nil {
+		return errors.Join(ErrFile, err)
+	}
+
+	if !fileInfo.Mode().IsRegular() {
+		return ErrFileNotFound
+	}
+
+	if f.Content != nil && c.atomic {
+		// Retain the mode of the file unless the mode is changed
+		if f.Mode == 0 {
+			f.Mode = fileInfo.Mode().Perm()
+		}
+
+		tmp := newFileTempPath(f.Path)
+
+		err = c.upload(ctx, tmp, f.Mode, f.Content)
+		if err != nil {
+			c.removeTemp(ctx, tmp)
+			return errors.Join(ErrFile, err)
+		}
+
+		updateCmds := filePreserveCommands(f)
+		updateCmds = append(updateCmds, fileOwnerCommands(f, `"${tmp}"`)...)
+		updateCmds = append(updateCmds, c.commitCommands()...)
+
+		return c.executeFileScript(ctx, fileScript(f.Path, tmp, `[ -f "${path}" ]`, codeFileNotFound, updateCmds), nil)
+	}
+
+	pathSub := `"${path}"`
+
+	var updateCmds []Command
+
+	if f.Content != nil {
+		// The mode is applied by the upload
+		err = c.upload(ctx, f.Path, f.Mode, f.Content)
+		if err != nil {
+			return errors.Join(ErrFile, err)
+		}
+	} else if f.Mode != 0 {
+		updateCmds = append(updateCmds, &ChmodCommand{Path: pathSub, Mode: f.Mode})
+	}
+
+	updateCmds = append(updateCmds, fileOwnerCommands(f, pathSub)...)
+
+	if len(updateCmds) == 0 {
+		return nil
+	}
+
+	return c.executeFileScript(ctx, fileScript(f.Path, "", "", 0, updateCmds), nil)
+}
+
+func (c *fileClient) updateShell(ctx context.Context, f File) error {
+	pathSub := `"${path}"`
+
+	// Write changed content to a temporary file if atomic writes are enabled
+	var tmp string
+	if c.atomic && (f.Content != nil || f.CopyFrom != "") {
+		tmp = newFileTempPath(f.Path)
+		pathSub = `"${tmp}"`
+	}
+
+	var updateCmds []Command
+	var updateCmdIn io.Reader
+
+	if f.CopyFrom != "" {
+		// File content is copied from a file on the system
+		updateCmds = append(updateCmds, copyFromCommand(f.CopyFrom, f.CopyFromSha256Sum, pathSub))
+	} else if f.Content != nil {
+		// File content is provided from io.Reader
+		var contentCmd Command
+		var err error
+
+		contentCmd, updateCmdIn, err = c.contentCommand(f.Content, pathSub)
+		if err != nil {
+			return errors.Join(ErrFile, err)
+		}
+
+		updateCmds = append(updateCmds, contentCmd)
+	}
+
+	if tmp != "" {
+		updateCmds = append(updateCmds, filePreserveCommands(f)...)
+	}
+
+	if f.Mode != 0 {
+		updateCmds = append(updateCmds, &ChmodCommand{Path: pathSub, Mode: f.Mode})
+	}
+
+	updateCmds = append(updateCmds, fileOwnerCommands(f, pathSub)...)
+
+	if len(updateCmds) == 0 {
+		// Nothing to do because up-to-date
+		return nil
+	}
+
+	if tmp != "" {
+		updateCmds = append(updateCmds, c.commitCommands()...)
+	}
+
+	return c.executeFileScript(ctx, fileScript(f.Path, tmp, `[ -f "${path}" ]`, codeFileNotFound, updateCmds), updateCmdIn)
+}
+
+// contentCommand returns a Command which writes its standard input to the file at pathSub and the standard input.
+// The standard input is compressed if compression is enabled.
+func (c *fileClient) contentCommand(content io.Reader, pathSub string) (Command, io.Reader, error) {
+	if !c.compress {
+		// Without transport compression
+		return NewCommand(fmt.Sprintf(`cat - > %s`, pathSub)), content, nil
+	}
+
+	// With transport compression
+	// Setup pipe to compress source
+	pipeReader, pipeWriter := io.Pipe()
+	gzipWriter, err := gzip.NewWriterLevel(pipeWriter, gzip.BestCompression)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	go func() {
+		_, _ = io.Copy(gzipWriter, content)
+		_ = gzipWriter.Close()
+		_ = pipeWriter.Close()
+	}()
+
+	// Remote command stdin is the pipe output
+	return NewCommand(fmt.Sprintf(`gzip -d > %s`, pathSub)), pipeReader, nil
+}
+
+// copyFromCommand returns a Command which copies the content of the file at src on the system to the file at pathSub.
+// The file at pathSub retains its mode and owner. The file at pathSub is not truncated if src is not a readable file or
+// if the SHA-256 checksum of src does not match expectedSha256Sum unless expectedSha256Sum is empty.
+func copyFromCommand(src string, expectedSha256Sum string, pathSub string) Command {
+	var verifyCmd string
+	if expectedSha256Sum != "" {
+		verifyCmd = fmt.Sprintf(`{ [ "$(%[1]s)" = '%[2]s' ] || return %[3]d; } && `, checksumCommand(256, fmt.Sprintf(`'%s'`, src)), expectedSha256Sum, codeFileChecksumMismatch)
+	}
+
+	return NewCommand(fmt.Sprintf(`[ -f '%[1]s' ] && [ -r '%[1]s' ] && %[3]scat -- '%[1]s' > %[2]s`, src, pathSub, verifyCmd))
+}
+
+// upload writes content to the file at path using system.WriteFS. The permissions of the file are set to mode unless
+// mode is 0. The file is empty if content is nil.
+func (c *fileClient) upload(ctx context.Context, path string, mode fs.FileMode, content io.Reader) error {
+	fileInfo := (&stat.Stat{Name: path, Mode: stat.FileMode(mode.Perm())}).ToFsFileInfo()
+
+	w, err := c.s.Create(ctx, fileInfo)
+	if err != nil {
+		return err
+	}
+
+	if content != nil {
+		_, err = io.Copy(w, content)
+		if err != nil {
+			_ = w.Close()
+			return err
+		}
+	}
+
+	return w.Close()
+}
+
+// removeTemp removes the temporary file at tmp. Errors are ignored because the file may not have been created.
+func (c *fileClient) removeTemp(ctx context.Context, tmp string) {
+	_, _ = ExecuteCommand(ctx, c.s, NewCommand(fmt.Sprintf(`rm -f '%s'`, tmp)))
+}
+
+// executeFileScript executes a script returned by fileScript
+func (c *fileClient) executeFileScript(ctx context.Context, script string, stdin io.Reader) error {
+	res, err := ExecuteCommand(ctx, c.s, NewInputCommand(script, stdin))
 	if err != nil {
 		return errors.Join(ErrFile, err)
 	}
@@ -218,6 +723,14 @@ func (c *fileClient) Create(ctx context.Context, f File) error {
 	switch res.ExitCode {
 	case codeFilePathExists:
 		return ErrFileExists
+	case codeFileNotFound:
+		return ErrFileNotFound
+	case codeFileValidation:
+		return &FileValidationError{Output: strings.TrimSpace(string(res.Stderr))}
+	case codeFileChecksumMismatch:
+		return errors.Join(ErrFileChecksumMismatch, errors.New("content does not match the expected SHA-256 checksum"))
+	case codeFileHardLinks:
+		return errors.Join(ErrFileHardLinks, errors.New("atomic writes are not supported for files with multiple hard links"))
 	}
 
 	err = res.Error()
@@ -228,79 +741,133 @@ func (c *fileClient) Create(ctx context.Context, f File) error {
 	return nil
 }
 
-func (c *fileClient) Update(ctx context.Context, f File) error {
-	pathSub := `"${path}"`
+// fileScript returns a script which executes cmds for the file at path. The path is available as "${path}" to cmds.
+// If test is not empty, the script returns code unless test succeeds. If tmp is not empty, the temporary file at tmp is
+// available as "${tmp}" to cmds and removed if test or cmds fail.
+func fileScript(path string, tmp string, test string, code int, cmds []Command) string {
+	vars := `path=$1;`
+	args := fmt.Sprintf(`'%s'`, path)
+
+	var cleanup string
+	if tmp != "" {
+		vars += ` tmp=$2;`
+		args += fmt.Sprintf(` '%s'`, tmp)
+		cleanup = `rm -f "${tmp}"; `
+	}
 
-	var updateCmds []Command
-	var updateCmdIn io.Reader
+	var precondition string
+	if test != "" {
+		precondition = fmt.Sprintf(`%s || { %sreturn %d; }; `, test, cleanup, code)
+	}
 
-	if f.Content != nil {
-		// File content is provided from io.Reader
+	return fmt.Sprintf(`_do() { %[1]s %[2]s{ %[3]s; } || { %[4]sreturn 1; }; }; _do %[5]s;`, vars, precondition, CompositeCommand(cmds).Command(), cleanup, args)
+}
 
-		if !c.compress {
-			// Without transport compression
-			updateCmds = append(updateCmds, NewCommand(fmt.Sprintf(`cat - > %s`, pathSub)))
-			updateCmdIn = f.Content
-		} else {
-			// With transport compression
-			updateCmds = append(updateCmds, NewCommand(fmt.Sprintf(`gzip -d > %s`, pathSub)))
-
-			// Setup pipe to compress source
-			pipeReader, pipeWriter := io.Pipe()
-			gzipWriter, err := gzip.NewWriterLevel(pipeWriter, gzip.BestCompression)
-			if err != nil {
-				return errors.Join(ErrFile, err)
-			}
+// newFileTempPath returns a random path of a temporary file in the directory of path
+func newFileTempPath(path string) string {
+	b := make([]byte, 6)
+	_, _ = rand.Read(b)
 
-			go func() {
-				_, _ = io.Copy(gzipWriter, f.Content)
-				_ = gzipWriter.Close()
-				_ = pipeWriter.Close()
-			}()
+	i := strings.LastIndex(path, "/")
 
-			// Remote command stdin is the pipe output
-			updateCmdIn = pipeReader
-		}
+	return path[:i+1] + "." + path[i+1:] + ".tmp-" + hex.EncodeToString(b)
+}
+
+// commitCommands returns the commands which validate the temporary file "${tmp}", flush it to disk and rename it to
+// "${path}". Flushing falls back to all file systems if sync does not accept files. If "${path}" is a symbolic link,
+// "${tmp}" is moved to the directory of the resolved target and renamed to the target, so that the link is retained. The
+// commands fail with codeFileHardLinks if the file has multiple hard links because the rename would detach the file from
+// its other links.
+func (c *fileClient) commitCommands() []Command {
+	var cmds []Command
+
+	if c.validateCommand != "" {
+		validateCmd := strings.ReplaceAll(c.validateCommand, "%s", `"${tmp}"`)
+		cmds = append(cmds, NewCommand(fmt.Sprintf(`{ ( %[1]s ) </dev/null >&2 || { rm -f "${tmp}"; return %[2]d; }; }`, validateCmd, codeFileValidation)))
 	}
 
-	if f.Mode != 0 {
-		updateCmds = append(updateCmds, &ChmodCommand{Path: pathSub, Mode: f.Mode})
+	return append(cmds,
+		NewCommand(fmt.Sprintf(`{ [ "$(stat -L -c %%h "${path}" 2>/dev/null || echo 1)" -le 1 ] || { rm -f "${tmp}"; return %[1]d; }; }`, codeFileHardLinks)),
+		NewCommand(`{ if [ -L "${path}" ]; then `+
+			`target=$(readlink -f -- "${path}") && staged="${target%/*}/.${target##*/}.tmp-${tmp##*.tmp-}" && `+
+			`{ mv -f "${tmp}" "${staged}" && { sync "${staged}" 2>/dev/null || sync; } && mv -f "${staged}" "${target}" || { rm -f "${staged}"; return 1; }; }; `+
+			`else { sync "${tmp}" 2>/dev/null || sync; } && mv -f "${tmp}" "${path}"; fi; }`),
+	)
+}
+
+// filePreserveCommands returns the commands which apply the mode and the owner of "${path}" to the temporary file
+// "${tmp}" unless they are defined by f
+func filePreserveCommands(f File) []Command {
+	var cmds []Command
+
+	if f.Mode == 0 {
+		cmds = append(cmds, NewCommand(`chmod "$(stat -L -c %a "${path}")" "${tmp}"`))
+	}
+
+	if f.Uid == -1 && f.User == "" {
+		cmds = append(cmds, NewCommand(`{ owner=$(stat -L -c %u "${path}") && { [ "$(stat -c %u "${tmp}")" = "${owner}" ] || chown "${owner}" "${tmp}"; }; }`))
 	}
 
+	if f.Gid == -1 && f.Group == "" {
+		cmds = append(cmds, NewCommand(`{ group=$(stat -L -c %g "${path}") && { [ "$(stat -c %g "${tmp}")" = "${group}" ] || chgrp "${group}" "${tmp}"; }; }`))
+	}
+
+	return cmds
+}
+
+// fileOwnerCommands returns the commands which change the owner of the file at pathSub to the owner of f
+func fileOwnerCommands(f File, pathSub string) []Command {
+	var cmds []Command
+
 	if f.Uid != -1 {
-		updateCmds = append(updateCmds, &ChownCommand{Path: pathSub, User: strconv.Itoa(f.Uid)})
+		cmds = append(cmds, &ChownCommand{Path: pathSub, User: strconv.Itoa(f.Uid)})
 	} else if f.User != "" {
-		updateCmds = append(updateCmds, &ChownCommand{Path: pathSub, User: f.User})
+		cmds = append(cmds, &ChownCommand{Path: pathSub, User: f.User})
 	}
 
 	if f.Gid != -1 {
-		updateCmds = append(updateCmds, &ChgrpCommand{Path: pathSub, Group: strconv.Itoa(f.Gid)})
+		cmds = append(cmds, &ChgrpCommand{Path: pathSub, Group: strconv.Itoa(f.Gid)})
 	} else if f.Group != "" {
-		updateCmds = append(updateCmds, &ChgrpCommand{Path: pathSub, Group: f.Group})
+		cmds = append(cmds, &ChgrpCommand{Path: pathSub, Group: f.Group})
 	}
 
-	if len(updateCmds) == 0 {
-		// Nothing to do because up-to-date
-		return nil
+	return cmds
+}
+
+// Backup copies the file at path including mode, owner and timestamps to a new backup. Backups which exceed the
+// retention are removed. An existing backup is never overwritten; Backup returns ErrFileExists if a backup with the same
+// name exists.
+func (c *fileClient) Backup(ctx context.Context, path string, b FileBackup) (string, error) {
+	suffix := b.Suffix
+	if suffix == "" {
+		suffix = FileBackupDefaultSuffix
+		b.Suffix = suffix
+	}
+
+	var retainCmd string
+	if b.Retention > 0 {
+		retainCmd = fmt.Sprintf(`{ ls -1d -- "${dir}/${name}"%[1]s 2>/dev/null | while IFS= read -r old; do printf '%%s\n' "${old#"${dir}/${name}"}" | grep -Eqx -- '%[2]s' && printf '%%s\n' "${old}"; done | sort -r | tail -n +%[3]d | while IFS= read -r old; do rm -f -- "${old}"; done; };`, b.glob(), b.pattern(), b.Retention+1)
 	}
 
-	cmd := NewInputCommand(fmt.Sprintf(`_do() { path=$1; [ -f "${path}" ] || return %[2]d; { %[3]s; } || return 1; }; _do '%[1]s';`, f.Path, codeFileNotFound, CompositeCommand(updateCmds).Command()), updateCmdIn)
+	cmd := NewCommand(fmt.Sprintf(`_do() { path=$1; dir=$2; [ -f "${path}" ] || return %[4]d; name=$(basename "${path}"); [ -n "${dir}" ] || dir=$(dirname "${path}"); mkdir -p "${dir}" && backup="${dir}/${name}$(date -u +'%[3]s')" || return 1; [ ! -e "${backup}" ] || { echo "${backup}"; return %[6]d; }; cp -p "${path}" "${backup}" || return 1; %[5]s echo "${backup}"; }; _do '%[1]s' '%[2]s';`, path, b.Dir, suffix, codeFileNotFound, retainCmd, codeFilePathExists))
 	res, err := ExecuteCommand(ctx, c.s, cmd)
 	if err != nil {
-		return errors.Join(ErrFile, err)
+		return "", errors.Join(ErrFile, err)
 	}
 
 	switch res.ExitCode {
 	case codeFileNotFound:
-		return ErrFileNotFound
+		return "", ErrFileNotFound
+	case codeFilePathExists:
+		return "", errors.Join(ErrFileExists, fmt.Errorf("backup %q exists", strings.TrimSpace(string(res.Stdout))))
 	}
 
 	err = res.Error()
 	if err != nil {
-		return errors.Join(ErrFile, err)
+		return "", errors.Join(ErrFile, fmt.Errorf("failed to backup %q", path), err)
 	}
 
-	return nil
+	return strings.TrimSpace(string(res.Stdout)), nil
 }
 
 func (c *fileClient) Delete(ctx context.Context, path string) error {
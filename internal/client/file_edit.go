@@ -0,0 +1,68 @@
+package client
+
+import (
+	"context"
+	"github.com/neuspaces/terraform-provider-system/internal/lib/lineedit"
+	"github.com/neuspaces/terraform-provider-system/internal/system"
+	"io"
+	"strings"
+)
+
+// FileEditFunc edits the lines of a file. FileEditFunc returns true if the lines have changed.
+type FileEditFunc func(t *lineedit.Text) (bool, error)
+
+// FileEditClient edits parts of an existing file using a FileClient. All other content of the file, the mode and the
+// owner are retained.
+type FileEditClient interface {
+	// Get returns the lines of the file at path
+	Get(ctx context.Context, path string) (*lineedit.Text, error)
+
+	// Edit applies edit to the lines of the file at path. The file is only written if the lines have changed.
+	Edit(ctx context.Context, path string, edit FileEditFunc) error
+}
+
+// NewFileEditClient returns a FileEditClient. The options are applied to the FileClient which writes the file.
+func NewFileEditClient(s system.System, opts ...FileClientOpt) FileEditClient {
+	return &fileEditClient{
+		reader: NewFileClient(s, FileClientIncludeContent(true)),
+		writer: NewFileClient(s, opts...),
+	}
+}
+
+type fileEditClient struct {
+	reader FileClient
+	writer FileClient
+}
+
+func (c *fileEditClient) Get(ctx context.Context, path string) (*lineedit.Text, error) {
+	f, err := c.reader.Get(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := io.ReadAll(f.Content)
+	if err != nil {
+		return nil, err
+	}
+
+	return lineedit.Parse(string(content)), nil
+}
+
+func (c *fileEditClient) Edit(ctx context.Context, path string, edit FileEditFunc) error {
+	t, err := c.Get(ctx, path)
+	if err != nil {
+		return err
+	}
+
+	changed, err := edit(t)
+	if err != nil || !changed {
+		return err
+	}
+
+	return c.writer.Update(ctx, File{
+		Path:    path,
+		Uid:     -1,
+		Gid:     -1,
+		Content: strings.NewReader(t.String()),
+	})
+}
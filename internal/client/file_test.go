@@ -0,0 +1,52 @@
+package client_test
+
+import (
+	"context"
+	"github.com/neuspaces/terraform-provider-system/internal/client"
+	"github.com/neuspaces/terraform-provider-system/internal/system/local"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileClient_Backup_retention(t *testing.T) {
+	dir := t.TempDir()
+
+	// The name of app is a prefix of the name of app.conf
+	appPath := filepath.Join(dir, "app")
+	confPath := filepath.Join(dir, "app.conf")
+	require.NoError(t, os.WriteFile(appPath, []byte("app"), 0o644))
+	require.NoError(t, os.WriteFile(confPath, []byte("conf"), 0o644))
+
+	s, err := local.NewSystem()
+	require.NoError(t, err)
+
+	c := client.NewFileClient(s)
+	b := client.FileBackup{Retention: 2}
+
+	var confBackups []string
+	for i := 0; i < 3; i++ {
+		backup, err := c.Backup(context.Background(), confPath, b)
+		require.NoError(t, err)
+		confBackups = append(confBackups, backup)
+	}
+
+	var appBackups []string
+	for i := 0; i < 3; i++ {
+		backup, err := c.Backup(context.Background(), appPath, b)
+		require.NoError(t, err)
+		appBackups = append(appBackups, backup)
+	}
+
+	// The retention of app removes only the oldest backup of app
+	assert.NoFileExists(t, appBackups[0])
+	assert.FileExists(t, appBackups[1])
+	assert.FileExists(t, appBackups[2])
+
+	// The retention of app does not remove the backups of app.conf
+	assert.NoFileExists(t, confBackups[0])
+	assert.FileExists(t, confBackups[1])
+	assert.FileExists(t, confBackups[2])
+}
@@ -0,0 +1,457 @@
+package client
+
+import (
+	"archive/tar"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/neuspaces/terraform-provider-system/internal/system"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// FolderSync mirrors a local directory to a directory on the system
+type FolderSync struct {
+	// Path is the directory on the system
+	Path string
+
+	// Source is the local directory
+	Source string
+
+	// Exclude contains glob patterns of paths relative to Source which are neither uploaded nor purged. A pattern
+	// without a slash matches the name of a file or directory at any depth. The content of an excluded directory is
+	// excluded.
+	Exclude []string
+
+	// FileMode and DirMode override the permissions of the local files and directories unless they are 0
+	FileMode fs.FileMode
+	DirMode  fs.FileMode
+
+	// User and Group optionally own all synchronized files and directories
+	User  string
+	Group string
+
+	// Purge removes files and directories which do not exist in Source
+	Purge bool
+}
+
+// FolderManifest contains the SHA-256 checksum in hexadecimal encoding of each regular file by the slash-separated path
+// relative to the directory
+type FolderManifest map[string]string
+
+type FolderSyncClient interface {
+	// Get returns the FolderManifest of all files in the directory on the system which are not excluded
+	Get(ctx context.Context, f FolderSync) (FolderManifest, error)
+
+	// Sync uploads all files of the local directory which are missing or differ on the system and applies the
+	// permission and owner overrides
+	Sync(ctx context.Context, f FolderSync) error
+
+	// Delete removes the files in m and the directories which become empty. The directory itself is not removed.
+	Delete(ctx context.Context, f FolderSync, m FolderManifest) error
+}
+
+func NewFolderSyncClient(s system.System) FolderSyncClient {
+	return &folderSyncClient{
+		s: s,
+	}
+}
+
+var (
+	ErrFolderSync = errors.New("folder sync resource")
+
+	ErrFolderSyncNotFound = errors.Join(ErrFolderSync, errors.New("folder not found"))
+
+	ErrFolderSyncSource = errors.Join(ErrFolderSync, errors.New("invalid source"))
+
+	ErrFolderSyncUnexpected = errors.Join(ErrFolderSync, errors.New("unexpected error"))
+)
+
+const (
+	codeFolderSyncNotFound = 17
+)
+
+type folderSyncClient struct {
+	s system.System
+}
+
+// excluded returns true if the slash-separated path rel or one of its parent directories matches an exclude pattern
+func (f FolderSync) excluded(rel string) bool {
+	for p := rel; p != "." && p != "/" && p != ""; p = path.Dir(p) {
+		for _, pattern := range f.Exclude {
+			pattern = strings.Trim(pattern, "/")
+
+			name := p
+			if !strings.Contains(pattern, "/") {
+				name = path.Base(p)
+			}
+
+			if matched, _ := path.Match(pattern, name); matched {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// localFolder contains the files and directories of a local directory by the slash-separated path relative to the
+// directory
+type localFolder struct {
+	manifest FolderManifest
+	files    map[string]fs.FileMode
+	dirs     map[string]fs.FileMode
+}
+
+// LocalFolderManifest returns the FolderManifest of the local directory Source
+func LocalFolderManifest(f FolderSync) (FolderManifest, error) {
+	local, err := readLocalFolder(f)
+	if err != nil {
+		return nil, err
+	}
+
+	return local.manifest, nil
+}
+
+// readLocalFolder walks the local directory. Symbolic links and special files are skipped.
+func readLocalFolder(f FolderSync) (*localFolder, error) {
+	local := &localFolder{
+		manifest: FolderManifest{},
+		files:    map[string]fs.FileMode{},
+		dirs:     map[string]fs.FileMode{},
+	}
+
+	err := filepath.WalkDir(f.Source, func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(f.Source, name)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		if strings.ContainsAny(rel, "\n\x00") {
+			return fmt.Errorf("unsupported file name %q", rel)
+		}
+
+		if f.excluded(rel) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case d.IsDir():
+			if rel == "." {
+				return nil
+			}
+			local.dirs[rel] = info.Mode().Perm()
+		case d.Type().IsRegular():
+			sum, err := fileSha256(name)
+			if err != nil {
+				return err
+			}
+			local.manifest[rel] = sum
+			local.files[rel] = info.Mode().Perm()
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Join(ErrFolderSyncSource, err)
+	}
+
+	return local, nil
+}
+
+func fileSha256(name string) (string, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	h := sha256.New()
+	_, err = io.Copy(h, file)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// remoteFolder contains the files and directories of a directory on the system
+type remoteFolder struct {
+	manifest FolderManifest
+	dirs     map[string]bool
+}
+
+func (c *folderSyncClient) Get(ctx context.Context, f FolderSync) (FolderManifest, error) {
+	remote, err := c.get(ctx, f)
+	if err != nil {
+		return nil, err
+	}
+
+	return remote.manifest, nil
+}
+
+func (c *folderSyncClient) get(ctx context.Context, f FolderSync) (*remoteFolder, error) {
+	cmd := NewCommand(fmt.Sprintf(`_do() { path=$1; [ -d "${path}" ] || return %[2]d; cd "${path}" || return 1; if command -v sha256sum >/dev/null 2>&1; then h='sha256sum'; elif command -v shasum >/dev/null 2>&1; then h='shasum -a 256'; else h='openssl dgst -sha256 -r'; fi; { find . -type d -exec printf 'd %%s\0' {} + && find . -type f -exec sh -c 'for p; do printf "%%s %%s\0" "$($0 < "${p}" | cut -d " " -f 1)" "${p}"; done' "${h}" {} +; } || return 1; }; _do '%[1]s';`, f.Path, codeFolderSyncNotFound))
+	res, err := ExecuteCommand(ctx, c.s, cmd)
+	if err != nil {
+		return nil, errors.Join(ErrFolderSyncUnexpected, err)
+	}
+
+	switch res.ExitCode {
+	case codeFolderSyncNotFound:
+		return nil, ErrFolderSyncNotFound
+	}
+
+	if err := res.Error(); err != nil {
+		return nil, errors.Join(ErrFolderSyncUnexpected, err, errors.New(res.StderrString()))
+	}
+
+	remote := &remoteFolder{
+		manifest: FolderManifest{},
+		dirs:     map[string]bool{},
+	}
+
+	// Records are NUL-delimited because names may contain any character but NUL. The checksum of a file is computed
+	// from the standard input, so that the tools do not escape the name.
+	for _, record := range strings.Split(res.StdoutString(), "\x00") {
+		if record == "" {
+			continue
+		}
+
+		if strings.HasPrefix(record, "d ") {
+			rel := strings.TrimPrefix(strings.TrimPrefix(record, "d "), "./")
+			if rel != "." && !f.excluded(rel) {
+				remote.dirs[rel] = true
+			}
+			continue
+		}
+
+		// Record of a file is `<checksum> <name>`
+		if len(record) < 66 || record[64] != ' ' {
+			return nil, errors.Join(ErrFolderSyncUnexpected, fmt.Errorf("unexpected checksum record %q", record))
+		}
+
+		rel := strings.TrimPrefix(record[65:], "./")
+		if !f.excluded(rel) {
+			remote.manifest[rel] = strings.ToLower(record[:64])
+		}
+	}
+
+	return remote, nil
+}
+
+func (c *folderSyncClient) Sync(ctx context.Context, f FolderSync) error {
+	local, err := readLocalFolder(f)
+	if err != nil {
+		return err
+	}
+
+	remote, err := c.get(ctx, f)
+	if errors.Is(err, ErrFolderSyncNotFound) {
+		remote = &remoteFolder{manifest: FolderManifest{}, dirs: map[string]bool{}}
+	} else if err != nil {
+		return err
+	}
+
+	// Upload missing directories and missing or changed files
+	var upload []string
+	for rel, sum := range local.manifest {
+		if remote.manifest[rel] != sum {
+			upload = append(upload, rel)
+		}
+	}
+
+	missingDirs := false
+	for rel := range local.dirs {
+		if !remote.dirs[rel] {
+			missingDirs = true
+		}
+	}
+
+	if len(upload) > 0 || missingDirs || len(remote.dirs) == 0 {
+		err = extractTar(ctx, c.s, f.Path, func(tarWriter *tar.Writer) error {
+			return writeFolderTar(tarWriter, f, local, upload)
+		})
+		if err != nil {
+			return errors.Join(ErrFolderSync, fmt.Errorf("failed to upload to %q", f.Path), err)
+		}
+	}
+
+	// Apply overrides to all synchronized files and directories
+	var files, dirs []string
+	for rel := range local.files {
+		files = append(files, rel)
+	}
+	for rel := range local.dirs {
+		dirs = append(dirs, rel)
+	}
+	dirs = append(dirs, ".")
+
+	if f.FileMode != 0 {
+		err = xargs(ctx, c.s, f.Path, fmt.Sprintf(`chmod %o --`, f.FileMode.Perm()), files)
+		if err != nil {
+			return errors.Join(ErrFolderSync, err)
+		}
+	}
+
+	if f.DirMode != 0 {
+		err = xargs(ctx, c.s, f.Path, fmt.Sprintf(`chmod %o --`, f.DirMode.Perm()), dirs)
+		if err != nil {
+			return errors.Join(ErrFolderSync, err)
+		}
+	}
+
+	if f.User != "" || f.Group != "" {
+		err = chownPaths(ctx, c.s, f.Path, f.User, f.Group, append(files, dirs...))
+		if err != nil {
+			return errors.Join(ErrFolderSync, err)
+		}
+	}
+
+	if !f.Purge {
+		return nil
+	}
+
+	// Remove files and directories which do not exist in the local directory
+	var purgeFiles, purgeDirs []string
+	for rel := range remote.manifest {
+		if _, ok := local.manifest[rel]; !ok {
+			purgeFiles = append(purgeFiles, rel)
+		}
+	}
+	for rel := range remote.dirs {
+		if _, ok := local.dirs[rel]; !ok {
+			purgeDirs = append(purgeDirs, rel)
+		}
+	}
+
+	err = removePaths(ctx, c.s, f.Path, purgeFiles, purgeDirs)
+	if err != nil {
+		return errors.Join(ErrFolderSync, err)
+	}
+
+	return nil
+}
+
+// writeFolderTar writes all local directories and the files in names to tarWriter
+func writeFolderTar(tarWriter *tar.Writer, f FolderSync, local *localFolder, names []string) error {
+	// Parent directories precede their content
+	var dirs []string
+	for rel := range local.dirs {
+		dirs = append(dirs, rel)
+	}
+	sort.Strings(dirs)
+
+	for _, rel := range dirs {
+		mode := local.dirs[rel]
+		if f.DirMode != 0 {
+			mode = f.DirMode.Perm()
+		}
+
+		err := tarWriter.WriteHeader(&tar.Header{
+			Typeflag: tar.TypeDir,
+			Name:     rel + "/",
+			Mode:     int64(mode),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	sort.Strings(names)
+
+	for _, rel := range names {
+		err := writeFolderTarFile(tarWriter, f, rel, local.files[rel])
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func writeFolderTarFile(tarWriter *tar.Writer, f FolderSync, rel string, mode fs.FileMode) error {
+	file, err := os.Open(filepath.Join(f.Source, filepath.FromSlash(rel)))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	if f.FileMode != 0 {
+		mode = f.FileMode.Perm()
+	}
+
+	err = tarWriter.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     rel,
+		Mode:     int64(mode),
+		Size:     info.Size(),
+		ModTime:  info.ModTime(),
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(tarWriter, file)
+
+	return err
+}
+
+func (c *folderSyncClient) Delete(ctx context.Context, f FolderSync, m FolderManifest) error {
+	var files []string
+	dirs := map[string]bool{}
+	for rel := range m {
+		files = append(files, rel)
+
+		for d := path.Dir(rel); d != "."; d = path.Dir(d) {
+			dirs[d] = true
+		}
+	}
+
+	var dirList []string
+	for d := range dirs {
+		dirList = append(dirList, d)
+	}
+
+	err := removePaths(ctx, c.s, f.Path, files, dirList)
+	if err != nil {
+		// The directory may have been removed
+		_, getErr := c.get(ctx, f)
+		if errors.Is(getErr, ErrFolderSyncNotFound) {
+			return nil
+		}
+
+		return errors.Join(ErrFolderSync, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,107 @@
+package client
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/neuspaces/terraform-provider-system/internal/system"
+	"io"
+	"sort"
+)
+
+// extractTar streams a gzip compressed tar archive to the system and extracts the archive into the directory dir. The
+// directory is created if it does not exist. write writes the entries of the archive. The owner of the entries is not
+// restored.
+func extractTar(ctx context.Context, s system.System, dir string, write func(tarWriter *tar.Writer) error) error {
+	pipeReader, pipeWriter := io.Pipe()
+
+	go func() {
+		_ = pipeWriter.CloseWithError(writeTarGzip(pipeWriter, write))
+	}()
+
+	cmd := NewInputCommand(fmt.Sprintf(`_do() { path=$1; mkdir -p "${path}" && gzip -d | tar -x -o -f - -C "${path}"; }; _do '%[1]s';`, dir), pipeReader)
+	res, err := ExecuteCommand(ctx, s, cmd)
+	_ = pipeReader.Close()
+	if err != nil {
+		return err
+	}
+
+	if err := res.Error(); err != nil {
+		return errors.Join(err, errors.New(res.StderrString()))
+	}
+
+	return nil
+}
+
+func writeTarGzip(w io.Writer, write func(tarWriter *tar.Writer) error) error {
+	gzipWriter, err := gzip.NewWriterLevel(w, gzip.BestCompression)
+	if err != nil {
+		return err
+	}
+
+	tarWriter := tar.NewWriter(gzipWriter)
+
+	err = write(tarWriter)
+	if err != nil {
+		return err
+	}
+
+	err = tarWriter.Close()
+	if err != nil {
+		return err
+	}
+
+	return gzipWriter.Close()
+}
+
+// xargs executes command in the directory dir with the relative paths in names as arguments
+func xargs(ctx context.Context, s system.System, dir string, command string, names []string) error {
+	if len(names) == 0 {
+		return nil
+	}
+
+	stdin := &bytes.Buffer{}
+	for _, name := range names {
+		stdin.WriteString(name)
+		stdin.WriteByte(0)
+	}
+
+	cmd := NewInputCommand(fmt.Sprintf(`_do() { cd "$1" && xargs -0 %[2]s; }; _do '%[1]s';`, dir, command), stdin)
+	res, err := ExecuteCommand(ctx, s, cmd)
+	if err != nil {
+		return err
+	}
+
+	if err := res.Error(); err != nil {
+		return errors.Join(err, errors.New(res.StderrString()))
+	}
+
+	return nil
+}
+
+// chownPaths changes the owner of the relative paths in names in the directory dir. Symbolic links are not followed.
+func chownPaths(ctx context.Context, s system.System, dir string, user string, group string, names []string) error {
+	owner := user
+	if group != "" {
+		owner += ":" + group
+	}
+
+	return xargs(ctx, s, dir, fmt.Sprintf(`chown -h -- '%s'`, owner), names)
+}
+
+// removePaths removes the files and the directories which are empty in the directory dir. Directories which are not
+// empty are retained.
+func removePaths(ctx context.Context, s system.System, dir string, files []string, dirs []string) error {
+	err := xargs(ctx, s, dir, `rm -f --`, files)
+	if err != nil {
+		return err
+	}
+
+	// Remove nested directories first
+	sort.Sort(sort.Reverse(sort.StringSlice(dirs)))
+
+	return xargs(ctx, s, dir, `sh -c 'for d in "$@"; do rmdir -- "${d}" 2>/dev/null; done; true' _`, dirs)
+}
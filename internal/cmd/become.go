@@ -0,0 +1,393 @@
+package cmd
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/alessio/shellescape"
+	"io"
+	"strings"
+	"sync"
+)
+
+// BecomeMethod is a method of privilege escalation
+type BecomeMethod string
+
+const (
+	BecomeSudo BecomeMethod = "sudo"
+	BecomeDoas BecomeMethod = "doas"
+	BecomeSu   BecomeMethod = "su"
+)
+
+// BecomeMethods are all supported methods of privilege escalation
+var BecomeMethods = []BecomeMethod{
+	BecomeSudo,
+	BecomeDoas,
+	BecomeSu,
+}
+
+// ErrBecomeIncorrectPassword is returned by a System if the password of the privilege escalation has been rejected
+var ErrBecomeIncorrectPassword = errors.New("become: incorrect password")
+
+// becomeFailures are messages which indicate that a password has been rejected
+var becomeFailures = []string{
+	"Sorry, try again",
+	"incorrect password",
+	"Authentication failure",
+}
+
+// becomeTranscriptMax is the maximum number of bytes of the output before privileges have been escalated which is
+// retained to detect a rejected password
+const becomeTranscriptMax = 4096
+
+type becomeArgs struct {
+	user     string
+	password string
+}
+
+type BecomeOption func(*becomeArgs)
+
+// BecomeUser sets the user as which commands are executed. Defaults to root.
+func BecomeUser(user string) BecomeOption {
+	return func(a *becomeArgs) {
+		a.user = user
+	}
+}
+
+// BecomePassword sets the password which is provided on the standard input when the method prompts for a password.
+// The password is never part of the command.
+func BecomePassword(password string) BecomeOption {
+	return func(a *becomeArgs) {
+		a.password = password
+	}
+}
+
+// BecomeMiddleware returns a Middleware which executes a command using the shell /bin/sh as another user using
+// method. BecomeMiddleware returns an error if the method does not support the options.
+func BecomeMiddleware(method BecomeMethod, opts ...BecomeOption) (Middleware, error) {
+	args := &becomeArgs{
+		user: "root",
+	}
+
+	for _, opt := range opts {
+		opt(args)
+	}
+
+	switch method {
+	case BecomeSudo:
+	case BecomeDoas, BecomeSu:
+		if args.password != "" {
+			// doas and su read the password from a terminal only
+			return nil, fmt.Errorf("become: password is not supported by %s", method)
+		}
+	default:
+		return nil, fmt.Errorf("become: unsupported method %q", method)
+	}
+
+	return func(c Command) Command {
+		if args.password == "" {
+			return NewCommandWithFunc(func() string {
+				return becomeCommandLine(method, args.user, "", c.Command())
+			}, Passthrough(c))
+		}
+
+		return newBecomeCommand(method, *args, c)
+	}, nil
+}
+
+// becomeCommandLine returns the command line which executes script as user. The method prompts with prompt if prompt
+// is not empty.
+func becomeCommandLine(method BecomeMethod, user string, prompt string, script string) string {
+	sh := `/bin/sh -c ` + shellescape.Quote(script)
+
+	switch method {
+	case BecomeDoas:
+		return `doas -n -u ` + shellescape.Quote(user) + ` ` + sh
+	case BecomeSu:
+		return `su -s /bin/sh ` + shellescape.Quote(user) + ` -c ` + shellescape.Quote(script)
+	default:
+		if prompt == "" {
+			return `sudo -n -u ` + shellescape.Quote(user) + ` ` + sh
+		}
+		return `sudo -S -p ` + shellescape.Quote(prompt) + ` -u ` + shellescape.Quote(user) + ` ` + sh
+	}
+}
+
+// becomeCommand is a Command which provides the password on the standard input when the method prompts for it.
+// The standard input of the parent command is withheld until the privileges have been escalated. The escalated
+// shell signals the escalation by writing a marker to the standard error.
+type becomeCommand struct {
+	method  BecomeMethod
+	command string
+
+	// prompt is the password prompt of the method
+	prompt []byte
+	// ready is the marker which is written by the escalated shell
+	ready []byte
+
+	stdin  *becomeStdin
+	stdout *becomeWriter
+	stderr *becomeWriter
+
+	m    sync.Mutex
+	cond *sync.Cond
+
+	password  []byte
+	prompts   int
+	escalated bool
+	done      bool
+
+	// transcript is the output before privileges have been escalated
+	transcript bytes.Buffer
+}
+
+var _ Finisher = &becomeCommand{}
+
+func newBecomeCommand(method BecomeMethod, args becomeArgs, parent Command) *becomeCommand {
+	c := &becomeCommand{
+		method:   method,
+		ready:    []byte(newBecomeMarker("ready")),
+		password: []byte(args.password + "\n"),
+	}
+	c.cond = sync.NewCond(&c.m)
+
+	prompt := newBecomeMarker("prompt")
+	c.prompt = []byte(prompt)
+
+	script := `printf '%s' ` + shellescape.Quote(string(c.ready)) + ` >&2; exec /bin/sh -c ` + shellescape.Quote(parent.Command())
+	c.command = becomeCommandLine(method, args.user, prompt, script)
+
+	c.stdin = &becomeStdin{c: c, r: parent.Stdin()}
+	c.stdout = &becomeWriter{c: c, w: parent.Stdout()}
+	c.stderr = &becomeWriter{c: c, w: parent.Stderr()}
+
+	return c
+}
+
+// newBecomeMarker returns a random marker which does not occur in the output of commands by chance
+func newBecomeMarker(kind string) string {
+	b := make([]byte, 12)
+	_, _ = rand.Read(b)
+	return "[become-" + kind + "-" + hex.EncodeToString(b) + "]"
+}
+
+func (c *becomeCommand) Command() string {
+	return c.command
+}
+
+func (c *becomeCommand) Stdin() io.Reader {
+	return c.stdin
+}
+
+func (c *becomeCommand) Stdout() io.Writer {
+	return c.stdout
+}
+
+func (c *becomeCommand) Stderr() io.Writer {
+	return c.stderr
+}
+
+// Finish releases the standard input and returns ErrBecomeIncorrectPassword if the password has been rejected
+func (c *becomeCommand) Finish(result Result, err error) (Result, error) {
+	c.m.Lock()
+	c.done = true
+	c.cond.Broadcast()
+	c.m.Unlock()
+
+	c.stdout.flush()
+	c.stderr.flush()
+
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	if err == nil && !c.escalated && c.prompts > 0 && c.rejected() {
+		return nil, fmt.Errorf("%w for %s", ErrBecomeIncorrectPassword, c.method)
+	}
+
+	return result, err
+}
+
+// rejected returns true if the password has been rejected. c.m must be held.
+func (c *becomeCommand) rejected() bool {
+	if c.prompts > 1 {
+		return true
+	}
+
+	transcript := c.transcript.String()
+	for _, failure := range becomeFailures {
+		if strings.Contains(transcript, failure) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// onPrompt provides the password on the first prompt and rejects subsequent prompts
+func (c *becomeCommand) onPrompt() {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	c.prompts++
+	if c.prompts > 1 {
+		c.password = nil
+	}
+	c.cond.Broadcast()
+}
+
+func (c *becomeCommand) onReady() {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	c.escalated = true
+	c.password = nil
+	c.cond.Broadcast()
+}
+
+func (c *becomeCommand) isEscalated() bool {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	return c.escalated
+}
+
+func (c *becomeCommand) record(p []byte) {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	if remaining := becomeTranscriptMax - c.transcript.Len(); remaining > 0 {
+		if len(p) > remaining {
+			p = p[:remaining]
+		}
+		c.transcript.Write(p)
+	}
+}
+
+// becomeStdin is the standard input of a becomeCommand
+type becomeStdin struct {
+	c *becomeCommand
+	r io.Reader
+}
+
+func (s *becomeStdin) Read(p []byte) (int, error) {
+	c := s.c
+
+	c.m.Lock()
+	for {
+		switch {
+		case c.escalated:
+			c.m.Unlock()
+			if s.r == nil {
+				return 0, io.EOF
+			}
+			return s.r.Read(p)
+		case c.done || c.prompts > 1:
+			c.m.Unlock()
+			return 0, io.EOF
+		case c.prompts == 1 && len(c.password) > 0:
+			n := copy(p, c.password)
+			c.password = c.password[n:]
+			c.m.Unlock()
+			return n, nil
+		}
+
+		c.cond.Wait()
+	}
+}
+
+// becomeWriter is the standard output or the standard error of a becomeCommand. becomeWriter removes the prompt and
+// the ready marker from the output until the privileges have been escalated.
+type becomeWriter struct {
+	c *becomeCommand
+	w io.Writer
+
+	m   sync.Mutex
+	buf []byte
+}
+
+func (w *becomeWriter) Write(p []byte) (int, error) {
+	w.m.Lock()
+	defer w.m.Unlock()
+
+	if w.c.isEscalated() && len(w.buf) == 0 {
+		return len(p), w.forward(p)
+	}
+
+	w.buf = append(w.buf, p...)
+
+	for !w.c.isEscalated() {
+		i, marker := w.nextMarker()
+		if i < 0 {
+			break
+		}
+
+		w.c.record(w.buf[:i])
+		if err := w.forward(w.buf[:i]); err != nil {
+			return 0, err
+		}
+		w.buf = w.buf[i+len(marker):]
+
+		if bytes.Equal(marker, w.c.ready) {
+			w.c.onReady()
+		} else {
+			w.c.onPrompt()
+		}
+	}
+
+	// Retain bytes which may be the beginning of a marker
+	keep := 0
+	if !w.c.isEscalated() {
+		keep = max(partialSuffix(w.buf, w.c.prompt), partialSuffix(w.buf, w.c.ready))
+	}
+
+	out := w.buf[:len(w.buf)-keep]
+	if !w.c.isEscalated() {
+		w.c.record(out)
+	}
+	if err := w.forward(out); err != nil {
+		return 0, err
+	}
+	w.buf = append([]byte(nil), w.buf[len(w.buf)-keep:]...)
+
+	return len(p), nil
+}
+
+// nextMarker returns the index and the marker of the first marker in the buffer
+func (w *becomeWriter) nextMarker() (int, []byte) {
+	i, marker := -1, []byte(nil)
+	for _, m := range [][]byte{w.c.prompt, w.c.ready} {
+		if j := bytes.Index(w.buf, m); j >= 0 && (i < 0 || j < i) {
+			i, marker = j, m
+		}
+	}
+	return i, marker
+}
+
+func (w *becomeWriter) flush() {
+	w.m.Lock()
+	defer w.m.Unlock()
+
+	_ = w.forward(w.buf)
+	w.buf = nil
+}
+
+func (w *becomeWriter) forward(p []byte) error {
+	if w.w == nil || len(p) == 0 {
+		return nil
+	}
+	_, err := w.w.Write(p)
+	return err
+}
+
+// partialSuffix returns the length of the longest suffix of b which is a proper prefix of marker
+func partialSuffix(b []byte, marker []byte) int {
+	n := min(len(b), len(marker)-1)
+	for ; n > 0; n-- {
+		if bytes.HasSuffix(b, marker[:n]) {
+			return n
+		}
+	}
+	return 0
+}
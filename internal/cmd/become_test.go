@@ -0,0 +1,152 @@
+package cmd_test
+
+import (
+	"bytes"
+	"context"
+	"github.com/neuspaces/terraform-provider-system/internal/cmd"
+	"github.com/neuspaces/terraform-provider-system/internal/extlib/heredoc"
+	"github.com/neuspaces/terraform-provider-system/internal/system/local"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testFakeSudo imitates `sudo [-n] [-S] [-p prompt] [-u user] command...` which accepts the password `secret`
+var testFakeSudo = heredoc.String(`
+	#!/bin/sh
+	prompt="Password:"
+	ask=1
+	while [ $# -gt 0 ]; do
+	  case "$1" in
+	    -n) ask=0; shift ;;
+	    -S) shift ;;
+	    -p) prompt="$2"; shift 2 ;;
+	    -u) shift 2 ;;
+	    *) break ;;
+	  esac
+	done
+	[ "$ask" = 0 ] && exec "$@"
+	tries=0
+	while [ $tries -lt 3 ]; do
+	  printf '%s' "$prompt" >&2
+	  IFS= read -r password || { echo "sudo: no password was provided" >&2; exit 1; }
+	  [ "$password" = "secret" ] && exec "$@"
+	  echo "Sorry, try again." >&2
+	  tries=$((tries+1))
+	done
+	exit 1
+`)
+
+// testFakePath installs fake executables in a temporary directory which takes precedence in PATH
+func testFakePath(t *testing.T, executables map[string]string) {
+	dir := t.TempDir()
+	for name, content := range executables {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0755))
+	}
+
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func TestBecomeMiddleware(t *testing.T) {
+	testFakePath(t, map[string]string{
+		"sudo": testFakeSudo,
+	})
+
+	type testCase struct {
+		Desc         string
+		Method       cmd.BecomeMethod
+		Opts         []cmd.BecomeOption
+		ExpectStdout string
+		ExpectErr    error
+	}
+
+	tcs := []testCase{
+		{
+			Desc:         "sudo without password",
+			Method:       cmd.BecomeSudo,
+			ExpectStdout: "content",
+		},
+		{
+			Desc:         "sudo with password",
+			Method:       cmd.BecomeSudo,
+			Opts:         []cmd.BecomeOption{cmd.BecomePassword("secret"), cmd.BecomeUser("service")},
+			ExpectStdout: "content",
+		},
+		{
+			Desc:      "sudo with incorrect password",
+			Method:    cmd.BecomeSudo,
+			Opts:      []cmd.BecomeOption{cmd.BecomePassword("wrong")},
+			ExpectErr: cmd.ErrBecomeIncorrectPassword,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.Desc, func(t *testing.T) {
+			m, err := cmd.BecomeMiddleware(tc.Method, tc.Opts...)
+			require.NoError(t, err)
+
+			s, err := local.NewSystem(local.CommandMiddleware(m))
+			require.NoError(t, err)
+
+			stdout := &bytes.Buffer{}
+			stderr := &bytes.Buffer{}
+			c := cmd.NewCommand(`cat`, cmd.Stdin(strings.NewReader("content")), cmd.Stdout(stdout), cmd.Stderr(stderr))
+
+			result, err := s.Execute(context.Background(), c)
+			if tc.ExpectErr != nil {
+				assert.ErrorIs(t, err, tc.ExpectErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, 0, result.ExitCode())
+			assert.Equal(t, tc.ExpectStdout, stdout.String())
+			assert.Empty(t, stderr.String())
+
+			// The password is never part of the command
+			assert.NotContains(t, m(c).Command(), "secret")
+		})
+	}
+}
+
+func TestBecomeMiddleware_Unsupported(t *testing.T) {
+	t.Parallel()
+
+	_, err := cmd.BecomeMiddleware(cmd.BecomeDoas, cmd.BecomePassword("secret"))
+	assert.Error(t, err)
+
+	_, err = cmd.BecomeMiddleware(cmd.BecomeSu, cmd.BecomePassword("secret"))
+	assert.Error(t, err)
+
+	_, err = cmd.BecomeMiddleware("runas")
+	assert.Error(t, err)
+}
+
+// TestBecomeMiddleware_Su executes commands using the su of the system which does not prompt for a password if the
+// test runs as root
+func TestBecomeMiddleware_Su(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("requires root")
+	}
+	if _, err := exec.LookPath("su"); err != nil {
+		t.Skip("requires su")
+	}
+
+	m, err := cmd.BecomeMiddleware(cmd.BecomeSu, cmd.BecomeUser("nobody"))
+	require.NoError(t, err)
+
+	s, err := local.NewSystem(local.CommandMiddleware(m))
+	require.NoError(t, err)
+
+	stdout := &bytes.Buffer{}
+	c := cmd.NewCommand(`id -un && cat`, cmd.Stdin(strings.NewReader("content")), cmd.Stdout(stdout))
+
+	result, err := s.Execute(context.Background(), c)
+	require.NoError(t, err)
+	assert.Equal(t, 0, result.ExitCode())
+	assert.Equal(t, "nobody\ncontent", stdout.String())
+}
@@ -0,0 +1,20 @@
+package cmd
+
+// Finisher is implemented by a Command which is notified when its execution by a System has terminated.
+// A Middleware may return a Finisher to release resources or to detect failures which are not reflected by the exit
+// code of the command.
+type Finisher interface {
+	// Finish is invoked with the result and the error of the execution. Finish returns the result and the error which
+	// are returned to the caller of the System.
+	Finish(result Result, err error) (Result, error)
+}
+
+// Finish notifies c that its execution has terminated if c implements Finisher. Finish returns the result and the
+// error of the execution.
+func Finish(c Command, result Result, err error) (Result, error) {
+	if f, ok := c.(Finisher); ok {
+		return f.Finish(result, err)
+	}
+
+	return result, err
+}
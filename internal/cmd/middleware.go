@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"github.com/alessio/shellescape"
+	"io"
 )
 
 // Middleware wraps to a command similar to a http middleware.
@@ -24,3 +25,59 @@ func SudoShMiddleware() Middleware {
 		}, Passthrough(c))
 	}
 }
+
+// MiddlewareCommand is a Command which is executed using its own Middleware instead of the Middleware of the System
+type MiddlewareCommand interface {
+	Command
+
+	// Middleware returns the Middleware which is applied to the Command
+	Middleware() Middleware
+}
+
+type middlewareCommand struct {
+	parent Command
+
+	m Middleware
+}
+
+func (c *middlewareCommand) Command() string {
+	return c.parent.Command()
+}
+
+func (c *middlewareCommand) Stdin() io.Reader {
+	return c.parent.Stdin()
+}
+
+func (c *middlewareCommand) Stdout() io.Writer {
+	return c.parent.Stdout()
+}
+
+func (c *middlewareCommand) Stderr() io.Writer {
+	return c.parent.Stderr()
+}
+
+func (c *middlewareCommand) Middleware() Middleware {
+	return c.m
+}
+
+// WithMiddleware returns a MiddlewareCommand which is executed using m instead of the Middleware of the System
+func WithMiddleware(c Command, m Middleware) MiddlewareCommand {
+	return &middlewareCommand{
+		parent: c,
+		m:      m,
+	}
+}
+
+// Apply applies the Middleware of c if c is a MiddlewareCommand or otherwise the default Middleware m.
+// Apply returns c unchanged if no Middleware applies.
+func Apply(c Command, m Middleware) Command {
+	if mc, ok := c.(MiddlewareCommand); ok {
+		m = mc.Middleware()
+	}
+
+	if m == nil {
+		return c
+	}
+
+	return m(c)
+}
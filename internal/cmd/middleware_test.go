@@ -0,0 +1,20 @@
+package cmd_test
+
+import (
+	"github.com/neuspaces/terraform-provider-system/internal/cmd"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestApply(t *testing.T) {
+	t.Parallel()
+
+	c := cmd.NewCommand(`id`)
+
+	assert.Equal(t, `id`, cmd.Apply(c, nil).Command())
+	assert.Equal(t, `/bin/sh -c id`, cmd.Apply(c, cmd.ShMiddleware()).Command())
+
+	// The Middleware of the command takes precedence over the default Middleware
+	mc := cmd.WithMiddleware(c, cmd.SudoShMiddleware())
+	assert.Equal(t, `sudo /bin/sh -c id`, cmd.Apply(mc, cmd.ShMiddleware()).Command())
+}
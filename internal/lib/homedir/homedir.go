@@ -0,0 +1,22 @@
+package homedir
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Expand replaces a leading `~` in path with the home directory of the current user.
+// Paths which do not start with `~` or `~/` are returned unchanged.
+func Expand(path string) (string, error) {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path, nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(home, strings.TrimPrefix(path, "~")), nil
+}
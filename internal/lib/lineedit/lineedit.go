@@ -0,0 +1,246 @@
+// Package lineedit edits lines and marker-delimited blocks of a text while retaining all other lines.
+package lineedit
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Text is a text split into lines
+type Text struct {
+	Lines []string
+
+	// TrailingNewline is true if the last line is terminated by a newline
+	TrailingNewline bool
+}
+
+// Parse splits content into lines
+func Parse(content string) *Text {
+	if content == "" {
+		return &Text{TrailingNewline: true}
+	}
+
+	t := &Text{
+		TrailingNewline: strings.HasSuffix(content, "\n"),
+	}
+
+	t.Lines = strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+
+	return t
+}
+
+// String joins the lines
+func (t *Text) String() string {
+	if len(t.Lines) == 0 {
+		return ""
+	}
+
+	s := strings.Join(t.Lines, "\n")
+	if t.TrailingNewline {
+		s += "\n"
+	}
+
+	return s
+}
+
+// insert inserts lines at index i
+func (t *Text) insert(i int, lines ...string) {
+	t.Lines = append(t.Lines[:i], append(append([]string{}, lines...), t.Lines[i:]...)...)
+}
+
+// Anchor defines where new lines are inserted. New lines are inserted after the last line which matches After or before
+// the first line which matches Before. New lines are appended to the end of the text if neither After nor Before is
+// defined or no line matches.
+type Anchor struct {
+	After  *regexp.Regexp
+	Before *regexp.Regexp
+}
+
+// index returns the index at which new lines are inserted
+func (a Anchor) index(t *Text) int {
+	if a.After != nil {
+		for i := len(t.Lines) - 1; i >= 0; i-- {
+			if a.After.MatchString(t.Lines[i]) {
+				return i + 1
+			}
+		}
+	}
+
+	if a.Before != nil {
+		for i, l := range t.Lines {
+			if a.Before.MatchString(l) {
+				return i
+			}
+		}
+	}
+
+	return len(t.Lines)
+}
+
+// Line defines a single line
+type Line struct {
+	Line string
+
+	// Regexp optionally matches the line which is replaced by Line. If Regexp is nil, only lines equal to Line match.
+	Regexp *regexp.Regexp
+
+	Anchor Anchor
+}
+
+func (l Line) matches(s string) bool {
+	if l.Regexp != nil {
+		return l.Regexp.MatchString(s)
+	}
+
+	return s == l.Line
+}
+
+// EnsureLine ensures that t contains the line. If Regexp is defined, the last matching line is replaced. Otherwise, the
+// line is inserted at the anchor unless t already contains the line. EnsureLine returns true if t has changed.
+func EnsureLine(t *Text, l Line) bool {
+	if l.Regexp != nil {
+		for i := len(t.Lines) - 1; i >= 0; i-- {
+			if l.Regexp.MatchString(t.Lines[i]) {
+				if t.Lines[i] == l.Line {
+					return false
+				}
+
+				t.Lines[i] = l.Line
+				return true
+			}
+		}
+	}
+
+	for _, s := range t.Lines {
+		if s == l.Line {
+			return false
+		}
+	}
+
+	t.insert(l.Anchor.index(t), l.Line)
+
+	return true
+}
+
+// RemoveLine removes all lines which match the line. RemoveLine returns true if t has changed.
+func RemoveLine(t *Text, l Line) bool {
+	lines := t.Lines[:0]
+	for _, s := range t.Lines {
+		if !l.matches(s) {
+			lines = append(lines, s)
+		}
+	}
+
+	changed := len(lines) != len(t.Lines)
+	t.Lines = lines
+
+	return changed
+}
+
+// ReplaceLine replaces all lines equal to old by new. ReplaceLine returns true if t has changed.
+func ReplaceLine(t *Text, old string, new string) bool {
+	changed := false
+	for i, s := range t.Lines {
+		if s == old && s != new {
+			t.Lines[i] = new
+			changed = true
+		}
+	}
+
+	return changed
+}
+
+// ErrBlockIncomplete is returned if the begin marker of a block is not followed by the end marker
+var ErrBlockIncomplete = errors.New("incomplete block")
+
+// Block defines lines which are delimited by a begin and an end marker line
+type Block struct {
+	Begin string
+	End   string
+
+	Lines []string
+
+	Anchor Anchor
+}
+
+// find returns the indexes of the begin and the end marker. find returns -1 if the block does not exist.
+func (b Block) find(t *Text) (int, int, error) {
+	for i, s := range t.Lines {
+		if s != b.Begin {
+			continue
+		}
+
+		for j := i + 1; j < len(t.Lines); j++ {
+			if t.Lines[j] == b.End {
+				return i, j, nil
+			}
+		}
+
+		return -1, -1, fmt.Errorf("%w: %q is not followed by %q", ErrBlockIncomplete, b.Begin, b.End)
+	}
+
+	return -1, -1, nil
+}
+
+// GetBlock returns the lines between the markers of the block. GetBlock returns false if t does not contain the
+// block.
+func GetBlock(t *Text, b Block) ([]string, bool, error) {
+	begin, end, err := b.find(t)
+	if err != nil || begin < 0 {
+		return nil, false, err
+	}
+
+	return append([]string{}, t.Lines[begin+1:end]...), true, nil
+}
+
+// EnsureBlock ensures that t contains the block. An existing block is replaced in place. Otherwise, the block is
+// inserted at the anchor. EnsureBlock returns true if t has changed.
+func EnsureBlock(t *Text, b Block) (bool, error) {
+	lines := append(append([]string{b.Begin}, b.Lines...), b.End)
+
+	begin, end, err := b.find(t)
+	if err != nil {
+		return false, err
+	}
+
+	if begin < 0 {
+		t.insert(b.Anchor.index(t), lines...)
+		return true, nil
+	}
+
+	if equalLines(t.Lines[begin:end+1], lines) {
+		return false, nil
+	}
+
+	t.Lines = append(t.Lines[:begin], append(lines, t.Lines[end+1:]...)...)
+
+	return true, nil
+}
+
+// RemoveBlock removes the block including the markers. RemoveBlock returns true if t has changed.
+func RemoveBlock(t *Text, b Block) (bool, error) {
+	begin, end, err := b.find(t)
+	if err != nil || begin < 0 {
+		return false, err
+	}
+
+	t.Lines = append(t.Lines[:begin], t.Lines[end+1:]...)
+
+	return true, nil
+}
+
+func equalLines(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,279 @@
+package lineedit_test
+
+import (
+	"github.com/neuspaces/terraform-provider-system/internal/extlib/heredoc"
+	"github.com/neuspaces/terraform-provider-system/internal/lib/lineedit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"regexp"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	for _, content := range []string{"", "a\n", "a\nb\n", "a\nb", "a\n\nb\n"} {
+		assert.Equal(t, content, lineedit.Parse(content).String())
+	}
+}
+
+func TestEnsureLine(t *testing.T) {
+	t.Parallel()
+
+	type testCase struct {
+		Desc          string
+		Content       string
+		Line          lineedit.Line
+		Expect        string
+		ExpectChanged bool
+	}
+
+	tcs := []testCase{
+		{
+			Desc:          "append to empty content",
+			Content:       "",
+			Line:          lineedit.Line{Line: "a"},
+			Expect:        "a\n",
+			ExpectChanged: true,
+		},
+		{
+			Desc:          "append",
+			Content:       "a\nb\n",
+			Line:          lineedit.Line{Line: "c"},
+			Expect:        "a\nb\nc\n",
+			ExpectChanged: true,
+		},
+		{
+			Desc:          "append without trailing newline",
+			Content:       "a\nb",
+			Line:          lineedit.Line{Line: "c"},
+			Expect:        "a\nb\nc",
+			ExpectChanged: true,
+		},
+		{
+			Desc:          "existing line",
+			Content:       "a\nb\nc\n",
+			Line:          lineedit.Line{Line: "b"},
+			Expect:        "a\nb\nc\n",
+			ExpectChanged: false,
+		},
+		{
+			Desc:          "replace last match",
+			Content:       "PermitRootLogin yes\n#PermitRootLogin no\nPort 22\nPermitRootLogin yes\n",
+			Line:          lineedit.Line{Line: "PermitRootLogin no", Regexp: regexp.MustCompile(`^PermitRootLogin\s`)},
+			Expect:        "PermitRootLogin yes\n#PermitRootLogin no\nPort 22\nPermitRootLogin no\n",
+			ExpectChanged: true,
+		},
+		{
+			Desc:          "last match equals line",
+			Content:       "PermitRootLogin yes\nPermitRootLogin no\n",
+			Line:          lineedit.Line{Line: "PermitRootLogin no", Regexp: regexp.MustCompile(`^PermitRootLogin\s`)},
+			Expect:        "PermitRootLogin yes\nPermitRootLogin no\n",
+			ExpectChanged: false,
+		},
+		{
+			Desc:    "insert after last match",
+			Content: "127.0.0.1 localhost\n::1 localhost\n# comment\n",
+			Line: lineedit.Line{
+				Line:   "10.0.0.1 db",
+				Regexp: regexp.MustCompile(`\sdb$`),
+				Anchor: lineedit.Anchor{After: regexp.MustCompile(`localhost$`)},
+			},
+			Expect:        "127.0.0.1 localhost\n::1 localhost\n10.0.0.1 db\n# comment\n",
+			ExpectChanged: true,
+		},
+		{
+			Desc:    "insert before first match",
+			Content: "a\n# end\nb\n# end\n",
+			Line: lineedit.Line{
+				Line:   "c",
+				Anchor: lineedit.Anchor{Before: regexp.MustCompile(`^# end`)},
+			},
+			Expect:        "a\nc\n# end\nb\n# end\n",
+			ExpectChanged: true,
+		},
+		{
+			Desc:    "append if anchor does not match",
+			Content: "a\n",
+			Line: lineedit.Line{
+				Line:   "c",
+				Anchor: lineedit.Anchor{After: regexp.MustCompile(`^b$`)},
+			},
+			Expect:        "a\nc\n",
+			ExpectChanged: true,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.Desc, func(t *testing.T) {
+			t.Parallel()
+
+			text := lineedit.Parse(tc.Content)
+			changed := lineedit.EnsureLine(text, tc.Line)
+
+			assert.Equal(t, tc.ExpectChanged, changed)
+			assert.Equal(t, tc.Expect, text.String())
+
+			// EnsureLine is idempotent
+			assert.False(t, lineedit.EnsureLine(text, tc.Line))
+		})
+	}
+}
+
+func TestRemoveLine(t *testing.T) {
+	t.Parallel()
+
+	text := lineedit.Parse("a\nb\na\nc\n")
+	assert.True(t, lineedit.RemoveLine(text, lineedit.Line{Line: "a"}))
+	assert.Equal(t, "b\nc\n", text.String())
+	assert.False(t, lineedit.RemoveLine(text, lineedit.Line{Line: "a"}))
+
+	text = lineedit.Parse("Port 22\nPort 2222\nPermitRootLogin no\n")
+	assert.True(t, lineedit.RemoveLine(text, lineedit.Line{Regexp: regexp.MustCompile(`^Port `)}))
+	assert.Equal(t, "PermitRootLogin no\n", text.String())
+
+	text = lineedit.Parse("a\n")
+	assert.True(t, lineedit.RemoveLine(text, lineedit.Line{Line: "a"}))
+	assert.Equal(t, "", text.String())
+}
+
+func TestEnsureBlock(t *testing.T) {
+	t.Parallel()
+
+	block := lineedit.Block{
+		Begin: "# BEGIN managed",
+		End:   "# END managed",
+		Lines: []string{"x", "y"},
+	}
+
+	type testCase struct {
+		Desc          string
+		Content       string
+		Block         lineedit.Block
+		Expect        string
+		ExpectChanged bool
+		ExpectErr     error
+	}
+
+	tcs := []testCase{
+		{
+			Desc:    "append",
+			Content: "a\n",
+			Block:   block,
+			Expect: heredoc.String(`
+				a
+				# BEGIN managed
+				x
+				y
+				# END managed
+			`),
+			ExpectChanged: true,
+		},
+		{
+			Desc: "replace in place",
+			Content: heredoc.String(`
+				a
+				# BEGIN managed
+				old
+				# END managed
+				b
+			`),
+			Block: block,
+			Expect: heredoc.String(`
+				a
+				# BEGIN managed
+				x
+				y
+				# END managed
+				b
+			`),
+			ExpectChanged: true,
+		},
+		{
+			Desc: "unchanged",
+			Content: heredoc.String(`
+				# BEGIN managed
+				x
+				y
+				# END managed
+			`),
+			Block: block,
+			Expect: heredoc.String(`
+				# BEGIN managed
+				x
+				y
+				# END managed
+			`),
+			ExpectChanged: false,
+		},
+		{
+			Desc:    "insert before",
+			Content: "a\nb\n",
+			Block: lineedit.Block{
+				Begin:  block.Begin,
+				End:    block.End,
+				Lines:  []string{"x"},
+				Anchor: lineedit.Anchor{Before: regexp.MustCompile(`^b$`)},
+			},
+			Expect: heredoc.String(`
+				a
+				# BEGIN managed
+				x
+				# END managed
+				b
+			`),
+			ExpectChanged: true,
+		},
+		{
+			Desc:      "incomplete block",
+			Content:   "# BEGIN managed\nx\n",
+			Block:     block,
+			ExpectErr: lineedit.ErrBlockIncomplete,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.Desc, func(t *testing.T) {
+			t.Parallel()
+
+			text := lineedit.Parse(tc.Content)
+			changed, err := lineedit.EnsureBlock(text, tc.Block)
+			if tc.ExpectErr != nil {
+				assert.ErrorIs(t, err, tc.ExpectErr)
+				return
+			}
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.ExpectChanged, changed)
+			assert.Equal(t, tc.Expect, text.String())
+
+			lines, found, err := lineedit.GetBlock(text, tc.Block)
+			require.NoError(t, err)
+			assert.True(t, found)
+			assert.Equal(t, tc.Block.Lines, lines)
+		})
+	}
+}
+
+func TestRemoveBlock(t *testing.T) {
+	t.Parallel()
+
+	block := lineedit.Block{
+		Begin: "# BEGIN managed",
+		End:   "# END managed",
+	}
+
+	text := lineedit.Parse("a\n# BEGIN managed\nx\n# END managed\nb\n")
+	changed, err := lineedit.RemoveBlock(text, block)
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, "a\nb\n", text.String())
+
+	changed, err = lineedit.RemoveBlock(text, block)
+	require.NoError(t, err)
+	assert.False(t, changed)
+
+	_, found, err := lineedit.GetBlock(text, block)
+	require.NoError(t, err)
+	assert.False(t, found)
+}
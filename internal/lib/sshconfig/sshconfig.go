@@ -0,0 +1,233 @@
+package sshconfig
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/kevinburke/ssh_config"
+	"github.com/neuspaces/terraform-provider-system/internal/lib/homedir"
+	"net/url"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+)
+
+// DefaultPath is the default path of the OpenSSH client configuration of the current user
+const DefaultPath = "~/.ssh/config"
+
+// Config is a parsed OpenSSH client configuration
+type Config struct {
+	cfg *ssh_config.Config
+}
+
+// Load reads and parses the OpenSSH client configuration file at path. A leading `~` in path is expanded.
+func Load(path string) (*Config, error) {
+	expanded, err := homedir.Expand(path)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := os.ReadFile(expanded)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ssh config %s: %w", path, err)
+	}
+
+	return Parse(content)
+}
+
+// Parse parses the content of an OpenSSH client configuration file
+func Parse(content []byte) (*Config, error) {
+	cfg, err := ssh_config.Decode(bytes.NewReader(content))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse ssh config: %w", err)
+	}
+
+	return &Config{cfg: cfg}, nil
+}
+
+// Host is the resolved configuration of a host alias.
+// Empty values indicate that the directive is not set for the alias.
+type Host struct {
+	Alias string
+
+	// HostName is the value of the HostName directive with tokens expanded. HostName equals Alias if the directive is
+	// not set.
+	HostName string
+
+	Port int
+	User string
+
+	// IdentityFiles are the values of all IdentityFile directives with tokens and `~` expanded
+	IdentityFiles []string
+
+	// ProxyJump are the jump hosts of the ProxyJump directive in order of connection
+	ProxyJump []Jump
+
+	// UserKnownHostsFiles are the values of the UserKnownHostsFile directive with tokens and `~` expanded
+	UserKnownHostsFiles []string
+}
+
+// Jump is a jump host of a ProxyJump directive.
+// Jump.Host may be an alias which is defined in the configuration.
+type Jump struct {
+	User string
+	Host string
+	Port int
+}
+
+// Host returns the resolved configuration of alias
+func (c *Config) Host(alias string) (h *Host, err error) {
+	// ssh_config panics on Match directives which are not supported
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("failed to resolve ssh config of host %s: %v", alias, r)
+		}
+	}()
+
+	h = &Host{
+		Alias:    alias,
+		HostName: alias,
+	}
+
+	if val, err := c.get(alias, "HostName"); err != nil {
+		return nil, err
+	} else if val != "" {
+		h.HostName = expandTokens(val, alias, "")
+	}
+
+	if val, err := c.get(alias, "Port"); err != nil {
+		return nil, err
+	} else if val != "" {
+		port, err := strconv.ParseUint(val, 10, 16)
+		if err != nil {
+			return nil, fmt.Errorf("invalid Port %q of host %s", val, alias)
+		}
+		h.Port = int(port)
+	}
+
+	if h.User, err = c.get(alias, "User"); err != nil {
+		return nil, err
+	}
+
+	identityFiles, err := c.cfg.GetAll(alias, "IdentityFile")
+	if err != nil {
+		return nil, err
+	}
+	for _, identityFile := range identityFiles {
+		path, err := homedir.Expand(expandTokens(identityFile, h.HostName, h.User))
+		if err != nil {
+			return nil, err
+		}
+		h.IdentityFiles = append(h.IdentityFiles, path)
+	}
+
+	if val, err := c.get(alias, "ProxyJump"); err != nil {
+		return nil, err
+	} else if val != "" && !strings.EqualFold(val, "none") {
+		for _, jumpStr := range strings.Split(val, ",") {
+			jump, err := parseJump(strings.TrimSpace(jumpStr))
+			if err != nil {
+				return nil, fmt.Errorf("invalid ProxyJump of host %s: %w", alias, err)
+			}
+			h.ProxyJump = append(h.ProxyJump, jump)
+		}
+	}
+
+	if val, err := c.get(alias, "UserKnownHostsFile"); err != nil {
+		return nil, err
+	} else if val != "" && !strings.EqualFold(val, "none") {
+		for _, knownHostsFile := range strings.Fields(val) {
+			path, err := homedir.Expand(expandTokens(knownHostsFile, h.HostName, h.User))
+			if err != nil {
+				return nil, err
+			}
+			h.UserKnownHostsFiles = append(h.UserKnownHostsFiles, path)
+		}
+	}
+
+	return h, nil
+}
+
+func (c *Config) get(alias string, key string) (string, error) {
+	val, err := c.cfg.Get(alias, key)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s of host %s: %w", key, alias, err)
+	}
+	return val, nil
+}
+
+// parseJump parses a jump host in the format `[user@]host[:port]` or `ssh://[user@]host[:port]`
+func parseJump(s string) (Jump, error) {
+	if s == "" {
+		return Jump{}, fmt.Errorf("empty jump host")
+	}
+
+	if !strings.HasPrefix(s, "ssh://") {
+		s = "ssh://" + s
+	}
+
+	u, err := url.Parse(s)
+	if err != nil {
+		return Jump{}, err
+	}
+
+	jump := Jump{
+		Host: u.Hostname(),
+	}
+
+	if u.User != nil {
+		jump.User = u.User.Username()
+	}
+
+	if portStr := u.Port(); portStr != "" {
+		port, err := strconv.ParseUint(portStr, 10, 16)
+		if err != nil {
+			return Jump{}, fmt.Errorf("invalid port %q", portStr)
+		}
+		jump.Port = int(port)
+	}
+
+	if jump.Host == "" {
+		return Jump{}, fmt.Errorf("missing host in %q", s)
+	}
+
+	return jump, nil
+}
+
+// expandTokens replaces the tokens %h (host), %r (remote user), %d (local home directory), %u (local user) and %%
+// in s. Unknown tokens are retained.
+func expandTokens(s string, host string, remoteUser string) string {
+	if !strings.Contains(s, "%") {
+		return s
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '%' || i+1 >= len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+
+		i++
+		switch s[i] {
+		case 'h':
+			b.WriteString(host)
+		case 'r':
+			b.WriteString(remoteUser)
+		case 'd':
+			home, _ := os.UserHomeDir()
+			b.WriteString(home)
+		case 'u':
+			if u, err := user.Current(); err == nil {
+				b.WriteString(u.Username)
+			}
+		case '%':
+			b.WriteByte('%')
+		default:
+			b.WriteByte('%')
+			b.WriteByte(s[i])
+		}
+	}
+
+	return b.String()
+}
@@ -0,0 +1,128 @@
+package sshconfig_test
+
+import (
+	"github.com/neuspaces/terraform-provider-system/internal/extlib/heredoc"
+	"github.com/neuspaces/terraform-provider-system/internal/lib/sshconfig"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConfig_Host(t *testing.T) {
+	t.Parallel()
+
+	home, err := os.UserHomeDir()
+	require.NoError(t, err)
+
+	content := heredoc.String(`
+		Host db1
+		  HostName 10.0.0.10
+		  Port 2222
+		  User admin
+		  IdentityFile ~/.ssh/id_db1
+		  IdentityFile /keys/%h_%r
+		  ProxyJump jump@bastion.example.com:2200,internal-jump
+		  UserKnownHostsFile ~/.ssh/known_hosts_db /etc/ssh/known_hosts_%h
+
+		Host web-*
+		  HostName %h.example.com
+		  ProxyJump none
+
+		Host *
+		  User default
+	`)
+
+	cfg, err := sshconfig.Parse([]byte(content))
+	require.NoError(t, err)
+
+	type testCase struct {
+		Desc   string
+		Alias  string
+		Expect *sshconfig.Host
+	}
+
+	tcs := []testCase{
+		{
+			Desc:  "all directives",
+			Alias: "db1",
+			Expect: &sshconfig.Host{
+				Alias:    "db1",
+				HostName: "10.0.0.10",
+				Port:     2222,
+				User:     "admin",
+				IdentityFiles: []string{
+					filepath.Join(home, ".ssh/id_db1"),
+					"/keys/10.0.0.10_admin",
+				},
+				ProxyJump: []sshconfig.Jump{
+					{User: "jump", Host: "bastion.example.com", Port: 2200},
+					{Host: "internal-jump"},
+				},
+				UserKnownHostsFiles: []string{
+					filepath.Join(home, ".ssh/known_hosts_db"),
+					"/etc/ssh/known_hosts_10.0.0.10",
+				},
+			},
+		},
+		{
+			Desc:  "pattern with token",
+			Alias: "web-1",
+			Expect: &sshconfig.Host{
+				Alias:    "web-1",
+				HostName: "web-1.example.com",
+				User:     "default",
+			},
+		},
+		{
+			Desc:  "unknown alias",
+			Alias: "other",
+			Expect: &sshconfig.Host{
+				Alias:    "other",
+				HostName: "other",
+				User:     "default",
+			},
+		},
+	}
+
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.Desc, func(t *testing.T) {
+			t.Parallel()
+
+			actual, err := cfg.Host(tc.Alias)
+			require.NoError(t, err)
+			assert.Equal(t, tc.Expect, actual)
+		})
+	}
+}
+
+func TestConfig_Host_Invalid(t *testing.T) {
+	t.Parallel()
+
+	type testCase struct {
+		Desc    string
+		Content string
+	}
+
+	tcs := []testCase{
+		{Desc: "invalid port", Content: "Host db1\n  Port abc\n"},
+		{Desc: "invalid proxy jump", Content: "Host db1\n  ProxyJump bastion:abc\n"},
+	}
+
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.Desc, func(t *testing.T) {
+			t.Parallel()
+
+			cfg, err := sshconfig.Parse([]byte(tc.Content))
+			if err != nil {
+				return
+			}
+
+			_, err = cfg.Host("db1")
+			assert.Error(t, err)
+		})
+	}
+}
@@ -48,6 +48,18 @@ func ParseTerseFormat(data []byte) (*Stat, error) {
 
 	mode := FileMode(modeInt)
 
+	// User id %u
+	uid, err := strconv.Atoi(parts[4])
+	if err != nil {
+		return nil, newParseError(fmt.Sprintf("failed to parse uid %s", parts[4]))
+	}
+
+	// Group id %g
+	gid, err := strconv.Atoi(parts[5])
+	if err != nil {
+		return nil, newParseError(fmt.Sprintf("failed to parse gid %s", parts[5]))
+	}
+
 	// Access time %X
 	accessTime, err := parseUnixEpochUTC(parts[11])
 	if err != nil {
@@ -69,6 +81,8 @@ func ParseTerseFormat(data []byte) (*Stat, error) {
 	s := &Stat{
 		Mode:         mode,
 		Name:         name,
+		Uid:          uid,
+		Gid:          gid,
 		Size:         size,
 		AccessTime:   accessTime,
 		ModifiedTime: modifiedTime,
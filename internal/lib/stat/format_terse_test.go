@@ -28,6 +28,8 @@ func TestParseTerseFormat(t *testing.T) {
 			Assert: func(t *testing.T, s *stat.Stat) {
 				assert.Equal(t, "/root/regular-file", s.Name)
 				assert.Equal(t, int64(6), s.Size)
+				assert.Equal(t, 0, s.Uid)
+				assert.Equal(t, 0, s.Gid)
 				assert.Equal(t, true, s.Mode.IsRegular())
 				assert.Equal(t, false, s.Mode.IsDir())
 				assert.Equal(t, "-rw-r--r--", s.Mode.ToFsFileMode().Perm().String())
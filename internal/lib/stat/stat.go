@@ -65,7 +65,25 @@ func (s *statFileInfo) Size() int64 {
 }
 
 func (s *statFileInfo) Mode() fs.FileMode {
-	return s.stat.Mode.ToFsFileMode()
+	mode := s.stat.Mode.ToFsFileMode()
+
+	// Retain the type bits which are not part of the permission bits
+	switch s.stat.Mode & ModeType {
+	case ModeDirectory:
+		mode |= fs.ModeDir
+	case ModeSymlink:
+		mode |= fs.ModeSymlink
+	case ModeNamedPipe:
+		mode |= fs.ModeNamedPipe
+	case ModeSocket:
+		mode |= fs.ModeSocket
+	case ModeBlockDevice:
+		mode |= fs.ModeDevice
+	case ModeCharDevice:
+		mode |= fs.ModeDevice | fs.ModeCharDevice
+	}
+
+	return mode
 }
 
 func (s *statFileInfo) ModTime() time.Time {
@@ -76,6 +94,8 @@ func (s *statFileInfo) IsDir() bool {
 	return s.stat.Mode.IsDir()
 }
 
+// Sys returns the underlying *Stat
 func (s *statFileInfo) Sys() interface{} {
-	return nil
+	stat := s.stat
+	return &stat
 }
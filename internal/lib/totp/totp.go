@@ -0,0 +1,69 @@
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultPeriod is the time step of codes according to RFC 6238
+	DefaultPeriod = 30 * time.Second
+
+	// DefaultDigits is the number of digits of codes
+	DefaultDigits = 6
+)
+
+// DecodeSecret decodes a base32 encoded secret as used by authenticator apps.
+// Spaces, dashes and padding are ignored and the secret is case-insensitive.
+func DecodeSecret(secret string) ([]byte, error) {
+	normalized := strings.ToUpper(secret)
+	normalized = strings.NewReplacer(" ", "", "-", "", "=", "").Replace(normalized)
+
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(normalized)
+	if err != nil {
+		return nil, fmt.Errorf("invalid totp secret: expected base32 encoding")
+	}
+
+	if len(key) == 0 {
+		return nil, fmt.Errorf("invalid totp secret: empty")
+	}
+
+	return key, nil
+}
+
+// Code returns the time-based one-time password (RFC 6238) for the base32 encoded secret at time t using HMAC-SHA1,
+// DefaultPeriod and DefaultDigits.
+func Code(secret string, t time.Time) (string, error) {
+	key, err := DecodeSecret(secret)
+	if err != nil {
+		return "", err
+	}
+
+	return code(key, uint64(t.Unix())/uint64(DefaultPeriod/time.Second), DefaultDigits), nil
+}
+
+// code returns the HMAC-based one-time password (RFC 4226) for key and counter
+func code(key []byte, counter uint64, digits int) string {
+	msg := make([]byte, 8)
+	binary.BigEndian.PutUint64(msg, counter)
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg)
+	sum := mac.Sum(nil)
+
+	// Dynamic truncation
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for i := 0; i < digits; i++ {
+		mod *= 10
+	}
+
+	return fmt.Sprintf("%0*d", digits, value%mod)
+}
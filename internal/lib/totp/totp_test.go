@@ -0,0 +1,59 @@
+package totp_test
+
+import (
+	"encoding/base32"
+	"github.com/neuspaces/terraform-provider-system/internal/lib/totp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestCode(t *testing.T) {
+	t.Parallel()
+
+	// Test vectors of RFC 6238 Appendix B for SHA1 truncated to 6 digits
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+
+	type testCase struct {
+		Time   int64
+		Expect string
+	}
+
+	tcs := []testCase{
+		{Time: 59, Expect: "287082"},
+		{Time: 1111111109, Expect: "081804"},
+		{Time: 1111111111, Expect: "050471"},
+		{Time: 1234567890, Expect: "005924"},
+		{Time: 2000000000, Expect: "279037"},
+		{Time: 20000000000, Expect: "353130"},
+	}
+
+	for _, tc := range tcs {
+		actual, err := totp.Code(secret, time.Unix(tc.Time, 0))
+		require.NoError(t, err)
+		assert.Equal(t, tc.Expect, actual, "time %d", tc.Time)
+	}
+}
+
+func TestDecodeSecret(t *testing.T) {
+	t.Parallel()
+
+	expect := []byte("12345678901234567890")
+
+	for _, secret := range []string{
+		"GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ",
+		"gezdgnbvgy3tqojqgezdgnbvgy3tqojq",
+		"GEZD GNBV GY3T QOJQ GEZD GNBV GY3T QOJQ",
+	} {
+		actual, err := totp.DecodeSecret(secret)
+		require.NoError(t, err)
+		assert.Equal(t, expect, actual)
+	}
+
+	_, err := totp.DecodeSecret("not base32!")
+	assert.Error(t, err)
+
+	_, err = totp.DecodeSecret("")
+	assert.Error(t, err)
+}
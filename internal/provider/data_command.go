@@ -109,6 +109,7 @@ func dataCommand() *schema.Resource {
 					},
 				},
 			},
+			SchemaAttrRunAs: schemaRunAs(),
 		},
 	}
 }
@@ -154,6 +155,11 @@ func dataCommandRead(ctx context.Context, d *schema.ResourceData, meta interface
 		return diagErr
 	}
 
+	s, diagErr := systemFromResourceData(p, d)
+	if diagErr != nil {
+		return diagErr
+	}
+
 	commandString := d.Get(dataCommandAttrCommand).(string)
 
 	var expect *dataCommandExpect
@@ -185,7 +191,7 @@ func dataCommandRead(ctx context.Context, d *schema.ResourceData, meta interface
 
 	// Execute command
 	command := client.NewCommand(commandString)
-	result, err := client.ExecuteCommandWithOptions(ctx, p.System, command, commandOptions...)
+	result, err := client.ExecuteCommandWithOptions(ctx, s, command, commandOptions...)
 	if err != nil {
 		if err.Error() == "short write" {
 			return []diag.Diagnostic{
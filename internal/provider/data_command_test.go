@@ -208,3 +208,61 @@ func TestAccDataCommand(t *testing.T) {
 		})
 	}
 }
+
+func TestAccDataCommand_runAs(t *testing.T) {
+	type testCase struct {
+		Desc         string
+		RunAs        []tfbuild.BlockElement
+		ExpectStdout string
+	}
+
+	tcs := []testCase{
+		{
+			Desc:         "without run_as",
+			ExpectStdout: "someadmin\n",
+		},
+		{
+			Desc:         "sudo",
+			RunAs:        []tfbuild.BlockElement{tfbuild.AttributeBool(provider.SchemaAttrRunAsSudo, true)},
+			ExpectStdout: "root\n",
+		},
+		{
+			Desc:         "user",
+			RunAs:        []tfbuild.BlockElement{tfbuild.AttributeString(provider.SchemaAttrRunAsUser, "someone")},
+			ExpectStdout: "someone\n",
+		},
+	}
+
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.Desc, func(t *testing.T) {
+			acctest.Current().Targets.Foreach(t, func(t *testing.T, target acctest.Target) {
+				t.Parallel()
+
+				targetConfig := getTargetConfigOrSkip(t, target, "auth-privileged")
+
+				dataAttrs := []tfbuild.BlockElement{
+					tfbuild.AttributeString("command", "whoami"),
+				}
+				if tc.RunAs != nil {
+					dataAttrs = append(dataAttrs, tfbuild.InnerBlock(provider.SchemaAttrRunAs, tc.RunAs...))
+				}
+
+				resource.Test(t, resource.TestCase{
+					ProviderFactories: acctest.ProviderFactories(),
+					Steps: []resource.TestStep{
+						{
+							Config: tfbuild.FileString(tfbuild.File(
+								acctest.ProviderConfigBlock(targetConfig),
+								tfbuild.Data("system_command", "test", dataAttrs...),
+							)),
+							Check: resource.ComposeTestCheckFunc(
+								resource.TestCheckResourceAttr("data.system_command.test", "stdout", base64.StdEncoding.EncodeToString([]byte(tc.ExpectStdout))),
+							),
+						},
+					},
+				})
+			})
+		})
+	}
+}
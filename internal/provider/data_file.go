@@ -2,6 +2,8 @@ package provider
 
 import (
 	"context"
+	"encoding/base64"
+	"errors"
 	"fmt"
 	"github.com/neuspaces/terraform-provider-system/internal/lib/filemode"
 	"io"
@@ -9,6 +11,7 @@ import (
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
 	"github.com/neuspaces/terraform-provider-system/internal/client"
 	"github.com/neuspaces/terraform-provider-system/internal/validate"
 )
@@ -16,16 +19,24 @@ import (
 const dataFileName = "system_file"
 
 const (
-	dataFileAttrId       = "id"
-	dataFileAttrPath     = resourceFileAttrPath
-	dataFileAttrMode     = resourceFileAttrMode
-	dataFileAttrUser     = resourceFileAttrUser
-	dataFileAttrUid      = resourceFileAttrUid
-	dataFileAttrGroup    = resourceFileAttrGroup
-	dataFileAttrGid      = resourceFileAttrGid
-	dataFileAttrContent  = resourceFileAttrContent
-	dataFileAttrMd5Sum   = resourceFileAttrMd5Sum
-	dataFileAttrBasename = resourceFileAttrBasename
+	dataFileAttrId            = "id"
+	dataFileAttrPath          = resourceFileAttrPath
+	dataFileAttrMode          = resourceFileAttrMode
+	dataFileAttrUser          = resourceFileAttrUser
+	dataFileAttrUid           = resourceFileAttrUid
+	dataFileAttrGroup         = resourceFileAttrGroup
+	dataFileAttrGid           = resourceFileAttrGid
+	dataFileAttrContent       = resourceFileAttrContent
+	dataFileAttrContentBase64 = "content_base64"
+	dataFileAttrMaxSize       = "max_size"
+	dataFileAttrOffset        = "offset"
+	dataFileAttrLength        = "length"
+	dataFileAttrMd5Sum        = resourceFileAttrMd5Sum
+	dataFileAttrBasename      = resourceFileAttrBasename
+)
+
+const (
+	dataFileMaxSizeDefault = 1048576 // bytes
 )
 
 func dataFile() *schema.Resource {
@@ -75,11 +86,37 @@ func dataFile() *schema.Resource {
 				Computed:    true,
 			},
 			dataFileAttrContent: {
-				Description: "Content of the file",
+				Description: fmt.Sprintf("Content of the file. Use `%s` for binary files.", dataFileAttrContentBase64),
 				Type:        schema.TypeString,
 				Computed:    true,
 				Sensitive:   true,
 			},
+			dataFileAttrContentBase64: {
+				Description: "Base64 encoded content of the file. Binary files are preserved unlike in `content`.",
+				Type:        schema.TypeString,
+				Computed:    true,
+				Sensitive:   true,
+			},
+			dataFileAttrMaxSize: {
+				Description:  fmt.Sprintf("Maximum bytes read from the file. Define a reasonable limit to prevent unintended growth of the terraform state. If the content exceeds this limit, the data source fails. Set to `0` or `-1` to read content of any size. Defaults to `%d`.", dataFileMaxSizeDefault),
+				Type:         schema.TypeInt,
+				Optional:     true,
+				Default:      dataFileMaxSizeDefault,
+				ValidateFunc: validation.IntAtLeast(-1),
+			},
+			dataFileAttrOffset: {
+				Description:  "Offset in bytes from which the content is read. Defaults to `0`.",
+				Type:         schema.TypeInt,
+				Optional:     true,
+				Default:      0,
+				ValidateFunc: validation.IntAtLeast(0),
+			},
+			dataFileAttrLength: {
+				Description:  fmt.Sprintf("Number of bytes which are read from `%s`. Defaults to the remaining content of the file.", dataFileAttrOffset),
+				Type:         schema.TypeInt,
+				Optional:     true,
+				ValidateFunc: validation.IntAtLeast(0),
+			},
 			dataFileAttrMd5Sum: {
 				Description: "MD5 checksum of the remote file contents on the system in base64 encoding.",
 				Type:        schema.TypeString,
@@ -113,8 +150,10 @@ func dataFileSetResourceData(r *client.File, d *schema.ResourceData) diag.Diagno
 			return diag.FromErr(err)
 		}
 		_ = d.Set(dataFileAttrContent, string(content))
+		_ = d.Set(dataFileAttrContentBase64, base64.StdEncoding.EncodeToString(content))
 	} else {
 		_ = d.Set(dataFileAttrContent, nil)
+		_ = d.Set(dataFileAttrContentBase64, nil)
 	}
 
 	return nil
@@ -127,12 +166,35 @@ func dataFileRead(ctx context.Context, d *schema.ResourceData, meta interface{})
 	}
 
 	includeContentOpt := client.FileClientIncludeContent(true)
-	c := client.NewFileClient(p.System, includeContentOpt, client.FileClientCompression(true))
+
+	// A max size of 0 or -1 does not limit the content
+	maxSize := int64(d.Get(dataFileAttrMaxSize).(int))
+	if maxSize <= 0 {
+		maxSize = -1
+	}
+	contentLimitOpt := client.FileClientContentLimit(maxSize)
+
+	// Use deprecated GetOkExists instead of GetOk because GetOk does not distinguish a length of 0 from an unset length
+	length := int64(-1)
+	if v, exists := d.GetOkExists(dataFileAttrLength); exists {
+		length = int64(v.(int))
+	}
+	contentRangeOpt := client.FileClientContentRange(int64(d.Get(dataFileAttrOffset).(int)), length)
+
+	c := client.NewFileClient(p.System, includeContentOpt, contentLimitOpt, contentRangeOpt)
 
 	filePath := d.Get(dataFileAttrPath).(string)
 
 	r, err := c.Get(ctx, filePath)
-	if err != nil {
+	if errors.Is(err, client.ErrFileContentLimit) {
+		return []diag.Diagnostic{
+			{
+				Severity: diag.Error,
+				Summary:  "content exceeded limit",
+				Detail:   fmt.Sprintf("The content of %[1]q exceeds %[2]d bytes. If necessary, adjust the limit using the attribute `%[3]s` or set `%[3]s` to `0` to read content of any size.", filePath, d.Get(dataFileAttrMaxSize).(int), dataFileAttrMaxSize),
+			},
+		}
+	} else if err != nil {
 		return diag.FromErr(err)
 	}
 
@@ -15,15 +15,17 @@ import (
 const dataFileMetaName = "system_file_meta"
 
 const (
-	dataFileMetaAttrId       = "id"
-	dataFileMetaAttrPath     = resourceFileAttrPath
-	dataFileMetaAttrMode     = resourceFileAttrMode
-	dataFileMetaAttrUser     = resourceFileAttrUser
-	dataFileMetaAttrUid      = resourceFileAttrUid
-	dataFileMetaAttrGroup    = resourceFileAttrGroup
-	dataFileMetaAttrGid      = resourceFileAttrGid
-	dataFileMetaAttrMd5Sum   = resourceFileAttrMd5Sum
-	dataFileMetaAttrBasename = resourceFileAttrBasename
+	dataFileMetaAttrId        = "id"
+	dataFileMetaAttrPath      = resourceFileAttrPath
+	dataFileMetaAttrMode      = resourceFileAttrMode
+	dataFileMetaAttrUser      = resourceFileAttrUser
+	dataFileMetaAttrUid       = resourceFileAttrUid
+	dataFileMetaAttrGroup     = resourceFileAttrGroup
+	dataFileMetaAttrGid       = resourceFileAttrGid
+	dataFileMetaAttrMd5Sum    = resourceFileAttrMd5Sum
+	dataFileMetaAttrSha256Sum = resourceFileAttrSha256Sum
+	dataFileMetaAttrSha512Sum = resourceFileAttrSha512Sum
+	dataFileMetaAttrBasename  = resourceFileAttrBasename
 )
 
 func dataFileMeta() *schema.Resource {
@@ -77,6 +79,16 @@ func dataFileMeta() *schema.Resource {
 				Type:        schema.TypeString,
 				Computed:    true,
 			},
+			dataFileMetaAttrSha256Sum: {
+				Description: "SHA-256 checksum of the remote file contents on the system in hexadecimal encoding. Empty if the system provides none of `sha256sum`, `shasum` and `openssl`.",
+				Type:        schema.TypeString,
+				Computed:    true,
+			},
+			dataFileMetaAttrSha512Sum: {
+				Description: "SHA-512 checksum of the remote file contents on the system in hexadecimal encoding. Empty if the system provides none of `sha512sum`, `shasum` and `openssl`.",
+				Type:        schema.TypeString,
+				Computed:    true,
+			},
 			dataFileMetaAttrBasename: {
 				Description: fmt.Sprintf("Base name of the file. Returns the last element of path. Example: Given the attribute `%[1]s` is `/path/to/file.txt`, the `%[2]s` is `file.txt`.", dataFileMetaAttrPath, dataFileMetaAttrBasename),
 				Type:        schema.TypeString,
@@ -97,6 +109,8 @@ func dataFileMetaSetResourceData(r *client.File, d *schema.ResourceData) diag.Di
 	_ = d.Set(dataFileMetaAttrGid, r.Gid)
 
 	_ = d.Set(dataFileMetaAttrMd5Sum, r.Md5Sum)
+	_ = d.Set(dataFileMetaAttrSha256Sum, r.Sha256Sum)
+	_ = d.Set(dataFileMetaAttrSha512Sum, r.Sha512Sum)
 	_ = d.Set(dataFileMetaAttrBasename, path.Base(r.Path))
 
 	return nil
@@ -1,6 +1,7 @@
 package provider_test
 
 import (
+	"regexp"
 	"testing"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
@@ -44,3 +45,114 @@ func TestAccDataFile_read_content(t *testing.T) {
 		})
 	})
 }
+
+func TestAccDataFile_read_content_base64(t *testing.T) {
+	testConfig := newTestFileConfig()
+
+	acctest.Current().Targets.Foreach(t, func(t *testing.T, target acctest.Target) {
+		t.Parallel()
+
+		resource.Test(t, resource.TestCase{
+			ProviderFactories: acctest.ProviderFactories(),
+			Steps: []resource.TestStep{
+				{
+					Config: tfbuild.FileString(tfbuild.File(
+						acctest.ProviderConfigBlock(target.Configs.Default()),
+						testAccFileBlock("test", testRunFilePath(target, testConfig.fileName),
+							tfbuild.AttributeString("mode", "644"),
+							// printf '\x00\x01\xff\xfe' | openssl base64
+							tfbuild.AttributeString("source", "data:application/octet-stream;base64,AAH//g=="),
+						),
+						tfbuild.Data("system_file", "test",
+							tfbuild.AttributeTraversal("path", tfbuild.TraversalResourceAttribute("system_file", "test", "path")),
+						),
+					)),
+					Check: resource.ComposeTestCheckFunc(
+						resource.TestCheckResourceAttr("data.system_file.test", "content_base64", "AAH//g=="),
+					),
+				},
+			},
+		})
+	})
+}
+
+func TestAccDataFile_read_content_range(t *testing.T) {
+	testConfig := newTestFileConfig()
+
+	acctest.Current().Targets.Foreach(t, func(t *testing.T, target acctest.Target) {
+		t.Parallel()
+
+		resource.Test(t, resource.TestCase{
+			ProviderFactories: acctest.ProviderFactories(),
+			Steps: []resource.TestStep{
+				{
+					Config: tfbuild.FileString(tfbuild.File(
+						acctest.ProviderConfigBlock(target.Configs.Default()),
+						testAccFileBlock("test", testRunFilePath(target, testConfig.fileName),
+							tfbuild.AttributeString("mode", "644"),
+							tfbuild.AttributeString("content", "hello world!"),
+						),
+						tfbuild.Data("system_file", "test",
+							tfbuild.AttributeTraversal("path", tfbuild.TraversalResourceAttribute("system_file", "test", "path")),
+							tfbuild.AttributeInt("offset", 6),
+							tfbuild.AttributeInt("length", 5),
+							tfbuild.AttributeInt("max_size", 5),
+						),
+					)),
+					Check: resource.ComposeTestCheckFunc(
+						resource.TestCheckResourceAttr("data.system_file.test", "content", "world"),
+						// echo -n 'world' | openssl base64
+						resource.TestCheckResourceAttr("data.system_file.test", "content_base64", "d29ybGQ="),
+						// echo -n 'hello world!' | openssl dgst -binary -md5 | openssl base64
+						resource.TestCheckResourceAttr("data.system_file.test", "md5sum", "/D/5joxqDTCH1RXARz+Gdw=="),
+					),
+				},
+			},
+		})
+	})
+}
+
+func TestAccDataFile_read_content_max_size(t *testing.T) {
+	testConfig := newTestFileConfig()
+
+	acctest.Current().Targets.Foreach(t, func(t *testing.T, target acctest.Target) {
+		t.Parallel()
+
+		resource.Test(t, resource.TestCase{
+			ProviderFactories: acctest.ProviderFactories(),
+			Steps: []resource.TestStep{
+				{
+					Config: tfbuild.FileString(tfbuild.File(
+						acctest.ProviderConfigBlock(target.Configs.Default()),
+						testAccFileBlock("test", testRunFilePath(target, testConfig.fileName),
+							tfbuild.AttributeString("mode", "644"),
+							tfbuild.AttributeString("content", "hello world!"),
+						),
+						tfbuild.Data("system_file", "test",
+							tfbuild.AttributeTraversal("path", tfbuild.TraversalResourceAttribute("system_file", "test", "path")),
+							tfbuild.AttributeInt("max_size", 5),
+						),
+					)),
+					ExpectError: regexp.MustCompile(`content exceeded limit`),
+				},
+				{
+					// A max size of 0 does not limit the content
+					Config: tfbuild.FileString(tfbuild.File(
+						acctest.ProviderConfigBlock(target.Configs.Default()),
+						testAccFileBlock("test", testRunFilePath(target, testConfig.fileName),
+							tfbuild.AttributeString("mode", "644"),
+							tfbuild.AttributeString("content", "hello world!"),
+						),
+						tfbuild.Data("system_file", "test",
+							tfbuild.AttributeTraversal("path", tfbuild.TraversalResourceAttribute("system_file", "test", "path")),
+							tfbuild.AttributeInt("max_size", 0),
+						),
+					)),
+					Check: resource.ComposeTestCheckFunc(
+						resource.TestCheckResourceAttr("data.system_file.test", "content", "hello world!"),
+					),
+				},
+			},
+		})
+	})
+}
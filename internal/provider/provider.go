@@ -2,14 +2,20 @@ package provider
 
 import (
 	"context"
+	"fmt"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	"github.com/neuspaces/terraform-provider-system/internal/cmd"
+	"github.com/neuspaces/terraform-provider-system/internal/source"
 	"github.com/neuspaces/terraform-provider-system/internal/sshclient"
 	"github.com/neuspaces/terraform-provider-system/internal/system"
+	"github.com/neuspaces/terraform-provider-system/internal/system/local"
 	systemssh "github.com/neuspaces/terraform-provider-system/internal/system/ssh"
 	"github.com/sethvargo/go-retry"
 	"golang.org/x/crypto/ssh"
+	"net"
+	"sort"
+	"sync"
 	"time"
 )
 
@@ -17,18 +23,77 @@ const Name = "system"
 
 func New(version string) func() *schema.Provider {
 	return func() *schema.Provider {
+		// The sources of the provider instance are replaced when the provider is configured
+		sources := newProviderSources()
+
 		return &schema.Provider{
 			Schema:               providerSchema(),
-			ResourcesMap:         providerResources(),
+			ResourcesMap:         providerResources(sources),
 			DataSourcesMap:       providerDataSources(),
-			ConfigureContextFunc: configure(),
+			ConfigureContextFunc: configure(sources),
 		}
 	}
 }
 
 type Provider struct {
-	Config Schema
-	System system.System
+	Config  Schema
+	System  system.System
+	Sources *source.Registry
+
+	// fileEdits serializes the edits of resources which edit parts of the same file
+	fileEdits pathLocks
+}
+
+// pathLocks holds a mutex per path. The zero value is ready to use.
+type pathLocks struct {
+	mu    sync.Mutex
+	locks map[string]*sync.Mutex
+}
+
+// lock locks the mutex of path and returns a function which unlocks it
+func (l *pathLocks) lock(path string) func() {
+	l.mu.Lock()
+	if l.locks == nil {
+		l.locks = map[string]*sync.Mutex{}
+	}
+	m, ok := l.locks[path]
+	if !ok {
+		m = &sync.Mutex{}
+		l.locks[path] = m
+	}
+	l.mu.Unlock()
+
+	m.Lock()
+	return m.Unlock
+}
+
+// providerSources holds the source.Registry of a configured provider instance for schema functions without access to
+// the Provider, e.g. StateFunc. Until the provider instance is configured, sources are opened with default clients.
+type providerSources struct {
+	mu       sync.RWMutex
+	registry *source.Registry
+}
+
+func newProviderSources() *providerSources {
+	return &providerSources{
+		registry: newSourceRegistry(source.NewHttpClient(), source.NewSftpClient()),
+	}
+}
+
+// get returns the source.Registry of the provider instance
+func (s *providerSources) get() *source.Registry {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.registry
+}
+
+// set replaces the source.Registry of the provider instance
+func (s *providerSources) set(registry *source.Registry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.registry = registry
 }
 
 func init() {
@@ -36,10 +101,14 @@ func init() {
 	schema.DescriptionKind = schema.StringMarkdown
 }
 
-func providerResources() map[string]*schema.Resource {
+func providerResources(sources *providerSources) map[string]*schema.Resource {
 	return map[string]*schema.Resource{
-		resourceFileName:           resourceFile(),
+		resourceArchiveName:        resourceArchive(sources),
+		resourceFileName:           resourceFile(sources),
+		resourceFileLineName:       resourceFileLine(),
+		resourceFileBlockName:      resourceFileBlock(),
 		resourceFolderName:         resourceFolder(),
+		resourceFolderSyncName:     resourceFolderSync(),
 		resourceLinkName:           resourceLink(),
 		resourceUserName:           resourceUser(),
 		resourceGroupName:          resourceGroup(),
@@ -61,7 +130,7 @@ func providerDataSources() map[string]*schema.Resource {
 	}
 }
 
-func configure() schema.ConfigureContextFunc {
+func configure(sources *providerSources) schema.ConfigureContextFunc {
 	return func(ctx context.Context, d *schema.ResourceData) (interface{}, diag.Diagnostics) {
 		// Obtain stop context from parent context
 		// terraform-plugin-sdk signals the provider stop by cancelling a *separate* context (stop context)
@@ -78,135 +147,430 @@ func configure() schema.ConfigureContextFunc {
 			return nil, diag.FromErr(err)
 		}
 
-		// Require ssh schema
-		if c.Ssh == nil {
-			return nil, newInternalUnexpectedTypeDiagnostic("*SchemaSsh", c.Ssh)
+		// Configure http sources
+		var httpOpts []source.HttpOption
+		if c.SourceHttp != nil {
+			httpOpts, err = c.SourceHttp.options()
+			if err != nil {
+				return nil, diag.FromErr(err)
+			}
 		}
 
-		// Configure ssh client
-		sshConnectOpts := sshConnectOptsFromSshSchema(*c.Ssh)
+		// Configure sftp sources
+		var sftpOpts []sshclient.ConnectOption
+		if c.SourceSftp != nil {
+			sftpOpts = c.SourceSftp.connectOptions()
+		}
 
-		if c.Proxy != nil && c.Proxy.Ssh != nil {
-			// Connect via ssh proxy
-			sshProxyConnectOpts := sshConnectOptsFromSshSchema(*c.Proxy.Ssh)
+		// The clients of sources are constructed for each provider instance so that provider instances with different
+		// configurations do not share clients
+		registry := newSourceRegistry(source.NewHttpClient(httpOpts...), source.NewSftpClient(sftpOpts...))
+		sources.set(registry)
 
-			sshProxyNetConnect := sshclient.Dial(sshclient.NewHostPortAddr(sshclient.Tcp, c.Proxy.Ssh.Host, uint16(c.Proxy.Ssh.Port)), c.Proxy.Ssh.Timeout)
-			sshProxyConnectOpts = append(sshProxyConnectOpts, sshclient.Net(sshProxyNetConnect))
-			sshProxyConnect, err := sshclient.Prepare(sshProxyConnectOpts...)
-			if err != nil {
-				return nil, diag.FromErr(err)
+		// Configure system
+		var s system.System
+		if c.Local != nil {
+			s, err = localSystemFromSchema(*c)
+		} else {
+			// Require ssh schema
+			if c.Ssh == nil {
+				return nil, newInternalUnexpectedTypeDiagnostic("*SchemaSsh", c.Ssh)
 			}
 
-			sshNetConnect := sshclient.Proxy(sshclient.New(sshProxyConnect), sshclient.NewHostPortAddr(sshclient.Tcp, c.Ssh.Host, uint16(c.Ssh.Port)))
+			s, err = sshSystemFromSchema(*c)
+		}
+		if err != nil {
+			return nil, diag.FromErr(err)
+		}
 
-			sshConnectOpts = append(sshConnectOpts, sshclient.Net(sshNetConnect))
+		// Construct provider instance
+		p := &Provider{
+			Config:  *c,
+			System:  s,
+			Sources: registry,
+		}
 
-		} else {
-			// Connect directly
-			sshNetConnect := sshclient.Dial(sshclient.NewHostPortAddr(sshclient.Tcp, c.Ssh.Host, uint16(c.Ssh.Port)), c.Ssh.Timeout)
+		go func(ctx context.Context, s system.System) {
+			// Wait for stop context cancelled
+			<-stopCtx.Done()
+
+			// Disconnect system
+			_ = s.Close()
+		}(ctx, s)
+
+		return p, nil
+	}
+}
+
+// commandMiddlewareFromSchema returns the cmd.Middleware which is applied to every command executed on the system
+func commandMiddlewareFromSchema(c Schema) (cmd.Middleware, error) {
+	if c.Become != nil {
+		// Use privilege escalation with shell /bin/sh
+		return c.Become.middleware()
+	}
+
+	if c.Sudo {
+		// Use sudo with shell /bin/sh
+		return cmd.SudoShMiddleware(), nil
+	}
+
+	// Use shell /bin/sh
+	return cmd.ShMiddleware(), nil
+}
+
+// localSystemFromSchema returns a system.System which executes commands on the system on which the provider runs
+func localSystemFromSchema(c Schema) (system.System, error) {
+	cmdM, err := commandMiddlewareFromSchema(c)
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := local.NewSystem(local.CommandMiddleware(cmdM))
+	if err != nil {
+		return nil, err
+	}
+
+	// The provider process is not privileged: access files by executing commands if privileges are escalated
+	if c.Become != nil || c.Sudo {
+		return system.WithCommandMiddleware(s, cmdM), nil
+	}
+
+	return s, nil
+}
+
+// sshSystemFromSchema returns a system.System which executes commands on the remote connected via ssh
+func sshSystemFromSchema(c Schema) (system.System, error) {
+	cmdM, err := commandMiddlewareFromSchema(c)
+	if err != nil {
+		return nil, err
+	}
+
+	// Configure ssh client
+	sshConnectOpts := sshConnectOptsFromSshSchema(*c.Ssh)
+
+	sshNetConnect, err := sshNetConnectFromSchema(c)
+	if err != nil {
+		return nil, err
+	}
+
+	sshConnectOpts = append(sshConnectOpts, sshclient.Net(sshNetConnect))
+
+	sshConnect, err := sshclient.Prepare(sshConnectOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(c.Proxies) > 0 {
+		// Distinguish errors of the remote from errors of proxy hops
+		sshConnect = sshclient.Describe(fmt.Sprintf("remote %s", sshAddrFromSshSchema(*c.Ssh)))(sshConnect)
+	}
+
+	// Retries
+	if c.Retry {
+		// retries are limited by maximum duration according to provider config with constant 1 second backoff
+		retryM := sshclient.Retry(retry.WithMaxDuration(c.Timeout, retry.NewConstant(1*time.Second)))
+		sshConnect = retryM(sshConnect)
+	}
+
+	// Break circuit when authentication has failed once; other failures may be resolved by reconnecting later
+	cbM := sshclient.CircuitBreak()
+	sshConnect = cbM(sshConnect)
+
+	// Create ssh client with keepalive requests to detect dead connections
+	sshClient := sshclient.New(sshConnect, sshclient.Keepalive(c.KeepaliveInterval, c.KeepaliveCountMax))
+
+	// Configure system
+	sshSystemOpts := []systemssh.SystemOption{
+		systemssh.CommandMiddleware(cmdM),
+	}
+
+	// Parallel sessions
+	sshSystemOpts = append(sshSystemOpts, systemssh.Sessions(c.Parallel))
+
+	// Files accessed over SFTP are not subject to privilege escalation
+	sshSystemOpts = append(sshSystemOpts, systemssh.Sftp(c.Sftp && c.Become == nil && !c.Sudo))
+
+	return systemssh.NewSystem(sshClient, sshSystemOpts...)
+}
+
+// sshNetConnectFromSchema returns a NetConnectFunc which connects to the remote through all proxy hops in the order
+// of configuration. The first proxy hop, or the remote if no proxy hops are configured, is connected directly.
+func sshNetConnectFromSchema(c Schema) (sshclient.NetConnectFunc, error) {
+	remoteAddr := sshAddrFromSshSchema(*c.Ssh)
+
+	hops, err := resolveProxyHops(c.Proxies, remoteAddr, c.Ssh.User)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(hops) == 0 {
+		// Connect directly
+		return sshclient.Dial(remoteAddr, c.Ssh.Timeout), nil
+	}
+
+	// Connect to first proxy hop directly unless it is a local command
+	var netConnect sshclient.NetConnectFunc
+	if hops[0].addr != nil {
+		netConnect = sshclient.Dial(hops[0].addr, hops[0].timeout)
+	}
 
-			sshConnectOpts = append(sshConnectOpts, sshclient.Net(sshNetConnect))
+	for i, hop := range hops {
+		// Connect to next proxy hop or remote via this proxy hop
+		nextAddr := remoteAddr
+		if i+1 < len(hops) {
+			nextAddr = hops[i+1].addr
 		}
 
-		sshConnect, err := sshclient.Prepare(sshConnectOpts...)
+		netConnect, err = hop.connect(netConnect, nextAddr)
 		if err != nil {
-			return nil, diag.FromErr(err)
+			return nil, fmt.Errorf("%s: %w", hop.description, err)
 		}
+	}
 
-		// Retries
-		if c.Retry {
-			// retries are limited by maximum duration according to provider config with constant 1 second backoff
-			retryM := sshclient.Retry(retry.WithMaxDuration(c.Timeout, retry.NewConstant(1*time.Second)))
-			sshConnect = retryM(sshConnect)
+	return netConnect, nil
+}
+
+// proxyHop is a resolved proxy hop
+type proxyHop struct {
+	description string
+	// addr is the address of the proxy hop. addr is nil if the proxy hop is a local command.
+	addr    net.Addr
+	timeout time.Duration
+	// connect returns a NetConnectFunc which connects to nextAddr via this proxy hop. netConnect connects to this proxy
+	// hop and is nil if addr is nil.
+	connect func(netConnect sshclient.NetConnectFunc, nextAddr net.Addr) (sshclient.NetConnectFunc, error)
+}
+
+// resolveProxyHops returns the proxy hops which are used to connect to remoteAddr. Proxy hops are resolved in reverse
+// order because a proxy server from the environment depends on the address of the next hop. Proxy hops from the
+// environment which do not apply to the next hop are omitted.
+func resolveProxyHops(proxies []*SchemaProxy, remoteAddr net.Addr, remoteUser string) ([]proxyHop, error) {
+	var hops []proxyHop
+
+	nextAddr := remoteAddr
+	nextUser := remoteUser
+	for i := len(proxies) - 1; i >= 0; i-- {
+		proxy := proxies[i]
+
+		var hop proxyHop
+		switch {
+		case proxy != nil && proxy.Command != "":
+			if i != 0 {
+				return nil, fmt.Errorf("proxy hop %d: %s is supported in the first proxy hop only", i+1, SchemaAttrProxyCommand)
+			}
+			hop = commandProxyHop(i+1, proxy.Command, nextUser)
+		case proxy != nil && proxy.Ssh != nil:
+			hop = sshProxyHop(i+1, *proxy.Ssh)
+			nextUser = proxy.Ssh.User
+		case proxy != nil && proxy.Socks5 != nil:
+			server, err := proxy.Socks5.resolve(schemaProxyServerSocks5, nextAddr)
+			if err != nil {
+				return nil, fmt.Errorf("proxy hop %d: %w", i+1, err)
+			}
+			if server == nil {
+				continue
+			}
+			hop = socks5ProxyHop(i+1, *server)
+			nextUser = ""
+		case proxy != nil && proxy.Http != nil:
+			server, err := proxy.Http.resolve(schemaProxyServerHttp, nextAddr)
+			if err != nil {
+				return nil, fmt.Errorf("proxy hop %d: %w", i+1, err)
+			}
+			if server == nil {
+				continue
+			}
+			hop = httpProxyHop(i+1, *server)
+			nextUser = ""
+		default:
+			return nil, fmt.Errorf("proxy hop %d: expected %s or block %s, %s or %s", i+1, SchemaAttrProxyCommand, SchemaAttrSsh, SchemaAttrProxySocks5, SchemaAttrProxyHttp)
 		}
 
-		// Break circuit when connection has failed once (after retries)
-		cbM := sshclient.CircuitBreak()
-		sshConnect = cbM(sshConnect)
+		hops = append([]proxyHop{hop}, hops...)
+		nextAddr = hop.addr
+	}
 
-		// Create ssh client
-		sshClient := sshclient.New(sshConnect)
+	return hops, nil
+}
 
-		// Configure system
-		var sshSystemOpts []systemssh.SystemOption
+func newProxyHop(n int, addr net.Addr, timeout time.Duration) proxyHop {
+	return proxyHop{
+		description: fmt.Sprintf("proxy hop %d (%s)", n, addr),
+		addr:        addr,
+		timeout:     timeout,
+	}
+}
 
-		if c.Sudo {
-			// Use sudo with shell /bin/sh
-			sshSystemOpts = append(sshSystemOpts, systemssh.CommandMiddleware(cmd.SudoShMiddleware()))
-		} else {
-			// Use shell /bin/sh
-			sshSystemOpts = append(sshSystemOpts, systemssh.CommandMiddleware(cmd.ShMiddleware()))
+// commandProxyHop connects to the next hop using a local command. user is the user of the next hop.
+func commandProxyHop(n int, command string, user string) proxyHop {
+	hop := proxyHop{
+		description: fmt.Sprintf("proxy hop %d (%s)", n, SchemaAttrProxyCommand),
+	}
+
+	hop.connect = func(_ sshclient.NetConnectFunc, nextAddr net.Addr) (sshclient.NetConnectFunc, error) {
+		if _, err := sshclient.ExpandCommandTokens(command, nextAddr, user); err != nil {
+			return nil, err
 		}
 
-		// Parallel sessions
-		sshSystemOpts = append(sshSystemOpts, systemssh.Sessions(c.Parallel))
+		return sshclient.NetDescribe(hop.description)(sshclient.Command(command, nextAddr, user)), nil
+	}
+
+	return hop
+}
 
-		s, err := systemssh.NewSystem(sshClient, sshSystemOpts...)
+func sshProxyHop(n int, s SchemaSsh) proxyHop {
+	hop := newProxyHop(n, sshAddrFromSshSchema(s), s.Timeout)
+
+	hop.connect = func(netConnect sshclient.NetConnectFunc, nextAddr net.Addr) (sshclient.NetConnectFunc, error) {
+		proxyConnectOpts := append(sshConnectOptsFromSshSchema(s), sshclient.Net(netConnect))
+		proxyConnect, err := sshclient.Prepare(proxyConnectOpts...)
 		if err != nil {
-			return nil, diag.FromErr(err)
+			return nil, err
 		}
 
-		// Construct provider instance
-		p := &Provider{
-			Config: *c,
-			System: s,
-		}
+		proxyConnect = sshclient.Describe(hop.description)(proxyConnect)
 
-		go func(ctx context.Context, s *systemssh.System) {
-			// Wait for stop context cancelled
-			<-stopCtx.Done()
+		return sshclient.Proxy(sshclient.New(proxyConnect), nextAddr), nil
+	}
 
-			// Disconnect system
-			_ = s.Close()
-		}(ctx, s)
+	return hop
+}
 
-		return p, nil
+func socks5ProxyHop(n int, s SchemaProxyServer) proxyHop {
+	hop := newProxyHop(n, s.addr(), s.Timeout)
+
+	hop.connect = func(netConnect sshclient.NetConnectFunc, nextAddr net.Addr) (sshclient.NetConnectFunc, error) {
+		return sshclient.NetDescribe(hop.description)(sshclient.Socks5(netConnect, hop.addr, nextAddr, s.options()...)), nil
+	}
+
+	return hop
+}
+
+func httpProxyHop(n int, s SchemaProxyServer) proxyHop {
+	hop := newProxyHop(n, s.addr(), s.Timeout)
+
+	hop.connect = func(netConnect sshclient.NetConnectFunc, nextAddr net.Addr) (sshclient.NetConnectFunc, error) {
+		return sshclient.NetDescribe(hop.description)(sshclient.HttpConnect(netConnect, hop.addr, nextAddr, s.options()...)), nil
+	}
+
+	return hop
+}
+
+func sshAddrFromSshSchema(s SchemaSsh) net.Addr {
+	return sshclient.NewHostPortAddr(sshclient.Tcp, s.Host, uint16(s.Port))
+}
+
+// keyboardInteractivePrompts returns the prompts of keyboard-interactive authentication in order of precedence
+func keyboardInteractivePrompts(s SchemaSsh) []sshclient.KeyboardInteractivePrompt {
+	var prompts []sshclient.KeyboardInteractivePrompt
+
+	if s.KeyboardInteractive.TotpSecret != "" {
+		prompts = append(prompts, sshclient.PromptTOTP(s.KeyboardInteractive.TotpPrompt, s.KeyboardInteractive.TotpSecret))
+	}
+
+	patterns := make([]string, 0, len(s.KeyboardInteractive.Answers))
+	for pattern := range s.KeyboardInteractive.Answers {
+		patterns = append(patterns, pattern)
+	}
+	sort.Strings(patterns)
+
+	for _, pattern := range patterns {
+		prompts = append(prompts, sshclient.PromptAnswer(pattern, s.KeyboardInteractive.Answers[pattern]))
+	}
+
+	// The keyboard-interactive fallback of the password authentication is superseded
+	if s.Password != "" {
+		prompts = append(prompts, sshclient.PromptAnswer(`(?i)password`, s.Password))
 	}
+
+	return prompts
 }
 
 func sshConnectOptsFromSshSchema(s SchemaSsh) []sshclient.ConnectOption {
 	var sshConnectOpts []sshclient.ConnectOption
 
 	// Address
-	sshConnectOpts = append(sshConnectOpts, sshclient.Addr(sshclient.NewHostPortAddr(sshclient.Tcp, s.Host, uint16(s.Port))))
+	sshConnectOpts = append(sshConnectOpts, sshclient.Addr(sshAddrFromSshSchema(s)))
 
 	// User
 	sshConnectOpts = append(sshConnectOpts, sshclient.User(s.User))
 
-	// Password
-	if s.Password != "" {
-		sshConnectOpts = append(sshConnectOpts, sshclient.Auth(sshclient.Password(s.Password)))
+	// Authentication methods are tried in a deterministic order: public key, keyboard-interactive, password.
+	// The ssh client tries each kind of method at most once, so that all identities are offered by a single public key
+	// method in the order certificate, private key, agent.
+	var signers []sshclient.SignersFunc
+
+	// Certificate
+	if s.Certificate != "" && s.PrivateKey != "" {
+		signers = append(signers, sshclient.CertificateSigners(s.Certificate, s.PrivateKey, s.PrivateKeyPassphrase))
 	}
 
 	// Private key
 	if s.PrivateKey != "" {
-		sshConnectOpts = append(sshConnectOpts, sshclient.Auth(sshclient.PrivateKey(s.PrivateKey)))
-	}
-
-	// Certificate
-	if s.Certificate != "" && s.PrivateKey != "" {
-		sshConnectOpts = append(sshConnectOpts, sshclient.Auth(sshclient.Certificate(s.Certificate, s.PrivateKey)))
+		signers = append(signers, sshclient.PrivateKeySigners(s.PrivateKey, s.PrivateKeyPassphrase))
 	}
 
 	// Agent
 	if s.Agent {
-		var sshAuthMethod sshclient.AuthMethod
+		var agentSigners sshclient.SignersFunc
 
 		if len(s.AgentIdentities) > 0 {
 			// List of explicit identities
-			sshAuthMethod = sshclient.AgentExplicitIdentities(s.AgentIdentities...)
+			agentSigners = sshclient.AgentExplicitIdentitiesSigners(s.AgentIdentities...)
 		} else {
 			// No explicit identities
-			sshAuthMethod = sshclient.Agent()
+			agentSigners = sshclient.AgentSigners()
 		}
 
-		sshConnectOpts = append(sshConnectOpts, sshclient.Auth(sshAuthMethod))
+		if len(signers) > 0 {
+			// An unavailable agent does not prevent authentication by the configured identities
+			agentSigners = sshclient.OptionalSigners(agentSigners)
+		}
 
+		signers = append(signers, agentSigners)
+	}
+
+	if len(signers) > 0 {
+		sshConnectOpts = append(sshConnectOpts, sshclient.Auth(sshclient.PublicKeys(signers...)))
+	}
+
+	// Keyboard-interactive
+	if s.KeyboardInteractive != nil {
+		sshConnectOpts = append(sshConnectOpts, sshclient.Auth(sshclient.KeyboardInteractive(keyboardInteractivePrompts(s)...)))
+	}
+
+	// Password
+	if s.Password != "" {
+		sshConnectOpts = append(sshConnectOpts, sshclient.Auth(sshclient.Password(s.Password)))
 	}
 
 	// Host key
+	var hostKeyVerifier sshclient.HostKeyVerifier
+	var hostKeyKnownHosts bool
 	if s.HostKey != "" {
-		sshConnectOpts = append(sshConnectOpts, sshclient.HostKey(sshclient.StaticHostKey(s.HostKey)))
+		hostKeyVerifier = sshclient.StaticHostKey(s.HostKey)
+	} else if s.KnownHosts != "" {
+		hostKeyVerifier = sshclient.KnownHosts(s.KnownHosts)
+		hostKeyKnownHosts = true
+	}
+
+	if len(s.HostCaPublicKeys) > 0 {
+		// Host certificates with optional fallback for plain host keys
+		hostCertOpts := []sshclient.HostCertificateOption{
+			sshclient.HostCertificateRevokedSerials(s.HostCaRevokedSerials...),
+		}
+		if hostKeyVerifier != nil {
+			hostCertOpts = append(hostCertOpts, sshclient.HostCertificateFallback(hostKeyVerifier))
+		}
+
+		sshConnectOpts = append(sshConnectOpts, sshclient.HostKey(sshclient.HostCertificate(s.HostCaPublicKeys, hostCertOpts...)))
+	} else if hostKeyVerifier != nil {
+		sshConnectOpts = append(sshConnectOpts, sshclient.HostKey(hostKeyVerifier))
+
+		if hostKeyKnownHosts {
+			// Negotiate a host key type which is present in known_hosts
+			sshConnectOpts = append(sshConnectOpts, sshclient.KnownHostKeyAlgorithms())
+		}
 	} else {
 		sshConnectOpts = append(sshConnectOpts, sshclient.HostKeyCallback(ssh.InsecureIgnoreHostKey()))
 	}
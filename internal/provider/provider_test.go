@@ -1,10 +1,17 @@
 package provider_test
 
 import (
+	"context"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
 	"github.com/neuspaces/terraform-provider-system/internal/acctest"
 	"github.com/neuspaces/terraform-provider-system/internal/provider"
 	"github.com/stretchr/testify/require"
+	"io"
 	"log"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"strings"
 	"testing"
@@ -36,3 +43,87 @@ func TestProvider_InternalValidate(t *testing.T) {
 	err := p.InternalValidate()
 	require.NoError(t, err)
 }
+
+// TestProvider_ProxySshEnvDefaults validates that the ssh block of the first proxy hop is configured from the environment
+// and the ssh blocks of subsequent proxy hops are not
+func TestProvider_ProxySshEnvDefaults(t *testing.T) {
+	t.Setenv(provider.SchemaEnvPrefixProxySsh+"HOST", "bastion.example.com")
+
+	configure := func(proxies ...map[string]interface{}) diag.Diagnostics {
+		p := provider.New("dev")()
+
+		var proxiesV []interface{}
+		for _, proxySsh := range proxies {
+			proxiesV = append(proxiesV, map[string]interface{}{
+				provider.SchemaAttrSsh: []interface{}{proxySsh},
+			})
+		}
+
+		ctx := context.WithValue(context.Background(), schema.StopContextKey, context.Background())
+
+		return p.Configure(ctx, terraform.NewResourceConfigRaw(map[string]interface{}{
+			provider.SchemaAttrSsh: []interface{}{map[string]interface{}{
+				provider.SchemaAttrSshHost:     "remote.example.com",
+				provider.SchemaAttrSshUser:     "root",
+				provider.SchemaAttrSshPassword: "secret",
+			}},
+			provider.SchemaAttrProxy: proxiesV,
+		}))
+	}
+
+	// The first hop is configured from the environment
+	diags := configure(map[string]interface{}{
+		provider.SchemaAttrSshUser: "jump",
+	})
+	require.False(t, diags.HasError(), "%v", diags)
+
+	// Subsequent hops are not configured from the environment
+	diags = configure(map[string]interface{}{
+		provider.SchemaAttrSshUser: "jump",
+	}, map[string]interface{}{
+		provider.SchemaAttrSshUser: "jump",
+	})
+	require.True(t, diags.HasError())
+	require.Contains(t, diags[0].Summary, "proxy.1.ssh")
+}
+
+// TestProvider_ConfigureSources validates that each configuration of the provider opens sources with its own clients
+func TestProvider_ConfigureSources(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Header.Get("Authorization")))
+	}))
+	t.Cleanup(server.Close)
+
+	p := provider.New("dev")()
+
+	configure := func(bearerToken string) *provider.Provider {
+		ctx := context.WithValue(context.Background(), schema.StopContextKey, context.Background())
+
+		diags := p.Configure(ctx, terraform.NewResourceConfigRaw(map[string]interface{}{
+			provider.SchemaAttrLocal: []interface{}{map[string]interface{}{}},
+			provider.SchemaAttrSourceHttp: []interface{}{map[string]interface{}{
+				provider.SchemaAttrSourceHttpBearerToken: bearerToken,
+			}},
+		}))
+		require.False(t, diags.HasError(), "%v", diags)
+
+		return p.Meta().(*provider.Provider)
+	}
+
+	first := configure("first")
+	second := configure("second")
+
+	for expected, configured := range map[string]*provider.Provider{
+		"Bearer first":  first,
+		"Bearer second": second,
+	} {
+		s, err := configured.Sources.Open(server.URL)
+		require.NoError(t, err)
+
+		content, err := io.ReadAll(s)
+		require.NoError(t, err)
+		require.NoError(t, s.Close())
+
+		require.Equal(t, expected, string(content))
+	}
+}
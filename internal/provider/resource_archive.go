@@ -0,0 +1,339 @@
+package provider
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"github.com/neuspaces/terraform-provider-system/internal/client"
+	"github.com/neuspaces/terraform-provider-system/internal/lib/filemode"
+	"github.com/neuspaces/terraform-provider-system/internal/source"
+	"github.com/neuspaces/terraform-provider-system/internal/validate"
+	"io"
+)
+
+const resourceArchiveName = "system_archive"
+
+const (
+	resourceArchiveAttrId              = "id"
+	resourceArchiveAttrPath            = "path"
+	resourceArchiveAttrSource          = "source"
+	resourceArchiveAttrStripComponents = "strip_components"
+	resourceArchiveAttrFileMode        = resourceFolderSyncAttrFileMode
+	resourceArchiveAttrDirMode         = resourceFolderSyncAttrDirMode
+	resourceArchiveAttrUser            = "user"
+	resourceArchiveAttrGroup           = "group"
+	resourceArchiveAttrEtag            = "etag"
+	resourceArchiveAttrSha256Sum       = "sha256sum"
+	resourceArchiveAttrManifest        = "manifest"
+)
+
+func resourceArchive(sources *providerSources) *schema.Resource {
+	return &schema.Resource{
+		Description: fmt.Sprintf("`%s` extracts an archive into a folder on the remote system.", resourceArchiveName),
+
+		CreateContext: resourceArchiveCreate,
+		ReadContext:   resourceArchiveRead,
+		UpdateContext: resourceArchiveUpdate,
+		DeleteContext: resourceArchiveDelete,
+
+		CustomizeDiff: resourceArchiveCustomizeDiff,
+
+		SchemaVersion: 1,
+
+		Schema: map[string]*schema.Schema{
+			resourceArchiveAttrId: {
+				Description: "ID of the archive",
+				Type:        schema.TypeString,
+				Computed:    true,
+			},
+			resourceArchiveAttrPath: {
+				Description:      "Path to the folder into which the archive is extracted. Must be an absolute path. The folder is created if it does not exist.",
+				Type:             schema.TypeString,
+				Required:         true,
+				ForceNew:         true,
+				ValidateDiagFunc: validate.AbsolutePath(),
+			},
+			resourceArchiveAttrSource: {
+				Description:      fmt.Sprintf("Path to a local file or url of a tar, gzip compressed tar or zip archive. The format is detected from the content. Append the query parameter `%[1]s` with the SHA-256 checksum in hexadecimal encoding like `https://example.com/app.tar.gz?%[1]s=...` to verify the archive before it is extracted.", source.QueryExpectedSha256),
+				Type:             schema.TypeString,
+				Required:         true,
+				ValidateDiagFunc: validateSourceUrl(sources),
+			},
+			resourceArchiveAttrStripComponents: {
+				Description:  "Number of leading path elements which are removed from the entries of the archive like the option `--strip-components` of `tar`. Entries with fewer path elements are not extracted. Defaults to `0`.",
+				Type:         schema.TypeInt,
+				Optional:     true,
+				Default:      0,
+				ValidateFunc: validation.IntAtLeast(0),
+			},
+			resourceArchiveAttrFileMode: {
+				Description:      "Permissions of all extracted files in octal format like `644`. Defaults to the permissions in the archive.",
+				Type:             schema.TypeString,
+				Optional:         true,
+				ValidateDiagFunc: validate.FileMode(),
+			},
+			resourceArchiveAttrDirMode: {
+				Description:      "Permissions of all extracted folders in octal format like `755`. Defaults to the permissions in the archive.",
+				Type:             schema.TypeString,
+				Optional:         true,
+				ValidateDiagFunc: validate.FileMode(),
+			},
+			resourceArchiveAttrUser: {
+				Description: "Name or ID of the user who owns all extracted files and folders. Defaults to the connecting user.",
+				Type:        schema.TypeString,
+				Optional:    true,
+			},
+			resourceArchiveAttrGroup: {
+				Description: "Name or ID of the group that owns all extracted files and folders. Defaults to the primary group of the connecting user.",
+				Type:        schema.TypeString,
+				Optional:    true,
+			},
+			resourceArchiveAttrEtag: {
+				Description: fmt.Sprintf("ETag of the archive. The archive is extracted again if the ETag of `%[1]s` changes.", resourceArchiveAttrSource),
+				Type:        schema.TypeString,
+				Computed:    true,
+			},
+			resourceArchiveAttrSha256Sum: {
+				Description: fmt.Sprintf("SHA-256 checksum of the archive in hexadecimal encoding. The archive is extracted again if `%[1]s` provides a different checksum.", resourceArchiveAttrSource),
+				Type:        schema.TypeString,
+				Computed:    true,
+			},
+			resourceArchiveAttrManifest: {
+				Description: "Paths of the extracted files and folders relative to the folder. Paths of folders have a trailing slash. If an extracted path is removed outside of terraform, the archive is extracted again.",
+				Type:        schema.TypeList,
+				Computed:    true,
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
+			SchemaAttrRunAs: schemaRunAs(),
+		},
+	}
+}
+
+func resourceArchiveGetResourceData(d *schema.ResourceData) client.Archive {
+	a := client.Archive{
+		Path:            d.Get(resourceArchiveAttrPath).(string),
+		StripComponents: d.Get(resourceArchiveAttrStripComponents).(int),
+		User:            d.Get(resourceArchiveAttrUser).(string),
+		Group:           d.Get(resourceArchiveAttrGroup).(string),
+	}
+
+	if v := d.Get(resourceArchiveAttrFileMode).(string); v != "" {
+		a.FileMode = filemode.MustParse(v)
+	}
+
+	if v := d.Get(resourceArchiveAttrDirMode).(string); v != "" {
+		a.DirMode = filemode.MustParse(v)
+	}
+
+	return a
+}
+
+func resourceArchiveManifest(v interface{}) client.ArchiveManifest {
+	m := client.ArchiveManifest{}
+	for _, v := range v.([]interface{}) {
+		m = append(m, v.(string))
+	}
+
+	return m
+}
+
+// resourceArchiveCustomizeDiff plans the extraction of the archive if an attribute or the ETag or the checksum of the
+// source changes
+func resourceArchiveCustomizeDiff(_ context.Context, d *schema.ResourceDiff, meta interface{}) error {
+	changed := d.Id() == "" || d.HasChanges(
+		resourceArchiveAttrSource,
+		resourceArchiveAttrStripComponents,
+		resourceArchiveAttrFileMode,
+		resourceArchiveAttrDirMode,
+		resourceArchiveAttrUser,
+		resourceArchiveAttrGroup,
+	)
+
+	if !changed && d.NewValueKnown(resourceArchiveAttrSource) {
+		p, isProvider := meta.(*Provider)
+		if !isProvider {
+			return fmt.Errorf("expected type *Provider, got unexpected type %T", meta)
+		}
+
+		s, err := p.Sources.Open(d.Get(resourceArchiveAttrSource).(string))
+		if err != nil {
+			return err
+		}
+		defer func() {
+			_ = s.Close()
+		}()
+
+		m, err := s.Meta()
+		if err != nil {
+			return err
+		}
+
+		changed = m.ETag() != d.Get(resourceArchiveAttrEtag).(string) ||
+			(m.Sha256Sum() != "" && m.Sha256Sum() != d.Get(resourceArchiveAttrSha256Sum).(string))
+	}
+
+	if !changed {
+		return nil
+	}
+
+	for _, key := range []string{resourceArchiveAttrEtag, resourceArchiveAttrSha256Sum, resourceArchiveAttrManifest} {
+		err := d.SetNewComputed(key)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func resourceArchiveClient(meta interface{}, d *schema.ResourceData) (client.ArchiveClient, diag.Diagnostics) {
+	p, diagErr := providerFromMeta(meta)
+	if diagErr != nil {
+		return nil, diagErr
+	}
+
+	s, diagErr := systemFromResourceData(p, d)
+	if diagErr != nil {
+		return nil, diagErr
+	}
+
+	return client.NewArchiveClient(s), nil
+}
+
+// resourceArchiveExtract extracts the source and sets the attributes which describe the extracted archive
+func resourceArchiveExtract(ctx context.Context, c client.ArchiveClient, meta interface{}, d *schema.ResourceData) (client.ArchiveManifest, diag.Diagnostics) {
+	p, diagErr := providerFromMeta(meta)
+	if diagErr != nil {
+		return nil, diagErr
+	}
+
+	s, err := p.Sources.Open(d.Get(resourceArchiveAttrSource).(string))
+	if err != nil {
+		return nil, diag.FromErr(err)
+	}
+	defer func() {
+		_ = s.Close()
+	}()
+
+	// Verify the content before it is extracted
+	err = source.Verify(s)
+	if err != nil {
+		return nil, diag.FromErr(err)
+	}
+
+	m, err := s.Meta()
+	if err != nil {
+		return nil, diag.FromErr(err)
+	}
+
+	h := sha256.New()
+
+	manifest, err := c.Extract(ctx, resourceArchiveGetResourceData(d), io.TeeReader(s, h))
+	if err != nil {
+		return nil, diag.FromErr(err)
+	}
+
+	_ = d.Set(resourceArchiveAttrEtag, m.ETag())
+	_ = d.Set(resourceArchiveAttrSha256Sum, hex.EncodeToString(h.Sum(nil)))
+	_ = d.Set(resourceArchiveAttrManifest, []string(manifest))
+
+	return manifest, nil
+}
+
+func resourceArchiveCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	c, diagErr := resourceArchiveClient(meta, d)
+	if diagErr != nil {
+		return diagErr
+	}
+
+	_, diagErr = resourceArchiveExtract(ctx, c, meta, d)
+	if diagErr != nil {
+		return diagErr
+	}
+
+	d.SetId(d.Get(resourceArchiveAttrPath).(string))
+
+	return resourceArchiveRead(ctx, d, meta)
+}
+
+func resourceArchiveRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	c, diagErr := resourceArchiveClient(meta, d)
+	if diagErr != nil {
+		return diagErr
+	}
+
+	a := resourceArchiveGetResourceData(d)
+	a.Path = d.Id()
+
+	manifest := resourceArchiveManifest(d.Get(resourceArchiveAttrManifest))
+
+	existing, err := c.Get(ctx, a, manifest)
+	if errors.Is(err, client.ErrArchiveNotFound) {
+		d.SetId("")
+		return nil
+	} else if err != nil {
+		return diag.FromErr(err)
+	}
+
+	// Extract again if extracted paths have been removed
+	if len(existing) != len(manifest) {
+		d.SetId("")
+	}
+
+	return nil
+}
+
+func resourceArchiveUpdate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	c, diagErr := resourceArchiveClient(meta, d)
+	if diagErr != nil {
+		return diagErr
+	}
+
+	prevManifest, _ := d.GetChange(resourceArchiveAttrManifest)
+
+	manifest, diagErr := resourceArchiveExtract(ctx, c, meta, d)
+	if diagErr != nil {
+		return diagErr
+	}
+
+	// Remove paths of the previous archive which are not contained in the current archive
+	extracted := map[string]bool{}
+	for _, p := range manifest {
+		extracted[p] = true
+	}
+
+	var stale client.ArchiveManifest
+	for _, p := range resourceArchiveManifest(prevManifest) {
+		if !extracted[p] {
+			stale = append(stale, p)
+		}
+	}
+
+	err := c.Delete(ctx, resourceArchiveGetResourceData(d), stale)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	return resourceArchiveRead(ctx, d, meta)
+}
+
+func resourceArchiveDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	c, diagErr := resourceArchiveClient(meta, d)
+	if diagErr != nil {
+		return diagErr
+	}
+
+	err := c.Delete(ctx, resourceArchiveGetResourceData(d), resourceArchiveManifest(d.Get(resourceArchiveAttrManifest)))
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	return nil
+}
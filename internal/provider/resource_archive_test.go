@@ -0,0 +1,98 @@
+package provider_test
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/neuspaces/terraform-provider-system/internal/acctest"
+	"github.com/neuspaces/terraform-provider-system/internal/acctest/tfbuild"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testAccArchiveWrite writes a gzip compressed tar archive which contains the files in the folder app-1.0
+func testAccArchiveWrite(t *testing.T, name string, files map[string]string) {
+	file, err := os.Create(name)
+	require.NoError(t, err)
+	defer func() {
+		_ = file.Close()
+	}()
+
+	gzipWriter := gzip.NewWriter(file)
+	tarWriter := tar.NewWriter(gzipWriter)
+
+	require.NoError(t, tarWriter.WriteHeader(&tar.Header{Typeflag: tar.TypeDir, Name: "app-1.0/", Mode: 0755}))
+	for fileName, content := range files {
+		require.NoError(t, tarWriter.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: "app-1.0/" + fileName, Mode: 0644, Size: int64(len(content))}))
+		_, err = tarWriter.Write([]byte(content))
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, tarWriter.Close())
+	require.NoError(t, gzipWriter.Close())
+}
+
+func TestAccArchive(t *testing.T) {
+	testConfig := newTestFolderConfig()
+
+	acctest.Current().Targets.Foreach(t, func(t *testing.T, target acctest.Target) {
+		t.Parallel()
+
+		// Each target modifies its own local archive
+		archivePath := filepath.Join(t.TempDir(), "app.tar.gz")
+		testAccArchiveWrite(t, archivePath, map[string]string{
+			"app.conf": "a",
+			"VERSION":  "1.0",
+		})
+
+		folderPath := testRunFolderPath(target, testConfig.folderName)
+
+		config := tfbuild.FileString(tfbuild.File(
+			acctest.ProviderConfigBlock(target.Configs.Default()),
+			tfbuild.Resource("system_archive", "test",
+				tfbuild.AttributeString("path", folderPath),
+				tfbuild.AttributeString("source", archivePath),
+				tfbuild.AttributeInt("strip_components", 1),
+				tfbuild.AttributeString("file_mode", "600"),
+			),
+			tfbuild.Data("system_file", "test",
+				tfbuild.AttributeString("path", folderPath+"/VERSION"),
+				tfbuild.DependsOn(tfbuild.TraversalResource("system_archive", "test")),
+			),
+		))
+
+		resource.Test(t, resource.TestCase{
+			ProviderFactories: acctest.ProviderFactories(),
+			Steps: []resource.TestStep{
+				{
+					Config: config,
+					Check: resource.ComposeTestCheckFunc(
+						resource.TestCheckResourceAttr("system_archive.test", "id", folderPath),
+						resource.TestCheckResourceAttr("system_archive.test", "manifest.#", "2"),
+						resource.TestCheckResourceAttr("system_archive.test", "manifest.0", "VERSION"),
+						resource.TestCheckResourceAttr("system_archive.test", "manifest.1", "app.conf"),
+						resource.TestCheckResourceAttrSet("system_archive.test", "etag"),
+						resource.TestCheckResourceAttrSet("system_archive.test", "sha256sum"),
+						resource.TestCheckResourceAttr("data.system_file.test", "content", "1.0"),
+					),
+				},
+				{
+					// Extract again after the archive has changed
+					PreConfig: func() {
+						testAccArchiveWrite(t, archivePath, map[string]string{
+							"VERSION": "2.0",
+						})
+					},
+					Config: config,
+					Check: resource.ComposeTestCheckFunc(
+						resource.TestCheckResourceAttr("system_archive.test", "manifest.#", "1"),
+						resource.TestCheckResourceAttr("system_archive.test", "manifest.0", "VERSION"),
+						resource.TestCheckResourceAttr("data.system_file.test", "content", "2.0"),
+					),
+				},
+			},
+		})
+	})
+}
@@ -3,16 +3,19 @@ package provider
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"github.com/hashicorp/go-cty/cty"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
 	"github.com/neuspaces/terraform-provider-system/internal/client"
 	"github.com/neuspaces/terraform-provider-system/internal/lib/filemode"
 	"github.com/neuspaces/terraform-provider-system/internal/source"
 	"github.com/neuspaces/terraform-provider-system/internal/validate"
 	"io"
 	"path"
+	"regexp"
 	"strings"
 )
 
@@ -30,28 +33,25 @@ const (
 	resourceFileAttrContentSensitive = "content_sensitive"
 	resourceFileAttrSource           = "source"
 	resourceFileAttrMd5Sum           = "md5sum"
+	resourceFileAttrSha256Sum        = "sha256sum"
+	resourceFileAttrSha512Sum        = "sha512sum"
 	resourceFileAttrBasename         = "basename"
+	resourceFileAttrAtomic           = "atomic"
+	resourceFileAttrValidateCommand  = "validate_command"
+	resourceFileAttrBackup           = "backup"
+	resourceFileAttrBackupDir        = "backup_dir"
+	resourceFileAttrBackupSuffix     = "backup_suffix"
+	resourceFileAttrBackupRetention  = "backup_retention"
+	resourceFileAttrBackupPath       = "backup_path"
 )
 
-func resourceFile() *schema.Resource {
-	// Configure source registry
-	sources, err := source.NewRegistry(
-		source.WithClients(
-			source.NewMetaCache(source.NewFileClient()),
-			source.NewMetaCache(source.NewHttpClient()),
-		),
-		source.WithDefaultScheme(source.FileScheme),
-	)
-	if err != nil {
-		panic(err)
-	}
-
+func resourceFile(sources *providerSources) *schema.Resource {
 	return &schema.Resource{
 		Description: fmt.Sprintf("`%s` manages a file on the remote system.", resourceFileName),
 
-		CreateContext: resourceFileCreateFactory(sources),
+		CreateContext: resourceFileCreate,
 		ReadContext:   resourceFileRead,
-		UpdateContext: resourceFileUpdateFactory(sources),
+		UpdateContext: resourceFileUpdate,
 		DeleteContext: resourceFileDelete,
 
 		Importer: &schema.ResourceImporter{
@@ -129,34 +129,11 @@ func resourceFile() *schema.Resource {
 				},
 			},
 			resourceFileAttrSource: {
-				Description: fmt.Sprintf("Path to a local file to upload as the file. Mutually exclusive with attributes `%[1]s` and `%[2]s`.", resourceFileAttrContent, resourceFileAttrContentSensitive, resourceFileAttrSource),
-				Type:        schema.TypeString,
-				Optional:    true,
-				Sensitive:   false,
-				ForceNew:    true,
-				ValidateDiagFunc: func(val interface{}, path cty.Path) diag.Diagnostics {
-					valUrl, err := validate.ExpectUrl(val, path)
-					if err != nil {
-						return err
-					}
-
-					// Attempt to open
-					s, openErr := sources.OpenUrl(valUrl)
-					if openErr != nil {
-						return []diag.Diagnostic{
-							{
-								Severity:      diag.Error,
-								Summary:       fmt.Sprintf("failed to open url %q", valUrl.String()),
-								Detail:        openErr.Error(),
-								AttributePath: path,
-							},
-						}
-					}
-
-					_ = s.Close()
-
-					return nil
-				},
+				Description:      fmt.Sprintf("Path to a local file or url to upload as the file. Urls with the scheme `http` or `https` are downloaded by the provider. Urls like `sftp://user@host/path` or `scp://user@host/path` are read from another host over ssh by the provider. Urls like `remote:///path` reference a file on the remote system which is copied on the remote system. Data urls like `data:;base64,SGVsbG8=` contain the content which is not sensitive and may be shown in the plan output and logs; prefer `%[2]s` for secrets. Urls like `template://./app.conf.tmpl?%[5]s=...` render a local file as Go template with the variables in the query parameter `%[5]s` as url encoded JSON object. Append the query parameter `%[4]s` with the SHA-256 checksum in hexadecimal encoding like `https://example.com/file.tar.gz?%[4]s=...` to verify the content before it is uploaded. Mutually exclusive with attributes `%[1]s` and `%[2]s`.", resourceFileAttrContent, resourceFileAttrContentSensitive, resourceFileAttrSource, source.QueryExpectedSha256, source.QueryTemplateVars),
+				Type:             schema.TypeString,
+				Optional:         true,
+				Sensitive:        false,
+				ValidateDiagFunc: validateSourceUrl(sources),
 				// StateFunc stores the etag of the referenced source in the state in the form etag=[etag]
 				StateFunc: func(val interface{}) string {
 					// Expect a string
@@ -171,7 +148,7 @@ func resourceFile() *schema.Resource {
 					}
 
 					// Get etag from source meta struct
-					s, err := sources.Open(valStr)
+					s, err := sources.get().Open(valStr)
 					if err != nil {
 						panic(err)
 					}
@@ -193,20 +170,159 @@ func resourceFile() *schema.Resource {
 					resourceFileAttrContentSensitive,
 				},
 			},
+			resourceFileAttrAtomic: {
+				Description: "If `true`, the content is written to a temporary file in the same directory which receives the mode and the owner of the file, is flushed to disk and is renamed to the path. Readers never observe partially written content and the temporary file is removed if writing fails. If the path is a symbolic link, the target of the link is replaced and the link is retained. Files with multiple hard links cannot be written atomically and fail the apply because the rename would detach the file from its other links. The rename does not retain access control lists, extended attributes or SELinux labels of the file. Set to `false` for files which must be written in place such as device files, files with multiple hard links, files with access control lists or files in directories which are not writable. Defaults to `true`.",
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     true,
+			},
+			resourceFileAttrValidateCommand: {
+				Description:  fmt.Sprintf("Command which validates the content before it replaces the file, for example `visudo -cf %%s` or `sshd -t -f %%s`. Each `%%s` is replaced by the path of the temporary file which already has the mode and the owner of the file. The command is executed whenever the content is written. If the command exits with a non-zero exit code, the apply fails with the output of the command and the file remains unchanged. Requires `%[1]s`.", resourceFileAttrAtomic),
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validation.StringMatch(regexp.MustCompile(`%s`), "validate command must reference the temporary file with %s"),
+			},
+			resourceFileAttrBackup: {
+				Description: fmt.Sprintf("If `true`, a copy of the file including mode, owner and timestamps is created before the content is overwritten or the file is deleted. The path of the latest backup is provided by `%[1]s`. A backup is never overwritten: writing fails if a backup with the same name exists. Defaults to `false`.", resourceFileAttrBackupPath),
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+			},
+			resourceFileAttrBackupDir: {
+				Description:      fmt.Sprintf("Directory of the backups. The directory is created if it does not exist. Defaults to the directory of the file. Requires `%[1]s`.", resourceFileAttrBackup),
+				Type:             schema.TypeString,
+				Optional:         true,
+				ValidateDiagFunc: validate.AbsolutePath(),
+				RequiredWith:     []string{resourceFileAttrBackup},
+			},
+			resourceFileAttrBackupSuffix: {
+				Description:  fmt.Sprintf("Suffix which is appended to the name of the file to name a backup. The suffix is a format of `date` which is evaluated in UTC such as `.%%Y%%m%%d%%H%%M%%S~`. Backups are ordered by name, so the suffix should order chronologically. The suffix should contain nanoseconds `%%N` to name backups uniquely. The retention recognizes backups by the suffix with digits in place of each conversion, so the suffix should only contain numeric conversions. Defaults to `%[1]s`.", client.FileBackupDefaultSuffix),
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      client.FileBackupDefaultSuffix,
+				ValidateFunc: validation.StringMatch(regexp.MustCompile(`^[A-Za-z0-9._%+~-]+$`), "backup suffix must only contain letters, digits and the characters . _ % + ~ -"),
+			},
+			resourceFileAttrBackupRetention: {
+				Description:  "Number of most recent backups of the file which are retained. Older backups are removed. Set to `0` to retain all backups. Defaults to `5`.",
+				Type:         schema.TypeInt,
+				Optional:     true,
+				Default:      5,
+				ValidateFunc: validation.IntAtLeast(0),
+			},
+			resourceFileAttrBackupPath: {
+				Description: fmt.Sprintf("Path of the latest backup which has been created by this resource. Empty if no backup has been created. Requires `%[1]s`.", resourceFileAttrBackup),
+				Type:        schema.TypeString,
+				Computed:    true,
+			},
 			resourceFileAttrMd5Sum: {
 				Description: "MD5 checksum of the remote file contents on the system in base64 encoding.",
 				Type:        schema.TypeString,
 				Computed:    true,
 			},
+			resourceFileAttrSha256Sum: {
+				Description: fmt.Sprintf("SHA-256 checksum of the remote file contents on the system in hexadecimal encoding. Empty if the system provides none of `sha256sum`, `shasum` and `openssl`. If the checksum changes outside of terraform, a file with attribute `%[1]s` is overwritten with the source.", resourceFileAttrSource),
+				Type:        schema.TypeString,
+				Computed:    true,
+			},
+			resourceFileAttrSha512Sum: {
+				Description: "SHA-512 checksum of the remote file contents on the system in hexadecimal encoding. Empty if the system provides none of `sha512sum`, `shasum` and `openssl`.",
+				Type:        schema.TypeString,
+				Computed:    true,
+			},
 			resourceFileAttrBasename: {
 				Description: fmt.Sprintf("Base name of the file. Returns the last element of path. Example: Given the attribute `%[1]s` is `/path/to/file.txt`, the `%[2]s` is `file.txt`.", resourceFileAttrPath, resourceFileAttrBasename),
 				Type:        schema.TypeString,
 				Computed:    true,
 			},
+			SchemaAttrRunAs: schemaRunAs(),
 		},
 	}
 }
 
+// newSourceRegistry returns the source.Registry which opens the attribute `source` of resources. http and https urls
+// are opened with httpClient, sftp and scp urls are opened with sftpClient.
+func newSourceRegistry(httpClient *source.Http, sftpClient *source.Sftp) *source.Registry {
+	sources, err := source.NewRegistry(
+		source.WithClients(
+			source.NewMetaCache(source.NewFileClient()),
+			source.NewMetaCache(httpClient),
+			source.NewMetaCache(sftpClient),
+			source.NewRemoteClient(),
+			source.NewDataClient(),
+			source.NewTemplateClient(),
+		),
+		source.WithDefaultScheme(source.FileScheme),
+	)
+	if err != nil {
+		panic(err)
+	}
+
+	return sources
+}
+
+// validateSourceLocalSchemes are the schemes of source urls which are opened during validation. Urls of other hosts are
+// not opened because the provider is not configured during validation.
+var validateSourceLocalSchemes = map[string]bool{
+	"":                    true,
+	source.FileScheme:     true,
+	source.RemoteScheme:   true,
+	source.DataScheme:     true,
+	source.TemplateScheme: true,
+}
+
+// validateSourceUrl returns a schema.SchemaValidateDiagFunc which validates that a source url can be opened
+func validateSourceUrl(sources *providerSources) schema.SchemaValidateDiagFunc {
+	return func(val interface{}, path cty.Path) diag.Diagnostics {
+		valUrl, err := validate.ExpectUrl(val, path)
+		if err != nil {
+			return err
+		}
+
+		if !sources.get().Supports(valUrl) {
+			return []diag.Diagnostic{
+				{
+					Severity:      diag.Error,
+					Summary:       fmt.Sprintf("unsupported scheme of url %q", valUrl.String()),
+					AttributePath: path,
+				},
+			}
+		}
+
+		// Reject passwords in urls of other ssh hosts because the attribute is not sensitive
+		if _, hasPassword := valUrl.User.Password(); hasPassword && (valUrl.Scheme == source.SftpScheme || valUrl.Scheme == source.ScpScheme) {
+			return []diag.Diagnostic{
+				{
+					Severity:      diag.Error,
+					Summary:       fmt.Sprintf("password in url %q is not supported", valUrl.Redacted()),
+					Detail:        fmt.Sprintf("configure the password by the attribute `%s` of the provider block `%s`", SchemaAttrSourceSftpPassword, SchemaAttrSourceSftp),
+					AttributePath: path,
+				},
+			}
+		}
+
+		if !validateSourceLocalSchemes[valUrl.Scheme] {
+			return nil
+		}
+
+		// Attempt to open
+		s, openErr := sources.get().OpenUrl(valUrl)
+		if openErr != nil {
+			return []diag.Diagnostic{
+				{
+					Severity:      diag.Error,
+					Summary:       fmt.Sprintf("failed to open url %q", valUrl.String()),
+					Detail:        openErr.Error(),
+					AttributePath: path,
+				},
+			}
+		}
+
+		_ = s.Close()
+
+		return nil
+	}
+}
+
 func resourceFileGetResourceData(sources *source.Registry, d *schema.ResourceData) (*client.File, diag.Diagnostics) {
 	r := &client.File{
 		Path:    d.Get(resourceFileAttrPath).(string),
@@ -239,20 +355,29 @@ func resourceFileGetResourceData(sources *source.Registry, d *schema.ResourceDat
 		r.Gid = intOrDefault(optional(d.GetOk(resourceFileAttrGid)), -1)
 	}
 
-	if d.HasChange(resourceFileAttrContent) {
-		content := d.Get(resourceFileAttrContent).(string)
-		if content != "" {
-			r.Content = bytes.NewReader([]byte(content))
+	// The attributes are mutually exclusive. The attribute which is set receives the content when switching between them.
+	if content := d.Get(resourceFileAttrContent).(string); d.HasChange(resourceFileAttrContent) && content != "" {
+		r.Content = bytes.NewReader([]byte(content))
+	} else if content := d.Get(resourceFileAttrContentSensitive).(string); d.HasChange(resourceFileAttrContentSensitive) && content != "" {
+		r.Content = bytes.NewReader([]byte(content))
+	} else if sourceUrlStr := d.Get(resourceFileAttrSource).(string); d.HasChange(resourceFileAttrSource) && sourceUrlStr != "" {
+		s, err := sources.Open(sourceUrlStr)
+		if err != nil {
+			return nil, diag.FromErr(err)
 		}
-	} else if d.HasChange(resourceFileAttrContentSensitive) {
-		content := d.Get(resourceFileAttrContentSensitive).(string)
-		if content != "" {
-			r.Content = bytes.NewReader([]byte(content))
+
+		// The content of a file on the system is copied on the system
+		if remote, isRemote := s.(*source.RemoteSource); isRemote {
+			_ = s.Close()
+			r.CopyFrom = remote.Path
+			r.CopyFromSha256Sum = remote.ExpectedSha256
+			return r, nil
 		}
-	} else if d.HasChange(resourceFileAttrSource) {
-		sourceUrlStr := d.Get(resourceFileAttrSource).(string)
-		s, err := sources.Open(sourceUrlStr)
+
+		// Verify the content before it is uploaded
+		err = source.Verify(s)
 		if err != nil {
+			_ = s.Close()
 			return nil, diag.FromErr(err)
 		}
 
@@ -271,6 +396,8 @@ func resourceFileSetResourceData(r *client.File, d *schema.ResourceData) diag.Di
 	_ = d.Set(resourceFileAttrGid, r.Gid)
 
 	_ = d.Set(resourceFileAttrMd5Sum, r.Md5Sum)
+	_ = d.Set(resourceFileAttrSha256Sum, r.Sha256Sum)
+	_ = d.Set(resourceFileAttrSha512Sum, r.Sha512Sum)
 	_ = d.Set(resourceFileAttrBasename, path.Base(r.Path))
 
 	if r.Content != nil {
@@ -296,42 +423,73 @@ func resourceFileSetResourceData(r *client.File, d *schema.ResourceData) diag.Di
 	return nil
 }
 
-func resourceFileCreateFactory(sources *source.Registry) schema.CreateContextFunc {
-	return func(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
-		p, diagErr := providerFromMeta(meta)
-		if diagErr != nil {
-			return diagErr
-		}
+// resourceFileWriteOpts returns the options of the client.FileClient which writes the file
+func resourceFileWriteOpts(d *schema.ResourceData) []client.FileClientOpt {
+	return []client.FileClientOpt{
+		client.FileClientAtomic(d.Get(resourceFileAttrAtomic).(bool)),
+		client.FileClientValidateCommand(d.Get(resourceFileAttrValidateCommand).(string)),
+	}
+}
 
-		c := client.NewFileClient(p.System, client.FileClientCompression(true))
+// resourceFileBackup returns the client.FileBackup of the file
+func resourceFileBackup(d *schema.ResourceData) client.FileBackup {
+	return client.FileBackup{
+		Dir:       d.Get(resourceFileAttrBackupDir).(string),
+		Suffix:    d.Get(resourceFileAttrBackupSuffix).(string),
+		Retention: d.Get(resourceFileAttrBackupRetention).(int),
+	}
+}
 
-		r, diagErr := resourceFileGetResourceData(sources, d)
-		if diagErr != nil {
-			return diagErr
-		}
+// resourceFileWriteDiagnostics returns diag.Diagnostics of an error returned when writing the file. The output of a
+// rejecting validate command is provided as detail.
+func resourceFileWriteDiagnostics(err error) diag.Diagnostics {
+	var validationErr *client.FileValidationError
+	if errors.As(err, &validationErr) {
+		return newDetailedDiagnostic(diag.Error, fmt.Sprintf("%s rejected the content of the file", resourceFileAttrValidateCommand), validationErr.Output, cty.GetAttrPath(resourceFileAttrValidateCommand))
+	}
 
-		err := c.Create(ctx, *r)
-		if err != nil {
-			return diag.FromErr(err)
-		}
+	return diag.FromErr(err)
+}
 
-		// Close source if source is an io.Closer
-		if contentCloser, isCloser := r.Content.(io.Closer); isCloser {
-			err := contentCloser.Close()
-			if err != nil {
-				return diag.FromErr(err)
-			}
-		}
+func resourceFileCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	p, diagErr := providerFromMeta(meta)
+	if diagErr != nil {
+		return diagErr
+	}
+
+	s, diagErr := systemFromResourceData(p, d)
+	if diagErr != nil {
+		return diagErr
+	}
 
-		d.SetId(r.Path)
+	c := client.NewFileClient(s, append(resourceFileWriteOpts(d), client.FileClientCompression(true))...)
 
-		diagErr = resourceFileRead(ctx, d, meta)
-		if diagErr != nil {
-			return diagErr
+	r, diagErr := resourceFileGetResourceData(p.Sources, d)
+	if diagErr != nil {
+		return diagErr
+	}
+
+	err := c.Create(ctx, *r)
+	if err != nil {
+		return resourceFileWriteDiagnostics(err)
+	}
+
+	// Close source if source is an io.Closer
+	if contentCloser, isCloser := r.Content.(io.Closer); isCloser {
+		err := contentCloser.Close()
+		if err != nil {
+			return diag.FromErr(err)
 		}
+	}
 
-		return nil
+	d.SetId(r.Path)
+
+	diagErr = resourceFileRead(ctx, d, meta)
+	if diagErr != nil {
+		return diagErr
 	}
+
+	return nil
 }
 
 func resourceFileRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
@@ -340,13 +498,18 @@ func resourceFileRead(ctx context.Context, d *schema.ResourceData, meta interfac
 		return diagErr
 	}
 
+	s, diagErr := systemFromResourceData(p, d)
+	if diagErr != nil {
+		return diagErr
+	}
+
 	_, hasContent := d.GetOk(resourceFileAttrContent)
 	_, hasContentSensitive := d.GetOk(resourceFileAttrContentSensitive)
 	_, hasSource := d.GetOk(resourceFileAttrSource)
 
 	// Include content when attributes `content` or `content_sensitive` are set or when attribute `source` is not set
 	includeContentOpt := client.FileClientIncludeContent((hasContent || hasContentSensitive) && !hasSource)
-	c := client.NewFileClient(p.System, includeContentOpt, client.FileClientCompression(true))
+	c := client.NewFileClient(s, includeContentOpt, client.FileClientCompression(true))
 
 	id := d.Id()
 
@@ -355,6 +518,12 @@ func resourceFileRead(ctx context.Context, d *schema.ResourceData, meta interfac
 		return diag.FromErr(err)
 	}
 
+	// The content of a source is not read. Detect a change of the content outside of terraform by the checksum and
+	// clear the source to upload the source again.
+	if prevSha256Sum := d.Get(resourceFileAttrSha256Sum).(string); hasSource && prevSha256Sum != "" && r.Sha256Sum != "" && prevSha256Sum != r.Sha256Sum {
+		_ = d.Set(resourceFileAttrSource, "")
+	}
+
 	diagErr = resourceFileSetResourceData(r, d)
 	if diagErr != nil {
 		return diagErr
@@ -363,27 +532,44 @@ func resourceFileRead(ctx context.Context, d *schema.ResourceData, meta interfac
 	return nil
 }
 
-func resourceFileUpdateFactory(sources *source.Registry) schema.UpdateContextFunc {
-	return func(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
-		p, diagErr := providerFromMeta(meta)
-		if diagErr != nil {
-			return diagErr
-		}
+func resourceFileUpdate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	p, diagErr := providerFromMeta(meta)
+	if diagErr != nil {
+		return diagErr
+	}
 
-		c := client.NewFileClient(p.System)
+	s, diagErr := systemFromResourceData(p, d)
+	if diagErr != nil {
+		return diagErr
+	}
 
-		r, diagErr := resourceFileGetResourceData(sources, d)
-		if diagErr != nil {
-			return diagErr
-		}
+	c := client.NewFileClient(s, resourceFileWriteOpts(d)...)
 
-		err := c.Update(ctx, *r)
-		if err != nil {
+	r, diagErr := resourceFileGetResourceData(p.Sources, d)
+	if diagErr != nil {
+		return diagErr
+	}
+
+	// Backup before the content is overwritten
+	var backupPath string
+	if (r.Content != nil || r.CopyFrom != "") && d.Get(resourceFileAttrBackup).(bool) {
+		var err error
+		backupPath, err = c.Backup(ctx, r.Path, resourceFileBackup(d))
+		if err != nil && !errors.Is(err, client.ErrFileNotFound) {
 			return diag.FromErr(err)
 		}
+	}
 
-		return resourceFileRead(ctx, d, meta)
+	err := c.Update(ctx, *r)
+	if err != nil {
+		return resourceFileWriteDiagnostics(err)
 	}
+
+	if backupPath != "" {
+		_ = d.Set(resourceFileAttrBackupPath, backupPath)
+	}
+
+	return resourceFileRead(ctx, d, meta)
 }
 
 func resourceFileDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
@@ -392,10 +578,23 @@ func resourceFileDelete(ctx context.Context, d *schema.ResourceData, meta interf
 		return diagErr
 	}
 
-	c := client.NewFileClient(p.System)
+	s, diagErr := systemFromResourceData(p, d)
+	if diagErr != nil {
+		return diagErr
+	}
+
+	c := client.NewFileClient(s)
 
 	id := d.Id()
 
+	// Backup before the file is deleted
+	if d.Get(resourceFileAttrBackup).(bool) {
+		_, err := c.Backup(ctx, id, resourceFileBackup(d))
+		if err != nil && !errors.Is(err, client.ErrFileNotFound) {
+			return diag.FromErr(err)
+		}
+	}
+
 	err := c.Delete(ctx, id)
 	if err != nil {
 		return diag.FromErr(err)
@@ -426,6 +625,8 @@ func resourceFileImportState(ctx context.Context, d *schema.ResourceData, m inte
 		}
 	}
 
+	_ = d.Set(resourceFileAttrAtomic, true)
+
 	d.SetId(importIdParts[0])
 
 	return []*schema.ResourceData{d}, nil
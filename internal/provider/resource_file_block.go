@@ -0,0 +1,240 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"github.com/neuspaces/terraform-provider-system/internal/client"
+	"github.com/neuspaces/terraform-provider-system/internal/lib/lineedit"
+	"github.com/neuspaces/terraform-provider-system/internal/validate"
+	"regexp"
+	"strings"
+)
+
+const resourceFileBlockName = "system_file_block"
+
+const (
+	resourceFileBlockAttrId              = "id"
+	resourceFileBlockAttrPath            = resourceFileAttrPath
+	resourceFileBlockAttrContent         = resourceFileAttrContent
+	resourceFileBlockAttrMarker          = "marker"
+	resourceFileBlockAttrMarkerBegin     = "marker_begin"
+	resourceFileBlockAttrMarkerEnd       = "marker_end"
+	resourceFileBlockAttrInsertAfter     = resourceFileLineAttrInsertAfter
+	resourceFileBlockAttrInsertBefore    = resourceFileLineAttrInsertBefore
+	resourceFileBlockAttrAtomic          = resourceFileAttrAtomic
+	resourceFileBlockAttrValidateCommand = resourceFileAttrValidateCommand
+)
+
+const (
+	resourceFileBlockMarkerPlaceholder = "{mark}"
+
+	resourceFileBlockMarkerDefault = "# " + resourceFileBlockMarkerPlaceholder + " TERRAFORM MANAGED BLOCK"
+)
+
+func resourceFileBlock() *schema.Resource {
+	return &schema.Resource{
+		Description: fmt.Sprintf("`%s` manages a block of lines which is delimited by marker lines in an existing file on the remote system without managing the remaining content of the file.", resourceFileBlockName),
+
+		CreateContext: resourceFileBlockCreate,
+		ReadContext:   resourceFileBlockRead,
+		UpdateContext: resourceFileBlockUpdate,
+		DeleteContext: resourceFileBlockDelete,
+
+		SchemaVersion: 1,
+
+		Schema: map[string]*schema.Schema{
+			resourceFileBlockAttrId: {
+				Description: "ID of the block",
+				Type:        schema.TypeString,
+				Computed:    true,
+			},
+			resourceFileBlockAttrPath: {
+				Description:      "Path to the file. Must be an absolute path. The file must exist.",
+				Type:             schema.TypeString,
+				Required:         true,
+				ForceNew:         true,
+				ValidateDiagFunc: validate.AbsolutePath(),
+			},
+			resourceFileBlockAttrContent: {
+				Description: "Lines of the block between the marker lines. A trailing newline is ignored.",
+				Type:        schema.TypeString,
+				Required:    true,
+			},
+			resourceFileBlockAttrMarker: {
+				Description:  fmt.Sprintf("Format of the marker lines which delimit the block. `%[1]s` is replaced by `%[2]s` or `%[3]s`. Use a comment syntax which is valid in the file. The marker must be unique within the file if the file contains multiple blocks. Defaults to `%[4]s`.", resourceFileBlockMarkerPlaceholder, resourceFileBlockAttrMarkerBegin, resourceFileBlockAttrMarkerEnd, resourceFileBlockMarkerDefault),
+				Type:         schema.TypeString,
+				Optional:     true,
+				ForceNew:     true,
+				Default:      resourceFileBlockMarkerDefault,
+				ValidateFunc: validation.All(validation.StringDoesNotContainAny("\n"), validation.StringMatch(regexp.MustCompile(regexp.QuoteMeta(resourceFileBlockMarkerPlaceholder)), fmt.Sprintf("marker must contain %s", resourceFileBlockMarkerPlaceholder))),
+			},
+			resourceFileBlockAttrMarkerBegin: {
+				Description:  fmt.Sprintf("Value which replaces `%[1]s` in the marker line before the block. Defaults to `BEGIN`.", resourceFileBlockMarkerPlaceholder),
+				Type:         schema.TypeString,
+				Optional:     true,
+				ForceNew:     true,
+				Default:      "BEGIN",
+				ValidateFunc: validation.StringDoesNotContainAny("\n"),
+			},
+			resourceFileBlockAttrMarkerEnd: {
+				Description:  fmt.Sprintf("Value which replaces `%[1]s` in the marker line after the block. Defaults to `END`.", resourceFileBlockMarkerPlaceholder),
+				Type:         schema.TypeString,
+				Optional:     true,
+				ForceNew:     true,
+				Default:      "END",
+				ValidateFunc: validation.StringDoesNotContainAny("\n"),
+			},
+			resourceFileBlockAttrInsertAfter: {
+				Description:   "Regular expression of the line after which the block is inserted if the file does not contain the block. The block is inserted after the last matching line. Defaults to the end of the file. An existing block is not moved.",
+				Type:          schema.TypeString,
+				Optional:      true,
+				ValidateFunc:  validation.StringIsValidRegExp,
+				ConflictsWith: []string{resourceFileBlockAttrInsertBefore},
+			},
+			resourceFileBlockAttrInsertBefore: {
+				Description:   "Regular expression of the line before which the block is inserted if the file does not contain the block. The block is inserted before the first matching line. Defaults to the end of the file. An existing block is not moved.",
+				Type:          schema.TypeString,
+				Optional:      true,
+				ValidateFunc:  validation.StringIsValidRegExp,
+				ConflictsWith: []string{resourceFileBlockAttrInsertAfter},
+			},
+			resourceFileBlockAttrAtomic: {
+				Description: fmt.Sprintf("If `true`, the file is written atomically equivalent to the attribute `%[1]s` of `%[2]s`. Set to `false` for files which must be written in place such as `/etc/hosts` in a container. Defaults to `true`.", resourceFileAttrAtomic, resourceFileName),
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     true,
+			},
+			resourceFileBlockAttrValidateCommand: {
+				Description:  fmt.Sprintf("Command which validates the content before it replaces the file equivalent to the attribute `%[1]s` of `%[2]s`. Requires `%[3]s`.", resourceFileAttrValidateCommand, resourceFileName, resourceFileBlockAttrAtomic),
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validation.StringMatch(regexp.MustCompile(`%s`), "validate command must reference the temporary file with %s"),
+			},
+			SchemaAttrRunAs: schemaRunAs(),
+		},
+	}
+}
+
+func resourceFileBlockGetResourceData(d *schema.ResourceData) lineedit.Block {
+	marker := d.Get(resourceFileBlockAttrMarker).(string)
+
+	b := lineedit.Block{
+		Begin: strings.ReplaceAll(marker, resourceFileBlockMarkerPlaceholder, d.Get(resourceFileBlockAttrMarkerBegin).(string)),
+		End:   strings.ReplaceAll(marker, resourceFileBlockMarkerPlaceholder, d.Get(resourceFileBlockAttrMarkerEnd).(string)),
+	}
+
+	if content := strings.TrimSuffix(d.Get(resourceFileBlockAttrContent).(string), "\n"); content != "" {
+		b.Lines = strings.Split(content, "\n")
+	}
+
+	if v, ok := d.GetOk(resourceFileBlockAttrInsertAfter); ok {
+		b.Anchor.After = regexp.MustCompile(v.(string))
+	}
+
+	if v, ok := d.GetOk(resourceFileBlockAttrInsertBefore); ok {
+		b.Anchor.Before = regexp.MustCompile(v.(string))
+	}
+
+	return b
+}
+
+func resourceFileBlockSetResourceData(lines []string, d *schema.ResourceData) {
+	content := strings.Join(lines, "\n")
+
+	// Retain the trailing newline of the configured content
+	if strings.TrimSuffix(d.Get(resourceFileBlockAttrContent).(string), "\n") == content {
+		return
+	}
+
+	_ = d.Set(resourceFileBlockAttrContent, content)
+}
+
+func resourceFileBlockCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	c, diagErr := resourceFileEditClient(meta, d)
+	if diagErr != nil {
+		return diagErr
+	}
+
+	b := resourceFileBlockGetResourceData(d)
+	path := d.Get(resourceFileBlockAttrPath).(string)
+
+	err := c.Edit(ctx, path, func(t *lineedit.Text) (bool, error) {
+		return lineedit.EnsureBlock(t, b)
+	})
+	if err != nil {
+		return resourceFileWriteDiagnostics(err)
+	}
+
+	d.SetId(path)
+
+	return resourceFileBlockRead(ctx, d, meta)
+}
+
+func resourceFileBlockRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	c, diagErr := resourceFileEditClient(meta, d)
+	if diagErr != nil {
+		return diagErr
+	}
+
+	t, err := c.Get(ctx, d.Id())
+	if errors.Is(err, client.ErrFileNotFound) {
+		d.SetId("")
+		return nil
+	} else if err != nil {
+		return diag.FromErr(err)
+	}
+
+	lines, found, err := lineedit.GetBlock(t, resourceFileBlockGetResourceData(d))
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	if !found {
+		d.SetId("")
+		return nil
+	}
+
+	resourceFileBlockSetResourceData(lines, d)
+
+	return nil
+}
+
+func resourceFileBlockUpdate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	c, diagErr := resourceFileEditClient(meta, d)
+	if diagErr != nil {
+		return diagErr
+	}
+
+	b := resourceFileBlockGetResourceData(d)
+
+	err := c.Edit(ctx, d.Id(), func(t *lineedit.Text) (bool, error) {
+		return lineedit.EnsureBlock(t, b)
+	})
+	if err != nil {
+		return resourceFileWriteDiagnostics(err)
+	}
+
+	return resourceFileBlockRead(ctx, d, meta)
+}
+
+func resourceFileBlockDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	c, diagErr := resourceFileEditClient(meta, d)
+	if diagErr != nil {
+		return diagErr
+	}
+
+	b := resourceFileBlockGetResourceData(d)
+
+	err := c.Edit(ctx, d.Id(), func(t *lineedit.Text) (bool, error) {
+		return lineedit.RemoveBlock(t, b)
+	})
+	if err != nil && !errors.Is(err, client.ErrFileNotFound) {
+		return resourceFileWriteDiagnostics(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,55 @@
+package provider_test
+
+import (
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/neuspaces/terraform-provider-system/internal/acctest"
+	"github.com/neuspaces/terraform-provider-system/internal/acctest/tfbuild"
+	"github.com/neuspaces/terraform-provider-system/internal/extlib/heredoc"
+	"testing"
+)
+
+func TestAccFileBlock(t *testing.T) {
+	testConfig := newTestFileConfig()
+
+	acctest.Current().Targets.Foreach(t, func(t *testing.T, target acctest.Target) {
+		t.Parallel()
+
+		filePath := testRunFilePath(target, testConfig.fileName)
+
+		resource.Test(t, resource.TestCase{
+			ProviderFactories: acctest.ProviderFactories(),
+			Steps: []resource.TestStep{
+				{
+					Config: testAccFileEditConfig(target, filePath, "system_file_block",
+						tfbuild.AttributeString("content", "x\ny\n"),
+					),
+					Check: resource.ComposeTestCheckFunc(
+						resource.TestCheckResourceAttr("system_file_block.test", "id", filePath),
+						resource.TestCheckResourceAttr("system_file_block.test", "content", "x\ny\n"),
+						resource.TestCheckResourceAttr("data.system_file.test", "content", heredoc.String(`
+							# BEGIN TERRAFORM MANAGED BLOCK
+							x
+							y
+							# END TERRAFORM MANAGED BLOCK
+						`)),
+					),
+				},
+				{
+					// Replace the block with a block with different markers
+					Config: testAccFileEditConfig(target, filePath, "system_file_block",
+						tfbuild.AttributeString("content", "z"),
+						tfbuild.AttributeString("marker", "// {mark} managed"),
+					),
+					Check: resource.ComposeTestCheckFunc(
+						resource.TestCheckResourceAttr("system_file_block.test", "content", "z"),
+						resource.TestCheckResourceAttr("data.system_file.test", "content", heredoc.String(`
+							// BEGIN managed
+							z
+							// END managed
+						`)),
+					),
+				},
+			},
+		})
+	})
+}
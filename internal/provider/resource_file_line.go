@@ -0,0 +1,300 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"github.com/neuspaces/terraform-provider-system/internal/client"
+	"github.com/neuspaces/terraform-provider-system/internal/lib/lineedit"
+	"github.com/neuspaces/terraform-provider-system/internal/validate"
+	"regexp"
+)
+
+const resourceFileLineName = "system_file_line"
+
+const (
+	resourceFileLineAttrId              = "id"
+	resourceFileLineAttrPath            = resourceFileAttrPath
+	resourceFileLineAttrLine            = "line"
+	resourceFileLineAttrRegexp          = "regexp"
+	resourceFileLineAttrInsertAfter     = "insert_after"
+	resourceFileLineAttrInsertBefore    = "insert_before"
+	resourceFileLineAttrState           = "state"
+	resourceFileLineAttrAtomic          = resourceFileAttrAtomic
+	resourceFileLineAttrValidateCommand = resourceFileAttrValidateCommand
+)
+
+const (
+	resourceFileLineAttrStatePresent = "present"
+	resourceFileLineAttrStateAbsent  = "absent"
+)
+
+func resourceFileLine() *schema.Resource {
+	return &schema.Resource{
+		Description: fmt.Sprintf("`%s` manages a single line in an existing file on the remote system without managing the remaining content of the file.", resourceFileLineName),
+
+		CreateContext: resourceFileLineCreate,
+		ReadContext:   resourceFileLineRead,
+		UpdateContext: resourceFileLineUpdate,
+		DeleteContext: resourceFileLineDelete,
+
+		SchemaVersion: 1,
+
+		Schema: map[string]*schema.Schema{
+			resourceFileLineAttrId: {
+				Description: "ID of the line",
+				Type:        schema.TypeString,
+				Computed:    true,
+			},
+			resourceFileLineAttrPath: {
+				Description:      "Path to the file. Must be an absolute path. The file must exist.",
+				Type:             schema.TypeString,
+				Required:         true,
+				ForceNew:         true,
+				ValidateDiagFunc: validate.AbsolutePath(),
+			},
+			resourceFileLineAttrLine: {
+				Description:  fmt.Sprintf("Line which is ensured to be present in the file. Required if `%[1]s` is `%[2]s`.", resourceFileLineAttrState, resourceFileLineAttrStatePresent),
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validation.StringDoesNotContainAny("\n"),
+				AtLeastOneOf: []string{resourceFileLineAttrLine, resourceFileLineAttrRegexp},
+			},
+			resourceFileLineAttrRegexp: {
+				Description:  fmt.Sprintf("Regular expression which matches the line. If `%[1]s` is `%[2]s`, the last matching line is replaced by `%[4]s`. If `%[1]s` is `%[3]s`, all matching lines are removed. If not set, only lines equal to `%[4]s` match.", resourceFileLineAttrState, resourceFileLineAttrStatePresent, resourceFileLineAttrStateAbsent, resourceFileLineAttrLine),
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validation.StringIsValidRegExp,
+			},
+			resourceFileLineAttrInsertAfter: {
+				Description:   "Regular expression of the line after which the line is inserted if no line matches. The line is inserted after the last matching line. Defaults to the end of the file.",
+				Type:          schema.TypeString,
+				Optional:      true,
+				ValidateFunc:  validation.StringIsValidRegExp,
+				ConflictsWith: []string{resourceFileLineAttrInsertBefore},
+			},
+			resourceFileLineAttrInsertBefore: {
+				Description:   "Regular expression of the line before which the line is inserted if no line matches. The line is inserted before the first matching line. Defaults to the end of the file.",
+				Type:          schema.TypeString,
+				Optional:      true,
+				ValidateFunc:  validation.StringIsValidRegExp,
+				ConflictsWith: []string{resourceFileLineAttrInsertAfter},
+			},
+			resourceFileLineAttrState: {
+				Description: fmt.Sprintf("If `%[1]s`, the line is ensured to be present in the file. If `%[2]s`, the matching lines are ensured to be absent from the file. Defaults to `%[1]s`.", resourceFileLineAttrStatePresent, resourceFileLineAttrStateAbsent),
+				Type:        schema.TypeString,
+				Optional:    true,
+				Default:     resourceFileLineAttrStatePresent,
+				ValidateFunc: validation.StringInSlice([]string{
+					resourceFileLineAttrStatePresent,
+					resourceFileLineAttrStateAbsent,
+				}, false),
+			},
+			resourceFileLineAttrAtomic: {
+				Description: fmt.Sprintf("If `true`, the file is written atomically equivalent to the attribute `%[1]s` of `%[2]s`. Set to `false` for files which must be written in place such as `/etc/hosts` in a container. Defaults to `true`.", resourceFileAttrAtomic, resourceFileName),
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     true,
+			},
+			resourceFileLineAttrValidateCommand: {
+				Description:  fmt.Sprintf("Command which validates the content before it replaces the file equivalent to the attribute `%[1]s` of `%[2]s`. Requires `%[3]s`.", resourceFileAttrValidateCommand, resourceFileName, resourceFileLineAttrAtomic),
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validation.StringMatch(regexp.MustCompile(`%s`), "validate command must reference the temporary file with %s"),
+			},
+			SchemaAttrRunAs: schemaRunAs(),
+		},
+	}
+}
+
+// resourceFileLineGetResourceData returns the lineedit.Line of the resource and true if the state is present
+func resourceFileLineGetResourceData(d *schema.ResourceData) (lineedit.Line, bool, diag.Diagnostics) {
+	l := lineedit.Line{
+		Line: d.Get(resourceFileLineAttrLine).(string),
+	}
+
+	present := d.Get(resourceFileLineAttrState).(string) == resourceFileLineAttrStatePresent
+
+	if _, hasLine := d.GetOk(resourceFileLineAttrLine); present && !hasLine {
+		return l, present, diag.Errorf("attribute `%s` is required if `%s` is `%s`", resourceFileLineAttrLine, resourceFileLineAttrState, resourceFileLineAttrStatePresent)
+	}
+
+	if v, ok := d.GetOk(resourceFileLineAttrRegexp); ok {
+		l.Regexp = regexp.MustCompile(v.(string))
+	}
+
+	if v, ok := d.GetOk(resourceFileLineAttrInsertAfter); ok {
+		l.Anchor.After = regexp.MustCompile(v.(string))
+	}
+
+	if v, ok := d.GetOk(resourceFileLineAttrInsertBefore); ok {
+		l.Anchor.Before = regexp.MustCompile(v.(string))
+	}
+
+	return l, present, nil
+}
+
+// resourceFileEditClient returns the client.FileEditClient of a resource which edits parts of a file
+func resourceFileEditClient(meta interface{}, d *schema.ResourceData) (client.FileEditClient, diag.Diagnostics) {
+	p, diagErr := providerFromMeta(meta)
+	if diagErr != nil {
+		return nil, diagErr
+	}
+
+	s, diagErr := systemFromResourceData(p, d)
+	if diagErr != nil {
+		return nil, diagErr
+	}
+
+	return &lockedFileEditClient{
+		FileEditClient: client.NewFileEditClient(s,
+			client.FileClientAtomic(d.Get(resourceFileLineAttrAtomic).(bool)),
+			client.FileClientValidateCommand(d.Get(resourceFileLineAttrValidateCommand).(string)),
+		),
+		locks: &p.fileEdits,
+	}, nil
+}
+
+// lockedFileEditClient is a client.FileEditClient which holds the lock of the path while the file is read, edited and
+// written, so that resources which edit the same file in parallel do not overwrite each other's edits
+type lockedFileEditClient struct {
+	client.FileEditClient
+	locks *pathLocks
+}
+
+func (c *lockedFileEditClient) Edit(ctx context.Context, path string, edit client.FileEditFunc) error {
+	unlock := c.locks.lock(path)
+	defer unlock()
+
+	return c.FileEditClient.Edit(ctx, path, edit)
+}
+
+// resourceFileLineApply returns a client.FileEditFunc which ensures the line is present or absent
+func resourceFileLineApply(l lineedit.Line, present bool) client.FileEditFunc {
+	return func(t *lineedit.Text) (bool, error) {
+		if present {
+			return lineedit.EnsureLine(t, l), nil
+		}
+
+		return lineedit.RemoveLine(t, l), nil
+	}
+}
+
+func resourceFileLineCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	c, diagErr := resourceFileEditClient(meta, d)
+	if diagErr != nil {
+		return diagErr
+	}
+
+	l, present, diagErr := resourceFileLineGetResourceData(d)
+	if diagErr != nil {
+		return diagErr
+	}
+
+	path := d.Get(resourceFileLineAttrPath).(string)
+
+	err := c.Edit(ctx, path, resourceFileLineApply(l, present))
+	if err != nil && !(errors.Is(err, client.ErrFileNotFound) && !present) {
+		return resourceFileWriteDiagnostics(err)
+	}
+
+	d.SetId(path)
+
+	return resourceFileLineRead(ctx, d, meta)
+}
+
+func resourceFileLineRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	c, diagErr := resourceFileEditClient(meta, d)
+	if diagErr != nil {
+		return diagErr
+	}
+
+	l, present, diagErr := resourceFileLineGetResourceData(d)
+	if diagErr != nil {
+		return diagErr
+	}
+
+	t, err := c.Get(ctx, d.Id())
+	if errors.Is(err, client.ErrFileNotFound) {
+		if present {
+			d.SetId("")
+		}
+		return nil
+	} else if err != nil {
+		return diag.FromErr(err)
+	}
+
+	// Remove from the state if the line would be changed
+	changed, _ := resourceFileLineApply(l, present)(t)
+	if changed {
+		d.SetId("")
+	}
+
+	return nil
+}
+
+func resourceFileLineUpdate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	c, diagErr := resourceFileEditClient(meta, d)
+	if diagErr != nil {
+		return diagErr
+	}
+
+	l, present, diagErr := resourceFileLineGetResourceData(d)
+	if diagErr != nil {
+		return diagErr
+	}
+
+	oldLine, _ := d.GetChange(resourceFileLineAttrLine)
+	oldState, _ := d.GetChange(resourceFileLineAttrState)
+	oldPresent := oldState.(string) == resourceFileLineAttrStatePresent
+
+	apply := resourceFileLineApply(l, present)
+
+	err := c.Edit(ctx, d.Id(), func(t *lineedit.Text) (bool, error) {
+		// Replace the previous line in place
+		var changed bool
+		if oldPresent && oldLine.(string) != l.Line {
+			if present {
+				changed = lineedit.ReplaceLine(t, oldLine.(string), l.Line)
+			} else {
+				changed = lineedit.RemoveLine(t, lineedit.Line{Line: oldLine.(string)})
+			}
+		}
+
+		applyChanged, err := apply(t)
+
+		return changed || applyChanged, err
+	})
+	if err != nil && !(errors.Is(err, client.ErrFileNotFound) && !present) {
+		return resourceFileWriteDiagnostics(err)
+	}
+
+	return resourceFileLineRead(ctx, d, meta)
+}
+
+func resourceFileLineDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	// Removed lines are not restored
+	if d.Get(resourceFileLineAttrState).(string) != resourceFileLineAttrStatePresent {
+		return nil
+	}
+
+	c, diagErr := resourceFileEditClient(meta, d)
+	if diagErr != nil {
+		return diagErr
+	}
+
+	// Remove only the managed line
+	l := lineedit.Line{
+		Line: d.Get(resourceFileLineAttrLine).(string),
+	}
+
+	err := c.Edit(ctx, d.Id(), resourceFileLineApply(l, false))
+	if err != nil && !errors.Is(err, client.ErrFileNotFound) {
+		return resourceFileWriteDiagnostics(err)
+	}
+
+	return nil
+}
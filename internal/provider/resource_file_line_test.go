@@ -0,0 +1,113 @@
+package provider_test
+
+import (
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/neuspaces/terraform-provider-system/internal/acctest"
+	"github.com/neuspaces/terraform-provider-system/internal/acctest/tfbuild"
+	"regexp"
+	"testing"
+)
+
+// testAccFileEditConfig returns the configuration of an empty file, the resource which edits the file and a
+// `system_file` data source which reads the edited content
+func testAccFileEditConfig(target acctest.Target, filePath string, resourceType string, attrs ...tfbuild.BlockElement) string {
+	resourceAttrs := []tfbuild.BlockElement{
+		tfbuild.AttributeTraversal("path", tfbuild.TraversalResourceAttribute("system_file", "test", "path")),
+	}
+	resourceAttrs = append(resourceAttrs, attrs...)
+
+	return tfbuild.FileString(tfbuild.File(
+		acctest.ProviderConfigBlock(target.Configs.Default()),
+		testAccFileBlock("test", filePath),
+		tfbuild.Resource(resourceType, "test", resourceAttrs...),
+		tfbuild.Data("system_file", "test",
+			tfbuild.AttributeTraversal("path", tfbuild.TraversalResourceAttribute(resourceType, "test", "id")),
+		),
+	))
+}
+
+func TestAccFileLine(t *testing.T) {
+	testConfig := newTestFileConfig()
+
+	acctest.Current().Targets.Foreach(t, func(t *testing.T, target acctest.Target) {
+		t.Parallel()
+
+		filePath := testRunFilePath(target, testConfig.fileName)
+
+		resource.Test(t, resource.TestCase{
+			ProviderFactories: acctest.ProviderFactories(),
+			Steps: []resource.TestStep{
+				{
+					Config: testAccFileEditConfig(target, filePath, "system_file_line",
+						tfbuild.AttributeString("line", "Port 22"),
+					),
+					Check: resource.ComposeTestCheckFunc(
+						resource.TestCheckResourceAttr("system_file_line.test", "id", filePath),
+						resource.TestCheckResourceAttr("system_file_line.test", "state", "present"),
+						resource.TestCheckResourceAttr("data.system_file.test", "content", "Port 22\n"),
+					),
+				},
+				{
+					// Replace the matching line in place
+					Config: testAccFileEditConfig(target, filePath, "system_file_line",
+						tfbuild.AttributeString("line", "Port 2222"),
+						tfbuild.AttributeString("regexp", "^Port "),
+					),
+					Check: resource.ComposeTestCheckFunc(
+						resource.TestCheckResourceAttr("data.system_file.test", "content", "Port 2222\n"),
+					),
+				},
+				{
+					Config: testAccFileEditConfig(target, filePath, "system_file_line",
+						tfbuild.AttributeString("regexp", "^Port "),
+						tfbuild.AttributeString("state", "absent"),
+					),
+					Check: resource.ComposeTestCheckFunc(
+						resource.TestCheckResourceAttr("data.system_file.test", "content", ""),
+					),
+				},
+			},
+		})
+	})
+}
+
+func TestAccFileLine_parallel(t *testing.T) {
+	testConfig := newTestFileConfig()
+
+	acctest.Current().Targets.Foreach(t, func(t *testing.T, target acctest.Target) {
+		t.Parallel()
+
+		filePath := testRunFilePath(target, testConfig.fileName)
+
+		resource.Test(t, resource.TestCase{
+			ProviderFactories: acctest.ProviderFactories(),
+			Steps: []resource.TestStep{
+				{
+					// Both lines are applied in parallel to the same file; neither edit is lost
+					Config: tfbuild.FileString(tfbuild.File(
+						acctest.ProviderConfigBlock(target.Configs.Default()),
+						testAccFileBlock("test", filePath),
+						tfbuild.Resource("system_file_line", "port",
+							tfbuild.AttributeTraversal("path", tfbuild.TraversalResourceAttribute("system_file", "test", "path")),
+							tfbuild.AttributeString("line", "Port 22"),
+						),
+						tfbuild.Resource("system_file_line", "login",
+							tfbuild.AttributeTraversal("path", tfbuild.TraversalResourceAttribute("system_file", "test", "path")),
+							tfbuild.AttributeString("line", "PermitRootLogin no"),
+						),
+						tfbuild.Data("system_file", "test",
+							tfbuild.AttributeTraversal("path", tfbuild.TraversalResourceAttribute("system_file", "test", "path")),
+							tfbuild.DependsOn(
+								tfbuild.TraversalResource("system_file_line", "port"),
+								tfbuild.TraversalResource("system_file_line", "login"),
+							),
+						),
+					)),
+					Check: resource.ComposeTestCheckFunc(
+						resource.TestMatchResourceAttr("data.system_file.test", "content", regexp.MustCompile(`^(Port 22\nPermitRootLogin no\n|PermitRootLogin no\nPort 22\n)$`)),
+					),
+				},
+			},
+		})
+	})
+}
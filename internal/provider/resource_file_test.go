@@ -128,6 +128,108 @@ func TestAccFile_create_source_file(t *testing.T) {
 						resource.TestCheckResourceAttr("system_file.test", "gid", "0"),
 						// cat ./internal/provider/test/hello-world.txt | openssl dgst -binary -md5 | openssl base64
 						resource.TestCheckResourceAttr("system_file.test", "md5sum", "/D/5joxqDTCH1RXARz+Gdw=="),
+						// sha256sum ./internal/provider/test/hello-world.txt
+						resource.TestCheckResourceAttr("system_file.test", "sha256sum", "7509e5bda0c762d2bac7f90d758b5b2263fa01ccbc542ab5e3df163be08e6ca9"),
+						// sha512sum ./internal/provider/test/hello-world.txt
+						resource.TestCheckResourceAttr("system_file.test", "sha512sum", "db9b1cd3262dee37756a09b9064973589847caa8e53d31a9d142ea2701b1b28abd97838bb9a27068ba305dc8d04a45a1fcf079de54d607666996b3cc54f6b67c"),
+					),
+				},
+			},
+		})
+	})
+}
+
+func TestAccFile_create_source_file_expected_sha256(t *testing.T) {
+	testConfig := newTestFileConfig()
+
+	acctest.Current().Targets.Foreach(t, func(t *testing.T, target acctest.Target) {
+		t.Parallel()
+
+		resource.Test(t, resource.TestCase{
+			ProviderFactories: acctest.ProviderFactories(),
+			Steps: []resource.TestStep{
+				{
+					Config: tfbuild.FileString(tfbuild.File(
+						acctest.ProviderConfigBlock(target.Configs.Default()),
+						testAccFileBlock("test", testRunFilePath(target, testConfig.fileName),
+							tfbuild.AttributeString("source", "./test/hello-world.txt?expected_sha256=0000000000000000000000000000000000000000000000000000000000000000"),
+						),
+					)),
+					ExpectError: regexp.MustCompile(`checksum mismatch`),
+				},
+				{
+					Config: tfbuild.FileString(tfbuild.File(
+						acctest.ProviderConfigBlock(target.Configs.Default()),
+						testAccFileBlock("test", testRunFilePath(target, testConfig.fileName),
+							tfbuild.AttributeString("source", "./test/hello-world.txt?expected_sha256=7509e5bda0c762d2bac7f90d758b5b2263fa01ccbc542ab5e3df163be08e6ca9"),
+						),
+					)),
+					Check: resource.ComposeTestCheckFunc(
+						resource.TestCheckResourceAttr("system_file.test", "sha256sum", "7509e5bda0c762d2bac7f90d758b5b2263fa01ccbc542ab5e3df163be08e6ca9"),
+					),
+				},
+			},
+		})
+	})
+}
+
+func TestAccFile_create_source_data(t *testing.T) {
+	testConfig := newTestFileConfig()
+
+	acctest.Current().Targets.Foreach(t, func(t *testing.T, target acctest.Target) {
+		t.Parallel()
+
+		resource.Test(t, resource.TestCase{
+			ProviderFactories: acctest.ProviderFactories(),
+			Steps: []resource.TestStep{
+				{
+					Config: tfbuild.FileString(tfbuild.File(
+						acctest.ProviderConfigBlock(target.Configs.Default()),
+						testAccFileBlock("test", testRunFilePath(target, testConfig.fileName),
+							// echo -n 'hello world!' | base64
+							tfbuild.AttributeString("source", "data:text/plain;base64,aGVsbG8gd29ybGQh"),
+						),
+					)),
+					Check: resource.ComposeTestCheckFunc(
+						resource.TestCheckResourceAttr("system_file.test", "sha256sum", "7509e5bda0c762d2bac7f90d758b5b2263fa01ccbc542ab5e3df163be08e6ca9"),
+					),
+				},
+			},
+		})
+	})
+}
+
+func TestAccFile_create_source_template(t *testing.T) {
+	testConfig := newTestFileConfig()
+
+	acctest.Current().Targets.Foreach(t, func(t *testing.T, target acctest.Target) {
+		t.Parallel()
+
+		resource.Test(t, resource.TestCase{
+			ProviderFactories: acctest.ProviderFactories(),
+			Steps: []resource.TestStep{
+				{
+					Config: tfbuild.FileString(tfbuild.File(
+						acctest.ProviderConfigBlock(target.Configs.Default()),
+						testAccFileBlock("test", testRunFilePath(target, testConfig.fileName),
+							tfbuild.AttributeString("source", `template://./test/hello-world.txt.tmpl?vars=%7B%22name%22%3A%22world%22%7D`),
+						),
+					)),
+					Check: resource.ComposeTestCheckFunc(
+						resource.TestCheckResourceAttr("system_file.test", "sha256sum", "7509e5bda0c762d2bac7f90d758b5b2263fa01ccbc542ab5e3df163be08e6ca9"),
+					),
+				},
+				{
+					// Changed vars replace the file
+					Config: tfbuild.FileString(tfbuild.File(
+						acctest.ProviderConfigBlock(target.Configs.Default()),
+						testAccFileBlock("test", testRunFilePath(target, testConfig.fileName),
+							tfbuild.AttributeString("source", `template://./test/hello-world.txt.tmpl?vars=%7B%22name%22%3A%22terraform%22%7D`),
+						),
+					)),
+					Check: resource.ComposeTestCheckFunc(
+						// echo -n 'hello terraform!' | sha256sum
+						resource.TestCheckResourceAttr("system_file.test", "sha256sum", "35259c540766aee76076acefbf690016c42bd79ed9d3d2ab4bae76c0ea06ed71"),
 					),
 				},
 			},
@@ -539,6 +641,109 @@ func TestAccFile_update_content(t *testing.T) {
 	})
 }
 
+func TestAccFile_update_content_atomic(t *testing.T) {
+	testConfig := newTestFileConfig()
+
+	acctest.Current().Targets.Foreach(t, func(t *testing.T, target acctest.Target) {
+		t.Parallel()
+
+		resource.Test(t, resource.TestCase{
+			ProviderFactories: acctest.ProviderFactories(),
+			Steps: []resource.TestStep{
+				{
+					Config: tfbuild.FileString(tfbuild.File(
+						acctest.ProviderConfigBlock(target.Configs.Default()),
+						testAccFileBlock("test", testRunFilePath(target, testConfig.fileName),
+							tfbuild.AttributeString("mode", "600"),
+							tfbuild.AttributeString("content", "hello world!"),
+						),
+					)),
+					Check: resource.ComposeTestCheckFunc(
+						resource.TestCheckResourceAttr("system_file.test", "atomic", "true"),
+						resource.TestCheckResourceAttr("system_file.test", "mode", "600"),
+					),
+				},
+				{
+					// Mode is retained when only the content changes
+					Config: tfbuild.FileString(tfbuild.File(
+						acctest.ProviderConfigBlock(target.Configs.Default()),
+						testAccFileBlock("test", testRunFilePath(target, testConfig.fileName),
+							tfbuild.AttributeString("mode", "600"),
+							tfbuild.AttributeString("content", "hello universe!"),
+						),
+					)),
+					Check: resource.ComposeTestCheckFunc(
+						resource.TestCheckResourceAttr("system_file.test", "mode", "600"),
+						resource.TestCheckResourceAttr("system_file.test", "user", "root"),
+						// echo -n 'hello universe!' | openssl dgst -binary -md5 | openssl base64
+						resource.TestCheckResourceAttr("system_file.test", "md5sum", "w0Y+MwVOASL+sUYDnI0Eww=="),
+					),
+				},
+				{
+					Config: tfbuild.FileString(tfbuild.File(
+						acctest.ProviderConfigBlock(target.Configs.Default()),
+						testAccFileBlock("test", testRunFilePath(target, testConfig.fileName),
+							tfbuild.AttributeString("mode", "600"),
+							tfbuild.AttributeString("content", "hello world!"),
+							tfbuild.AttributeBool("atomic", false),
+						),
+					)),
+					Check: resource.ComposeTestCheckFunc(
+						resource.TestCheckResourceAttr("system_file.test", "atomic", "false"),
+						resource.TestCheckResourceAttr("system_file.test", "mode", "600"),
+						// echo -n 'hello world!' | openssl dgst -binary -md5 | openssl base64
+						resource.TestCheckResourceAttr("system_file.test", "md5sum", "/D/5joxqDTCH1RXARz+Gdw=="),
+					),
+				},
+			},
+		})
+	})
+}
+
+func TestAccFile_update_content_atomic_symlink(t *testing.T) {
+	testConfig := newTestFileConfig()
+
+	acctest.Current().Targets.Foreach(t, func(t *testing.T, target acctest.Target) {
+		t.Parallel()
+
+		filePath := testRunFilePath(target, testConfig.fileName)
+		linkPath := testRunFilePath(target, testConfig.fileName+"-link")
+
+		resource.Test(t, resource.TestCase{
+			ProviderFactories: acctest.ProviderFactories(),
+			Steps: []resource.TestStep{
+				{
+					// The atomic write through the symbolic link retains the link and changes the file
+					Config: tfbuild.FileString(tfbuild.File(
+						acctest.ProviderConfigBlock(target.Configs.Default()),
+						testAccFileBlock("test", filePath,
+							tfbuild.AttributeString("mode", "640"),
+						),
+						tfbuild.Resource("system_link", "test",
+							tfbuild.AttributeString("path", linkPath),
+							tfbuild.AttributeTraversal("target", tfbuild.TraversalResourceAttribute("system_file", "test", "path")),
+						),
+						tfbuild.Resource("system_file_line", "test",
+							tfbuild.AttributeTraversal("path", tfbuild.TraversalResourceAttribute("system_link", "test", "path")),
+							tfbuild.AttributeString("line", "Port 22"),
+						),
+						tfbuild.Data("system_file", "test",
+							tfbuild.AttributeTraversal("path", tfbuild.TraversalResourceAttribute("system_file", "test", "path")),
+							tfbuild.DependsOn(tfbuild.TraversalResource("system_file_line", "test")),
+						),
+					)),
+					Check: resource.ComposeTestCheckFunc(
+						resource.TestCheckResourceAttr("system_file_line.test", "atomic", "true"),
+						resource.TestCheckResourceAttr("system_link.test", "target", filePath),
+						resource.TestCheckResourceAttr("data.system_file.test", "content", "Port 22\n"),
+						resource.TestCheckResourceAttr("data.system_file.test", "mode", "640"),
+					),
+				},
+			},
+		})
+	})
+}
+
 func TestAccFile_update_content_sensitive(t *testing.T) {
 	testConfig := newTestFileConfig()
 
@@ -623,6 +828,183 @@ func TestAccFile_update_source(t *testing.T) {
 	})
 }
 
+func TestAccFile_validate_command(t *testing.T) {
+	testConfig := newTestFileConfig()
+
+	acctest.Current().Targets.Foreach(t, func(t *testing.T, target acctest.Target) {
+		t.Parallel()
+
+		resource.Test(t, resource.TestCase{
+			ProviderFactories: acctest.ProviderFactories(),
+			Steps: []resource.TestStep{
+				{
+					Config: tfbuild.FileString(tfbuild.File(
+						acctest.ProviderConfigBlock(target.Configs.Default()),
+						testAccFileBlock("test", testRunFilePath(target, testConfig.fileName),
+							tfbuild.AttributeString("content", "hello world!"),
+							tfbuild.AttributeString("validate_command", "grep -q hello %s"),
+						),
+					)),
+					Check: resource.ComposeTestCheckFunc(
+						// echo -n 'hello world!' | openssl dgst -binary -md5 | openssl base64
+						resource.TestCheckResourceAttr("system_file.test", "md5sum", "/D/5joxqDTCH1RXARz+Gdw=="),
+					),
+				},
+				{
+					Config: tfbuild.FileString(tfbuild.File(
+						acctest.ProviderConfigBlock(target.Configs.Default()),
+						testAccFileBlock("test", testRunFilePath(target, testConfig.fileName),
+							tfbuild.AttributeString("content", "goodbye world!"),
+							tfbuild.AttributeString("validate_command", "grep hello %s || { echo 'missing greeting' >&2; exit 1; }"),
+						),
+					)),
+					ExpectError: regexp.MustCompile(`validate_command rejected the content of the file`),
+				},
+				{
+					// The rejected content has not been written
+					Config: tfbuild.FileString(tfbuild.File(
+						acctest.ProviderConfigBlock(target.Configs.Default()),
+						testAccFileBlock("test", testRunFilePath(target, testConfig.fileName),
+							tfbuild.AttributeString("content", "hello world!"),
+							tfbuild.AttributeString("validate_command", "grep -q hello %s"),
+						),
+					)),
+					PlanOnly: true,
+				},
+			},
+		})
+	})
+}
+
+func TestAccFile_backup(t *testing.T) {
+	testConfig := newTestFileConfig()
+
+	acctest.Current().Targets.Foreach(t, func(t *testing.T, target acctest.Target) {
+		t.Parallel()
+
+		filePath := testRunFilePath(target, testConfig.fileName)
+
+		resource.Test(t, resource.TestCase{
+			ProviderFactories: acctest.ProviderFactories(),
+			Steps: []resource.TestStep{
+				{
+					Config: tfbuild.FileString(tfbuild.File(
+						acctest.ProviderConfigBlock(target.Configs.Default()),
+						testAccFileBlock("test", filePath,
+							tfbuild.AttributeString("content", "hello world!"),
+							tfbuild.AttributeBool("backup", true),
+							tfbuild.AttributeInt("backup_retention", 1),
+						),
+					)),
+					Check: resource.ComposeTestCheckFunc(
+						resource.TestCheckResourceAttr("system_file.test", "backup", "true"),
+						resource.TestCheckResourceAttr("system_file.test", "backup_path", ""),
+					),
+				},
+				{
+					Config: tfbuild.FileString(tfbuild.File(
+						acctest.ProviderConfigBlock(target.Configs.Default()),
+						testAccFileBlock("test", filePath,
+							tfbuild.AttributeString("content", "hello universe!"),
+							tfbuild.AttributeBool("backup", true),
+							tfbuild.AttributeInt("backup_retention", 1),
+						),
+					)),
+					Check: resource.ComposeTestCheckFunc(
+						resource.TestMatchResourceAttr("system_file.test", "backup_path", regexp.MustCompile(`^`+regexp.QuoteMeta(filePath)+`\.\d{8}T\d{6}\.\d{9}Z\.bak$`)),
+						// echo -n 'hello universe!' | openssl dgst -binary -md5 | openssl base64
+						resource.TestCheckResourceAttr("system_file.test", "md5sum", "w0Y+MwVOASL+sUYDnI0Eww=="),
+					),
+				},
+			},
+		})
+	})
+}
+
+func TestAccFile_backup_source(t *testing.T) {
+	testConfig := newTestFileConfig()
+
+	acctest.Current().Targets.Foreach(t, func(t *testing.T, target acctest.Target) {
+		t.Parallel()
+
+		filePath := testRunFilePath(target, testConfig.fileName)
+
+		resource.Test(t, resource.TestCase{
+			ProviderFactories: acctest.ProviderFactories(),
+			Steps: []resource.TestStep{
+				{
+					Config: tfbuild.FileString(tfbuild.File(
+						acctest.ProviderConfigBlock(target.Configs.Default()),
+						testAccFileBlock("test", filePath,
+							tfbuild.AttributeString("source", "./test/hello-world.txt"),
+							tfbuild.AttributeBool("backup", true),
+						),
+					)),
+					Check: resource.ComposeTestCheckFunc(
+						resource.TestCheckResourceAttr("system_file.test", "backup_path", ""),
+					),
+				},
+				{
+					// The file is updated with the changed source instead of replaced, so a backup is created
+					Config: tfbuild.FileString(tfbuild.File(
+						acctest.ProviderConfigBlock(target.Configs.Default()),
+						testAccFileBlock("test", filePath,
+							tfbuild.AttributeString("source", "./test/hello-universe.txt"),
+							tfbuild.AttributeBool("backup", true),
+						),
+					)),
+					Check: resource.ComposeTestCheckFunc(
+						resource.TestMatchResourceAttr("system_file.test", "backup_path", regexp.MustCompile(`^`+regexp.QuoteMeta(filePath)+`\.\d{8}T\d{6}\.\d{9}Z\.bak$`)),
+						// echo -n 'hello universe!' | openssl dgst -binary -md5 | openssl base64
+						resource.TestCheckResourceAttr("system_file.test", "md5sum", "w0Y+MwVOASL+sUYDnI0Eww=="),
+					),
+				},
+			},
+		})
+	})
+}
+
+func TestAccFile_source_remote_expected_sha256(t *testing.T) {
+	testConfig := newTestFileConfig()
+
+	acctest.Current().Targets.Foreach(t, func(t *testing.T, target acctest.Target) {
+		t.Parallel()
+
+		srcPath := testRunFilePath(target, testConfig.fileName+"-src")
+		filePath := testRunFilePath(target, testConfig.fileName)
+
+		config := func(expectedSha256 string) string {
+			return tfbuild.FileString(tfbuild.File(
+				acctest.ProviderConfigBlock(target.Configs.Default()),
+				testAccFileBlock("src", srcPath,
+					tfbuild.AttributeString("content", "hello world!"),
+				),
+				testAccFileBlock("test", filePath,
+					tfbuild.AttributeString("source", fmt.Sprintf("remote://%s?expected_sha256=%s", srcPath, expectedSha256)),
+					tfbuild.DependsOn(tfbuild.TraversalResource("system_file", "src")),
+				),
+			))
+		}
+
+		resource.Test(t, resource.TestCase{
+			ProviderFactories: acctest.ProviderFactories(),
+			Steps: []resource.TestStep{
+				{
+					Config:      config("0000000000000000000000000000000000000000000000000000000000000000"),
+					ExpectError: regexp.MustCompile(`content does not match the expected SHA-256 checksum`),
+				},
+				{
+					// echo -n 'hello world!' | sha256sum
+					Config: config("7509e5bda0c762d2bac7f90d758b5b2263fa01ccbc542ab5e3df163be08e6ca9"),
+					Check: resource.ComposeTestCheckFunc(
+						resource.TestCheckResourceAttr("system_file.test", "sha256sum", "7509e5bda0c762d2bac7f90d758b5b2263fa01ccbc542ab5e3df163be08e6ca9"),
+					),
+				},
+			},
+		})
+	})
+}
+
 func TestAccFile_fail_existing(t *testing.T) {
 	testConfig := newTestFileConfig()
 
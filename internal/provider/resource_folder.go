@@ -92,6 +92,7 @@ func resourceFolder() *schema.Resource {
 				Type:        schema.TypeString,
 				Computed:    true,
 			},
+			SchemaAttrRunAs: schemaRunAs(),
 		},
 	}
 }
@@ -147,7 +148,12 @@ func resourceFolderCreate(ctx context.Context, d *schema.ResourceData, meta inte
 		return diagErr
 	}
 
-	c := client.NewFolderClient(p.System)
+	s, diagErr := systemFromResourceData(p, d)
+	if diagErr != nil {
+		return diagErr
+	}
+
+	c := client.NewFolderClient(s)
 
 	r, diagErr := resourceFolderGetResourceData(d)
 	if diagErr != nil {
@@ -170,7 +176,12 @@ func resourceFolderRead(ctx context.Context, d *schema.ResourceData, meta interf
 		return diagErr
 	}
 
-	c := client.NewFolderClient(p.System)
+	s, diagErr := systemFromResourceData(p, d)
+	if diagErr != nil {
+		return diagErr
+	}
+
+	c := client.NewFolderClient(s)
 
 	id := d.Id()
 
@@ -193,7 +204,12 @@ func resourceFolderUpdate(ctx context.Context, d *schema.ResourceData, meta inte
 		return diagErr
 	}
 
-	c := client.NewFolderClient(p.System)
+	s, diagErr := systemFromResourceData(p, d)
+	if diagErr != nil {
+		return diagErr
+	}
+
+	c := client.NewFolderClient(s)
 
 	r, diagErr := resourceFolderGetResourceData(d)
 	if diagErr != nil {
@@ -214,7 +230,12 @@ func resourceFolderDelete(ctx context.Context, d *schema.ResourceData, meta inte
 		return diagErr
 	}
 
-	c := client.NewFolderClient(p.System)
+	s, diagErr := systemFromResourceData(p, d)
+	if diagErr != nil {
+		return diagErr
+	}
+
+	c := client.NewFolderClient(s)
 
 	id := d.Id()
 
@@ -0,0 +1,264 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"github.com/neuspaces/terraform-provider-system/internal/client"
+	"github.com/neuspaces/terraform-provider-system/internal/lib/filemode"
+	"github.com/neuspaces/terraform-provider-system/internal/validate"
+	"reflect"
+)
+
+const resourceFolderSyncName = "system_folder_sync"
+
+const (
+	resourceFolderSyncAttrId       = "id"
+	resourceFolderSyncAttrPath     = "path"
+	resourceFolderSyncAttrSource   = "source"
+	resourceFolderSyncAttrExclude  = "exclude"
+	resourceFolderSyncAttrFileMode = "file_mode"
+	resourceFolderSyncAttrDirMode  = "dir_mode"
+	resourceFolderSyncAttrUser     = "user"
+	resourceFolderSyncAttrGroup    = "group"
+	resourceFolderSyncAttrPurge    = "purge"
+	resourceFolderSyncAttrManifest = "manifest"
+)
+
+func resourceFolderSync() *schema.Resource {
+	return &schema.Resource{
+		Description: fmt.Sprintf("`%s` mirrors a local folder to a folder on the remote system.", resourceFolderSyncName),
+
+		CreateContext: resourceFolderSyncCreate,
+		ReadContext:   resourceFolderSyncRead,
+		UpdateContext: resourceFolderSyncUpdate,
+		DeleteContext: resourceFolderSyncDelete,
+
+		CustomizeDiff: resourceFolderSyncCustomizeDiff,
+
+		SchemaVersion: 1,
+
+		Schema: map[string]*schema.Schema{
+			resourceFolderSyncAttrId: {
+				Description: "ID of the folder",
+				Type:        schema.TypeString,
+				Computed:    true,
+			},
+			resourceFolderSyncAttrPath: {
+				Description:      "Path to the folder on the remote system. Must be an absolute path. The folder is created if it does not exist.",
+				Type:             schema.TypeString,
+				Required:         true,
+				ForceNew:         true,
+				ValidateDiagFunc: validate.AbsolutePath(),
+			},
+			resourceFolderSyncAttrSource: {
+				Description:  "Path to the local folder. Regular files and folders are synchronized. Symbolic links and special files are ignored.",
+				Type:         schema.TypeString,
+				Required:     true,
+				ValidateFunc: validation.StringIsNotEmpty,
+			},
+			resourceFolderSyncAttrExclude: {
+				Description: fmt.Sprintf("Glob patterns of paths relative to `%[1]s` which are not synchronized. A pattern without a slash such as `*.tmp` matches the name of a file or folder at any depth. A pattern with a slash such as `cache/*` matches the relative path. The content of an excluded folder is excluded. Excluded paths on the remote system are neither compared nor removed by `%[2]s`.", resourceFolderSyncAttrSource, resourceFolderSyncAttrPurge),
+				Type:        schema.TypeSet,
+				Optional:    true,
+				Elem: &schema.Schema{
+					Type:         schema.TypeString,
+					ValidateFunc: validation.StringIsNotEmpty,
+				},
+			},
+			resourceFolderSyncAttrFileMode: {
+				Description:      "Permissions of all synchronized files in octal format like `644`. Defaults to the permissions of the local files.",
+				Type:             schema.TypeString,
+				Optional:         true,
+				ValidateDiagFunc: validate.FileMode(),
+			},
+			resourceFolderSyncAttrDirMode: {
+				Description:      "Permissions of the folder and all synchronized subfolders in octal format like `755`. Defaults to the permissions of the local folders.",
+				Type:             schema.TypeString,
+				Optional:         true,
+				ValidateDiagFunc: validate.FileMode(),
+			},
+			resourceFolderSyncAttrUser: {
+				Description: "Name or ID of the user who owns the folder and all synchronized files and subfolders. Defaults to the connecting user.",
+				Type:        schema.TypeString,
+				Optional:    true,
+			},
+			resourceFolderSyncAttrGroup: {
+				Description: "Name or ID of the group that owns the folder and all synchronized files and subfolders. Defaults to the primary group of the connecting user.",
+				Type:        schema.TypeString,
+				Optional:    true,
+			},
+			resourceFolderSyncAttrPurge: {
+				Description: fmt.Sprintf("If `true`, files and folders on the remote system which do not exist in `%[1]s` are removed. Defaults to `false`.", resourceFolderSyncAttrSource),
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+			},
+			resourceFolderSyncAttrManifest: {
+				Description: "SHA-256 checksum in hexadecimal encoding of each synchronized file by the path relative to the folder. Changes of the files on the remote system or in the local folder are detected by comparing the manifest.",
+				Type:        schema.TypeMap,
+				Computed:    true,
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
+			SchemaAttrRunAs: schemaRunAs(),
+		},
+	}
+}
+
+type resourceFolderSyncData interface {
+	Get(key string) interface{}
+}
+
+func resourceFolderSyncGetResourceData(d resourceFolderSyncData) client.FolderSync {
+	f := client.FolderSync{
+		Path:   d.Get(resourceFolderSyncAttrPath).(string),
+		Source: d.Get(resourceFolderSyncAttrSource).(string),
+		User:   d.Get(resourceFolderSyncAttrUser).(string),
+		Group:  d.Get(resourceFolderSyncAttrGroup).(string),
+		Purge:  d.Get(resourceFolderSyncAttrPurge).(bool),
+	}
+
+	for _, v := range d.Get(resourceFolderSyncAttrExclude).(*schema.Set).List() {
+		f.Exclude = append(f.Exclude, v.(string))
+	}
+
+	if v := d.Get(resourceFolderSyncAttrFileMode).(string); v != "" {
+		f.FileMode = filemode.MustParse(v)
+	}
+
+	if v := d.Get(resourceFolderSyncAttrDirMode).(string); v != "" {
+		f.DirMode = filemode.MustParse(v)
+	}
+
+	return f
+}
+
+func resourceFolderSyncManifest(m client.FolderManifest) map[string]interface{} {
+	r := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		r[k] = v
+	}
+
+	return r
+}
+
+// resourceFolderSyncCustomizeDiff plans the manifest of the local folder
+func resourceFolderSyncCustomizeDiff(_ context.Context, d *schema.ResourceDiff, _ interface{}) error {
+	if !d.NewValueKnown(resourceFolderSyncAttrSource) || !d.NewValueKnown(resourceFolderSyncAttrExclude) {
+		return d.SetNewComputed(resourceFolderSyncAttrManifest)
+	}
+
+	local, err := client.LocalFolderManifest(resourceFolderSyncGetResourceData(d))
+	if err != nil {
+		return err
+	}
+
+	planned := resourceFolderSyncManifest(local)
+	if reflect.DeepEqual(planned, d.Get(resourceFolderSyncAttrManifest).(map[string]interface{})) {
+		return nil
+	}
+
+	return d.SetNew(resourceFolderSyncAttrManifest, planned)
+}
+
+func resourceFolderSyncClient(meta interface{}, d *schema.ResourceData) (client.FolderSyncClient, diag.Diagnostics) {
+	p, diagErr := providerFromMeta(meta)
+	if diagErr != nil {
+		return nil, diagErr
+	}
+
+	s, diagErr := systemFromResourceData(p, d)
+	if diagErr != nil {
+		return nil, diagErr
+	}
+
+	return client.NewFolderSyncClient(s), nil
+}
+
+func resourceFolderSyncCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	c, diagErr := resourceFolderSyncClient(meta, d)
+	if diagErr != nil {
+		return diagErr
+	}
+
+	f := resourceFolderSyncGetResourceData(d)
+
+	err := c.Sync(ctx, f)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	d.SetId(f.Path)
+
+	return resourceFolderSyncRead(ctx, d, meta)
+}
+
+func resourceFolderSyncRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	c, diagErr := resourceFolderSyncClient(meta, d)
+	if diagErr != nil {
+		return diagErr
+	}
+
+	f := resourceFolderSyncGetResourceData(d)
+	f.Path = d.Id()
+
+	remote, err := c.Get(ctx, f)
+	if errors.Is(err, client.ErrFolderSyncNotFound) {
+		d.SetId("")
+		return nil
+	} else if err != nil {
+		return diag.FromErr(err)
+	}
+
+	// Files which are not managed by the resource are only relevant if they are purged
+	if !f.Purge {
+		managed := d.Get(resourceFolderSyncAttrManifest).(map[string]interface{})
+		for rel := range remote {
+			if _, ok := managed[rel]; !ok {
+				delete(remote, rel)
+			}
+		}
+	}
+
+	_ = d.Set(resourceFolderSyncAttrManifest, resourceFolderSyncManifest(remote))
+
+	return nil
+}
+
+func resourceFolderSyncUpdate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	c, diagErr := resourceFolderSyncClient(meta, d)
+	if diagErr != nil {
+		return diagErr
+	}
+
+	err := c.Sync(ctx, resourceFolderSyncGetResourceData(d))
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	return resourceFolderSyncRead(ctx, d, meta)
+}
+
+func resourceFolderSyncDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	c, diagErr := resourceFolderSyncClient(meta, d)
+	if diagErr != nil {
+		return diagErr
+	}
+
+	manifest := client.FolderManifest{}
+	for rel, sum := range d.Get(resourceFolderSyncAttrManifest).(map[string]interface{}) {
+		manifest[rel] = sum.(string)
+	}
+
+	err := c.Delete(ctx, resourceFolderSyncGetResourceData(d), manifest)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,115 @@
+package provider_test
+
+import (
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/neuspaces/terraform-provider-system/internal/acctest"
+	"github.com/neuspaces/terraform-provider-system/internal/acctest/tfbuild"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAccFolderSync(t *testing.T) {
+	testConfig := newTestFolderConfig()
+
+	acctest.Current().Targets.Foreach(t, func(t *testing.T, target acctest.Target) {
+		t.Parallel()
+
+		// Each target modifies its own local folder
+		source := t.TempDir()
+		require.NoError(t, os.MkdirAll(filepath.Join(source, "conf.d"), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(source, "app.conf"), []byte("a"), 0644))
+		require.NoError(t, os.WriteFile(filepath.Join(source, "conf.d", "b.conf"), []byte("b"), 0644))
+		require.NoError(t, os.WriteFile(filepath.Join(source, "conf.d", "b.conf.swp"), []byte("c"), 0644))
+
+		folderPath := testRunFolderPath(target, testConfig.folderName)
+
+		config := func(attrs ...tfbuild.BlockElement) string {
+			resourceAttrs := []tfbuild.BlockElement{
+				tfbuild.AttributeString("path", folderPath),
+				tfbuild.AttributeString("source", source),
+				tfbuild.Attribute("exclude", tfbuild.StringList("*.swp")),
+			}
+			resourceAttrs = append(resourceAttrs, attrs...)
+
+			return tfbuild.FileString(tfbuild.File(
+				acctest.ProviderConfigBlock(target.Configs.Default()),
+				tfbuild.Resource("system_folder_sync", "test", resourceAttrs...),
+				tfbuild.Data("system_file_meta", "test",
+					tfbuild.AttributeString("path", folderPath+"/conf.d/b.conf"),
+					tfbuild.DependsOn(tfbuild.TraversalResource("system_folder_sync", "test")),
+				),
+			))
+		}
+
+		resource.Test(t, resource.TestCase{
+			ProviderFactories: acctest.ProviderFactories(),
+			Steps: []resource.TestStep{
+				{
+					Config: config(
+						tfbuild.AttributeString("file_mode", "600"),
+					),
+					Check: resource.ComposeTestCheckFunc(
+						resource.TestCheckResourceAttr("system_folder_sync.test", "id", folderPath),
+						resource.TestCheckResourceAttr("system_folder_sync.test", "manifest.%", "2"),
+						resource.TestCheckResourceAttr("system_folder_sync.test", "manifest.app.conf", "ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb"),
+						resource.TestCheckResourceAttr("system_folder_sync.test", "manifest.conf.d/b.conf", "3e23e8160039594a33894f6564e1b1348bbd7a0088d42c4acb73eeaed59c009d"),
+						resource.TestCheckResourceAttr("data.system_file_meta.test", "mode", "600"),
+					),
+				},
+				{
+					// Change of a local file
+					PreConfig: func() {
+						require.NoError(t, os.WriteFile(filepath.Join(source, "app.conf"), []byte("b"), 0644))
+					},
+					Config: config(
+						tfbuild.AttributeString("file_mode", "640"),
+						tfbuild.AttributeBool("purge", true),
+					),
+					Check: resource.ComposeTestCheckFunc(
+						resource.TestCheckResourceAttr("system_folder_sync.test", "manifest.%", "2"),
+						resource.TestCheckResourceAttr("system_folder_sync.test", "manifest.app.conf", "3e23e8160039594a33894f6564e1b1348bbd7a0088d42c4acb73eeaed59c009d"),
+						resource.TestCheckResourceAttr("data.system_file_meta.test", "mode", "640"),
+					),
+				},
+			},
+		})
+	})
+}
+
+func TestAccFolderSync_names(t *testing.T) {
+	testConfig := newTestFolderConfig()
+
+	acctest.Current().Targets.Foreach(t, func(t *testing.T, target acctest.Target) {
+		t.Parallel()
+
+		// Names which are escaped by sha256sum
+		source := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(source, "with space.conf"), []byte("a"), 0644))
+		require.NoError(t, os.WriteFile(filepath.Join(source, `back\slash.conf`), []byte("b"), 0644))
+
+		folderPath := testRunFolderPath(target, testConfig.folderName)
+
+		resource.Test(t, resource.TestCase{
+			ProviderFactories: acctest.ProviderFactories(),
+			Steps: []resource.TestStep{
+				{
+					// The plan is empty after the apply if the manifest of the system matches
+					Config: tfbuild.FileString(tfbuild.File(
+						acctest.ProviderConfigBlock(target.Configs.Default()),
+						tfbuild.Resource("system_folder_sync", "test",
+							tfbuild.AttributeString("path", folderPath),
+							tfbuild.AttributeString("source", source),
+						),
+					)),
+					Check: resource.ComposeTestCheckFunc(
+						resource.TestCheckResourceAttr("system_folder_sync.test", "manifest.%", "2"),
+						resource.TestCheckResourceAttr("system_folder_sync.test", "manifest.with space.conf", "ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb"),
+						resource.TestCheckResourceAttr("system_folder_sync.test", `manifest.back\slash.conf`, "3e23e8160039594a33894f6564e1b1348bbd7a0088d42c4acb73eeaed59c009d"),
+					),
+				},
+			},
+		})
+	})
+}
@@ -5,6 +5,7 @@ import (
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
 	"github.com/neuspaces/terraform-provider-system/internal/acctest"
 	"github.com/neuspaces/terraform-provider-system/internal/acctest/tfbuild"
+	"github.com/neuspaces/terraform-provider-system/internal/provider"
 	"path"
 	"sync/atomic"
 	"testing"
@@ -107,3 +108,36 @@ func testAccFolderBlock(name string, path string, attrs ...tfbuild.BlockElement)
 
 	return tfbuild.Resource("system_folder", name, resourceAttrs...)
 }
+
+func TestAccFolder_runAs(t *testing.T) {
+	testConfig := newTestFolderConfig()
+
+	acctest.Current().Targets.Foreach(t, func(t *testing.T, target acctest.Target) {
+		t.Parallel()
+
+		targetConfig := getTargetConfigOrSkip(t, target, "auth-privileged")
+
+		// The folder is created by an unprivileged user in a folder which is writable by all users
+		folderPath := path.Join("/tmp", fmt.Sprintf("%s-%s", target.Id, testConfig.folderName))
+
+		resource.Test(t, resource.TestCase{
+			ProviderFactories: acctest.ProviderFactories(),
+			Steps: []resource.TestStep{
+				{
+					Config: tfbuild.FileString(tfbuild.File(
+						acctest.ProviderConfigBlock(targetConfig),
+						testAccFolderBlock("test", folderPath,
+							tfbuild.InnerBlock(provider.SchemaAttrRunAs,
+								tfbuild.AttributeString(provider.SchemaAttrRunAsUser, "someone"),
+							),
+						),
+					)),
+					Check: resource.ComposeTestCheckFunc(
+						resource.TestCheckResourceAttr("system_folder.test", "path", folderPath),
+						resource.TestCheckResourceAttr("system_folder.test", "user", "someone"),
+					),
+				},
+			},
+		})
+	})
+}
@@ -10,22 +10,44 @@ import (
 
 // Schema is a struct to represent the configuration of the provider
 type Schema struct {
+	// Local is set if commands are executed on the system on which the provider runs. Ssh and Proxies are not set if
+	// Local is set.
+	Local *SchemaLocal
+
 	Ssh *SchemaSsh
 
-	Proxy *SchemaProxy
+	// Proxies is the ordered list of proxy hops from the provider towards the remote
+	Proxies []*SchemaProxy
 
 	Parallel int
 	Timeout  time.Duration
 	Retry    bool
 
+	KeepaliveInterval time.Duration
+	KeepaliveCountMax int
+
+	Sftp bool
+
 	Sudo bool
+
+	// Become is the privilege escalation. Become takes precedence over Sudo.
+	Become *SchemaBecome
+
+	// SourceHttp is the configuration of the requests to http and https sources. SourceHttp is nil if not configured.
+	SourceHttp *SchemaSourceHttp
+
+	// SourceSftp is the configuration of the connections to sftp and scp sources. SourceSftp is nil if not configured.
+	SourceSftp *SchemaSourceSftp
 }
 
 // expandProviderSchema returns a Schema from schema.ResourceData of the provider configuration
 func expandProviderSchema(d *schema.ResourceData) (*Schema, error) {
 	s := &Schema{}
 
-	if sshV, sshOk := d.GetOk(SchemaAttrSsh); sshOk {
+	if _, localOk := d.GetOk(SchemaAttrLocal); localOk {
+		// Local configuration with `local` block
+		s.Local = &SchemaLocal{}
+	} else if sshV, sshOk := d.GetOk(SchemaAttrSsh); sshOk {
 		// Standard configuration with `ssh` block
 		// Recommended configuration using `ssh` block and optional `proxy` block
 		schemaSsh, err := expandSchemaSsh(sshV)
@@ -34,15 +56,24 @@ func expandProviderSchema(d *schema.ResourceData) (*Schema, error) {
 		}
 		s.Ssh = schemaSsh
 
-		// Optional proxy
-		if proxySshV, proxySshOk := d.GetOk(newAttrPath(SchemaAttrProxy, "0", SchemaAttrSsh).String()); proxySshOk {
-			proxySchemaSsh, err := expandSchemaSsh(proxySshV)
+		// Optional proxies
+		proxies, err := expandSchemaProxies(d)
+		if err != nil {
+			return nil, err
+		}
+		s.Proxies = proxies
+
+		// Optional configuration from OpenSSH client configuration file
+		if schemaSsh.Alias != "" {
+			explicit := schemaSshExplicit(rawConfigBlock(d.GetRawConfig(), SchemaAttrSsh), SchemaEnvPrefix+"SSH_")
+
+			jumpProxies, err := applySshConfig(schemaSsh, explicit)
 			if err != nil {
-				return nil, err
+				return nil, fmt.Errorf("%s: %w", SchemaAttrSsh, err)
 			}
 
-			s.Proxy = &SchemaProxy{
-				Ssh: proxySchemaSsh,
+			if len(s.Proxies) == 0 {
+				s.Proxies = jumpProxies
 			}
 		}
 	} else if connectionV, connectionOk := d.GetOk(SchemaAttrConnection); connectionOk {
@@ -58,13 +89,25 @@ func expandProviderSchema(d *schema.ResourceData) (*Schema, error) {
 		s.Ssh = schemaSsh
 
 		if bastionSchemaSsh != nil {
-			s.Proxy = &SchemaProxy{
-				Ssh: bastionSchemaSsh,
+			s.Proxies = []*SchemaProxy{
+				{
+					Ssh: bastionSchemaSsh,
+				},
 			}
 		}
 	} else {
-		// TODO support configuration from environment variables if neither `ssh` nor `connection` is configured
-		return nil, fmt.Errorf("provider configuration requires either one of the following blocks: %s, %s", SchemaAttrSsh, SchemaAttrConnection)
+		// Configuration from environment variables using a host alias of an OpenSSH client configuration file
+		schemaSsh, proxies, err := expandSchemaSshFromEnvironment(SchemaEnvPrefix + "SSH_")
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", SchemaAttrSsh, err)
+		}
+
+		if schemaSsh == nil {
+			return nil, fmt.Errorf("provider configuration requires either one of the following blocks: %s, %s, %s", SchemaAttrSsh, SchemaAttrConnection, SchemaAttrLocal)
+		}
+
+		s.Ssh = schemaSsh
+		s.Proxies = proxies
 	}
 
 	// Other
@@ -79,8 +122,43 @@ func expandProviderSchema(d *schema.ResourceData) (*Schema, error) {
 		s.Timeout = timeout
 	}
 
+	if keepaliveIntervalStr := d.Get(SchemaAttrKeepaliveInterval).(string); keepaliveIntervalStr != "" {
+		keepaliveInterval, err := time.ParseDuration(keepaliveIntervalStr)
+		if err != nil {
+			return nil, err
+		}
+		s.KeepaliveInterval = keepaliveInterval
+	}
+	s.KeepaliveCountMax = d.Get(SchemaAttrKeepaliveCountMax).(int)
+
+	s.Sftp = d.Get(SchemaAttrSftp).(bool)
+
 	s.Sudo = d.Get(SchemaAttrSudo).(bool)
 
+	if becomeV, becomeOk := d.GetOk(SchemaAttrBecome); becomeOk {
+		schemaBecome, err := expandSchemaBecome(becomeV)
+		if err != nil {
+			return nil, err
+		}
+		s.Become = schemaBecome
+	}
+
+	if sourceHttpV, sourceHttpOk := d.GetOk(SchemaAttrSourceHttp); sourceHttpOk {
+		schemaSourceHttp, err := expandSchemaSourceHttp(sourceHttpV)
+		if err != nil {
+			return nil, err
+		}
+		s.SourceHttp = schemaSourceHttp
+	}
+
+	if sourceSftpV, sourceSftpOk := d.GetOk(SchemaAttrSourceSftp); sourceSftpOk {
+		schemaSourceSftp, err := expandSchemaSourceSftp(sourceSftpV)
+		if err != nil {
+			return nil, err
+		}
+		s.SourceSftp = schemaSourceSftp
+	}
+
 	return s, nil
 }
 
@@ -98,6 +176,11 @@ const (
 	SchemaAttrTimeout  = "timeout"
 	SchemaAttrRetry    = "retry"
 
+	SchemaAttrKeepaliveInterval = "keepalive_interval"
+	SchemaAttrKeepaliveCountMax = "keepalive_count_max"
+
+	SchemaAttrSftp = "sftp"
+
 	SchemaAttrShell = "shell"
 	SchemaAttrSudo  = "sudo"
 )
@@ -112,6 +195,7 @@ func providerSchema() map[string]*schema.Schema {
 			ConflictsWith: []string{
 				SchemaAttrSsh,
 				SchemaAttrProxy,
+				SchemaAttrLocal,
 			},
 			Elem: &schema.Resource{
 				Schema: providerSchemaConnection(newAttrPath(SchemaAttrConnection, "0")),
@@ -123,29 +207,38 @@ func providerSchema() map[string]*schema.Schema {
 			MaxItems: 1,
 			ConflictsWith: []string{
 				SchemaAttrConnection,
+				SchemaAttrLocal,
 			},
 			Elem: &schema.Resource{
-				Schema: providerSchemaSsh(newAttrPath(SchemaAttrSsh, "0"), SchemaEnvPrefix+"SSH_"),
+				Schema: mergeSchemaMaps(
+					providerSchemaSsh(newAttrPath(SchemaAttrSsh, "0"), SchemaEnvPrefix+"SSH_"),
+					providerSchemaSshConfig(SchemaEnvPrefix+"SSH_"),
+				),
+			},
+		},
+		SchemaAttrLocal: {
+			Description: "Execute commands on and manage files of the system on which the provider runs instead of a remote system. Use the `local` block to configure the machine on which Terraform runs, e.g. when building images. No ssh server is required. Commands are executed using `/bin/sh` and `sudo` if enabled. If `sudo` or `become` is configured, files are read and written by executing commands with escalated privileges as well.",
+			Type:        schema.TypeList,
+			Optional:    true,
+			MaxItems:    1,
+			ConflictsWith: []string{
+				SchemaAttrConnection,
+				SchemaAttrSsh,
+				SchemaAttrProxy,
+			},
+			Elem: &schema.Resource{
+				Schema: providerSchemaLocal(),
 			},
 		},
 		SchemaAttrProxy: {
-			Type:     schema.TypeList,
-			Optional: true,
-			MaxItems: 1,
+			Description: "Ordered list of proxy hops through which the connection to the remote is established. The first `proxy` block is the hop which is connected first. Every subsequent hop is connected through the previous hop.",
+			Type:        schema.TypeList,
+			Optional:    true,
 			RequiredWith: []string{
 				SchemaAttrSsh,
 			},
 			Elem: &schema.Resource{
-				Schema: map[string]*schema.Schema{
-					SchemaAttrSsh: {
-						Type:     schema.TypeList,
-						Optional: true,
-						MaxItems: 1,
-						Elem: &schema.Resource{
-							Schema: providerSchemaSsh(newAttrPath(SchemaAttrProxy, "0", SchemaAttrSsh, "0"), SchemaEnvPrefix+"PROXY_SSH_"),
-						},
-					},
-				},
+				Schema: providerSchemaProxy(),
 			},
 		},
 		SchemaAttrParallel: {
@@ -171,11 +264,64 @@ func providerSchema() map[string]*schema.Schema {
 			Optional:    true,
 			DefaultFunc: schemaEnvDefaultFunc(SchemaAttrRetry, SchemaEnvPrefix, true),
 		},
+		SchemaAttrKeepaliveInterval: {
+			Description: fmt.Sprintf("Interval of keepalive requests to the remote. Keepalive requests prevent idle connections from being dropped and detect dead connections. A dead connection is closed after `%[1]s` consecutive keepalive requests have not been answered and is reestablished transparently for subsequent commands. Provided as a duration string like `30s` or `1m`. Set to `0s` to disable keepalive requests. Defaults to `30s`.", SchemaAttrKeepaliveCountMax),
+			Type:        schema.TypeString,
+			Optional:    true,
+			ValidateDiagFunc: validate.All(
+				validate.DurationAtLeast(0),
+				validate.DurationAtMost(60*time.Minute),
+			),
+			DefaultFunc: schemaEnvDefaultFunc(SchemaAttrKeepaliveInterval, SchemaEnvPrefix, "30s"),
+		},
+		SchemaAttrKeepaliveCountMax: {
+			Description:  "Number of consecutive unanswered keepalive requests after which the connection to the remote is considered dead. Defaults to `3`.",
+			Type:         schema.TypeInt,
+			Optional:     true,
+			ValidateFunc: validation.IntAtLeast(1),
+			DefaultFunc:  schemaEnvDefaultFunc(SchemaAttrKeepaliveCountMax, SchemaEnvPrefix, 3),
+		},
+		SchemaAttrSftp: {
+			Description: fmt.Sprintf("If `true`, files are uploaded and read over the SFTP subsystem of the remote. Files are transferred by executing shell commands if SFTP is disabled, the remote does not support the SFTP subsystem or `%[1]s` or `%[2]s` is configured. Defaults to `true`.", SchemaAttrSudo, SchemaAttrBecome),
+			Type:        schema.TypeBool,
+			Optional:    true,
+			DefaultFunc: schemaEnvDefaultFunc(SchemaAttrSftp, SchemaEnvPrefix, true),
+		},
 		SchemaAttrSudo: {
 			Description: "If `true`, commands are executed on the remote using `sudo` by default. Enable `sudo` to connect to the remote with an unprivileged used and execute commands as root. As a prerequisite `sudo` must be installed and configured on the remote system. The `user` must be able to run `sudo` without password (`NOPASSWD`). Defaults to `false`.",
 			Type:        schema.TypeBool,
 			Optional:    true,
 			DefaultFunc: schemaEnvDefaultFunc(SchemaAttrSudo, SchemaEnvPrefix, false),
 		},
+		SchemaAttrBecome: {
+			Description: fmt.Sprintf("Privilege escalation using `sudo`, `doas` or `su`. Commands are executed as `%[1]s` using the shell `/bin/sh`. Mutually exclusive with `%[2]s`.", SchemaAttrBecomeUser, SchemaAttrSudo),
+			Type:        schema.TypeList,
+			Optional:    true,
+			MaxItems:    1,
+			ConflictsWith: []string{
+				SchemaAttrSudo,
+			},
+			Elem: &schema.Resource{
+				Schema: providerSchemaBecome(SchemaEnvPrefix + "BECOME_"),
+			},
+		},
+		SchemaAttrSourceHttp: {
+			Description: "Configuration of the requests to http and https urls of the attribute `source` of resources. The provider downloads from the urls and not the remote.",
+			Type:        schema.TypeList,
+			Optional:    true,
+			MaxItems:    1,
+			Elem: &schema.Resource{
+				Schema: providerSchemaSourceHttp(SchemaEnvPrefix + "SOURCE_HTTP_"),
+			},
+		},
+		SchemaAttrSourceSftp: {
+			Description: "Configuration of the connections to sftp and scp urls of the attribute `source` of resources. The provider connects to the hosts of the urls and not the remote. If not configured, the identities of the ssh agent and the unencrypted identity files `~/.ssh/id_ed25519`, `~/.ssh/id_ecdsa` and `~/.ssh/id_rsa` authenticate the user and `~/.ssh/known_hosts` verifies the host keys.",
+			Type:        schema.TypeList,
+			Optional:    true,
+			MaxItems:    1,
+			Elem: &schema.Resource{
+				Schema: providerSchemaSourceSftp(SchemaEnvPrefix + "SOURCE_SFTP_"),
+			},
+		},
 	}
 }
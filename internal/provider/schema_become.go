@@ -0,0 +1,87 @@
+package provider
+
+import (
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"github.com/neuspaces/terraform-provider-system/internal/cmd"
+	"strings"
+)
+
+const (
+	SchemaAttrBecome = "become"
+
+	SchemaAttrBecomeMethod   = "method"
+	SchemaAttrBecomeUser     = "user"
+	SchemaAttrBecomePassword = "password"
+)
+
+// SchemaBecome is the configuration of the privilege escalation
+type SchemaBecome struct {
+	Method   cmd.BecomeMethod
+	User     string
+	Password string
+}
+
+func providerSchemaBecome(envPrefix string) map[string]*schema.Schema {
+	var methods []string
+	for _, method := range cmd.BecomeMethods {
+		methods = append(methods, string(method))
+	}
+
+	return map[string]*schema.Schema{
+		SchemaAttrBecomeMethod: {
+			Description:  fmt.Sprintf("Method of the privilege escalation. Supported methods are `%[1]s`. Defaults to `%[2]s`.", strings.Join(methods, "`, `"), cmd.BecomeSudo),
+			Type:         schema.TypeString,
+			Optional:     true,
+			ValidateFunc: validation.StringInSlice(methods, false),
+			DefaultFunc:  schemaEnvDefaultFunc(SchemaAttrBecomeMethod, envPrefix, string(cmd.BecomeSudo)),
+		},
+		SchemaAttrBecomeUser: {
+			Description: "User as which commands are executed, equivalent to `become_user` of Ansible. Use a non-root user to execute commands as a service account. Defaults to `root`.",
+			Type:        schema.TypeString,
+			Optional:    true,
+			DefaultFunc: schemaEnvDefaultFunc(SchemaAttrBecomeUser, envPrefix, "root"),
+		},
+		SchemaAttrBecomePassword: {
+			Description: fmt.Sprintf("Password of the connecting user which is provided on the standard input when `%[1]s` prompts for a password. The password is never part of a command. An incorrect password is reported as an error. Not supported by `%[3]s` and `%[2]s` which read passwords from a terminal only. If not set, the method must not require a password, e.g. `NOPASSWD` for `%[1]s`, `nopass` for `%[3]s` or a connecting user `root` for `%[2]s`.", cmd.BecomeSudo, cmd.BecomeSu, cmd.BecomeDoas),
+			Type:        schema.TypeString,
+			Optional:    true,
+			Sensitive:   true,
+			DefaultFunc: schemaEnvDefaultFunc(SchemaAttrBecomePassword, envPrefix, nil),
+		},
+	}
+}
+
+func expandSchemaBecome(v interface{}) (*SchemaBecome, error) {
+	d, err := expandListSingle(v)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &SchemaBecome{
+		Method:   cmd.BecomeMethod(d[SchemaAttrBecomeMethod].(string)),
+		User:     d[SchemaAttrBecomeUser].(string),
+		Password: d[SchemaAttrBecomePassword].(string),
+	}
+
+	// doas and su read passwords from a terminal only
+	if (s.Method == cmd.BecomeDoas || s.Method == cmd.BecomeSu) && s.Password != "" {
+		return nil, fmt.Errorf("%s: %s is not supported by method %s", SchemaAttrBecome, SchemaAttrBecomePassword, s.Method)
+	}
+
+	return s, nil
+}
+
+// middleware returns the cmd.Middleware which executes commands using the privilege escalation
+func (s SchemaBecome) middleware() (cmd.Middleware, error) {
+	var opts []cmd.BecomeOption
+
+	opts = append(opts, cmd.BecomeUser(s.User))
+
+	if s.Password != "" {
+		opts = append(opts, cmd.BecomePassword(s.Password))
+	}
+
+	return cmd.BecomeMiddleware(s.Method, opts...)
+}
@@ -0,0 +1,99 @@
+package provider_test
+
+import (
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/neuspaces/terraform-provider-system/internal/acctest"
+	"github.com/neuspaces/terraform-provider-system/internal/acctest/tfbuild"
+	"github.com/neuspaces/terraform-provider-system/internal/provider"
+	"regexp"
+	"testing"
+)
+
+// testAccProviderBecomeConfig returns a provider configuration which connects with targetConfig and escalates
+// privileges using the become block with attrs
+func testAccProviderBecomeConfig(targetConfig acctest.ConfigTargetConfig, attrs ...tfbuild.BlockElement) tfbuild.FileElement {
+	return tfbuild.Provider(provider.Name,
+		tfbuild.InnerBlock(provider.SchemaAttrSsh,
+			tfbuild.AttributeString(provider.SchemaAttrSshHost, targetConfig.Ssh.Host),
+			tfbuild.AttributeInt(provider.SchemaAttrSshPort, int64(targetConfig.Ssh.Port)),
+			tfbuild.AttributeString(provider.SchemaAttrSshUser, targetConfig.Ssh.User),
+			tfbuild.AttributeString(provider.SchemaAttrSshPassword, targetConfig.Ssh.Password),
+		),
+		tfbuild.InnerBlock(provider.SchemaAttrBecome, attrs...),
+	)
+}
+
+func testAccProviderBecomeExpectUser(t *testing.T, providerConfig tfbuild.FileElement, user string) {
+	resource.Test(t, resource.TestCase{
+		ProviderFactories: acctest.ProviderFactories(),
+		Steps: []resource.TestStep{
+			{
+				Config: testAccProviderConnectTestConfig(providerConfig),
+				Check: resource.ComposeTestCheckFunc(
+					provider.TestLogResourceAttr(t, "data.system_identity.test"),
+					resource.TestCheckResourceAttr("data.system_identity.test", "user", user),
+				),
+			},
+		},
+	})
+}
+
+func TestAccProviderBecome(t *testing.T) {
+	t.Run("sudo without password", func(t *testing.T) {
+		acctest.Current().Targets.Foreach(t, func(t *testing.T, target acctest.Target) {
+			t.Parallel()
+
+			targetConfig := getTargetConfigOrSkip(t, target, "auth-privileged")
+
+			providerConfig := testAccProviderBecomeConfig(targetConfig)
+
+			testAccProviderBecomeExpectUser(t, providerConfig, "root")
+		})
+	})
+
+	t.Run("sudo as service account", func(t *testing.T) {
+		acctest.Current().Targets.Foreach(t, func(t *testing.T, target acctest.Target) {
+			t.Parallel()
+
+			targetConfig := getTargetConfigOrSkip(t, target, "auth-privileged")
+			serviceConfig := getTargetConfigOrSkip(t, target, "auth-unprivileged")
+
+			providerConfig := testAccProviderBecomeConfig(targetConfig,
+				tfbuild.AttributeString(provider.SchemaAttrBecomeUser, serviceConfig.Ssh.User),
+			)
+
+			testAccProviderBecomeExpectUser(t, providerConfig, serviceConfig.Ssh.User)
+		})
+	})
+
+	t.Run("su with password", func(t *testing.T) {
+		acctest.Current().Targets.Foreach(t, func(t *testing.T, target acctest.Target) {
+			t.Parallel()
+
+			targetConfig := getTargetConfigOrSkip(t, target, "auth-unprivileged")
+			rootConfig := getTargetConfigOrSkip(t, target, "auth-password")
+
+			providerConfig := testAccProviderBecomeConfig(targetConfig,
+				tfbuild.AttributeString(provider.SchemaAttrBecomeMethod, "su"),
+				tfbuild.AttributeString(provider.SchemaAttrBecomePassword, rootConfig.Ssh.Password),
+			)
+
+			testAccProviderConnectTestExpectError(t, providerConfig, regexp.MustCompile(`password is not supported by method su`))
+		})
+	})
+
+	t.Run("doas with password", func(t *testing.T) {
+		acctest.Current().Targets.Foreach(t, func(t *testing.T, target acctest.Target) {
+			t.Parallel()
+
+			targetConfig := getTargetConfigOrSkip(t, target, "auth-privileged")
+
+			providerConfig := testAccProviderBecomeConfig(targetConfig,
+				tfbuild.AttributeString(provider.SchemaAttrBecomeMethod, "doas"),
+				tfbuild.AttributeString(provider.SchemaAttrBecomePassword, targetConfig.Ssh.Password),
+			)
+
+			testAccProviderConnectTestExpectError(t, providerConfig, regexp.MustCompile(`password is not supported by method doas`))
+		})
+	})
+}
@@ -3,26 +3,32 @@ package provider
 import (
 	"fmt"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
 	"github.com/neuspaces/terraform-provider-system/internal/validate"
 	"time"
 )
 
 const (
-	SchemaAttrConnectionHost        = "host"
-	SchemaAttrConnectionHostKey     = "host_key"
-	SchemaAttrConnectionPort        = "port"
-	SchemaAttrConnectionUser        = "user"
-	SchemaAttrConnectionPassword    = "password"
-	SchemaAttrConnectionPrivateKey  = "private_key"
-	SchemaAttrConnectionCertificate = "certificate"
+	SchemaAttrConnectionHost                 = "host"
+	SchemaAttrConnectionHostKey              = "host_key"
+	SchemaAttrConnectionKnownHosts           = "known_hosts"
+	SchemaAttrConnectionHostCaPublicKeys     = "host_ca_public_keys"
+	SchemaAttrConnectionHostCaRevokedSerials = "host_ca_revoked_serials"
+	SchemaAttrConnectionPort                 = "port"
+	SchemaAttrConnectionUser                 = "user"
+	SchemaAttrConnectionPassword             = "password"
+	SchemaAttrConnectionPrivateKey           = "private_key"
+	SchemaAttrConnectionPrivateKeyPassphrase = "private_key_passphrase"
+	SchemaAttrConnectionCertificate          = "certificate"
 
-	SchemaAttrConnectionBastionHost        = "bastion_host"
-	SchemaAttrConnectionBastionHostKey     = "bastion_host_key"
-	SchemaAttrConnectionBastionPort        = "bastion_port"
-	SchemaAttrConnectionBastionUser        = "bastion_user"
-	SchemaAttrConnectionBastionPassword    = "bastion_password"
-	SchemaAttrConnectionBastionPrivateKey  = "bastion_private_key"
-	SchemaAttrConnectionBastionCertificate = "bastion_certificate"
+	SchemaAttrConnectionBastionHost                 = "bastion_host"
+	SchemaAttrConnectionBastionHostKey              = "bastion_host_key"
+	SchemaAttrConnectionBastionPort                 = "bastion_port"
+	SchemaAttrConnectionBastionUser                 = "bastion_user"
+	SchemaAttrConnectionBastionPassword             = "bastion_password"
+	SchemaAttrConnectionBastionPrivateKey           = "bastion_private_key"
+	SchemaAttrConnectionBastionPrivateKeyPassphrase = "bastion_private_key_passphrase"
+	SchemaAttrConnectionBastionCertificate          = "bastion_certificate"
 
 	SchemaAttrConnectionTimeout       = "timeout"
 	SchemaAttrConnectionAgent         = "agent"
@@ -45,13 +51,19 @@ func providerSchemaConnection(attrPath attrPath) map[string]*schema.Schema {
 			},
 		},
 		SchemaAttrConnectionPrivateKey: {
-			Description: fmt.Sprintf("The SSH private key to authenticate with the remote ssh server. The key can be provided as text or loaded from a file using the `file` function. The key must not be encrypted. Mutually exclusive with `%[1]s`.", SchemaAttrConnectionPassword, SchemaAttrConnectionPrivateKey),
+			Description: fmt.Sprintf("The SSH private key to authenticate with the remote ssh server. The key can be provided as text or loaded from a file using the `file` function. Encrypted keys require `%[3]s`. Mutually exclusive with `%[1]s`.", SchemaAttrConnectionPassword, SchemaAttrConnectionPrivateKey, SchemaAttrConnectionPrivateKeyPassphrase),
 			Type:        schema.TypeString,
 			Optional:    true,
 			ConflictsWith: []string{
 				attrPath.Extend(SchemaAttrConnectionPassword).String(),
 			},
 		},
+		SchemaAttrConnectionPrivateKeyPassphrase: {
+			Description: fmt.Sprintf("The passphrase to decrypt an encrypted `%[1]s`. Ignored if `%[1]s` is not encrypted.", SchemaAttrConnectionPrivateKey),
+			Type:        schema.TypeString,
+			Optional:    true,
+			Sensitive:   true,
+		},
 		SchemaAttrConnectionCertificate: {
 			Description: fmt.Sprintf("The ssh user certificate to authenticate with the remote ssh server. The certificate can be provided as text or loaded from a file using the `file` function. Must be used with in conjunction with `%[2]s`. Mutually exclusive with `%[1]s`.", SchemaAttrConnectionPassword, SchemaAttrConnectionPrivateKey),
 			Type:        schema.TypeString,
@@ -70,6 +82,33 @@ func providerSchemaConnection(attrPath attrPath) map[string]*schema.Schema {
 			Type:        schema.TypeString,
 			Optional:    true,
 		},
+		SchemaAttrConnectionKnownHosts: {
+			Description:      "Known hosts to verify the authenticity of the remote ssh host and the bastion ssh host. Provided either as path to a file in the OpenSSH `known_hosts` format like `~/.ssh/known_hosts` or as the content of such a file. Host keys provided in `host_key` or `bastion_host_key` take precedence.",
+			Type:             schema.TypeString,
+			Optional:         true,
+			ValidateDiagFunc: validate.KnownHosts(),
+		},
+		SchemaAttrConnectionHostCaPublicKeys: {
+			Description: "List of public keys of certificate authorities which are trusted to sign host certificates of the remote ssh host and the bastion ssh host. Expected format of a public key is a base64 encoded OpenSSH public key (`authorized_keys` format).",
+			Type:        schema.TypeList,
+			Optional:    true,
+			Elem: &schema.Schema{
+				Type:             schema.TypeString,
+				ValidateDiagFunc: validate.AuthorizedKey(),
+			},
+		},
+		SchemaAttrConnectionHostCaRevokedSerials: {
+			Description: fmt.Sprintf("List of serial numbers of revoked host certificates. Requires `%[1]s`.", SchemaAttrConnectionHostCaPublicKeys),
+			Type:        schema.TypeSet,
+			Optional:    true,
+			RequiredWith: []string{
+				attrPath.Extend(SchemaAttrConnectionHostCaPublicKeys).String(),
+			},
+			Elem: &schema.Schema{
+				Type:         schema.TypeInt,
+				ValidateFunc: validation.IntAtLeast(0),
+			},
+		},
 		SchemaAttrConnectionPort: {
 			Description: "The port of the remote ssh server to connect to. Defaults to `22`.",
 			Type:        schema.TypeInt,
@@ -120,13 +159,19 @@ func providerSchemaConnection(attrPath attrPath) map[string]*schema.Schema {
 			},
 		},
 		SchemaAttrConnectionBastionPrivateKey: {
-			Description: fmt.Sprintf("The SSH private key to authenticate with the bastion ssh server. The key can be provided as text or loaded from a file using the `file` function. The key must not be encrypted. Mutually exclusive with `%[1]s`.", SchemaAttrConnectionBastionPassword, SchemaAttrConnectionBastionPrivateKey),
+			Description: fmt.Sprintf("The SSH private key to authenticate with the bastion ssh server. The key can be provided as text or loaded from a file using the `file` function. Encrypted keys require `%[3]s`. Mutually exclusive with `%[1]s`.", SchemaAttrConnectionBastionPassword, SchemaAttrConnectionBastionPrivateKey, SchemaAttrConnectionBastionPrivateKeyPassphrase),
 			Type:        schema.TypeString,
 			Optional:    true,
 			ConflictsWith: []string{
 				attrPath.Extend(SchemaAttrConnectionBastionPassword).String(),
 			},
 		},
+		SchemaAttrConnectionBastionPrivateKeyPassphrase: {
+			Description: fmt.Sprintf("The passphrase to decrypt an encrypted `%[1]s`. Ignored if `%[1]s` is not encrypted.", SchemaAttrConnectionBastionPrivateKey),
+			Type:        schema.TypeString,
+			Optional:    true,
+			Sensitive:   true,
+		},
 		SchemaAttrConnectionBastionCertificate: {
 			Description: fmt.Sprintf("The ssh user certificate to authenticate with the bastion ssh server. The certificate can be provided as text or loaded from a file using the `file` function. Must be used with in conjunction with `%[2]s`. Mutually exclusive with `%[1]s`.", SchemaAttrConnectionBastionPassword, SchemaAttrConnectionBastionPrivateKey),
 			Type:        schema.TypeString,
@@ -148,17 +193,21 @@ func expandSchemaConnection(v interface{}) (*SchemaSsh, *SchemaSsh, error) {
 
 	// Remote ssh server
 	r := &SchemaSsh{
-		User:            d[SchemaAttrConnectionUser].(string),
-		Password:        d[SchemaAttrConnectionPassword].(string),
-		PrivateKey:      d[SchemaAttrConnectionPrivateKey].(string),
-		Certificate:     d[SchemaAttrConnectionCertificate].(string),
-		Host:            d[SchemaAttrConnectionHost].(string),
-		HostKey:         d[SchemaAttrConnectionHostKey].(string),
-		Port:            d[SchemaAttrConnectionPort].(int),
-		Agent:           d[SchemaAttrConnectionAgent].(bool),
-		AgentIdentities: []string{},
+		User:                 d[SchemaAttrConnectionUser].(string),
+		Password:             d[SchemaAttrConnectionPassword].(string),
+		PrivateKey:           d[SchemaAttrConnectionPrivateKey].(string),
+		PrivateKeyPassphrase: d[SchemaAttrConnectionPrivateKeyPassphrase].(string),
+		Certificate:          d[SchemaAttrConnectionCertificate].(string),
+		Host:                 d[SchemaAttrConnectionHost].(string),
+		HostKey:              d[SchemaAttrConnectionHostKey].(string),
+		KnownHosts:           d[SchemaAttrConnectionKnownHosts].(string),
+		Port:                 d[SchemaAttrConnectionPort].(int),
+		Agent:                d[SchemaAttrConnectionAgent].(bool),
+		AgentIdentities:      []string{},
 	}
 
+	r.HostCaPublicKeys, r.HostCaRevokedSerials = expandHostCa(d[SchemaAttrConnectionHostCaPublicKeys], d[SchemaAttrConnectionHostCaRevokedSerials])
+
 	if timeoutStr := d[SchemaAttrConnectionTimeout].(string); timeoutStr != "" {
 		timeout, err := time.ParseDuration(timeoutStr)
 		if err != nil {
@@ -171,18 +220,35 @@ func expandSchemaConnection(v interface{}) (*SchemaSsh, *SchemaSsh, error) {
 	var b *SchemaSsh
 	if d[SchemaAttrConnectionBastionHost].(string) != "" {
 		b = &SchemaSsh{
-			User:        d[SchemaAttrConnectionBastionUser].(string),
-			Password:    d[SchemaAttrConnectionBastionPassword].(string),
-			PrivateKey:  d[SchemaAttrConnectionBastionPrivateKey].(string),
-			Certificate: d[SchemaAttrConnectionBastionCertificate].(string),
-			Host:        d[SchemaAttrConnectionBastionHost].(string),
-			HostKey:     d[SchemaAttrConnectionBastionHostKey].(string),
-			Port:        d[SchemaAttrConnectionBastionPort].(int),
+			User:                 d[SchemaAttrConnectionBastionUser].(string),
+			Password:             d[SchemaAttrConnectionBastionPassword].(string),
+			PrivateKey:           d[SchemaAttrConnectionBastionPrivateKey].(string),
+			PrivateKeyPassphrase: d[SchemaAttrConnectionBastionPrivateKeyPassphrase].(string),
+			Certificate:          d[SchemaAttrConnectionBastionCertificate].(string),
+			Host:                 d[SchemaAttrConnectionBastionHost].(string),
+			HostKey:              d[SchemaAttrConnectionBastionHostKey].(string),
+			Port:                 d[SchemaAttrConnectionBastionPort].(int),
 
 			// Shared fields
-			Agent:           r.Agent,
-			AgentIdentities: r.AgentIdentities,
-			Timeout:         r.Timeout,
+			KnownHosts:           r.KnownHosts,
+			HostCaPublicKeys:     r.HostCaPublicKeys,
+			HostCaRevokedSerials: r.HostCaRevokedSerials,
+			Agent:                r.Agent,
+			AgentIdentities:      r.AgentIdentities,
+			Timeout:              r.Timeout,
+		}
+	}
+
+	// Ensure that encrypted private keys can be decrypted using the passphrase
+	if r.PrivateKey != "" {
+		if err := validate.PrivateKeyPassphrase(r.PrivateKey, r.PrivateKeyPassphrase); err != nil {
+			return nil, nil, fmt.Errorf("invalid %s or %s: %w", SchemaAttrConnectionPrivateKey, SchemaAttrConnectionPrivateKeyPassphrase, err)
+		}
+	}
+
+	if b != nil && b.PrivateKey != "" {
+		if err := validate.PrivateKeyPassphrase(b.PrivateKey, b.PrivateKeyPassphrase); err != nil {
+			return nil, nil, fmt.Errorf("invalid %s or %s: %w", SchemaAttrConnectionBastionPrivateKey, SchemaAttrConnectionBastionPrivateKeyPassphrase, err)
 		}
 	}
 
@@ -0,0 +1,18 @@
+package provider
+
+import (
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+const (
+	SchemaAttrLocal = "local"
+)
+
+// SchemaLocal is the configuration of the local system. The presence of SchemaLocal selects the local system instead
+// of a remote system connected via ssh.
+type SchemaLocal struct {
+}
+
+func providerSchemaLocal() map[string]*schema.Schema {
+	return map[string]*schema.Schema{}
+}
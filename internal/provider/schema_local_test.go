@@ -0,0 +1,106 @@
+package provider_test
+
+import (
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/neuspaces/terraform-provider-system/internal/acctest"
+	"github.com/neuspaces/terraform-provider-system/internal/acctest/tfbuild"
+	"github.com/neuspaces/terraform-provider-system/internal/provider"
+	"github.com/stretchr/testify/require"
+	"os"
+	"os/exec"
+	"os/user"
+	"path"
+	"regexp"
+	"testing"
+)
+
+func TestAccProviderConnect_Local(t *testing.T) {
+	t.Run("local system", func(t *testing.T) {
+		t.Parallel()
+
+		currentUser, err := user.Current()
+		require.NoError(t, err)
+
+		providerConfig := tfbuild.Provider(provider.Name,
+			tfbuild.InnerBlock(provider.SchemaAttrLocal),
+		)
+
+		resource.Test(t, resource.TestCase{
+			ProviderFactories: acctest.ProviderFactories(),
+			Steps: []resource.TestStep{
+				{
+					Config: testAccProviderConnectTestConfig(providerConfig),
+					Check: resource.ComposeTestCheckFunc(
+						provider.TestLogResourceAttr(t, "data.system_identity.test"),
+						resource.TestCheckResourceAttr("data.system_identity.test", "user", currentUser.Username),
+					),
+				},
+			},
+		})
+	})
+
+	t.Run("become writes files", func(t *testing.T) {
+		t.Parallel()
+
+		// su does not prompt for a password if the provider runs as root
+		if os.Geteuid() != 0 {
+			t.Skip("requires root")
+		}
+		if _, err := exec.LookPath("su"); err != nil {
+			t.Skip("requires su")
+		}
+
+		// The folder must be writable by nobody; the temporary folder of the test is not accessible by nobody
+		dir, err := os.MkdirTemp("", "local-become-")
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			_ = os.RemoveAll(dir)
+		})
+		require.NoError(t, os.Chmod(dir, 0777))
+
+		filePath := path.Join(dir, "file")
+
+		providerConfig := tfbuild.Provider(provider.Name,
+			tfbuild.InnerBlock(provider.SchemaAttrLocal),
+			tfbuild.InnerBlock(provider.SchemaAttrBecome,
+				tfbuild.AttributeString(provider.SchemaAttrBecomeMethod, "su"),
+				tfbuild.AttributeString(provider.SchemaAttrBecomeUser, "nobody"),
+			),
+		)
+
+		resource.Test(t, resource.TestCase{
+			ProviderFactories: acctest.ProviderFactories(),
+			Steps: []resource.TestStep{
+				{
+					Config: tfbuild.FileString(tfbuild.File(
+						providerConfig,
+						testAccFileBlock("test", filePath,
+							tfbuild.AttributeString("mode", "600"),
+							tfbuild.AttributeString("content", "hello world!"),
+						),
+						tfbuild.Data("system_file", "test",
+							tfbuild.AttributeTraversal("path", tfbuild.TraversalResourceAttribute("system_file", "test", "path")),
+						),
+					)),
+					Check: resource.ComposeTestCheckFunc(
+						resource.TestCheckResourceAttr("system_file.test", "user", "nobody"),
+						resource.TestCheckResourceAttr("data.system_file.test", "content", "hello world!"),
+					),
+				},
+			},
+		})
+	})
+
+	t.Run("conflicts with ssh", func(t *testing.T) {
+		t.Parallel()
+
+		providerConfig := tfbuild.Provider(provider.Name,
+			tfbuild.InnerBlock(provider.SchemaAttrLocal),
+			tfbuild.InnerBlock(provider.SchemaAttrSsh,
+				tfbuild.AttributeString(provider.SchemaAttrSshHost, "127.0.0.1"),
+			),
+		)
+
+		testAccProviderConnectTestExpectError(t, providerConfig, regexp.MustCompile(`"local": conflicts with ssh`))
+	})
+}
@@ -1,5 +1,213 @@
 package provider
 
+import (
+	"fmt"
+	"github.com/hashicorp/go-cty/cty"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	SchemaAttrProxyCommand = "command"
+)
+
+// SchemaEnvPrefixProxySsh is the prefix of the environment variables which provide defaults of the ssh block of the
+// first proxy hop
+const SchemaEnvPrefixProxySsh = SchemaEnvPrefix + "PROXY_SSH_"
+
+// SchemaProxy is a single proxy hop. Exactly one of Command, Ssh, Socks5 or Http is set.
 type SchemaProxy struct {
-	Ssh *SchemaSsh
+	Command string
+	Ssh     *SchemaSsh
+	Socks5  *SchemaProxyServer
+	Http    *SchemaProxyServer
+}
+
+func providerSchemaProxy() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		SchemaAttrProxyCommand: {
+			Description: "Local command which connects to the next hop using its stdin and stdout, equivalent to `ProxyCommand` of OpenSSH. The command is executed using `/bin/sh`. The tokens `%h`, `%p` and `%r` are replaced with host, port and user of the next hop; `%%` is a literal `%`. The command is stopped when the connection is closed. Supported in the first `proxy` block only.",
+			Type:        schema.TypeString,
+			Optional:    true,
+		},
+		SchemaAttrSsh: {
+			Type:     schema.TypeList,
+			Optional: true,
+			MaxItems: 1,
+			Elem: &schema.Resource{
+				// Absolute paths of attributes and defaults from the environment are ambiguous because proxy is a list of
+				// blocks. Defaults of the first hop are read from the environment by applyProxySshEnvDefaults.
+				Schema: providerSchemaSsh(nil, ""),
+			},
+		},
+		SchemaAttrProxySocks5: {
+			Description: "SOCKS5 proxy server through which the next hop is connected.",
+			Type:        schema.TypeList,
+			Optional:    true,
+			MaxItems:    1,
+			Elem: &schema.Resource{
+				Schema: providerSchemaProxyServer(schemaProxyServerSocks5),
+			},
+		},
+		SchemaAttrProxyHttp: {
+			Description: "HTTP proxy server through which the next hop is connected using the HTTP `CONNECT` method.",
+			Type:        schema.TypeList,
+			Optional:    true,
+			MaxItems:    1,
+			Elem: &schema.Resource{
+				Schema: providerSchemaProxyServer(schemaProxyServerHttp),
+			},
+		},
+	}
+}
+
+// expandSchemaProxies returns the ordered list of proxy hops from schema.ResourceData of the provider configuration
+func expandSchemaProxies(d *schema.ResourceData) ([]*SchemaProxy, error) {
+	proxiesV, ok := d.Get(SchemaAttrProxy).([]interface{})
+	if !ok {
+		return nil, nil
+	}
+
+	rawProxies := cty.NullVal(cty.DynamicPseudoType)
+	if rawConfig := d.GetRawConfig(); rawConfig.IsKnown() && !rawConfig.IsNull() {
+		rawProxies = rawConfig.GetAttr(SchemaAttrProxy)
+	}
+
+	var proxies []*SchemaProxy
+	for i := range proxiesV {
+		proxyAttrPath := newAttrPath(SchemaAttrProxy, strconv.Itoa(i))
+		proxySshAttrPath := proxyAttrPath.Extend(SchemaAttrSsh)
+		proxySocks5AttrPath := proxyAttrPath.Extend(SchemaAttrProxySocks5)
+		proxyHttpAttrPath := proxyAttrPath.Extend(SchemaAttrProxyHttp)
+
+		proxyCommandAttrPath := proxyAttrPath.Extend(SchemaAttrProxyCommand)
+
+		proxyCommandV, proxyCommandOk := d.GetOk(proxyCommandAttrPath.String())
+		proxySshV, proxySshOk := d.GetOk(proxySshAttrPath.String())
+		proxySocks5V, proxySocks5Ok := d.GetOk(proxySocks5AttrPath.String())
+		proxyHttpV, proxyHttpOk := d.GetOk(proxyHttpAttrPath.String())
+
+		blocks := 0
+		for _, ok := range []bool{proxyCommandOk, proxySshOk, proxySocks5Ok, proxyHttpOk} {
+			if ok {
+				blocks++
+			}
+		}
+		if blocks != 1 {
+			return nil, fmt.Errorf("%s: expected exactly one of %s or block %s, %s or %s", proxyAttrPath, SchemaAttrProxyCommand, SchemaAttrSsh, SchemaAttrProxySocks5, SchemaAttrProxyHttp)
+		}
+
+		switch {
+		case proxyCommandOk:
+			if i != 0 {
+				return nil, fmt.Errorf("%s: supported in the first %s block only", proxyCommandAttrPath, SchemaAttrProxy)
+			}
+
+			proxies = append(proxies, &SchemaProxy{
+				Command: proxyCommandV.(string),
+			})
+			continue
+		case proxySocks5Ok:
+			proxySchemaSocks5, err := expandSchemaProxyServer(proxySocks5V)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", proxySocks5AttrPath, err)
+			}
+
+			proxies = append(proxies, &SchemaProxy{
+				Socks5: proxySchemaSocks5,
+			})
+			continue
+		case proxyHttpOk:
+			proxySchemaHttp, err := expandSchemaProxyServer(proxyHttpV)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", proxyHttpAttrPath, err)
+			}
+
+			proxies = append(proxies, &SchemaProxy{
+				Http: proxySchemaHttp,
+			})
+			continue
+		}
+
+		// Validate conflicting attributes which cannot be declared in the schema
+		if rawProxies.IsKnown() && !rawProxies.IsNull() && rawProxies.LengthInt() > i {
+			rawProxySsh := rawProxies.Index(cty.NumberIntVal(int64(i))).GetAttr(SchemaAttrSsh)
+			if rawProxySsh.IsKnown() && !rawProxySsh.IsNull() && rawProxySsh.LengthInt() == 1 {
+				if err := validateSchemaSshRawConfig(rawProxySsh.Index(cty.NumberIntVal(0))); err != nil {
+					return nil, fmt.Errorf("%s: %w", proxySshAttrPath, err)
+				}
+			}
+		}
+
+		// Defaults from the environment apply to the first hop only
+		if i == 0 {
+			var rawProxySsh cty.Value
+			if rawProxies.IsKnown() && !rawProxies.IsNull() && rawProxies.LengthInt() > i {
+				rawProxySsh = rawProxies.Index(cty.NumberIntVal(int64(i))).GetAttr(SchemaAttrSsh)
+			}
+
+			var err error
+			proxySshV, err = applyProxySshEnvDefaults(proxySshV, rawProxySsh)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", proxySshAttrPath, err)
+			}
+		}
+
+		proxySchemaSsh, err := expandSchemaSsh(proxySshV)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", proxySshAttrPath, err)
+		}
+
+		proxies = append(proxies, &SchemaProxy{
+			Ssh: proxySchemaSsh,
+		})
+	}
+
+	return proxies, nil
+}
+
+// applyProxySshEnvDefaults sets attributes of the ssh block v which are not set in the configuration rawSsh from the
+// environment variables with prefix SchemaEnvPrefixProxySsh
+func applyProxySshEnvDefaults(v interface{}, rawSsh cty.Value) (interface{}, error) {
+	d, err := expandListSingle(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var rawAttrs cty.Value
+	if rawSsh.IsKnown() && !rawSsh.IsNull() && rawSsh.LengthInt() == 1 {
+		rawAttrs = rawSsh.Index(cty.NumberIntVal(0))
+	}
+
+	for key, attrSchema := range providerSchemaSsh(nil, "") {
+		if attrSchema.DefaultFunc == nil {
+			continue
+		}
+
+		envVal, envOk := os.LookupEnv(SchemaEnvPrefixProxySsh + strings.ToUpper(key))
+		if !envOk {
+			continue
+		}
+
+		if rawAttrs.IsKnown() && !rawAttrs.IsNull() && rawAttrs.Type().HasAttribute(key) && !rawAttrs.GetAttr(key).IsNull() {
+			// Configured values take precedence
+			continue
+		}
+
+		switch attrSchema.Type {
+		case schema.TypeInt:
+			d[key], err = strconv.Atoi(envVal)
+		case schema.TypeBool:
+			d[key], err = strconv.ParseBool(envVal)
+		default:
+			d[key] = envVal
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid value of environment variable %s: %w", SchemaEnvPrefixProxySsh+strings.ToUpper(key), err)
+		}
+	}
+
+	return []interface{}{d}, nil
 }
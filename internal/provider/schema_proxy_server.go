@@ -0,0 +1,246 @@
+package provider
+
+import (
+	"crypto/tls"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"github.com/neuspaces/terraform-provider-system/internal/sshclient"
+	"github.com/neuspaces/terraform-provider-system/internal/validate"
+	"net"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const (
+	SchemaAttrProxySocks5 = "socks5"
+	SchemaAttrProxyHttp   = "http"
+
+	SchemaAttrProxyServerHost            = "host"
+	SchemaAttrProxyServerPort            = "port"
+	SchemaAttrProxyServerUser            = "user"
+	SchemaAttrProxyServerPassword        = "password"
+	SchemaAttrProxyServerTls             = "tls"
+	SchemaAttrProxyServerFromEnvironment = "from_environment"
+	SchemaAttrProxyServerTimeout         = "timeout"
+)
+
+// SchemaProxyServer is a SOCKS5 or HTTP proxy server
+type SchemaProxyServer struct {
+	Host            string
+	Port            int
+	User            string
+	Password        string
+	Tls             bool
+	FromEnvironment bool
+	Timeout         time.Duration
+}
+
+// schemaProxyServerKind describes the differences between the supported kinds of proxy servers
+type schemaProxyServerKind struct {
+	name        string
+	defaultPort int
+	// supportsTls is true if the connection to the proxy server can be established using TLS
+	supportsTls bool
+	// envNames are the environment variables which are considered if from_environment is enabled
+	envNames []string
+	// schemes are the supported url schemes of proxy urls from the environment. The value is true if the scheme
+	// implies TLS.
+	schemes map[string]bool
+}
+
+var (
+	schemaProxyServerSocks5 = schemaProxyServerKind{
+		name:        "socks5",
+		defaultPort: 1080,
+		envNames:    []string{sshclient.EnvAllProxy},
+		schemes:     map[string]bool{"socks5": false, "socks5h": false},
+	}
+	schemaProxyServerHttp = schemaProxyServerKind{
+		name:        "http",
+		defaultPort: 3128,
+		supportsTls: true,
+		envNames:    []string{sshclient.EnvHttpsProxy},
+		schemes:     map[string]bool{"http": false, "https": true},
+	}
+)
+
+func providerSchemaProxyServer(kind schemaProxyServerKind) map[string]*schema.Schema {
+	s := map[string]*schema.Schema{
+		SchemaAttrProxyServerHost: {
+			Description: fmt.Sprintf("The hostname or ip address of the %[1]s proxy server. Required unless `%[2]s` is `true`.", kind.name, SchemaAttrProxyServerFromEnvironment),
+			Type:        schema.TypeString,
+			Optional:    true,
+		},
+		SchemaAttrProxyServerPort: {
+			Description:  fmt.Sprintf("The port of the %[1]s proxy server. Defaults to `%[2]d`.", kind.name, kind.defaultPort),
+			Type:         schema.TypeInt,
+			Optional:     true,
+			ValidateFunc: validation.IsPortNumber,
+		},
+		SchemaAttrProxyServerUser: {
+			Description: fmt.Sprintf("The user to authenticate with the %[1]s proxy server. Authentication is disabled if not set.", kind.name),
+			Type:        schema.TypeString,
+			Optional:    true,
+		},
+		SchemaAttrProxyServerPassword: {
+			Description: fmt.Sprintf("The password to authenticate with the %[1]s proxy server.", kind.name),
+			Type:        schema.TypeString,
+			Optional:    true,
+			Sensitive:   true,
+		},
+		SchemaAttrProxyServerFromEnvironment: {
+			Description: fmt.Sprintf("If `true`, the proxy server is read from the environment variable %[1]s of the provider process. The proxy hop is skipped if the environment variable is not set or if the next hop is excluded by `NO_PROXY`. Explicitly configured attributes take precedence over the environment. If `%[2]s` is configured, the port and the credentials of the environment variable are not used either because they belong to another proxy server. Defaults to `false`.", formatEnvNames(kind.envNames), SchemaAttrProxyServerHost),
+			Type:        schema.TypeBool,
+			Optional:    true,
+			Default:     false,
+		},
+		SchemaAttrProxyServerTimeout: {
+			Description: "Timeout to connect to the proxy server. Should be provided as a string like `30s` or `5m`. Defaults to 30 seconds (`30s`).",
+			Type:        schema.TypeString,
+			Optional:    true,
+			Default:     "30s",
+			ValidateDiagFunc: validate.All(
+				validate.DurationAtLeast(1*time.Second),
+				validate.DurationAtMost(60*time.Minute),
+			),
+		},
+	}
+
+	if kind.supportsTls {
+		s[SchemaAttrProxyServerTls] = &schema.Schema{
+			Description: fmt.Sprintf("If `true`, the connection to the %[1]s proxy server is established using TLS. Defaults to `false`.", kind.name),
+			Type:        schema.TypeBool,
+			Optional:    true,
+			Default:     false,
+		}
+	}
+
+	return s
+}
+
+func formatEnvNames(names []string) string {
+	var s string
+	for i, name := range names {
+		if i > 0 {
+			s += " or "
+		}
+		s += fmt.Sprintf("`%s`", name)
+	}
+	return s
+}
+
+func expandSchemaProxyServer(v interface{}) (*SchemaProxyServer, error) {
+	d, err := expandListSingle(v)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &SchemaProxyServer{
+		Host:            d[SchemaAttrProxyServerHost].(string),
+		Port:            d[SchemaAttrProxyServerPort].(int),
+		User:            d[SchemaAttrProxyServerUser].(string),
+		Password:        d[SchemaAttrProxyServerPassword].(string),
+		FromEnvironment: d[SchemaAttrProxyServerFromEnvironment].(bool),
+	}
+
+	if val, ok := d[SchemaAttrProxyServerTls].(bool); ok {
+		s.Tls = val
+	}
+
+	if timeoutStr := d[SchemaAttrProxyServerTimeout].(string); timeoutStr != "" {
+		timeout, err := time.ParseDuration(timeoutStr)
+		if err != nil {
+			return nil, err
+		}
+		s.Timeout = timeout
+	}
+
+	if s.Host == "" && !s.FromEnvironment {
+		return nil, fmt.Errorf("%q: required unless %s is true", SchemaAttrProxyServerHost, SchemaAttrProxyServerFromEnvironment)
+	}
+
+	return s, nil
+}
+
+// resolve returns the effective proxy server to connect to nextAddr. Values from the environment are merged if
+// FromEnvironment is enabled; explicitly configured attributes take precedence. resolve returns nil if the proxy server
+// is read from the environment and no proxy server should be used to connect to nextAddr.
+func (s SchemaProxyServer) resolve(kind schemaProxyServerKind, nextAddr net.Addr) (*SchemaProxyServer, error) {
+	r := s
+
+	if s.FromEnvironment {
+		envUrl, err := sshclient.ProxyUrlFromEnvironment(nextAddr, kind.envNames...)
+		if err != nil {
+			return nil, err
+		}
+
+		if envUrl == nil && s.Host == "" {
+			// No proxy server
+			return nil, nil
+		}
+
+		if envUrl != nil {
+			if err := r.mergeUrl(kind, envUrl); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if r.Port == 0 {
+		r.Port = kind.defaultPort
+	}
+
+	return &r, nil
+}
+
+// mergeUrl sets all attributes which are not explicitly configured from a proxy url. The port and the credentials of
+// the url are only merged if the host is taken from the url because they do not apply to another proxy server.
+func (s *SchemaProxyServer) mergeUrl(kind schemaProxyServerKind, u *url.URL) error {
+	if s.Host != "" {
+		// The proxy server is explicitly configured
+		return nil
+	}
+
+	useTls, ok := kind.schemes[u.Scheme]
+	if !ok {
+		return fmt.Errorf("unsupported scheme %q of %s proxy url in environment", u.Scheme, kind.name)
+	}
+
+	s.Host = u.Hostname()
+	s.Tls = s.Tls || useTls
+
+	if s.Port == 0 && u.Port() != "" {
+		port, err := strconv.Atoi(u.Port())
+		if err != nil {
+			return fmt.Errorf("invalid port of %s proxy url in environment: %w", kind.name, err)
+		}
+		s.Port = port
+	}
+
+	if s.User == "" && u.User != nil {
+		s.User = u.User.Username()
+		s.Password, _ = u.User.Password()
+	}
+
+	return nil
+}
+
+func (s SchemaProxyServer) addr() net.Addr {
+	return sshclient.NewHostPortAddr(sshclient.Tcp, s.Host, uint16(s.Port))
+}
+
+func (s SchemaProxyServer) options() []sshclient.ProxyServerOption {
+	var opts []sshclient.ProxyServerOption
+
+	if s.User != "" {
+		opts = append(opts, sshclient.ProxyCredentials(s.User, s.Password))
+	}
+
+	if s.Tls {
+		opts = append(opts, sshclient.ProxyTLS(&tls.Config{ServerName: s.Host}))
+	}
+
+	return opts
+}
@@ -0,0 +1,115 @@
+package provider
+
+import (
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/neuspaces/terraform-provider-system/internal/cmd"
+	"github.com/neuspaces/terraform-provider-system/internal/system"
+)
+
+const (
+	SchemaAttrRunAs = "run_as"
+
+	SchemaAttrRunAsUser = "user"
+	SchemaAttrRunAsSudo = "sudo"
+)
+
+// SchemaRunAs is the configuration of the user as which the commands of a single resource are executed
+type SchemaRunAs struct {
+	User string
+	Sudo bool
+}
+
+// schemaRunAs returns the schema of the `run_as` block of a resource or a data source
+func schemaRunAs() *schema.Schema {
+	return &schema.Schema{
+		Description: fmt.Sprintf("Executes the commands of this resource as another user instead of using the provider attributes `%[1]s` and `%[2]s`. If the block is set without arguments, commands are executed as the connecting user without privilege escalation.", SchemaAttrSudo, SchemaAttrBecome),
+		Type:        schema.TypeList,
+		Optional:    true,
+		MaxItems:    1,
+		Elem: &schema.Resource{
+			Schema: map[string]*schema.Schema{
+				SchemaAttrRunAsUser: {
+					Description: fmt.Sprintf("User as which commands are executed. Implies privilege escalation. Defaults to `root` if `%[1]s` is `true`.", SchemaAttrRunAsSudo),
+					Type:        schema.TypeString,
+					Optional:    true,
+				},
+				SchemaAttrRunAsSudo: {
+					Description: fmt.Sprintf("If `true`, privileges are escalated using the method and the password of the provider `%[1]s` block or using `sudo` without password if the `%[1]s` block is not configured. Defaults to `false`.", SchemaAttrBecome),
+					Type:        schema.TypeBool,
+					Optional:    true,
+					Default:     false,
+				},
+			},
+		},
+	}
+}
+
+// expandSchemaRunAs returns the `run_as` block of a resource or nil if the block is not set
+func expandSchemaRunAs(d *schema.ResourceData) (*SchemaRunAs, error) {
+	v, ok := d.GetOk(SchemaAttrRunAs)
+	if !ok {
+		return nil, nil
+	}
+
+	// Block without arguments
+	if l, isList := v.([]interface{}); isList && len(l) == 1 && l[0] == nil {
+		return &SchemaRunAs{}, nil
+	}
+
+	m, err := expandListSingle(v)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SchemaRunAs{
+		User: m[SchemaAttrRunAsUser].(string),
+		Sudo: m[SchemaAttrRunAsSudo].(bool),
+	}, nil
+}
+
+// middleware returns the cmd.Middleware which executes commands as configured by s. become is the privilege escalation
+// of the provider and may be nil.
+func (s SchemaRunAs) middleware(become *SchemaBecome) (cmd.Middleware, error) {
+	if !s.Sudo && s.User == "" {
+		// Use shell /bin/sh without privilege escalation
+		return cmd.ShMiddleware(), nil
+	}
+
+	method := cmd.BecomeSudo
+	var opts []cmd.BecomeOption
+
+	if become != nil {
+		method = become.Method
+		if become.Password != "" {
+			opts = append(opts, cmd.BecomePassword(become.Password))
+		}
+	}
+
+	if s.User != "" {
+		opts = append(opts, cmd.BecomeUser(s.User))
+	}
+
+	return cmd.BecomeMiddleware(method, opts...)
+}
+
+// systemFromResourceData returns the system of the provider which executes commands as configured by the `run_as`
+// block of the resource
+func systemFromResourceData(p *Provider, d *schema.ResourceData) (system.System, diag.Diagnostics) {
+	runAs, err := expandSchemaRunAs(d)
+	if err != nil {
+		return nil, diag.FromErr(err)
+	}
+
+	if runAs == nil {
+		return p.System, nil
+	}
+
+	m, err := runAs.middleware(p.Config.Become)
+	if err != nil {
+		return nil, diag.FromErr(fmt.Errorf("%s: %w", SchemaAttrRunAs, err))
+	}
+
+	return system.WithCommandMiddleware(p.System, m), nil
+}
@@ -0,0 +1,119 @@
+package provider
+
+import (
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/neuspaces/terraform-provider-system/internal/sshclient"
+	"github.com/neuspaces/terraform-provider-system/internal/validate"
+)
+
+const (
+	SchemaAttrSourceSftp = "source_sftp"
+
+	SchemaAttrSourceSftpPrivateKey           = "private_key"
+	SchemaAttrSourceSftpPrivateKeyPassphrase = "private_key_passphrase"
+	SchemaAttrSourceSftpPassword             = "password"
+	SchemaAttrSourceSftpAgent                = "agent"
+	SchemaAttrSourceSftpKnownHosts           = "known_hosts"
+)
+
+// SchemaSourceSftp is the configuration of the connections to sftp and scp urls of the attribute `source` of resources
+type SchemaSourceSftp struct {
+	PrivateKey           string
+	PrivateKeyPassphrase string
+	Password             string
+	Agent                bool
+	KnownHosts           string
+}
+
+func providerSchemaSourceSftp(envPrefix string) map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		SchemaAttrSourceSftpPrivateKey: {
+			Description:      fmt.Sprintf("Private key which authenticates the user of the url. The identities of the ssh agent and the private key are offered together. Encrypted private keys require `%[1]s`.", SchemaAttrSourceSftpPrivateKeyPassphrase),
+			Type:             schema.TypeString,
+			Optional:         true,
+			Sensitive:        true,
+			DefaultFunc:      schemaEnvDefaultFunc(SchemaAttrSourceSftpPrivateKey, envPrefix, nil),
+			ValidateDiagFunc: validate.PrivateKey(),
+		},
+		SchemaAttrSourceSftpPrivateKeyPassphrase: {
+			Description: fmt.Sprintf("The passphrase to decrypt an encrypted `%[1]s`. Ignored if `%[1]s` is not encrypted.", SchemaAttrSourceSftpPrivateKey),
+			Type:        schema.TypeString,
+			Optional:    true,
+			Sensitive:   true,
+			DefaultFunc: schemaEnvDefaultFunc(SchemaAttrSourceSftpPrivateKeyPassphrase, envPrefix, nil),
+		},
+		SchemaAttrSourceSftpPassword: {
+			Description: "Password which authenticates the user of the url. Urls must not contain a password because the attribute `source` is not sensitive.",
+			Type:        schema.TypeString,
+			Optional:    true,
+			Sensitive:   true,
+			DefaultFunc: schemaEnvDefaultFunc(SchemaAttrSourceSftpPassword, envPrefix, nil),
+		},
+		SchemaAttrSourceSftpAgent: {
+			Description: "If `true`, the identities of the ssh agent authenticate the user of the url. Defaults to `true`.",
+			Type:        schema.TypeBool,
+			Optional:    true,
+			DefaultFunc: schemaEnvDefaultFunc(SchemaAttrSourceSftpAgent, envPrefix, true),
+		},
+		SchemaAttrSourceSftpKnownHosts: {
+			Description:      "Known hosts to verify the host keys. Provided either as path to a file in the OpenSSH `known_hosts` format or as the content of such a file. Defaults to `~/.ssh/known_hosts`.",
+			Type:             schema.TypeString,
+			Optional:         true,
+			DefaultFunc:      schemaEnvDefaultFunc(SchemaAttrSourceSftpKnownHosts, envPrefix, "~/.ssh/known_hosts"),
+			ValidateDiagFunc: validate.KnownHosts(),
+		},
+	}
+}
+
+func expandSchemaSourceSftp(v interface{}) (*SchemaSourceSftp, error) {
+	d, err := expandListSingle(v)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &SchemaSourceSftp{
+		PrivateKey:           d[SchemaAttrSourceSftpPrivateKey].(string),
+		PrivateKeyPassphrase: d[SchemaAttrSourceSftpPrivateKeyPassphrase].(string),
+		Password:             d[SchemaAttrSourceSftpPassword].(string),
+		Agent:                d[SchemaAttrSourceSftpAgent].(bool),
+		KnownHosts:           d[SchemaAttrSourceSftpKnownHosts].(string),
+	}
+
+	// Ensure that an encrypted private key can be decrypted using the passphrase
+	if s.PrivateKey != "" {
+		if err := validate.PrivateKeyPassphrase(s.PrivateKey, s.PrivateKeyPassphrase); err != nil {
+			return nil, fmt.Errorf("%s: invalid %s or %s: %w", SchemaAttrSourceSftp, SchemaAttrSourceSftpPrivateKey, SchemaAttrSourceSftpPrivateKeyPassphrase, err)
+		}
+	}
+
+	return s, nil
+}
+
+// connectOptions returns the sshclient.ConnectOption which authenticate the user and verify the host key of the
+// source.Sftp client. The identities of the agent and the private key are offered by a single public key method.
+func (s SchemaSourceSftp) connectOptions() []sshclient.ConnectOption {
+	var signers []sshclient.SignersFunc
+
+	if s.Agent {
+		signers = append(signers, sshclient.OptionalSigners(sshclient.AgentSigners()))
+	}
+
+	if s.PrivateKey != "" {
+		signers = append(signers, sshclient.PrivateKeySigners(s.PrivateKey, s.PrivateKeyPassphrase))
+	}
+
+	var opts []sshclient.ConnectOption
+
+	if len(signers) > 0 {
+		opts = append(opts, sshclient.Auth(sshclient.PublicKeys(signers...)))
+	}
+
+	if s.Password != "" {
+		opts = append(opts, sshclient.Auth(sshclient.Password(s.Password)))
+	}
+
+	opts = append(opts, sshclient.HostKey(sshclient.KnownHosts(s.KnownHosts)), sshclient.KnownHostKeyAlgorithms())
+
+	return opts
+}
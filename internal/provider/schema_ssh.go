@@ -2,6 +2,7 @@ package provider
 
 import (
 	"fmt"
+	"github.com/hashicorp/go-cty/cty"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
 	"github.com/neuspaces/terraform-provider-system/internal/validate"
@@ -9,32 +10,61 @@ import (
 )
 
 const (
-	SchemaAttrSshHost            = "host"
-	SchemaAttrSshHostKey         = "host_key"
-	SchemaAttrSshPort            = "port"
-	SchemaAttrSshUser            = "user"
-	SchemaAttrSshPassword        = "password"
-	SchemaAttrSshPrivateKey      = "private_key"
-	SchemaAttrSshCertificate     = "certificate"
-	SchemaAttrSshTimeout         = "timeout"
-	SchemaAttrSshAgent           = "agent"
-	SchemaAttrSshAgentIdentity   = "agent_identity"
-	SchemaAttrSshAgentIdentities = "agent_identities"
+	SchemaAttrSshHost                 = "host"
+	SchemaAttrSshHostKey              = "host_key"
+	SchemaAttrSshKnownHosts           = "known_hosts"
+	SchemaAttrSshHostCaPublicKeys     = "host_ca_public_keys"
+	SchemaAttrSshHostCaRevokedSerials = "host_ca_revoked_serials"
+	SchemaAttrSshPort                 = "port"
+	SchemaAttrSshUser                 = "user"
+	SchemaAttrSshPassword             = "password"
+	SchemaAttrSshPrivateKey           = "private_key"
+	SchemaAttrSshPrivateKeyPassphrase = "private_key_passphrase"
+	SchemaAttrSshCertificate          = "certificate"
+	SchemaAttrSshTimeout              = "timeout"
+	SchemaAttrSshAgent                = "agent"
+	SchemaAttrSshAgentIdentity        = "agent_identity"
+	SchemaAttrSshAgentIdentities      = "agent_identities"
+
+	SchemaAttrSshKeyboardInteractive           = "keyboard_interactive"
+	SchemaAttrSshKeyboardInteractiveAnswers    = "answers"
+	SchemaAttrSshKeyboardInteractiveTotpSecret = "totp_secret"
+	SchemaAttrSshKeyboardInteractiveTotpPrompt = "totp_prompt"
 )
 
+// schemaSshDefaultTotpPrompt is the default pattern of questions which are answered with a time-based one-time password
+const schemaSshDefaultTotpPrompt = `(?i)(verification code|one-time|otp|token)`
+
 type SchemaSsh struct {
-	User            string
-	Password        string
-	PrivateKey      string
-	Certificate     string
-	Host            string
-	HostKey         string
-	Port            int
-	Timeout         time.Duration
-	Agent           bool
-	AgentIdentities []string
+	User                 string
+	Password             string
+	PrivateKey           string
+	PrivateKeyPassphrase string
+	Certificate          string
+	Host                 string
+	HostKey              string
+	KnownHosts           string
+	HostCaPublicKeys     []string
+	HostCaRevokedSerials []uint64
+	Port                 int
+	Timeout              time.Duration
+	Agent                bool
+	AgentIdentities      []string
+
+	// KeyboardInteractive is nil if keyboard-interactive authentication is not configured
+	KeyboardInteractive *SchemaSshKeyboardInteractive
+
+	// ConfigFile and Alias configure the connection from an OpenSSH client configuration file. See applySshConfig.
+	ConfigFile string
+	Alias      string
 }
 
+// providerSchemaSsh returns the schema of an ssh block.
+// attrPath is the absolute path of the ssh block used to declare conflicting attributes. If the ssh block is nested in
+// a list with multiple elements, attrPath must be nil because absolute paths are ambiguous. In this case, conflicting
+// attributes must be validated using validateSchemaSshRawConfig.
+// envPrefix is the prefix of the environment variables which provide defaults of the attributes. If envPrefix is empty,
+// defaults are not read from the environment.
 func providerSchemaSsh(attrPath attrPath, envPrefix string) map[string]*schema.Schema {
 	return map[string]*schema.Schema{
 		SchemaAttrSshUser: {
@@ -44,47 +74,76 @@ func providerSchemaSsh(attrPath attrPath, envPrefix string) map[string]*schema.S
 			DefaultFunc: schemaEnvDefaultFunc(SchemaAttrSshUser, envPrefix, nil),
 		},
 		SchemaAttrSshPassword: {
-			Description: fmt.Sprintf("The password that should be used to authenticate with the remote ssh server. Mutually exclusive with `%[2]s`.", SchemaAttrSshPassword, SchemaAttrSshPrivateKey),
-			Type:        schema.TypeString,
-			Optional:    true,
-			ConflictsWith: []string{
-				attrPath.Extend(SchemaAttrSshPrivateKey).String(),
-			},
-			DefaultFunc: schemaEnvDefaultFunc(SchemaAttrSshPassword, envPrefix, nil),
+			Description:   fmt.Sprintf("The password that should be used to authenticate with the remote ssh server. Mutually exclusive with `%[2]s`.", SchemaAttrSshPassword, SchemaAttrSshPrivateKey),
+			Type:          schema.TypeString,
+			Optional:      true,
+			ConflictsWith: attrPath.Paths(SchemaAttrSshPrivateKey),
+			DefaultFunc:   schemaEnvDefaultFunc(SchemaAttrSshPassword, envPrefix, nil),
 		},
 		SchemaAttrSshPrivateKey: {
-			Description: fmt.Sprintf("The SSH private key to authenticate with the remote ssh server. The key can be provided as string or loaded from a file using the `file` function. Supported private keys are unencrypted pem encoded RSA (PKCS#1), PKCS#8, DSA (OpenSSL), and ECDSA private keys. Mutually exclusive with `%[1]s`.", SchemaAttrSshPassword, SchemaAttrSshPrivateKey),
-			Type:        schema.TypeString,
-			Optional:    true,
-			ConflictsWith: []string{
-				attrPath.Extend(SchemaAttrSshPassword).String(),
-			},
+			Description:      fmt.Sprintf("The SSH private key to authenticate with the remote ssh server. The key can be provided as string or loaded from a file using the `file` function. Supported private keys are pem encoded RSA (PKCS#1), PKCS#8, DSA (OpenSSL), ECDSA and OpenSSH private keys. Encrypted private keys require `%[3]s`. Mutually exclusive with `%[1]s`.", SchemaAttrSshPassword, SchemaAttrSshPrivateKey, SchemaAttrSshPrivateKeyPassphrase),
+			Type:             schema.TypeString,
+			Optional:         true,
+			ConflictsWith:    attrPath.Paths(SchemaAttrSshPassword),
 			DefaultFunc:      schemaEnvDefaultFunc(SchemaAttrSshPrivateKey, envPrefix, nil),
 			ValidateDiagFunc: validate.PrivateKey(),
 		},
-		SchemaAttrSshCertificate: {
-			Description: fmt.Sprintf("The ssh user certificate to authenticate with the remote ssh server. The certificate can be provided as text or loaded from a file using the `file` function. Expected format of the certificate is a base64 encoded OpenSSH public key (`authorized_keys` format). Must be used with in conjunction with `%[2]s`. Mutually exclusive with `%[1]s`.", SchemaAttrSshPassword, SchemaAttrSshPrivateKey),
+		SchemaAttrSshPrivateKeyPassphrase: {
+			Description: fmt.Sprintf("The passphrase to decrypt an encrypted `%[1]s`. Ignored if `%[1]s` is not encrypted. The passphrase is verified to decrypt the private key when the provider is configured.", SchemaAttrSshPrivateKey),
 			Type:        schema.TypeString,
 			Optional:    true,
-			ConflictsWith: []string{
-				attrPath.Extend(SchemaAttrSshPassword).String(),
-			},
+			Sensitive:   true,
+			DefaultFunc: schemaEnvDefaultFunc(SchemaAttrSshPrivateKeyPassphrase, envPrefix, nil),
+		},
+		SchemaAttrSshCertificate: {
+			Description:      fmt.Sprintf("The ssh user certificate to authenticate with the remote ssh server. The certificate can be provided as text or loaded from a file using the `file` function. Expected format of the certificate is a base64 encoded OpenSSH public key (`authorized_keys` format). Must be used with in conjunction with `%[2]s`. Mutually exclusive with `%[1]s`.", SchemaAttrSshPassword, SchemaAttrSshPrivateKey),
+			Type:             schema.TypeString,
+			Optional:         true,
+			ConflictsWith:    attrPath.Paths(SchemaAttrSshPassword),
 			DefaultFunc:      schemaEnvDefaultFunc(SchemaAttrSshCertificate, envPrefix, nil),
 			ValidateDiagFunc: validate.AuthorizedKey(),
 		},
 		SchemaAttrSshHost: {
-			Description: "The host of the remote ssh server to connect to.",
+			Description: fmt.Sprintf("The host of the remote ssh server to connect to. Required unless `%[1]s` is set.", SchemaAttrSshAlias),
 			Type:        schema.TypeString,
-			Required:    true,
+			Optional:    true,
 			DefaultFunc: schemaEnvDefaultFunc(SchemaAttrSshHost, envPrefix, nil),
 		},
 		SchemaAttrSshHostKey: {
-			Description:      "The public key or the CA certificate of the remote ssh host to verify the remote authenticity. Expected format of the host key is a base64 encoded OpenSSH public key (`authorized_keys` format).",
+			Description:      fmt.Sprintf("The public key or the CA certificate of the remote ssh host to verify the remote authenticity. Expected format of the host key is a base64 encoded OpenSSH public key (`authorized_keys` format). Mutually exclusive with `%[1]s`.", SchemaAttrSshKnownHosts),
 			Type:             schema.TypeString,
 			Optional:         true,
+			ConflictsWith:    attrPath.Paths(SchemaAttrSshKnownHosts),
 			DefaultFunc:      schemaEnvDefaultFunc(SchemaAttrSshHostKey, envPrefix, nil),
 			ValidateDiagFunc: validate.AuthorizedKey(),
 		},
+		SchemaAttrSshKnownHosts: {
+			Description:      fmt.Sprintf("Known hosts to verify the remote authenticity. Provided either as path to a file in the OpenSSH `known_hosts` format like `~/.ssh/known_hosts` or as the content of such a file. Hashed host names, `[host]:port` entries as well as `@cert-authority` and `@revoked` markers are supported. Mutually exclusive with `%[1]s`.", SchemaAttrSshHostKey),
+			Type:             schema.TypeString,
+			Optional:         true,
+			ConflictsWith:    attrPath.Paths(SchemaAttrSshHostKey),
+			DefaultFunc:      schemaEnvDefaultFunc(SchemaAttrSshKnownHosts, envPrefix, nil),
+			ValidateDiagFunc: validate.KnownHosts(),
+		},
+		SchemaAttrSshHostCaPublicKeys: {
+			Description: fmt.Sprintf("List of public keys of certificate authorities which are trusted to sign host certificates. The remote ssh host must present a host certificate which is signed by one of the certificate authorities, which is valid at the time of connection and which lists the `%[1]s` as principal. Host keys which are not certificates are verified using `%[2]s` or `%[3]s` if configured and rejected otherwise. Expected format of a public key is a base64 encoded OpenSSH public key (`authorized_keys` format).", SchemaAttrSshHost, SchemaAttrSshHostKey, SchemaAttrSshKnownHosts),
+			Type:        schema.TypeList,
+			Optional:    true,
+			Elem: &schema.Schema{
+				Type:             schema.TypeString,
+				ValidateDiagFunc: validate.AuthorizedKey(),
+			},
+		},
+		SchemaAttrSshHostCaRevokedSerials: {
+			Description:  fmt.Sprintf("List of serial numbers of revoked host certificates. Host certificates with a revoked serial number are rejected. Requires `%[1]s`.", SchemaAttrSshHostCaPublicKeys),
+			Type:         schema.TypeSet,
+			Optional:     true,
+			RequiredWith: attrPath.Paths(SchemaAttrSshHostCaPublicKeys),
+			Elem: &schema.Schema{
+				Type:         schema.TypeInt,
+				ValidateFunc: validation.IntAtLeast(0),
+			},
+		},
 		SchemaAttrSshPort: {
 			Description:  "The port of the remote ssh server to connect to. Defaults to `22`.",
 			Type:         schema.TypeInt,
@@ -109,28 +168,99 @@ func providerSchemaSsh(attrPath attrPath, envPrefix string) map[string]*schema.S
 			DefaultFunc: schemaEnvDefaultFunc(SchemaAttrSshAgent, envPrefix, false),
 		},
 		SchemaAttrSshAgentIdentity: {
-			Description: "The preferred identity from the ssh agent for authentication. Expected format of an identity is a base64 encoded OpenSSH public key (`authorized_keys` format).",
-			Type:        schema.TypeString,
-			Optional:    true,
-			ConflictsWith: []string{
-				attrPath.Extend(SchemaAttrSshAgentIdentities).String(),
-			},
+			Description:      "The preferred identity from the ssh agent for authentication. Expected format of an identity is a base64 encoded OpenSSH public key (`authorized_keys` format).",
+			Type:             schema.TypeString,
+			Optional:         true,
+			ConflictsWith:    attrPath.Paths(SchemaAttrSshAgentIdentities),
 			ValidateDiagFunc: validate.AuthorizedKey(),
 			DefaultFunc:      schemaEnvDefaultFunc(SchemaAttrSshAgentIdentity, envPrefix, nil),
 		},
 		SchemaAttrSshAgentIdentities: {
-			Description: "List of preferred identities from the ssh agent for authentication. Expected format of an identity is a base64 encoded OpenSSH public key (`authorized_keys` format).",
-			Type:        schema.TypeList,
-			Optional:    true,
-			ConflictsWith: []string{
-				attrPath.Extend(SchemaAttrSshAgentIdentity).String(),
-			},
+			Description:   "List of preferred identities from the ssh agent for authentication. Expected format of an identity is a base64 encoded OpenSSH public key (`authorized_keys` format).",
+			Type:          schema.TypeList,
+			Optional:      true,
+			ConflictsWith: attrPath.Paths(SchemaAttrSshAgentIdentity),
 			Elem: &schema.Schema{
 				Type:             schema.TypeString,
 				ValidateDiagFunc: validate.AuthorizedKey(),
 			},
 		},
+		SchemaAttrSshKeyboardInteractive: {
+			Description: fmt.Sprintf("Keyboard-interactive authentication, e.g. for multi-factor authentication. Every question of the remote ssh server is answered by the first matching prompt: questions matching `%[2]s` are answered with a time-based one-time password, then `%[1]s` are matched in lexical order of their patterns, and finally questions containing `password` are answered with `%[3]s` if set. Authentication methods are tried in the order public key (`%[4]s`, `%[5]s`, `%[6]s`), keyboard-interactive, password.", SchemaAttrSshKeyboardInteractiveAnswers, SchemaAttrSshKeyboardInteractiveTotpPrompt, SchemaAttrSshPassword, SchemaAttrSshPrivateKey, SchemaAttrSshCertificate, SchemaAttrSshAgent),
+			Type:        schema.TypeList,
+			Optional:    true,
+			MaxItems:    1,
+			Elem: &schema.Resource{
+				Schema: map[string]*schema.Schema{
+					SchemaAttrSshKeyboardInteractiveAnswers: {
+						Description:      "Map of regular expressions to answers. Questions of the remote ssh server which match a regular expression are answered with the respective answer.",
+						Type:             schema.TypeMap,
+						Optional:         true,
+						Sensitive:        true,
+						ValidateDiagFunc: validate.RegexpMapKeys(),
+						Elem: &schema.Schema{
+							Type: schema.TypeString,
+						},
+					},
+					SchemaAttrSshKeyboardInteractiveTotpSecret: {
+						Description:      fmt.Sprintf("Base32 encoded secret to generate time-based one-time passwords (RFC 6238, HMAC-SHA1, 30 seconds, 6 digits) which answer questions matching `%[1]s`.", SchemaAttrSshKeyboardInteractiveTotpPrompt),
+						Type:             schema.TypeString,
+						Optional:         true,
+						Sensitive:        true,
+						ValidateDiagFunc: validate.TotpSecret(),
+					},
+					SchemaAttrSshKeyboardInteractiveTotpPrompt: {
+						Description:      fmt.Sprintf("Regular expression of questions which are answered with a time-based one-time password. Defaults to `%[1]s`.", schemaSshDefaultTotpPrompt),
+						Type:             schema.TypeString,
+						Optional:         true,
+						Default:          schemaSshDefaultTotpPrompt,
+						ValidateDiagFunc: validate.Regexp(),
+					},
+				},
+			},
+		},
+	}
+}
+
+// SchemaSshKeyboardInteractive configures keyboard-interactive authentication
+type SchemaSshKeyboardInteractive struct {
+	// Answers maps patterns of questions to answers
+	Answers    map[string]string
+	TotpSecret string
+	TotpPrompt string
+}
+
+// schemaSshConflicts are pairs of mutually exclusive attributes of an ssh block
+var schemaSshConflicts = [][2]string{
+	{SchemaAttrSshPassword, SchemaAttrSshPrivateKey},
+	{SchemaAttrSshPassword, SchemaAttrSshCertificate},
+	{SchemaAttrSshHostKey, SchemaAttrSshKnownHosts},
+	{SchemaAttrSshAgentIdentity, SchemaAttrSshAgentIdentities},
+}
+
+// validateSchemaSshRawConfig returns an error if mutually exclusive attributes are set in the raw configuration of an
+// ssh block. validateSchemaSshRawConfig complements ConflictsWith for ssh blocks which have been declared without
+// attrPath.
+func validateSchemaSshRawConfig(v cty.Value) error {
+	if v.IsNull() || !v.IsKnown() || !v.Type().IsObjectType() {
+		return nil
+	}
+
+	isSetRaw := func(key string) bool {
+		return v.Type().HasAttribute(key) && !v.GetAttr(key).IsNull()
+	}
+
+	for _, conflict := range schemaSshConflicts {
+		if isSetRaw(conflict[0]) && isSetRaw(conflict[1]) {
+			return fmt.Errorf("%q: conflicts with %s", conflict[0], conflict[1])
+		}
+	}
+
+	if isSetRaw(SchemaAttrSshHostCaRevokedSerials) && !isSetRaw(SchemaAttrSshHostCaPublicKeys) {
+		return fmt.Errorf("%q: all of `%s,%s` must be specified", SchemaAttrSshHostCaRevokedSerials, SchemaAttrSshHostCaPublicKeys, SchemaAttrSshHostCaRevokedSerials)
 	}
+
+	return nil
 }
 
 func expandSchemaSsh(v interface{}) (*SchemaSsh, error) {
@@ -140,15 +270,17 @@ func expandSchemaSsh(v interface{}) (*SchemaSsh, error) {
 	}
 
 	s := &SchemaSsh{
-		User:            d[SchemaAttrSshUser].(string),
-		Password:        d[SchemaAttrSshPassword].(string),
-		PrivateKey:      d[SchemaAttrSshPrivateKey].(string),
-		Certificate:     d[SchemaAttrSshCertificate].(string),
-		Host:            d[SchemaAttrSshHost].(string),
-		HostKey:         d[SchemaAttrSshHostKey].(string),
-		Port:            d[SchemaAttrSshPort].(int),
-		Agent:           d[SchemaAttrSshAgent].(bool),
-		AgentIdentities: []string{},
+		User:                 d[SchemaAttrSshUser].(string),
+		Password:             d[SchemaAttrSshPassword].(string),
+		PrivateKey:           d[SchemaAttrSshPrivateKey].(string),
+		PrivateKeyPassphrase: d[SchemaAttrSshPrivateKeyPassphrase].(string),
+		Certificate:          d[SchemaAttrSshCertificate].(string),
+		Host:                 d[SchemaAttrSshHost].(string),
+		HostKey:              d[SchemaAttrSshHostKey].(string),
+		KnownHosts:           d[SchemaAttrSshKnownHosts].(string),
+		Port:                 d[SchemaAttrSshPort].(int),
+		Agent:                d[SchemaAttrSshAgent].(bool),
+		AgentIdentities:      []string{},
 	}
 
 	if val, ok := d[SchemaAttrSshAgentIdentity].(string); ok && val != "" {
@@ -161,6 +293,30 @@ func expandSchemaSsh(v interface{}) (*SchemaSsh, error) {
 		}
 	}
 
+	if v, ok := d[SchemaAttrSshKeyboardInteractive].([]interface{}); ok && len(v) > 0 {
+		s.KeyboardInteractive, err = expandSchemaSshKeyboardInteractive(v)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Attributes of the ssh block of the provider only
+	s.ConfigFile, _ = d[SchemaAttrSshConfigFile].(string)
+	s.Alias, _ = d[SchemaAttrSshAlias].(string)
+
+	if s.Host == "" && s.Alias == "" {
+		return nil, fmt.Errorf("%q: required unless %s is set", SchemaAttrSshHost, SchemaAttrSshAlias)
+	}
+
+	s.HostCaPublicKeys, s.HostCaRevokedSerials = expandHostCa(d[SchemaAttrSshHostCaPublicKeys], d[SchemaAttrSshHostCaRevokedSerials])
+
+	// Ensure that an encrypted private key can be decrypted using the passphrase
+	if s.PrivateKey != "" {
+		if err := validate.PrivateKeyPassphrase(s.PrivateKey, s.PrivateKeyPassphrase); err != nil {
+			return nil, fmt.Errorf("invalid %s or %s: %w", SchemaAttrSshPrivateKey, SchemaAttrSshPrivateKeyPassphrase, err)
+		}
+	}
+
 	if timeoutStr := d[SchemaAttrSshTimeout].(string); timeoutStr != "" {
 		timeout, err := time.ParseDuration(timeoutStr)
 		if err != nil {
@@ -171,3 +327,43 @@ func expandSchemaSsh(v interface{}) (*SchemaSsh, error) {
 
 	return s, nil
 }
+
+// expandHostCa expands the values of attributes `host_ca_public_keys` and `host_ca_revoked_serials`
+func expandHostCa(publicKeysV interface{}, revokedSerialsV interface{}) ([]string, []uint64) {
+	var publicKeys []string
+	if vals, ok := publicKeysV.([]interface{}); ok {
+		for _, val := range vals {
+			publicKeys = append(publicKeys, val.(string))
+		}
+	}
+
+	var revokedSerials []uint64
+	if set, ok := revokedSerialsV.(*schema.Set); ok {
+		for _, val := range set.List() {
+			revokedSerials = append(revokedSerials, uint64(val.(int)))
+		}
+	}
+
+	return publicKeys, revokedSerials
+}
+
+func expandSchemaSshKeyboardInteractive(v interface{}) (*SchemaSshKeyboardInteractive, error) {
+	d, err := expandListSingle(v)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &SchemaSshKeyboardInteractive{
+		Answers:    map[string]string{},
+		TotpSecret: d[SchemaAttrSshKeyboardInteractiveTotpSecret].(string),
+		TotpPrompt: d[SchemaAttrSshKeyboardInteractiveTotpPrompt].(string),
+	}
+
+	if answers, ok := d[SchemaAttrSshKeyboardInteractiveAnswers].(map[string]interface{}); ok {
+		for pattern, answer := range answers {
+			s.Answers[pattern] = answer.(string)
+		}
+	}
+
+	return s, nil
+}
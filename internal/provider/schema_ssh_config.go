@@ -0,0 +1,262 @@
+package provider
+
+import (
+	"fmt"
+	"github.com/hashicorp/go-cty/cty"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/neuspaces/terraform-provider-system/internal/lib/env"
+	"github.com/neuspaces/terraform-provider-system/internal/lib/sshconfig"
+	"github.com/neuspaces/terraform-provider-system/internal/sshclient"
+	"github.com/neuspaces/terraform-provider-system/internal/validate"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	SchemaAttrSshConfigFile = "config_file"
+	SchemaAttrSshAlias      = "alias"
+)
+
+// sshConfigDefaultKnownHosts verifies the host keys of jump hosts unless known hosts are configured, equivalent to the
+// default UserKnownHostsFile of OpenSSH
+const sshConfigDefaultKnownHosts = "~/.ssh/known_hosts"
+
+// providerSchemaSshConfig returns the attributes of the ssh block of the provider which configure the connection from
+// an OpenSSH client configuration file
+func providerSchemaSshConfig(envPrefix string) map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		SchemaAttrSshConfigFile: {
+			Description: fmt.Sprintf("Path to an OpenSSH client configuration file from which the connection to `%[1]s` is configured. Defaults to `%[2]s`.", SchemaAttrSshAlias, sshconfig.DefaultPath),
+			Type:        schema.TypeString,
+			Optional:    true,
+			DefaultFunc: schemaEnvDefaultFunc(SchemaAttrSshConfigFile, envPrefix, sshconfig.DefaultPath),
+		},
+		SchemaAttrSshAlias: {
+			Description: fmt.Sprintf("Host alias in `%[1]s`. The directives `HostName`, `Port`, `User`, `IdentityFile`, `ProxyJump` and `UserKnownHostsFile` which apply to the alias configure the connection. Arguments of the `ssh` block, including values from environment variables, take precedence over the directives. `ProxyJump` is applied only if no `proxy` block is configured. Jump hosts authenticate by their own `IdentityFile` and by the ssh agent and verify host keys against `~/.ssh/known_hosts` unless configured otherwise. If `%[2]s` is not set, the host name of the alias is used.", SchemaAttrSshConfigFile, SchemaAttrSshHost),
+			Type:        schema.TypeString,
+			Optional:    true,
+			DefaultFunc: schemaEnvDefaultFunc(SchemaAttrSshAlias, envPrefix, nil),
+		},
+	}
+}
+
+// schemaSshExplicit returns a function which reports whether an attribute of an ssh block is set explicitly, either in
+// the raw configuration v or using the environment variable with envPrefix.
+func schemaSshExplicit(v cty.Value, envPrefix string) func(key string) bool {
+	return func(key string) bool {
+		if env.Has(envPrefix + strings.ToUpper(key)) {
+			return true
+		}
+
+		if v.IsNull() || !v.IsKnown() || !v.Type().IsObjectType() || !v.Type().HasAttribute(key) {
+			return false
+		}
+
+		return !v.GetAttr(key).IsNull()
+	}
+}
+
+// rawConfigBlock returns the raw configuration of the single nested block key of v or a null value
+func rawConfigBlock(v cty.Value, key string) cty.Value {
+	if v.IsNull() || !v.IsKnown() || !v.Type().IsObjectType() || !v.Type().HasAttribute(key) {
+		return cty.NilVal
+	}
+
+	block := v.GetAttr(key)
+	if block.IsNull() || !block.IsKnown() || !block.CanIterateElements() || block.LengthInt() != 1 {
+		return cty.NilVal
+	}
+
+	return block.Index(cty.NumberIntVal(0))
+}
+
+// applySshConfig resolves the alias of the ssh block s from the OpenSSH client configuration file and sets all
+// attributes which are not set explicitly. applySshConfig returns the proxy hops of the ProxyJump directive.
+func applySshConfig(s *SchemaSsh, explicit func(key string) bool) ([]*SchemaProxy, error) {
+	cfg, err := sshconfig.Load(s.ConfigFile)
+	if err != nil {
+		return nil, err
+	}
+
+	host, err := cfg.Host(s.Alias)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := applySshConfigHost(s, host, explicit); err != nil {
+		return nil, fmt.Errorf("%s %s: %w", SchemaAttrSshAlias, s.Alias, err)
+	}
+
+	var proxies []*SchemaProxy
+	for _, jump := range host.ProxyJump {
+		jumpHost, err := cfg.Host(jump.Host)
+		if err != nil {
+			return nil, err
+		}
+
+		// Jump hosts do not receive the credentials of the remote. Like OpenSSH, jump hosts authenticate by their own
+		// IdentityFile and the identities of the agent and verify the host key against known hosts.
+		j := &SchemaSsh{
+			Host:                 jump.Host,
+			Port:                 jump.Port,
+			User:                 jump.User,
+			KnownHosts:           s.KnownHosts,
+			HostCaPublicKeys:     s.HostCaPublicKeys,
+			HostCaRevokedSerials: s.HostCaRevokedSerials,
+			Agent:                true,
+			Timeout:              s.Timeout,
+		}
+
+		// Values of the jump specification take precedence over the configuration of the jump host. The IdentityFile
+		// of the jump host is read by applySshConfigJumpIdentity.
+		jumpExplicit := func(key string) bool {
+			switch key {
+			case SchemaAttrSshPort:
+				return jump.Port != 0
+			case SchemaAttrSshUser:
+				return jump.User != ""
+			case SchemaAttrSshPrivateKey:
+				return true
+			}
+			return false
+		}
+
+		if err := applySshConfigHost(j, jumpHost, jumpExplicit); err != nil {
+			return nil, fmt.Errorf("ProxyJump %s: %w", jump.Host, err)
+		}
+
+		applySshConfigJumpIdentity(j, jumpHost)
+
+		if j.HostKey == "" && j.KnownHosts == "" && len(j.HostCaPublicKeys) == 0 {
+			j.KnownHosts = sshConfigDefaultKnownHosts
+		}
+
+		if j.Port == 0 {
+			j.Port = 22
+		}
+
+		if j.User == "" {
+			j.User = s.User
+		}
+
+		proxies = append(proxies, &SchemaProxy{
+			Ssh: j,
+		})
+	}
+
+	return proxies, nil
+}
+
+// applySshConfigJumpIdentity sets the private key of the jump host s from the first IdentityFile of host. Identity
+// files which are encrypted or cannot be read are skipped because the passphrase cannot be prompted for; the agent
+// may provide the identity instead.
+func applySshConfigJumpIdentity(s *SchemaSsh, host *sshconfig.Host) {
+	if len(host.IdentityFiles) == 0 {
+		return
+	}
+
+	privateKey, err := readFirstFile(host.IdentityFiles)
+	if err != nil {
+		return
+	}
+
+	if _, err := sshclient.ParsePrivateKey(privateKey, ""); err != nil {
+		return
+	}
+
+	s.PrivateKey = privateKey
+}
+
+// applySshConfigHost sets all attributes of s from host which are not set explicitly
+func applySshConfigHost(s *SchemaSsh, host *sshconfig.Host, explicit func(key string) bool) error {
+	if !explicit(SchemaAttrSshHost) {
+		s.Host = host.HostName
+	}
+
+	if !explicit(SchemaAttrSshPort) && host.Port != 0 {
+		s.Port = host.Port
+	}
+
+	if !explicit(SchemaAttrSshUser) && host.User != "" {
+		s.User = host.User
+	}
+
+	if !explicit(SchemaAttrSshPrivateKey) && len(host.IdentityFiles) > 0 {
+		privateKey, err := readFirstFile(host.IdentityFiles)
+		if err != nil {
+			return fmt.Errorf("IdentityFile: %w", err)
+		}
+
+		if err := validate.PrivateKeyPassphrase(privateKey, s.PrivateKeyPassphrase); err != nil {
+			return fmt.Errorf("IdentityFile: %w", err)
+		}
+
+		s.PrivateKey = privateKey
+	}
+
+	if !explicit(SchemaAttrSshHostKey) && !explicit(SchemaAttrSshKnownHosts) && len(host.UserKnownHostsFiles) > 0 {
+		knownHostsFile, err := firstExistingFile(host.UserKnownHostsFiles)
+		if err != nil {
+			return fmt.Errorf("UserKnownHostsFile: %w", err)
+		}
+
+		s.HostKey = ""
+		s.KnownHosts = knownHostsFile
+	}
+
+	return nil
+}
+
+// expandSchemaSshFromEnvironment returns the ssh configuration from the environment variable of the alias if neither
+// the `ssh` nor the `connection` block is configured. expandSchemaSshFromEnvironment returns nil if the alias is not
+// set.
+func expandSchemaSshFromEnvironment(envPrefix string) (*SchemaSsh, []*SchemaProxy, error) {
+	alias := os.Getenv(envPrefix + strings.ToUpper(SchemaAttrSshAlias))
+	if alias == "" {
+		return nil, nil, nil
+	}
+
+	s := &SchemaSsh{
+		Alias:      alias,
+		ConfigFile: sshconfig.DefaultPath,
+		Port:       22,
+		Timeout:    30 * time.Second,
+	}
+
+	if configFile := os.Getenv(envPrefix + strings.ToUpper(SchemaAttrSshConfigFile)); configFile != "" {
+		s.ConfigFile = configFile
+	}
+
+	proxies, err := applySshConfig(s, func(string) bool { return false })
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return s, proxies, nil
+}
+
+func readFirstFile(paths []string) (string, error) {
+	path, err := firstExistingFile(paths)
+	if err != nil {
+		return "", err
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	return string(content), nil
+}
+
+// firstExistingFile returns the first path of paths which exists
+func firstExistingFile(paths []string) (string, error) {
+	for _, path := range paths {
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+
+	return "", fmt.Errorf("none of the files exists: %s", strings.Join(paths, ", "))
+}
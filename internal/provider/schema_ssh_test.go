@@ -2,13 +2,21 @@ package provider_test
 
 import (
 	_ "embed"
+	"encoding/pem"
 	"fmt"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
 	"github.com/neuspaces/terraform-provider-system/internal/acctest"
 	"github.com/neuspaces/terraform-provider-system/internal/acctest/sshagent"
 	"github.com/neuspaces/terraform-provider-system/internal/acctest/tfbuild"
 	"github.com/neuspaces/terraform-provider-system/internal/provider"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+	"net"
+	"os"
+	"path/filepath"
 	"regexp"
+	"strconv"
 	"testing"
 )
 
@@ -168,6 +176,81 @@ func TestAccProviderConnect_SshPrivateKey(t *testing.T) {
 	})
 }
 
+func testAccEncryptPrivateKey(t *testing.T, privateKey string, passphrase string) string {
+	rawPrivateKey, err := ssh.ParseRawPrivateKey([]byte(privateKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	block, err := ssh.MarshalPrivateKeyWithPassphrase(rawPrivateKey, "", []byte(passphrase))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(pem.EncodeToMemory(block))
+}
+
+func TestAccProviderConnect_SshPrivateKeyPassphrase(t *testing.T) {
+	t.Run("connect", func(t *testing.T) {
+		acctest.Current().Targets.Foreach(t, func(t *testing.T, target acctest.Target) {
+			t.Parallel()
+
+			targetConfig := getTargetConfigOrSkip(t, target, "auth-private-key")
+
+			providerConfig := tfbuild.Provider(provider.Name,
+				tfbuild.InnerBlock(provider.SchemaAttrSsh,
+					tfbuild.AttributeString(provider.SchemaAttrSshHost, targetConfig.Ssh.Host),
+					tfbuild.AttributeInt(provider.SchemaAttrSshPort, int64(targetConfig.Ssh.Port)),
+					tfbuild.AttributeString(provider.SchemaAttrSshUser, targetConfig.Ssh.User),
+					tfbuild.AttributeString(provider.SchemaAttrSshPrivateKey, testAccEncryptPrivateKey(t, targetConfig.Ssh.PrivateKey, "secret!")),
+					tfbuild.AttributeString(provider.SchemaAttrSshPrivateKeyPassphrase, "secret!"),
+				),
+			)
+
+			testAccProviderConnectTestExpectConnect(t, targetConfig, providerConfig)
+		})
+	})
+
+	t.Run("wrong passphrase", func(t *testing.T) {
+		acctest.Current().Targets.Foreach(t, func(t *testing.T, target acctest.Target) {
+			t.Parallel()
+
+			targetConfig := getTargetConfigOrSkip(t, target, "auth-private-key")
+
+			providerConfig := tfbuild.Provider(provider.Name,
+				tfbuild.InnerBlock(provider.SchemaAttrSsh,
+					tfbuild.AttributeString(provider.SchemaAttrSshHost, targetConfig.Ssh.Host),
+					tfbuild.AttributeInt(provider.SchemaAttrSshPort, int64(targetConfig.Ssh.Port)),
+					tfbuild.AttributeString(provider.SchemaAttrSshUser, targetConfig.Ssh.User),
+					tfbuild.AttributeString(provider.SchemaAttrSshPrivateKey, testAccEncryptPrivateKey(t, targetConfig.Ssh.PrivateKey, "secret!")),
+					tfbuild.AttributeString(provider.SchemaAttrSshPrivateKeyPassphrase, "wrong!"),
+				),
+			)
+
+			testAccProviderConnectTestExpectError(t, providerConfig, regexp.MustCompile(regexp.QuoteMeta(`incorrect passphrase`)))
+		})
+	})
+
+	t.Run("missing passphrase", func(t *testing.T) {
+		acctest.Current().Targets.Foreach(t, func(t *testing.T, target acctest.Target) {
+			t.Parallel()
+
+			targetConfig := getTargetConfigOrSkip(t, target, "auth-private-key")
+
+			providerConfig := tfbuild.Provider(provider.Name,
+				tfbuild.InnerBlock(provider.SchemaAttrSsh,
+					tfbuild.AttributeString(provider.SchemaAttrSshHost, targetConfig.Ssh.Host),
+					tfbuild.AttributeInt(provider.SchemaAttrSshPort, int64(targetConfig.Ssh.Port)),
+					tfbuild.AttributeString(provider.SchemaAttrSshUser, targetConfig.Ssh.User),
+					tfbuild.AttributeString(provider.SchemaAttrSshPrivateKey, testAccEncryptPrivateKey(t, targetConfig.Ssh.PrivateKey, "secret!")),
+				),
+			)
+
+			testAccProviderConnectTestExpectError(t, providerConfig, regexp.MustCompile(regexp.QuoteMeta(`passphrase required`)))
+		})
+	})
+}
+
 func TestAccProviderConnect_SshAgent(t *testing.T) {
 	t.Run("no explicit agent identities", func(t *testing.T) {
 		acctest.Current().Targets.Foreach(t, func(t *testing.T, target acctest.Target) {
@@ -377,6 +460,102 @@ func TestAccProviderConnect_SshHostKey(t *testing.T) {
 	})
 }
 
+func testAccKnownHostsLine(t *testing.T, host string, port int, hostKey string, hashed bool) string {
+	pk, _, _, _, err := ssh.ParseAuthorizedKey([]byte(hostKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	address := knownhosts.Normalize(net.JoinHostPort(host, strconv.Itoa(port)))
+	if hashed {
+		address = knownhosts.HashHostname(address)
+	}
+
+	return knownhosts.Line([]string{address}, pk)
+}
+
+func TestAccProviderConnect_SshKnownHosts(t *testing.T) {
+	t.Run("valid known hosts", func(t *testing.T) {
+		acctest.Current().Targets.Foreach(t, func(t *testing.T, target acctest.Target) {
+			t.Parallel()
+
+			targetConfig := getTargetConfigOrSkip(t, target, "auth-password")
+
+			providerConfig := tfbuild.Provider(provider.Name,
+				tfbuild.InnerBlock(provider.SchemaAttrSsh,
+					tfbuild.AttributeString(provider.SchemaAttrSshHost, targetConfig.Ssh.Host),
+					tfbuild.AttributeInt(provider.SchemaAttrSshPort, int64(targetConfig.Ssh.Port)),
+					tfbuild.AttributeString(provider.SchemaAttrSshKnownHosts, testAccKnownHostsLine(t, targetConfig.Ssh.Host, targetConfig.Ssh.Port, targetConfig.Ssh.HostKey, false)),
+					tfbuild.AttributeString(provider.SchemaAttrSshUser, targetConfig.Ssh.User),
+					tfbuild.AttributeString(provider.SchemaAttrSshPassword, targetConfig.Ssh.Password),
+				),
+			)
+
+			testAccProviderConnectTestExpectConnect(t, targetConfig, providerConfig)
+		})
+	})
+
+	t.Run("hashed known hosts", func(t *testing.T) {
+		acctest.Current().Targets.Foreach(t, func(t *testing.T, target acctest.Target) {
+			t.Parallel()
+
+			targetConfig := getTargetConfigOrSkip(t, target, "auth-password")
+
+			providerConfig := tfbuild.Provider(provider.Name,
+				tfbuild.InnerBlock(provider.SchemaAttrSsh,
+					tfbuild.AttributeString(provider.SchemaAttrSshHost, targetConfig.Ssh.Host),
+					tfbuild.AttributeInt(provider.SchemaAttrSshPort, int64(targetConfig.Ssh.Port)),
+					tfbuild.AttributeString(provider.SchemaAttrSshKnownHosts, testAccKnownHostsLine(t, targetConfig.Ssh.Host, targetConfig.Ssh.Port, targetConfig.Ssh.HostKey, true)),
+					tfbuild.AttributeString(provider.SchemaAttrSshUser, targetConfig.Ssh.User),
+					tfbuild.AttributeString(provider.SchemaAttrSshPassword, targetConfig.Ssh.Password),
+				),
+			)
+
+			testAccProviderConnectTestExpectConnect(t, targetConfig, providerConfig)
+		})
+	})
+
+	t.Run("unknown host", func(t *testing.T) {
+		acctest.Current().Targets.Foreach(t, func(t *testing.T, target acctest.Target) {
+			t.Parallel()
+
+			targetConfig := getTargetConfigOrSkip(t, target, "auth-password")
+
+			providerConfig := tfbuild.Provider(provider.Name,
+				tfbuild.InnerBlock(provider.SchemaAttrSsh,
+					tfbuild.AttributeString(provider.SchemaAttrSshHost, targetConfig.Ssh.Host),
+					tfbuild.AttributeInt(provider.SchemaAttrSshPort, int64(targetConfig.Ssh.Port)),
+					tfbuild.AttributeString(provider.SchemaAttrSshKnownHosts, testAccKnownHostsLine(t, "unknown.example.com", 22, targetConfig.Ssh.HostKey, false)),
+					tfbuild.AttributeString(provider.SchemaAttrSshUser, targetConfig.Ssh.User),
+					tfbuild.AttributeString(provider.SchemaAttrSshPassword, targetConfig.Ssh.Password),
+				),
+			)
+
+			testAccProviderConnectTestExpectError(t, providerConfig, regexp.MustCompile(regexp.QuoteMeta(`is unknown in known_hosts`)))
+		})
+	})
+
+	t.Run("host key mismatch", func(t *testing.T) {
+		acctest.Current().Targets.Foreach(t, func(t *testing.T, target acctest.Target) {
+			t.Parallel()
+
+			targetConfig := getTargetConfigOrSkip(t, target, "auth-password")
+
+			providerConfig := tfbuild.Provider(provider.Name,
+				tfbuild.InnerBlock(provider.SchemaAttrSsh,
+					tfbuild.AttributeString(provider.SchemaAttrSshHost, targetConfig.Ssh.Host),
+					tfbuild.AttributeInt(provider.SchemaAttrSshPort, int64(targetConfig.Ssh.Port)),
+					tfbuild.AttributeString(provider.SchemaAttrSshKnownHosts, testAccKnownHostsLine(t, targetConfig.Ssh.Host, targetConfig.Ssh.Port, string(testAccEcdsaPrimaryPublicKey), false)),
+					tfbuild.AttributeString(provider.SchemaAttrSshUser, targetConfig.Ssh.User),
+					tfbuild.AttributeString(provider.SchemaAttrSshPassword, targetConfig.Ssh.Password),
+				),
+			)
+
+			testAccProviderConnectTestExpectError(t, providerConfig, regexp.MustCompile(regexp.QuoteMeta(`host key mismatch`)))
+		})
+	})
+}
+
 func TestAccProviderSudo(t *testing.T) {
 	t.Run("privileged user should become root", func(t *testing.T) {
 		acctest.Current().Targets.Foreach(t, func(t *testing.T, target acctest.Target) {
@@ -458,4 +637,245 @@ func TestAccProviderConnect_SshProxy(t *testing.T) {
 			testAccProviderConnectTestExpectConnect(t, targetConfig, providerConfig)
 		})
 	})
+
+	t.Run("connect via multiple hops", func(t *testing.T) {
+		acctest.Current().Targets.Foreach(t, func(t *testing.T, target acctest.Target) {
+			t.Parallel()
+
+			proxyTargetConfig := getTargetConfigOrSkip(t, target, "auth-unprivileged")
+			targetConfig := getTargetConfigOrSkip(t, target, "auth-password")
+
+			providerConfig := tfbuild.Provider(provider.Name,
+				tfbuild.InnerBlock(provider.SchemaAttrSsh,
+					tfbuild.AttributeString(provider.SchemaAttrSshHost, "localhost"),
+					tfbuild.AttributeInt(provider.SchemaAttrSshPort, 22),
+					tfbuild.AttributeString(provider.SchemaAttrSshUser, targetConfig.Ssh.User),
+					tfbuild.AttributeString(provider.SchemaAttrSshPassword, targetConfig.Ssh.Password),
+				),
+				tfbuild.InnerBlock(provider.SchemaAttrProxy,
+					tfbuild.InnerBlock(provider.SchemaAttrSsh,
+						tfbuild.AttributeString(provider.SchemaAttrSshHost, proxyTargetConfig.Ssh.Host),
+						tfbuild.AttributeInt(provider.SchemaAttrSshPort, int64(proxyTargetConfig.Ssh.Port)),
+						tfbuild.AttributeString(provider.SchemaAttrSshUser, proxyTargetConfig.Ssh.User),
+						tfbuild.AttributeString(provider.SchemaAttrSshPassword, proxyTargetConfig.Ssh.Password),
+					),
+				),
+				tfbuild.InnerBlock(provider.SchemaAttrProxy,
+					tfbuild.InnerBlock(provider.SchemaAttrSsh,
+						tfbuild.AttributeString(provider.SchemaAttrSshHost, "localhost"),
+						tfbuild.AttributeInt(provider.SchemaAttrSshPort, 22),
+						tfbuild.AttributeString(provider.SchemaAttrSshUser, proxyTargetConfig.Ssh.User),
+						tfbuild.AttributeString(provider.SchemaAttrSshPassword, proxyTargetConfig.Ssh.Password),
+					),
+				),
+			)
+
+			testAccProviderConnectTestExpectConnect(t, targetConfig, providerConfig)
+		})
+	})
+
+	t.Run("failing hop", func(t *testing.T) {
+		acctest.Current().Targets.Foreach(t, func(t *testing.T, target acctest.Target) {
+			t.Parallel()
+
+			proxyTargetConfig := getTargetConfigOrSkip(t, target, "auth-unprivileged")
+			targetConfig := getTargetConfigOrSkip(t, target, "auth-password")
+
+			providerConfig := tfbuild.Provider(provider.Name,
+				tfbuild.InnerBlock(provider.SchemaAttrSsh,
+					tfbuild.AttributeString(provider.SchemaAttrSshHost, "localhost"),
+					tfbuild.AttributeInt(provider.SchemaAttrSshPort, 22),
+					tfbuild.AttributeString(provider.SchemaAttrSshUser, targetConfig.Ssh.User),
+					tfbuild.AttributeString(provider.SchemaAttrSshPassword, targetConfig.Ssh.Password),
+				),
+				tfbuild.InnerBlock(provider.SchemaAttrProxy,
+					tfbuild.InnerBlock(provider.SchemaAttrSsh,
+						tfbuild.AttributeString(provider.SchemaAttrSshHost, proxyTargetConfig.Ssh.Host),
+						tfbuild.AttributeInt(provider.SchemaAttrSshPort, int64(proxyTargetConfig.Ssh.Port)),
+						tfbuild.AttributeString(provider.SchemaAttrSshUser, proxyTargetConfig.Ssh.User),
+						tfbuild.AttributeString(provider.SchemaAttrSshPassword, proxyTargetConfig.Ssh.Password),
+					),
+				),
+				tfbuild.InnerBlock(provider.SchemaAttrProxy,
+					tfbuild.InnerBlock(provider.SchemaAttrSsh,
+						tfbuild.AttributeString(provider.SchemaAttrSshHost, "localhost"),
+						tfbuild.AttributeInt(provider.SchemaAttrSshPort, 22),
+						tfbuild.AttributeString(provider.SchemaAttrSshUser, proxyTargetConfig.Ssh.User),
+						tfbuild.AttributeString(provider.SchemaAttrSshPassword, "wrong!"),
+					),
+				),
+				tfbuild.AttributeBool(provider.SchemaAttrRetry, false),
+			)
+
+			testAccProviderConnectTestExpectError(t, providerConfig, regexp.MustCompile(regexp.QuoteMeta(`proxy hop 2 (localhost:22): ssh: handshake failed`)))
+		})
+	})
+}
+
+func TestAccProviderConnect_ProxyServer(t *testing.T) {
+	for _, proxyBlock := range []string{provider.SchemaAttrProxySocks5, provider.SchemaAttrProxyHttp} {
+		proxyBlock := proxyBlock
+
+		t.Run(fmt.Sprintf("%s unreachable", proxyBlock), func(t *testing.T) {
+			acctest.Current().Targets.Foreach(t, func(t *testing.T, target acctest.Target) {
+				t.Parallel()
+
+				targetConfig := getTargetConfigOrSkip(t, target, "auth-password")
+
+				providerConfig := tfbuild.Provider(provider.Name,
+					tfbuild.InnerBlock(provider.SchemaAttrSsh,
+						tfbuild.AttributeString(provider.SchemaAttrSshHost, targetConfig.Ssh.Host),
+						tfbuild.AttributeInt(provider.SchemaAttrSshPort, int64(targetConfig.Ssh.Port)),
+						tfbuild.AttributeString(provider.SchemaAttrSshUser, targetConfig.Ssh.User),
+						tfbuild.AttributeString(provider.SchemaAttrSshPassword, targetConfig.Ssh.Password),
+					),
+					tfbuild.InnerBlock(provider.SchemaAttrProxy,
+						tfbuild.InnerBlock(proxyBlock,
+							tfbuild.AttributeString(provider.SchemaAttrProxyServerHost, "127.0.0.1"),
+							tfbuild.AttributeInt(provider.SchemaAttrProxyServerPort, 1),
+						),
+					),
+					tfbuild.AttributeBool(provider.SchemaAttrRetry, false),
+				)
+
+				testAccProviderConnectTestExpectError(t, providerConfig, regexp.MustCompile(regexp.QuoteMeta(`proxy hop 1 (127.0.0.1:1)`)))
+			})
+		})
+	}
+}
+
+func TestAccProviderConnect_ProxyCommand(t *testing.T) {
+	t.Run("failing command", func(t *testing.T) {
+		acctest.Current().Targets.Foreach(t, func(t *testing.T, target acctest.Target) {
+			t.Parallel()
+
+			targetConfig := getTargetConfigOrSkip(t, target, "auth-password")
+
+			providerConfig := tfbuild.Provider(provider.Name,
+				tfbuild.InnerBlock(provider.SchemaAttrSsh,
+					tfbuild.AttributeString(provider.SchemaAttrSshHost, targetConfig.Ssh.Host),
+					tfbuild.AttributeInt(provider.SchemaAttrSshPort, int64(targetConfig.Ssh.Port)),
+					tfbuild.AttributeString(provider.SchemaAttrSshUser, targetConfig.Ssh.User),
+					tfbuild.AttributeString(provider.SchemaAttrSshPassword, targetConfig.Ssh.Password),
+				),
+				tfbuild.InnerBlock(provider.SchemaAttrProxy,
+					tfbuild.AttributeString(provider.SchemaAttrProxyCommand, "echo cannot reach %h >&2; exit 1"),
+				),
+				tfbuild.AttributeBool(provider.SchemaAttrRetry, false),
+			)
+
+			testAccProviderConnectTestExpectError(t, providerConfig, regexp.MustCompile(regexp.QuoteMeta(fmt.Sprintf("proxy command failed: exit status 1: cannot reach %s", targetConfig.Ssh.Host))))
+		})
+	})
+
+	t.Run("unsupported token", func(t *testing.T) {
+		acctest.Current().Targets.Foreach(t, func(t *testing.T, target acctest.Target) {
+			t.Parallel()
+
+			targetConfig := getTargetConfigOrSkip(t, target, "auth-password")
+
+			providerConfig := tfbuild.Provider(provider.Name,
+				tfbuild.InnerBlock(provider.SchemaAttrSsh,
+					tfbuild.AttributeString(provider.SchemaAttrSshHost, targetConfig.Ssh.Host),
+					tfbuild.AttributeInt(provider.SchemaAttrSshPort, int64(targetConfig.Ssh.Port)),
+					tfbuild.AttributeString(provider.SchemaAttrSshUser, targetConfig.Ssh.User),
+					tfbuild.AttributeString(provider.SchemaAttrSshPassword, targetConfig.Ssh.Password),
+				),
+				tfbuild.InnerBlock(provider.SchemaAttrProxy,
+					tfbuild.AttributeString(provider.SchemaAttrProxyCommand, "nc %x"),
+				),
+			)
+
+			testAccProviderConnectTestExpectError(t, providerConfig, regexp.MustCompile(regexp.QuoteMeta(`unsupported token %x in command`)))
+		})
+	})
+}
+
+// testAccSshConfigFile writes an OpenSSH client configuration file with the content and returns its path
+func testAccSshConfigFile(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "config")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+	return path
+}
+
+func TestAccProviderConnect_SshConfig(t *testing.T) {
+	t.Run("connect via alias", func(t *testing.T) {
+		acctest.Current().Targets.Foreach(t, func(t *testing.T, target acctest.Target) {
+			t.Parallel()
+
+			targetConfig := getTargetConfigOrSkip(t, target, "auth-password")
+
+			configFile := testAccSshConfigFile(t, fmt.Sprintf("Host target\n  HostName %s\n  Port %d\n  User %s\n", targetConfig.Ssh.Host, targetConfig.Ssh.Port, targetConfig.Ssh.User))
+
+			providerConfig := tfbuild.Provider(provider.Name,
+				tfbuild.InnerBlock(provider.SchemaAttrSsh,
+					tfbuild.AttributeString(provider.SchemaAttrSshConfigFile, configFile),
+					tfbuild.AttributeString(provider.SchemaAttrSshAlias, "target"),
+					tfbuild.AttributeString(provider.SchemaAttrSshPassword, targetConfig.Ssh.Password),
+				),
+			)
+
+			testAccProviderConnectTestExpectConnect(t, targetConfig, providerConfig)
+		})
+	})
+
+	t.Run("explicit attributes take precedence", func(t *testing.T) {
+		acctest.Current().Targets.Foreach(t, func(t *testing.T, target acctest.Target) {
+			t.Parallel()
+
+			targetConfig := getTargetConfigOrSkip(t, target, "auth-password")
+
+			configFile := testAccSshConfigFile(t, fmt.Sprintf("Host target\n  HostName %s\n  Port 1\n  User nobody\n", targetConfig.Ssh.Host))
+
+			providerConfig := tfbuild.Provider(provider.Name,
+				tfbuild.InnerBlock(provider.SchemaAttrSsh,
+					tfbuild.AttributeString(provider.SchemaAttrSshConfigFile, configFile),
+					tfbuild.AttributeString(provider.SchemaAttrSshAlias, "target"),
+					tfbuild.AttributeInt(provider.SchemaAttrSshPort, int64(targetConfig.Ssh.Port)),
+					tfbuild.AttributeString(provider.SchemaAttrSshUser, targetConfig.Ssh.User),
+					tfbuild.AttributeString(provider.SchemaAttrSshPassword, targetConfig.Ssh.Password),
+				),
+			)
+
+			testAccProviderConnectTestExpectConnect(t, targetConfig, providerConfig)
+		})
+	})
+
+	t.Run("proxy jump", func(t *testing.T) {
+		acctest.Current().Targets.Foreach(t, func(t *testing.T, target acctest.Target) {
+			t.Parallel()
+
+			targetConfig := getTargetConfigOrSkip(t, target, "auth-private-key")
+
+			identityFile := filepath.Join(t.TempDir(), "id")
+			require.NoError(t, os.WriteFile(identityFile, []byte(targetConfig.Ssh.PrivateKey), 0600))
+
+			knownHostsFile := filepath.Join(t.TempDir(), "known_hosts")
+			require.NoError(t, os.WriteFile(knownHostsFile, []byte(testAccKnownHostsLine(t, targetConfig.Ssh.Host, targetConfig.Ssh.Port, targetConfig.Ssh.HostKey, false)+"\n"), 0600))
+
+			// The remote is connected as localhost through the jump host which authenticates by its own identity file
+			configFile := testAccSshConfigFile(t, fmt.Sprintf("Host jump\n  HostName %s\n  Port %d\n  User %s\n  IdentityFile %s\n  UserKnownHostsFile %s\nHost target\n  HostName localhost\n  Port 22\n  ProxyJump jump\n", targetConfig.Ssh.Host, targetConfig.Ssh.Port, targetConfig.Ssh.User, identityFile, knownHostsFile))
+
+			providerConfig := tfbuild.Provider(provider.Name,
+				tfbuild.InnerBlock(provider.SchemaAttrSsh,
+					tfbuild.AttributeString(provider.SchemaAttrSshConfigFile, configFile),
+					tfbuild.AttributeString(provider.SchemaAttrSshAlias, "target"),
+					tfbuild.AttributeString(provider.SchemaAttrSshUser, targetConfig.Ssh.User),
+					tfbuild.AttributeString(provider.SchemaAttrSshPrivateKey, targetConfig.Ssh.PrivateKey),
+				),
+			)
+
+			testAccProviderConnectTestExpectConnect(t, targetConfig, providerConfig)
+		})
+	})
+
+	t.Run("missing host and alias", func(t *testing.T) {
+		providerConfig := tfbuild.Provider(provider.Name,
+			tfbuild.InnerBlock(provider.SchemaAttrSsh,
+				tfbuild.AttributeString(provider.SchemaAttrSshUser, "root"),
+			),
+		)
+
+		testAccProviderConnectTestExpectError(t, providerConfig, regexp.MustCompile(`"host": required unless alias is set`))
+	})
 }
@@ -44,7 +44,15 @@ func expandListSingle(v interface{}) (map[string]interface{}, error) {
 	return d, nil
 }
 
+// schemaEnvDefaultFunc returns a schema.SchemaDefaultFunc which reads the default from the environment variable of
+// schemaKey with prefix. If prefix is empty, the default is not read from the environment and dv is returned.
 func schemaEnvDefaultFunc(schemaKey string, prefix string, dv interface{}) schema.SchemaDefaultFunc {
+	if prefix == "" {
+		return func() (interface{}, error) {
+			return dv, nil
+		}
+	}
+
 	return schema.EnvDefaultFunc(prefix+strings.ToUpper(schemaKey), dv)
 }
 
@@ -62,6 +70,32 @@ func (p attrPath) Extend(parts ...string) attrPath {
 	return append(p, parts...)
 }
 
+// Paths returns the absolute paths of the provided attributes relative to the attrPath.
+// Paths returns nil if the attrPath is nil.
+func (p attrPath) Paths(keys ...string) []string {
+	if p == nil {
+		return nil
+	}
+
+	var paths []string
+	for _, key := range keys {
+		paths = append(paths, p.Extend(key).String())
+	}
+	return paths
+}
+
 func newAttrPath(parts ...string) attrPath {
 	return parts
 }
+
+// mergeSchemaMaps returns a single schema map which contains the attributes of all provided schema maps.
+// Attributes of later schema maps replace attributes of earlier schema maps with the same key.
+func mergeSchemaMaps(maps ...map[string]*schema.Schema) map[string]*schema.Schema {
+	merged := map[string]*schema.Schema{}
+	for _, m := range maps {
+		for key, s := range m {
+			merged[key] = s
+		}
+	}
+	return merged
+}
@@ -0,0 +1,175 @@
+package source
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// QueryExpectedSha256 is the query parameter of a source url which defines the expected SHA-256 checksum of the
+// content in hexadecimal encoding. The query parameter is removed from the url before the url is opened by a Client.
+const QueryExpectedSha256 = "expected_sha256"
+
+var ErrChecksumMismatch = &Error{msg: "checksum mismatch"}
+
+var ErrChecksumInvalid = &Error{msg: "invalid expected checksum"}
+
+// Verifier is a Source which verifies its content
+type Verifier interface {
+	Verify() error
+}
+
+// Verify verifies the content of s before the content is read if s is a Verifier. Verify returns ErrChecksumMismatch
+// if the content does not match the expected checksum.
+func Verify(s Source) error {
+	if v, ok := s.(Verifier); ok {
+		return v.Verify()
+	}
+
+	return nil
+}
+
+// expectedSha256FromUrl removes the query parameter QueryExpectedSha256 from u and returns the decoded checksum. The
+// returned checksum is nil if u does not define an expected checksum. All other query parameters are retained verbatim
+// because re-encoding the query would invalidate signed urls.
+func expectedSha256FromUrl(u *url.URL) ([]byte, error) {
+	query := u.Query()
+	if !query.Has(QueryExpectedSha256) {
+		return nil, nil
+	}
+
+	expectedHex := query.Get(QueryExpectedSha256)
+	expected, err := hex.DecodeString(strings.TrimPrefix(strings.ToLower(expectedHex), "sha256:"))
+	if err != nil || len(expected) != sha256.Size {
+		return nil, ErrChecksumInvalid.WithCause(fmt.Errorf("%q is not a hex encoded SHA-256 checksum", expectedHex))
+	}
+
+	var params []string
+	for _, param := range strings.Split(u.RawQuery, "&") {
+		key, _, _ := strings.Cut(param, "=")
+		if key, err := url.QueryUnescape(key); err == nil && key == QueryExpectedSha256 {
+			continue
+		}
+		params = append(params, param)
+	}
+	u.RawQuery = strings.Join(params, "&")
+	u.ForceQuery = false
+
+	return expected, nil
+}
+
+// verifiedSource is a Source whose content is verified against an expected SHA-256 checksum before the content is
+// returned by Read. The content is downloaded completely into a temporary file on the first Read.
+type verifiedSource struct {
+	source   Source
+	url      string
+	expected []byte
+
+	// file contains the verified content
+	file *os.File
+
+	// err is returned by all Read if the verification failed
+	err error
+}
+
+var _ Source = &verifiedSource{}
+var _ Verifier = &verifiedSource{}
+
+func newVerifiedSource(s Source, u *url.URL, expected []byte) *verifiedSource {
+	return &verifiedSource{
+		source:   s,
+		url:      u.String(),
+		expected: expected,
+	}
+}
+
+func (s *verifiedSource) Read(p []byte) (int, error) {
+	err := s.Verify()
+	if err != nil {
+		return 0, err
+	}
+
+	return s.file.Read(p)
+}
+
+// Verify downloads and verifies the content unless it has been verified before
+func (s *verifiedSource) Verify() error {
+	if s.file == nil && s.err == nil {
+		s.err = s.verify()
+	}
+
+	return s.err
+}
+
+// verify reads the content of the source into a temporary file and compares the checksum of the content
+func (s *verifiedSource) verify() error {
+	file, err := os.CreateTemp("", "terraform-provider-system-source-*")
+	if err != nil {
+		return err
+	}
+
+	h := sha256.New()
+	_, err = io.Copy(io.MultiWriter(file, h), s.source)
+	if err == nil {
+		_, err = file.Seek(0, io.SeekStart)
+	}
+	if err == nil {
+		if actual := h.Sum(nil); !bytes.Equal(actual, s.expected) {
+			err = ErrChecksumMismatch.WithCause(fmt.Errorf("content of %q has SHA-256 checksum %s but expected %s", s.url, hex.EncodeToString(actual), hex.EncodeToString(s.expected)))
+		}
+	}
+	if err != nil {
+		_ = file.Close()
+		_ = os.Remove(file.Name())
+		return err
+	}
+
+	s.file = file
+
+	return nil
+}
+
+func (s *verifiedSource) Close() error {
+	if s.file != nil {
+		_ = s.file.Close()
+		_ = os.Remove(s.file.Name())
+		s.file = nil
+	}
+
+	return s.source.Close()
+}
+
+// Meta returns the Meta of the source. The SHA-256 checksum is the expected checksum unless the source provides the
+// checksum itself.
+func (s *verifiedSource) Meta() (Meta, error) {
+	m, err := s.source.Meta()
+	if err != nil {
+		return nil, err
+	}
+
+	if m.Sha256Sum() != "" {
+		return m, nil
+	}
+
+	return &verifiedMeta{
+		Meta:      m,
+		sha256sum: hex.EncodeToString(s.expected),
+	}, nil
+}
+
+type verifiedMeta struct {
+	Meta
+
+	sha256sum string
+}
+
+var _ Meta = &verifiedMeta{}
+
+func (m *verifiedMeta) Sha256Sum() string {
+	return m.sha256sum
+}
@@ -0,0 +1,87 @@
+package source_test
+
+import (
+	"github.com/neuspaces/terraform-provider-system/internal/source"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRegistry_ExpectedSha256(t *testing.T) {
+	t.Parallel()
+
+	name := filepath.Join(t.TempDir(), "file")
+	require.NoError(t, os.WriteFile(name, []byte("hello world!"), 0644))
+
+	registry, err := source.NewRegistry(
+		source.WithClient(source.NewFileClient()),
+		source.WithDefaultScheme(source.FileScheme),
+	)
+	require.NoError(t, err)
+
+	type testCase struct {
+		Desc          string
+		Url           string
+		ExpectOpenErr error
+		ExpectReadErr error
+	}
+
+	tcs := []testCase{
+		{
+			Desc: "without expected checksum",
+			Url:  name,
+		},
+		{
+			Desc: "matching checksum",
+			// echo -n 'hello world!' | sha256sum
+			Url: name + "?expected_sha256=7509e5bda0c762d2bac7f90d758b5b2263fa01ccbc542ab5e3df163be08e6ca9",
+		},
+		{
+			Desc: "matching checksum with prefix",
+			Url:  "file://" + name + "?expected_sha256=sha256:7509E5BDA0C762D2BAC7F90D758B5B2263FA01CCBC542AB5E3DF163BE08E6CA9",
+		},
+		{
+			Desc:          "mismatching checksum",
+			Url:           name + "?expected_sha256=0000000000000000000000000000000000000000000000000000000000000000",
+			ExpectReadErr: source.ErrChecksumMismatch,
+		},
+		{
+			Desc:          "invalid checksum",
+			Url:           name + "?expected_sha256=abc",
+			ExpectOpenErr: source.ErrChecksumInvalid,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.Desc, func(t *testing.T) {
+			t.Parallel()
+
+			s, err := registry.Open(tc.Url)
+			if tc.ExpectOpenErr != nil {
+				assert.ErrorIs(t, err, tc.ExpectOpenErr)
+				return
+			}
+			require.NoError(t, err)
+			defer func() {
+				assert.NoError(t, s.Close())
+			}()
+
+			content, err := io.ReadAll(s)
+			if tc.ExpectReadErr != nil {
+				assert.ErrorIs(t, err, tc.ExpectReadErr)
+				assert.Empty(t, content)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "hello world!", string(content))
+
+			m, err := s.Meta()
+			require.NoError(t, err)
+			assert.Equal(t, "7509e5bda0c762d2bac7f90d758b5b2263fa01ccbc542ab5e3df163be08e6ca9", m.Sha256Sum())
+			assert.Equal(t, "/D/5joxqDTCH1RXARz+Gdw==", m.ETag())
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package source
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+)
+
+// contentSource is a Source of content in memory
+type contentSource struct {
+	client string
+	url    string
+
+	content []byte
+	reader  *bytes.Reader
+}
+
+var _ Source = &contentSource{}
+
+func newContentSource(client string, url string, content []byte) *contentSource {
+	return &contentSource{
+		client:  client,
+		url:     url,
+		content: content,
+		reader:  bytes.NewReader(content),
+	}
+}
+
+func (s *contentSource) Read(p []byte) (int, error) {
+	return s.reader.Read(p)
+}
+
+func (s *contentSource) Close() error {
+	return nil
+}
+
+// Meta returns the md5 sum as ETag and the SHA-256 and SHA-512 checksums of the content equivalent to the File client
+func (s *contentSource) Meta() (Meta, error) {
+	sums := newContentSums()
+	_, _ = sums.Writer().Write(s.content)
+
+	return &meta{
+		client:    s.client,
+		url:       s.url,
+		size:      int64(len(s.content)),
+		etag:      base64.StdEncoding.EncodeToString(sums.md5.Sum(nil)),
+		sha256sum: hex.EncodeToString(sums.sha256.Sum(nil)),
+		sha512sum: hex.EncodeToString(sums.sha512.Sum(nil)),
+	}, nil
+}
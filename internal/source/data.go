@@ -0,0 +1,69 @@
+package source
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+const DataScheme = "data"
+
+const dataClient = "data"
+
+// Data is a Client for data urls as defined in RFC 2397 like data:text/plain;base64,SGVsbG8= which contain the content
+// in the url. The media type is ignored.
+type Data struct {
+}
+
+var _ Client = &Data{}
+
+var ErrDataClient = &Error{msg: "data client error"}
+
+func NewDataClient() *Data {
+	return &Data{}
+}
+
+func (c *Data) Open(u *url.URL) (Source, error) {
+	// The content may contain a question mark which is parsed as the query and a number sign which is parsed as the
+	// fragment
+	raw := u.Opaque
+	if raw == "" {
+		raw = u.Path
+	}
+	if u.RawQuery != "" || u.ForceQuery {
+		raw += "?" + u.RawQuery
+	}
+	if u.Fragment != "" {
+		raw += "#" + u.EscapedFragment()
+	}
+
+	params, data, found := strings.Cut(raw, ",")
+	if !found {
+		return nil, ErrDataClient.WithCause(fmt.Errorf("expected an url like data:[<media type>][;base64],<data>"))
+	}
+
+	var content []byte
+	if strings.HasSuffix(params, ";base64") {
+		// Terraform base64encode uses the standard encoding with padding
+		var err error
+		content, err = base64.StdEncoding.DecodeString(data)
+		if err != nil {
+			return nil, ErrDataClient.WithCause(err)
+		}
+	} else {
+		unescaped, err := url.PathUnescape(data)
+		if err != nil {
+			return nil, ErrDataClient.WithCause(err)
+		}
+		content = []byte(unescaped)
+	}
+
+	return newContentSource(dataClient, u.String(), content), nil
+}
+
+func (c *Data) Schemes() []string {
+	return []string{
+		DataScheme,
+	}
+}
@@ -0,0 +1,99 @@
+package source_test
+
+import (
+	"github.com/neuspaces/terraform-provider-system/internal/source"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"testing"
+)
+
+func TestData_Open(t *testing.T) {
+	t.Parallel()
+
+	registry, err := source.NewRegistry(
+		source.WithClient(source.NewDataClient()),
+	)
+	require.NoError(t, err)
+
+	type testCase struct {
+		Desc          string
+		Url           string
+		ExpectContent []byte
+		ExpectErr     error
+	}
+
+	tcs := []testCase{
+		{
+			Desc:          "base64",
+			Url:           "data:text/plain;base64,aGVsbG8gd29ybGQh",
+			ExpectContent: []byte("hello world!"),
+		},
+		{
+			Desc:          "base64 binary",
+			Url:           "data:application/octet-stream;base64,AAH/fw==",
+			ExpectContent: []byte{0x00, 0x01, 0xff, 0x7f},
+		},
+		{
+			Desc:          "percent encoded",
+			Url:           "data:,hello%20world%3F",
+			ExpectContent: []byte("hello world?"),
+		},
+		{
+			Desc:          "question mark",
+			Url:           "data:,hello?world",
+			ExpectContent: []byte("hello?world"),
+		},
+		{
+			Desc:          "number sign",
+			Url:           "data:,color: #fff; #%20comment?#",
+			ExpectContent: []byte("color: #fff; # comment?#"),
+		},
+		{
+			Desc:          "trailing question mark",
+			Url:           "data:,hello?",
+			ExpectContent: []byte("hello?"),
+		},
+		{
+			Desc:          "empty",
+			Url:           "data:,",
+			ExpectContent: []byte{},
+		},
+		{
+			Desc:      "missing comma",
+			Url:       "data:text/plain",
+			ExpectErr: source.ErrDataClient,
+		},
+		{
+			Desc:      "invalid base64",
+			Url:       "data:;base64,!!",
+			ExpectErr: source.ErrDataClient,
+		},
+	}
+
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.Desc, func(t *testing.T) {
+			t.Parallel()
+
+			s, err := registry.Open(tc.Url)
+			if tc.ExpectErr != nil {
+				assert.ErrorIs(t, err, tc.ExpectErr)
+				return
+			}
+			require.NoError(t, err)
+			defer func() {
+				_ = s.Close()
+			}()
+
+			content, err := io.ReadAll(s)
+			require.NoError(t, err)
+			assert.Equal(t, tc.ExpectContent, content)
+
+			m, err := s.Meta()
+			require.NoError(t, err)
+			assert.Equal(t, int64(len(tc.ExpectContent)), m.Size())
+			assert.NotEmpty(t, m.ETag())
+		})
+	}
+}
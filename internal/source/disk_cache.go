@@ -0,0 +1,370 @@
+package source
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+const (
+	diskCacheBlobsDir   = "blobs"
+	diskCacheEntriesDir = "entries"
+	diskCacheTmpDir     = "tmp"
+	diskCacheLockFile   = ".lock"
+)
+
+// diskCacheTmpMaxAge is the age after which temporary files of interrupted downloads are removed
+const diskCacheTmpMaxAge = 24 * time.Hour
+
+var ErrDiskCache = &Error{msg: "disk cache error"}
+
+// DiskCache stores the content of sources in a directory. The content is stored once by its SHA-256 checksum. An entry
+// maps a key to the content and the validators ETag and Last-Modified of the content. The key identifies the url and
+// the headers of the request so that content is not shared between requests with different credentials. The least recently used content
+// is evicted if the total size of the content exceeds MaxSize. DiskCache can be shared by multiple processes.
+type DiskCache struct {
+	Dir string
+
+	// MaxSize is the maximum total size of the content in bytes. The size is not limited if MaxSize is 0.
+	MaxSize int64
+}
+
+// diskCacheEntry is an url of which the content is stored in the DiskCache. The entry is stored by the checksum of its
+// key.
+type diskCacheEntry struct {
+	key string
+
+	Url          string `json:"url"`
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+	Sha256Sum    string `json:"sha256sum"`
+	Size         int64  `json:"size"`
+}
+
+// NewDiskCache returns a DiskCache in dir. The directory is created if it does not exist.
+func NewDiskCache(dir string, maxSize int64) (*DiskCache, error) {
+	c := &DiskCache{
+		Dir:     dir,
+		MaxSize: maxSize,
+	}
+
+	for _, name := range []string{diskCacheBlobsDir, diskCacheEntriesDir, diskCacheTmpDir} {
+		err := os.MkdirAll(filepath.Join(dir, name), 0700)
+		if err != nil {
+			return nil, ErrDiskCache.WithCause(err)
+		}
+	}
+
+	return c, nil
+}
+
+func (c *DiskCache) blobPath(sha256sum string) string {
+	return filepath.Join(c.Dir, diskCacheBlobsDir, sha256sum)
+}
+
+func (c *DiskCache) entryPath(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(c.Dir, diskCacheEntriesDir, hex.EncodeToString(sum[:])+".json")
+}
+
+// lock acquires an exclusive lock of the cache across processes and returns a function which releases the lock
+func (c *DiskCache) lock() (func(), error) {
+	f, err := os.OpenFile(filepath.Join(c.Dir, diskCacheLockFile), os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	err = lockFile(f)
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+
+	return func() {
+		_ = unlockFile(f)
+		_ = f.Close()
+	}, nil
+}
+
+// lookup returns the entry of the key for the url or nil if the url is not cached
+func (c *DiskCache) lookup(key string, url string) (*diskCacheEntry, error) {
+	unlock, err := c.lock()
+	if err != nil {
+		return nil, ErrDiskCache.WithCause(err)
+	}
+	defer unlock()
+
+	data, err := os.ReadFile(c.entryPath(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, ErrDiskCache.WithCause(err)
+	}
+
+	e := &diskCacheEntry{key: key}
+	err = json.Unmarshal(data, e)
+	if err != nil || e.Url != url {
+		// Ignore corrupt entries
+		return nil, nil
+	}
+
+	return e, nil
+}
+
+// open returns the content of the entry. The content is marked as recently used.
+func (c *DiskCache) open(e *diskCacheEntry) (*os.File, error) {
+	unlock, err := c.lock()
+	if err != nil {
+		return nil, ErrDiskCache.WithCause(err)
+	}
+	defer unlock()
+
+	name := c.blobPath(e.Sha256Sum)
+
+	// The open file remains readable if the content is evicted by another process
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, ErrDiskCache.WithCause(err)
+	}
+
+	now := time.Now()
+	_ = os.Chtimes(name, now, now)
+
+	return f, nil
+}
+
+// create returns a diskCacheWriter which stores the content of the url by the key when it is committed
+func (c *DiskCache) create(key string, url string, etag string, lastModified string) (*diskCacheWriter, error) {
+	f, err := os.CreateTemp(filepath.Join(c.Dir, diskCacheTmpDir), "download-")
+	if err != nil {
+		return nil, ErrDiskCache.WithCause(err)
+	}
+
+	w := &diskCacheWriter{
+		cache: c,
+		entry: diskCacheEntry{
+			key:          key,
+			Url:          url,
+			ETag:         etag,
+			LastModified: lastModified,
+		},
+		file: f,
+		hash: sha256.New(),
+	}
+
+	return w, nil
+}
+
+// commit moves the temporary file into the cache and writes the entry of the key. The content of a replaced entry is
+// removed unless it is referenced by another entry.
+func (c *DiskCache) commit(tmp string, e diskCacheEntry) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	unlock, err := c.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	var replaced diskCacheEntry
+	if prevData, err := os.ReadFile(c.entryPath(e.key)); err == nil {
+		_ = json.Unmarshal(prevData, &replaced)
+	}
+
+	err = os.Rename(tmp, c.blobPath(e.Sha256Sum))
+	if err != nil {
+		return err
+	}
+
+	entryTmp := c.entryPath(e.key) + ".tmp"
+	err = os.WriteFile(entryTmp, data, 0600)
+	if err != nil {
+		return err
+	}
+
+	err = os.Rename(entryTmp, c.entryPath(e.key))
+	if err != nil {
+		return err
+	}
+
+	if replaced.Sha256Sum != "" && replaced.Sha256Sum != e.Sha256Sum {
+		err = c.removeUnreferenced(replaced.Sha256Sum)
+		if err != nil {
+			return err
+		}
+	}
+
+	return c.evict(e.Sha256Sum)
+}
+
+// removeUnreferenced removes the content with the checksum sha256sum if no entry references the content.
+// removeUnreferenced expects that the cache is locked.
+func (c *DiskCache) removeUnreferenced(sha256sum string) error {
+	entries, err := os.ReadDir(filepath.Join(c.Dir, diskCacheEntriesDir))
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		data, err := os.ReadFile(filepath.Join(c.Dir, diskCacheEntriesDir, entry.Name()))
+		if err != nil {
+			continue
+		}
+
+		e := &diskCacheEntry{}
+		if json.Unmarshal(data, e) == nil && e.Sha256Sum == sha256sum {
+			return nil
+		}
+	}
+
+	// Content which is open by another process may not be removable on some platforms and is left for eviction
+	_ = os.Remove(c.blobPath(sha256sum))
+
+	return nil
+}
+
+// evict removes the least recently used content except keep until the total size does not exceed MaxSize. Entries of
+// removed content and stale temporary files are removed as well. evict expects that the cache is locked.
+func (c *DiskCache) evict(keep string) error {
+	tmpFiles, err := os.ReadDir(filepath.Join(c.Dir, diskCacheTmpDir))
+	if err != nil {
+		return err
+	}
+
+	for _, tmpFile := range tmpFiles {
+		if info, err := tmpFile.Info(); err == nil && time.Since(info.ModTime()) > diskCacheTmpMaxAge {
+			_ = os.Remove(filepath.Join(c.Dir, diskCacheTmpDir, tmpFile.Name()))
+		}
+	}
+
+	if c.MaxSize <= 0 {
+		return nil
+	}
+
+	blobs, err := os.ReadDir(filepath.Join(c.Dir, diskCacheBlobsDir))
+	if err != nil {
+		return err
+	}
+
+	var infos []fs.FileInfo
+	var total int64
+	for _, blob := range blobs {
+		info, err := blob.Info()
+		if err != nil {
+			continue
+		}
+		infos = append(infos, info)
+		total += info.Size()
+	}
+
+	if total <= c.MaxSize {
+		return nil
+	}
+
+	// Least recently used first
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].ModTime().Before(infos[j].ModTime())
+	})
+
+	evicted := map[string]bool{}
+	for _, info := range infos {
+		if total <= c.MaxSize {
+			break
+		}
+
+		if info.Name() == keep {
+			continue
+		}
+
+		// Content which is open by another process may not be removable on some platforms
+		err = os.Remove(c.blobPath(info.Name()))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+
+		evicted[info.Name()] = true
+		total -= info.Size()
+	}
+
+	entries, err := os.ReadDir(filepath.Join(c.Dir, diskCacheEntriesDir))
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		name := filepath.Join(c.Dir, diskCacheEntriesDir, entry.Name())
+
+		data, err := os.ReadFile(name)
+		if err != nil {
+			continue
+		}
+
+		e := &diskCacheEntry{}
+		if json.Unmarshal(data, e) != nil || evicted[e.Sha256Sum] {
+			_ = os.Remove(name)
+		}
+	}
+
+	return nil
+}
+
+// diskCacheWriter writes the content of an url to a temporary file of the DiskCache
+type diskCacheWriter struct {
+	cache *DiskCache
+	entry diskCacheEntry
+	file  *os.File
+	hash  hash.Hash
+}
+
+var _ io.Writer = &diskCacheWriter{}
+
+func (w *diskCacheWriter) Write(p []byte) (int, error) {
+	n, err := w.file.Write(p)
+	w.hash.Write(p[:n])
+	w.entry.Size += int64(n)
+
+	return n, err
+}
+
+// Commit stores the written content in the cache if the content has the expected size. The expected size is ignored
+// if size is negative.
+func (w *diskCacheWriter) Commit(size int64) error {
+	err := w.file.Close()
+	if err != nil {
+		w.Abort()
+		return ErrDiskCache.WithCause(err)
+	}
+
+	if size >= 0 && size != w.entry.Size {
+		w.Abort()
+		return ErrDiskCache.WithCause(fmt.Errorf("expected %d bytes, got %d bytes", size, w.entry.Size))
+	}
+
+	w.entry.Sha256Sum = hex.EncodeToString(w.hash.Sum(nil))
+
+	err = w.cache.commit(w.file.Name(), w.entry)
+	if err != nil {
+		w.Abort()
+		return ErrDiskCache.WithCause(err)
+	}
+
+	return nil
+}
+
+// Abort removes the written content
+func (w *diskCacheWriter) Abort() {
+	_ = w.file.Close()
+	_ = os.Remove(w.file.Name())
+}
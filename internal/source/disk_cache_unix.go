@@ -0,0 +1,17 @@
+//go:build !windows
+
+package source
+
+import (
+	"os"
+	"syscall"
+)
+
+// lockFile acquires an exclusive lock of f. lockFile blocks until the lock is acquired.
+func lockFile(f *os.File) error {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_EX)
+}
+
+func unlockFile(f *os.File) error {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+}
@@ -0,0 +1,17 @@
+//go:build windows
+
+package source
+
+import (
+	"golang.org/x/sys/windows"
+	"os"
+)
+
+// lockFile acquires an exclusive lock of f. lockFile blocks until the lock is acquired.
+func lockFile(f *os.File) error {
+	return windows.LockFileEx(windows.Handle(f.Fd()), windows.LOCKFILE_EXCLUSIVE_LOCK, 0, 1, 0, &windows.Overlapped{})
+}
+
+func unlockFile(f *os.File) error {
+	return windows.UnlockFileEx(windows.Handle(f.Fd()), 0, 1, 0, &windows.Overlapped{})
+}
@@ -1,8 +1,8 @@
 package source
 
 import (
-	"crypto/md5"
 	"encoding/base64"
+	"encoding/hex"
 	"io"
 	"net/url"
 	"os"
@@ -43,14 +43,14 @@ func (c *File) Open(u *url.URL) (Source, error) {
 		fileInfo: fileInfo,
 	}
 
-	md5sum := md5.New()
-	hr := newHashReader(md5sum, file)
+	sums := newContentSums()
+	hr := newHashReader(sums.Writer(), file)
 	fs.readCloser = &readCloser{
 		reader: readerFunc(func(p []byte) (n int, err error) {
 			n, err = hr.Read(p)
 			if err == io.EOF {
-				if fs.md5sum == nil {
-					fs.md5sum = md5sum.Sum(nil)
+				if fs.sums == nil {
+					fs.sums = sums
 				}
 			}
 			return
@@ -73,7 +73,9 @@ type fileSource struct {
 	name     string
 	file     *os.File
 	fileInfo os.FileInfo
-	md5sum   []byte
+
+	// sums are available when the content has been read completely
+	sums *contentSums
 
 	readCloser io.ReadCloser
 }
@@ -88,7 +90,7 @@ func (s *fileSource) Close() error {
 	return s.readCloser.Close()
 }
 
-// Meta returns tags which includes TagMd5.
+// Meta returns tags which includes the md5 sum as ETag and the SHA-256 and SHA-512 checksums.
 // Meta must not be called simultaneously with Read.
 func (s *fileSource) Meta() (Meta, error) {
 	m := &httpMeta{
@@ -99,24 +101,30 @@ func (s *fileSource) Meta() (Meta, error) {
 		},
 	}
 
-	if s.md5sum == nil {
+	if s.sums == nil {
 		// Read has not been completed yet
 		// Therefore, read the entire file separately to calculate the hash
 		file, err := os.Open(s.name)
 		if err != nil {
 			return nil, ErrFileClient.WithCause(err)
 		}
+		defer func() {
+			_ = file.Close()
+		}()
 
-		md5sum, err := hashFromReader(md5.New(), file)
+		sums := newContentSums()
+		_, err = io.Copy(sums.Writer(), file)
 		if err != nil {
 			return nil, ErrFileClient.WithCause(err)
 		}
 
-		s.md5sum = md5sum
+		s.sums = sums
 	}
 
 	// Use base64 encoded md5 sum as entity tag
-	m.etag = base64.StdEncoding.EncodeToString(s.md5sum)
+	m.etag = base64.StdEncoding.EncodeToString(s.sums.md5.Sum(nil))
+	m.sha256sum = hex.EncodeToString(s.sums.sha256.Sum(nil))
+	m.sha512sum = hex.EncodeToString(s.sums.sha512.Sum(nil))
 
 	return m, nil
 }
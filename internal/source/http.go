@@ -1,11 +1,20 @@
 package source
 
 import (
+	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
 	"github.com/neuspaces/terraform-provider-system/internal/lib/etag"
+	"github.com/sethvargo/go-retry"
 	"io"
+	"net"
 	"net/http"
 	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
 )
 
 const HttpScheme = "http"
@@ -16,26 +25,126 @@ const httpClient = "http"
 
 type Http struct {
 	Client *http.Client
+
+	// Header contains the headers which are added to every request
+	Header http.Header
+
+	// Retries is the number of retries of a request which failed due to a network error or a temporary status such as
+	// 503 Service Unavailable. Interrupted downloads are resumed by a Range request if the server supports ranges.
+	Retries uint64
+
+	// RetryBackoff is the wait before the first retry. The wait doubles with every subsequent retry.
+	RetryBackoff time.Duration
+
+	// Cache optionally stores downloaded content. Cached content is validated by a conditional request with the ETag
+	// or the Last-Modified of the cached content.
+	Cache *DiskCache
 }
 
 var _ Client = &Http{}
 
 var ErrHttpClient = &Error{msg: "http client error"}
 
-func NewHttpClient() *Http {
-	return &Http{
-		Client: http.DefaultClient,
+type HttpOption func(*Http)
+
+// HttpHeader adds a header to every request
+func HttpHeader(key string, value string) HttpOption {
+	return func(c *Http) {
+		c.Header.Add(key, value)
+	}
+}
+
+// HttpBearerToken authenticates every request with the bearer token
+func HttpBearerToken(token string) HttpOption {
+	return func(c *Http) {
+		c.Header.Set("Authorization", "Bearer "+token)
+	}
+}
+
+// HttpTLSConfig sets the tls.Config of connections to https urls, e.g. to trust a custom certificate authority or to
+// authenticate with a client certificate
+func HttpTLSConfig(config *tls.Config) HttpOption {
+	return func(c *Http) {
+		c.transport().TLSClientConfig = config
+	}
+}
+
+// HttpTimeout limits the time to establish a connection and to receive the response headers
+func HttpTimeout(timeout time.Duration) HttpOption {
+	return func(c *Http) {
+		t := c.transport()
+		t.DialContext = (&net.Dialer{Timeout: timeout, KeepAlive: 30 * time.Second}).DialContext
+		t.TLSHandshakeTimeout = timeout
+		t.ResponseHeaderTimeout = timeout
+	}
+}
+
+// HttpRetries sets the number of retries of failed requests
+func HttpRetries(retries uint64) HttpOption {
+	return func(c *Http) {
+		c.Retries = retries
+	}
+}
+
+// HttpCache stores downloaded content in cache
+func HttpCache(cache *DiskCache) HttpOption {
+	return func(c *Http) {
+		c.Cache = cache
+	}
+}
+
+func NewHttpClient(opts ...HttpOption) *Http {
+	c := &Http{
+		Client: &http.Client{
+			Transport: http.DefaultTransport.(*http.Transport).Clone(),
+		},
+		Header:       http.Header{},
+		RetryBackoff: 1 * time.Second,
+	}
+
+	c.Configure(opts...)
+
+	return c
+}
+
+// Configure applies opts to the client. Configure must not be invoked concurrently with Open.
+func (c *Http) Configure(opts ...HttpOption) {
+	for _, opt := range opts {
+		opt(c)
 	}
 }
 
+// transport returns the *http.Transport of the client
+func (c *Http) transport() *http.Transport {
+	t, isTransport := c.Client.Transport.(*http.Transport)
+	if !isTransport {
+		t = http.DefaultTransport.(*http.Transport).Clone()
+		c.Client.Transport = t
+	}
+
+	return t
+}
+
 // Open returns a Client for the provided url.
 // When Open is invoked, the client will send a HEAD request. Any error except HTTP Method Not Found from the HEAD request will be passed to the caller.
-// The returned Client will send a GET request when Read is called for the first time
+// The returned Client will send a GET request when Read is called for the first time. If the content is cached and the
+// cached content is valid, the returned Client reads the cached content.
 func (c *Http) Open(u *url.URL) (Source, error) {
 	urlStr := u.String()
 
+	if c.Cache != nil {
+		s, err := c.openCached(c.cacheKey(urlStr), urlStr)
+		if err != nil {
+			return nil, ErrHttpClient.WithCause(err)
+		}
+
+		if s != nil {
+			return s, nil
+		}
+	}
+
 	// HEAD request
-	resp, err := c.Client.Head(urlStr)
+	resp, err := c.do(http.MethodHead, urlStr, nil)
 	if err != nil {
 		return nil, ErrHttpClient.WithCause(err)
 	}
@@ -50,7 +159,7 @@ func (c *Http) Open(u *url.URL) (Source, error) {
 		// When status is "405 Method Not Allowed", perform a GET without reading the response
 
 		// GET request
-		resp, err = c.Client.Get(urlStr)
+		resp, err = c.do(http.MethodGet, urlStr, nil)
 		if err != nil {
 			return nil, ErrHttpClient.WithCause(err)
 		}
@@ -59,20 +168,149 @@ func (c *Http) Open(u *url.URL) (Source, error) {
 	}
 
 	if resp.StatusCode != http.StatusOK {
+		_ = resp.Body.Close()
 		return nil, ErrHttpClient.WithCause(fmt.Errorf("status %d %s", resp.StatusCode, resp.Status))
 	}
 
 	s := &httpSource{
 		url: urlStr,
-		reqFunc: func() (*http.Response, error) {
-			return c.Client.Get(urlStr)
+		reqFunc: func(header http.Header) (*http.Response, error) {
+			return c.do(http.MethodGet, urlStr, header)
 		},
-		resp: resp,
+		resp:       resp,
+		maxResumes: c.Retries,
+		cache:      c.Cache,
+		cacheKey:   c.cacheKey(urlStr),
+	}
+
+	if resp.Request.Method == http.MethodGet {
+		s.startCache()
 	}
 
 	return s, nil
 }
 
+// cacheKey returns the key of the cached content of the url. The key contains the headers of the client, e.g. the
+// Authorization header, so that content downloaded with different credentials is cached separately.
+func (c *Http) cacheKey(urlStr string) string {
+	var b strings.Builder
+	b.WriteString(urlStr)
+
+	keys := make([]string, 0, len(c.Header))
+	for key := range c.Header {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		for _, value := range c.Header[key] {
+			b.WriteString("\n" + key + ": " + value)
+		}
+	}
+
+	return b.String()
+}
+
+// openCached returns a Source of the cached content of the url if the cached content is valid. openCached returns nil
+// if the url is not cached or the cached content is not valid.
+func (c *Http) openCached(key string, urlStr string) (Source, error) {
+	entry, err := c.Cache.lookup(key, urlStr)
+	if err != nil || entry == nil {
+		// Download if the cache is not available
+		return nil, nil
+	}
+
+	header := http.Header{}
+	if entry.ETag != "" {
+		header.Set("If-None-Match", entry.ETag)
+	}
+	if entry.LastModified != "" {
+		header.Set("If-Modified-Since", entry.LastModified)
+	}
+
+	resp, err := c.do(http.MethodHead, urlStr, header)
+	if err != nil {
+		return nil, err
+	}
+	_ = resp.Body.Close()
+
+	valid := resp.StatusCode == http.StatusNotModified || (resp.StatusCode == http.StatusOK && httpValidates(entry, resp.Header))
+	if !valid {
+		return nil, nil
+	}
+
+	f, err := c.Cache.open(entry)
+	if err != nil {
+		// Download if the cached content has been evicted
+		return nil, nil
+	}
+
+	return &httpCachedSource{
+		url:   urlStr,
+		entry: entry,
+		file:  f,
+	}, nil
+}
+
+// httpValidates returns true if the ETag or the Last-Modified of header matches the cached entry
+func httpValidates(entry *diskCacheEntry, header http.Header) bool {
+	if entry.ETag != "" {
+		return header.Get(etag.HeaderKey) == entry.ETag
+	}
+
+	return entry.LastModified != "" && header.Get("Last-Modified") == entry.LastModified
+}
+
+// do sends a request with the headers of the client and header. The request is retried if it fails due to a network
+// error or a temporary status.
+func (c *Http) do(method string, urlStr string, header http.Header) (*http.Response, error) {
+	var resp *http.Response
+
+	backoff := retry.WithMaxRetries(c.Retries, retry.NewExponential(c.RetryBackoff))
+
+	err := retry.Do(context.Background(), backoff, func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, method, urlStr, nil)
+		if err != nil {
+			return err
+		}
+
+		for _, h := range []http.Header{c.Header, header} {
+			for key, values := range h {
+				for _, value := range values {
+					req.Header.Add(key, value)
+				}
+			}
+		}
+
+		resp, err = c.Client.Do(req)
+		if err != nil {
+			return retry.RetryableError(err)
+		}
+
+		if httpTemporaryStatus(resp.StatusCode) {
+			_ = resp.Body.Close()
+			return retry.RetryableError(fmt.Errorf("status %d %s", resp.StatusCode, resp.Status))
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// httpTemporaryStatus returns true if a request which failed with the status code should be retried
+func httpTemporaryStatus(code int) bool {
+	switch code {
+	case http.StatusRequestTimeout, http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+
+	return false
+}
+
 func (c *Http) Schemes() []string {
 	return []string{
 		HttpScheme,
@@ -83,17 +321,54 @@ func (c *Http) Schemes() []string {
 type httpSource struct {
 	url string
 
-	// reqFunc performs the GET request
-	reqFunc func() (*http.Response, error)
+	// reqFunc performs the GET request with additional headers
+	reqFunc func(header http.Header) (*http.Response, error)
 
 	// resp holds the *http.Response from the GET request
 	resp *http.Response
 
+	// offset is the number of bytes which have been read
+	offset int64
+
+	// resumes is the number of Range requests which resumed an interrupted download
+	resumes    uint64
+	maxResumes uint64
+
+	// cache stores the content by cacheKey if the response has a strong ETag or a Last-Modified
+	cache       *DiskCache
+	cacheKey    string
+	cacheWriter *diskCacheWriter
+
 	closed bool
 }
 
 var _ Source = &httpSource{}
 
+// startCache writes the content of the response to the cache while the content is read
+func (s *httpSource) startCache() {
+	if s.cache == nil {
+		return
+	}
+
+	etagHeader := s.resp.Header.Get(etag.HeaderKey)
+	if etagStruct, err := etag.Parse(etagHeader); err != nil || etagStruct.Weak {
+		etagHeader = ""
+	}
+
+	lastModified := s.resp.Header.Get("Last-Modified")
+	if etagHeader == "" && lastModified == "" {
+		// The cached content could not be validated
+		return
+	}
+
+	w, err := s.cache.create(s.cacheKey, s.url, etagHeader, lastModified)
+	if err != nil {
+		return
+	}
+
+	s.cacheWriter = w
+}
+
 func (s *httpSource) Read(p []byte) (int, error) {
 	if s.closed {
 		return 0, io.EOF
@@ -101,21 +376,93 @@ func (s *httpSource) Read(p []byte) (int, error) {
 
 	if s.resp == nil || s.resp.Request.Method == http.MethodHead {
 		// Perform lazy GET request
-		resp, err := s.reqFunc()
+		resp, err := s.reqFunc(nil)
 		if err != nil {
 			return 0, err
 		}
 
+		if resp.StatusCode != http.StatusOK {
+			_ = resp.Body.Close()
+			return 0, ErrHttpClient.WithCause(fmt.Errorf("status %d %s", resp.StatusCode, resp.Status))
+		}
+
 		// Replace response
 		s.resp = resp
+		s.startCache()
 	}
 
-	return s.resp.Body.Read(p)
+	n, err := s.resp.Body.Read(p)
+	s.offset += int64(n)
+
+	if s.cacheWriter != nil {
+		// Failures of the cache do not fail the download
+		if _, writeErr := s.cacheWriter.Write(p[:n]); writeErr != nil {
+			s.cacheWriter.Abort()
+			s.cacheWriter = nil
+		} else if errors.Is(err, io.EOF) {
+			_ = s.cacheWriter.Commit(s.resp.ContentLength)
+			s.cacheWriter = nil
+		}
+	}
+
+	if err != nil && !errors.Is(err, io.EOF) {
+		// Resume the interrupted download
+		if resumeErr := s.resume(); resumeErr == nil {
+			if n > 0 {
+				return n, nil
+			}
+
+			return s.Read(p)
+		}
+	}
+
+	return n, err
+}
+
+// resume replaces the response by a Range request for the content after offset. resume fails if the server does not
+// support ranges or the content has changed since the download has started.
+func (s *httpSource) resume() error {
+	if s.resumes >= s.maxResumes || s.resp.Header.Get("Accept-Ranges") != "bytes" {
+		return fmt.Errorf("download cannot be resumed")
+	}
+	s.resumes++
+
+	header := http.Header{}
+	header.Set("Range", fmt.Sprintf("bytes=%d-", s.offset))
+
+	// Resume only if the content is unchanged. If-Range requires a strong ETag.
+	if etagStruct, err := etag.Parse(s.resp.Header.Get(etag.HeaderKey)); err == nil && !etagStruct.Weak {
+		header.Set("If-Range", s.resp.Header.Get(etag.HeaderKey))
+	} else if lastModified := s.resp.Header.Get("Last-Modified"); lastModified != "" {
+		header.Set("If-Range", lastModified)
+	}
+
+	resp, err := s.reqFunc(header)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusPartialContent {
+		_ = resp.Body.Close()
+		return fmt.Errorf("status %d %s", resp.StatusCode, resp.Status)
+	}
+
+	// Retain the headers of the complete content
+	_ = s.resp.Body.Close()
+	s.resp.Body = resp.Body
+
+	return nil
 }
 
 func (s *httpSource) Close() error {
 	s.closed = true
 
+	// Discard incomplete content
+	if s.cacheWriter != nil {
+		s.cacheWriter.Abort()
+		s.cacheWriter = nil
+	}
+
 	if s.resp != nil {
 		err := s.resp.Body.Close()
 		if err != nil {
@@ -136,20 +483,25 @@ func (s *httpSource) Meta() (Meta, error) {
 			client: httpClient,
 			url:    s.url,
 			size:   s.resp.ContentLength,
-			etag:   "",
+			etag:   httpMetaETag(s.resp.Header.Get(etag.HeaderKey)),
 		},
 	}
 
-	etagHeader := s.resp.Header.Get(etag.HeaderKey)
-	if etagHeader != "" {
-		etagStruct, err := etag.Parse(etagHeader)
-		if err == nil && !etagStruct.Weak {
-			// Ignore invalid or weak ETags
-			m.etag = etagStruct.ETag
-		}
+	return m, nil
+}
+
+// httpMetaETag returns the ETag of an ETag header. Invalid or weak ETags are ignored.
+func httpMetaETag(etagHeader string) string {
+	if etagHeader == "" {
+		return ""
 	}
 
-	return m, nil
+	etagStruct, err := etag.Parse(etagHeader)
+	if err != nil || etagStruct.Weak {
+		return ""
+	}
+
+	return etagStruct.ETag
 }
 
 type httpMeta struct {
@@ -157,3 +509,33 @@ type httpMeta struct {
 }
 
 var _ Meta = &httpMeta{}
+
+// httpCachedSource reads the cached content of an url
+type httpCachedSource struct {
+	url   string
+	entry *diskCacheEntry
+	file  *os.File
+}
+
+var _ Source = &httpCachedSource{}
+
+func (s *httpCachedSource) Read(p []byte) (int, error) {
+	return s.file.Read(p)
+}
+
+func (s *httpCachedSource) Close() error {
+	return s.file.Close()
+}
+
+// Meta returns the meta of the cached content including the checksum of the content
+func (s *httpCachedSource) Meta() (Meta, error) {
+	return &httpMeta{
+		meta{
+			client:    httpClient,
+			url:       s.url,
+			size:      s.entry.Size,
+			etag:      httpMetaETag(s.entry.ETag),
+			sha256sum: s.entry.Sha256Sum,
+		},
+	}, nil
+}
@@ -1 +1,286 @@
 package source_test
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/neuspaces/terraform-provider-system/internal/source"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func openHttp(t *testing.T, c *source.Http, rawurl string) (source.Source, error) {
+	u, err := url.Parse(rawurl)
+	require.NoError(t, err)
+
+	return c.Open(u)
+}
+
+func TestHttp_Header(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" || r.Header.Get("X-Tenant") != "test" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		_, _ = w.Write([]byte("hello world!"))
+	}))
+	t.Cleanup(server.Close)
+
+	_, err := openHttp(t, source.NewHttpClient(), server.URL)
+	assert.ErrorIs(t, err, source.ErrHttpClient)
+
+	s, err := openHttp(t, source.NewHttpClient(source.HttpBearerToken("secret"), source.HttpHeader("X-Tenant", "test")), server.URL)
+	require.NoError(t, err)
+	defer func() {
+		_ = s.Close()
+	}()
+
+	content, err := io.ReadAll(s)
+	require.NoError(t, err)
+	assert.Equal(t, "hello world!", string(content))
+}
+
+func TestHttp_Retries(t *testing.T) {
+	t.Parallel()
+
+	var requests atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Every other request fails temporarily
+		if requests.Add(1)%2 == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		_, _ = w.Write([]byte("hello world!"))
+	}))
+	t.Cleanup(server.Close)
+
+	_, err := openHttp(t, source.NewHttpClient(), server.URL)
+	assert.ErrorIs(t, err, source.ErrHttpClient)
+
+	c := source.NewHttpClient(source.HttpRetries(1))
+	c.RetryBackoff = 10 * time.Millisecond
+
+	s, err := openHttp(t, c, server.URL)
+	require.NoError(t, err)
+	defer func() {
+		_ = s.Close()
+	}()
+
+	content, err := io.ReadAll(s)
+	require.NoError(t, err)
+	assert.Equal(t, "hello world!", string(content))
+}
+
+func TestHttp_Resume(t *testing.T) {
+	t.Parallel()
+
+	content := bytes.Repeat([]byte("0123456789"), 10000)
+	modTime := time.Now()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+
+		if r.Method == http.MethodGet && r.Header.Get("Range") == "" {
+			// Interrupt the download of the complete content
+			w.Header().Set("Accept-Ranges", "bytes")
+			w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+			_, _ = w.Write(content[:len(content)/3])
+			w.(http.Flusher).Flush()
+			panic(http.ErrAbortHandler)
+		}
+
+		http.ServeContent(w, r, "", modTime, bytes.NewReader(content))
+	}))
+	t.Cleanup(server.Close)
+
+	s, err := openHttp(t, source.NewHttpClient(), server.URL)
+	require.NoError(t, err)
+	_, err = io.ReadAll(s)
+	assert.Error(t, err)
+	_ = s.Close()
+
+	s, err = openHttp(t, source.NewHttpClient(source.HttpRetries(1)), server.URL)
+	require.NoError(t, err)
+	defer func() {
+		_ = s.Close()
+	}()
+
+	actual, err := io.ReadAll(s)
+	require.NoError(t, err)
+	assert.Equal(t, content, actual)
+
+	m, err := s.Meta()
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(content)), m.Size())
+	assert.Equal(t, "v1", m.ETag())
+}
+
+func TestHttp_Cache(t *testing.T) {
+	t.Parallel()
+
+	var gets atomic.Int32
+	var version atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			gets.Add(1)
+		}
+
+		v := version.Load()
+		w.Header().Set("ETag", fmt.Sprintf(`"v%d"`, v))
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader([]byte(fmt.Sprintf("%s version %d", r.URL.Path, v))))
+	}))
+	t.Cleanup(server.Close)
+
+	cacheDir := t.TempDir()
+	cache, err := source.NewDiskCache(cacheDir, 0)
+	require.NoError(t, err)
+
+	c := source.NewHttpClient(source.HttpCache(cache))
+
+	read := func(path string) (string, source.Meta) {
+		s, err := openHttp(t, c, server.URL+path)
+		require.NoError(t, err)
+		defer func() {
+			_ = s.Close()
+		}()
+
+		content, err := io.ReadAll(s)
+		require.NoError(t, err)
+
+		m, err := s.Meta()
+		require.NoError(t, err)
+
+		return string(content), m
+	}
+
+	content, _ := read("/a")
+	assert.Equal(t, "/a version 0", content)
+	assert.Equal(t, int32(1), gets.Load())
+
+	// Cached content is valid
+	content, m := read("/a")
+	assert.Equal(t, "/a version 0", content)
+	assert.Equal(t, int32(1), gets.Load())
+	assert.Equal(t, "v0", m.ETag())
+	// echo -n '/a version 0' | sha256sum
+	assert.Equal(t, "31a47739c13037a139b35ffb62b1a1a383ae7c2968c6f7231e02eae3846fb525", m.Sha256Sum())
+
+	// Cached content is not valid after the content has changed
+	version.Add(1)
+	content, _ = read("/a")
+	assert.Equal(t, "/a version 1", content)
+	assert.Equal(t, int32(2), gets.Load())
+
+	content, _ = read("/a")
+	assert.Equal(t, "/a version 1", content)
+	assert.Equal(t, int32(2), gets.Load())
+
+	// Content of the replaced entry has been removed
+	blobs, err := os.ReadDir(filepath.Join(cacheDir, "blobs"))
+	require.NoError(t, err)
+	assert.Len(t, blobs, 1)
+}
+
+func TestHttp_CacheHeaders(t *testing.T) {
+	t.Parallel()
+
+	var gets atomic.Int32
+
+	// The server validates the ETag regardless of the credentials
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			gets.Add(1)
+		}
+
+		w.Header().Set("ETag", `"v1"`)
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader([]byte(r.Header.Get("Authorization"))))
+	}))
+	t.Cleanup(server.Close)
+
+	cache, err := source.NewDiskCache(t.TempDir(), 0)
+	require.NoError(t, err)
+
+	read := func(token string) string {
+		c := source.NewHttpClient(source.HttpCache(cache), source.HttpBearerToken(token))
+
+		s, err := openHttp(t, c, server.URL+"/a")
+		require.NoError(t, err)
+		defer func() {
+			_ = s.Close()
+		}()
+
+		content, err := io.ReadAll(s)
+		require.NoError(t, err)
+
+		return string(content)
+	}
+
+	assert.Equal(t, "Bearer first", read("first"))
+	assert.Equal(t, int32(1), gets.Load())
+
+	// Content cached with other credentials is not shared
+	assert.Equal(t, "Bearer second", read("second"))
+	assert.Equal(t, int32(2), gets.Load())
+
+	assert.Equal(t, "Bearer first", read("first"))
+	assert.Equal(t, int32(2), gets.Load())
+}
+
+func TestHttp_CacheEviction(t *testing.T) {
+	t.Parallel()
+
+	var gets atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			gets.Add(1)
+		}
+
+		w.Header().Set("ETag", `"v1"`)
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(bytes.Repeat([]byte(r.URL.Path), 100)))
+	}))
+	t.Cleanup(server.Close)
+
+	// Cache holds the content of a single url
+	cache, err := source.NewDiskCache(t.TempDir(), 300)
+	require.NoError(t, err)
+
+	c := source.NewHttpClient(source.HttpCache(cache))
+
+	read := func(path string) {
+		s, err := openHttp(t, c, server.URL+path)
+		require.NoError(t, err)
+		defer func() {
+			_ = s.Close()
+		}()
+
+		_, err = io.ReadAll(s)
+		require.NoError(t, err)
+	}
+
+	read("/a")
+	read("/a")
+	assert.Equal(t, int32(1), gets.Load())
+
+	read("/b")
+	assert.Equal(t, int32(2), gets.Load())
+
+	// Content of /a has been evicted
+	read("/a")
+	assert.Equal(t, int32(3), gets.Load())
+}
@@ -5,13 +5,21 @@ type Meta interface {
 	Url() string
 	Size() int64
 	ETag() string
+
+	// Sha256Sum returns the SHA-256 checksum of the content in hexadecimal encoding or an empty string if unknown
+	Sha256Sum() string
+
+	// Sha512Sum returns the SHA-512 checksum of the content in hexadecimal encoding or an empty string if unknown
+	Sha512Sum() string
 }
 
 type meta struct {
-	client string
-	url    string
-	size   int64
-	etag   string
+	client    string
+	url       string
+	size      int64
+	etag      string
+	sha256sum string
+	sha512sum string
 }
 
 var _ Meta = &meta{}
@@ -31,3 +39,11 @@ func (m *meta) Size() int64 {
 func (m *meta) ETag() string {
 	return m.etag
 }
+
+func (m *meta) Sha256Sum() string {
+	return m.sha256sum
+}
+
+func (m *meta) Sha512Sum() string {
+	return m.sha512sum
+}
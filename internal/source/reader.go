@@ -1,6 +1,9 @@
 package source
 
 import (
+	"crypto/md5"
+	"crypto/sha256"
+	"crypto/sha512"
 	"hash"
 	"io"
 )
@@ -37,7 +40,7 @@ func (r *readCloser) Close() error {
 	return r.closer.Close()
 }
 
-func newHashReader(h hash.Hash, rc io.ReadCloser) io.ReadCloser {
+func newHashReader(h io.Writer, rc io.ReadCloser) io.ReadCloser {
 	tee := io.TeeReader(rc, h)
 	return &readCloser{
 		reader: tee,
@@ -55,3 +58,23 @@ func hashFromReader(h hash.Hash, r io.Reader) ([]byte, error) {
 
 	return h.Sum(nil), nil
 }
+
+// contentSums are the checksums of a content
+type contentSums struct {
+	md5    hash.Hash
+	sha256 hash.Hash
+	sha512 hash.Hash
+}
+
+func newContentSums() *contentSums {
+	return &contentSums{
+		md5:    md5.New(),
+		sha256: sha256.New(),
+		sha512: sha512.New(),
+	}
+}
+
+// Writer returns an io.Writer which writes to all hashes
+func (s *contentSums) Writer() io.Writer {
+	return io.MultiWriter(s.md5, s.sha256, s.sha512)
+}
@@ -1,6 +1,7 @@
 package source
 
 import (
+	"encoding/hex"
 	"fmt"
 	"net/url"
 )
@@ -74,6 +75,19 @@ func WithDefaultScheme(scheme string) RegistryOption {
 	}
 }
 
+// Supports returns true if a Client is registered for the scheme of the url or for the default scheme if the url does
+// not contain a scheme
+func (r *Registry) Supports(u *url.URL) bool {
+	scheme := u.Scheme
+	if scheme == "" {
+		scheme = r.defaultScheme
+	}
+
+	_, clientExists := r.clients[scheme]
+
+	return clientExists
+}
+
 // Open returns a source for the provided raw url
 func (r *Registry) Open(rawurl string) (Source, error) {
 	u, err := url.Parse(rawurl)
@@ -84,11 +98,18 @@ func (r *Registry) Open(rawurl string) (Source, error) {
 	return r.OpenUrl(u)
 }
 
-// OpenUrl returns a source for the provided url
+// OpenUrl returns a source for the provided url. If the url contains the query parameter QueryExpectedSha256, the
+// content of the returned source is verified before it is returned by Read. The expected checksum of a *RemoteSource
+// is provided as RemoteSource.ExpectedSha256 instead.
 func (r *Registry) OpenUrl(u *url.URL) (Source, error) {
 	// Copy url struct
 	localU := *u
 
+	expectedSha256, err := expectedSha256FromUrl(&localU)
+	if err != nil {
+		return nil, ErrRegistryOpen.WithCause(err)
+	}
+
 	// Fallback to default scheme if provided
 	if localU.Scheme == "" && r.defaultScheme != "" {
 		localU.Scheme = r.defaultScheme
@@ -104,5 +125,19 @@ func (r *Registry) OpenUrl(u *url.URL) (Source, error) {
 		return nil, ErrRegistryOpen.WithCause(fmt.Errorf(`scheme "%s" is not supported`, localU.Scheme))
 	}
 
-	return client.Open(&localU)
+	s, err := client.Open(&localU)
+	if err != nil {
+		return nil, err
+	}
+
+	if expectedSha256 != nil {
+		// The content of a remote source is not read by the provider and is verified on the system by the consumer
+		if remote, isRemote := s.(*RemoteSource); isRemote {
+			remote.ExpectedSha256 = hex.EncodeToString(expectedSha256)
+		} else {
+			s = newVerifiedSource(s, &localU, expectedSha256)
+		}
+	}
+
+	return s, nil
 }
@@ -0,0 +1,78 @@
+package source
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+const RemoteScheme = "remote"
+
+const remoteClient = "remote"
+
+// Remote is a Client for files which already exist on the system managed by the provider. The content of a
+// RemoteSource is not read by the provider. Instead, the consumer copies the file on the system at Path.
+type Remote struct {
+}
+
+var _ Client = &Remote{}
+
+var ErrRemoteClient = &Error{msg: "remote client error"}
+
+func NewRemoteClient() *Remote {
+	return &Remote{}
+}
+
+// Open returns a *RemoteSource for urls of the form remote:///absolute/path
+func (c *Remote) Open(u *url.URL) (Source, error) {
+	name := u.Host + u.Path
+
+	if !strings.HasPrefix(name, "/") {
+		return nil, ErrRemoteClient.WithCause(fmt.Errorf("path %q is not absolute, use an url like remote:///path/to/file", name))
+	}
+
+	return &RemoteSource{
+		Path: name,
+		url:  u.String(),
+	}, nil
+}
+
+func (c *Remote) Schemes() []string {
+	return []string{
+		RemoteScheme,
+	}
+}
+
+// RemoteSource is a file on the system managed by the provider
+type RemoteSource struct {
+	// Path is the absolute path of the file on the system
+	Path string
+
+	// ExpectedSha256 is the expected SHA-256 checksum of the file in hexadecimal encoding if the url contains the query
+	// parameter QueryExpectedSha256. The consumer verifies the checksum on the system.
+	ExpectedSha256 string
+
+	url string
+}
+
+var _ Source = &RemoteSource{}
+
+// Read returns an error because the content of the file is not accessible to the provider
+func (s *RemoteSource) Read(_ []byte) (int, error) {
+	return 0, ErrRemoteClient.WithCause(fmt.Errorf("content of %q is copied on the system and cannot be read", s.url))
+}
+
+func (s *RemoteSource) Close() error {
+	return nil
+}
+
+// Meta returns the url as ETag because the content is not accessible to the provider
+func (s *RemoteSource) Meta() (Meta, error) {
+	return &meta{
+		client:    remoteClient,
+		url:       s.url,
+		size:      -1,
+		etag:      s.url,
+		sha256sum: s.ExpectedSha256,
+	}, nil
+}
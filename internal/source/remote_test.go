@@ -0,0 +1,95 @@
+package source_test
+
+import (
+	"github.com/neuspaces/terraform-provider-system/internal/source"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"testing"
+)
+
+func TestRemote_Open(t *testing.T) {
+	t.Parallel()
+
+	registry, err := source.NewRegistry(
+		source.WithClient(source.NewRemoteClient()),
+	)
+	require.NoError(t, err)
+
+	type testCase struct {
+		Desc       string
+		Url        string
+		ExpectPath string
+		ExpectErr  error
+	}
+
+	tcs := []testCase{
+		{
+			Desc:       "absolute path",
+			Url:        "remote:///etc/nginx/nginx.conf",
+			ExpectPath: "/etc/nginx/nginx.conf",
+		},
+		{
+			Desc:      "relative path",
+			Url:       "remote://nginx.conf",
+			ExpectErr: source.ErrRemoteClient,
+		},
+	}
+
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.Desc, func(t *testing.T) {
+			t.Parallel()
+
+			s, err := registry.Open(tc.Url)
+			if tc.ExpectErr != nil {
+				assert.ErrorIs(t, err, tc.ExpectErr)
+				return
+			}
+			require.NoError(t, err)
+			defer func() {
+				_ = s.Close()
+			}()
+
+			remote, isRemote := s.(*source.RemoteSource)
+			require.True(t, isRemote)
+			assert.Equal(t, tc.ExpectPath, remote.Path)
+
+			m, err := s.Meta()
+			require.NoError(t, err)
+			assert.Equal(t, tc.Url, m.ETag())
+
+			_, err = io.ReadAll(s)
+			assert.ErrorIs(t, err, source.ErrRemoteClient)
+		})
+	}
+}
+
+func TestRemote_Open_ExpectedSha256(t *testing.T) {
+	t.Parallel()
+
+	registry, err := source.NewRegistry(
+		source.WithClient(source.NewRemoteClient()),
+	)
+	require.NoError(t, err)
+
+	// echo -n 'hello world!' | sha256sum
+	s, err := registry.Open("remote:///etc/app.conf?b=1&a=%2f&expected_sha256=7509e5bda0c762d2bac7f90d758b5b2263fa01ccbc542ab5e3df163be08e6ca9")
+	require.NoError(t, err)
+	defer func() {
+		_ = s.Close()
+	}()
+
+	// The source is not wrapped because the checksum is verified on the system
+	remote, isRemote := s.(*source.RemoteSource)
+	require.True(t, isRemote)
+	assert.Equal(t, "/etc/app.conf", remote.Path)
+	assert.Equal(t, "7509e5bda0c762d2bac7f90d758b5b2263fa01ccbc542ab5e3df163be08e6ca9", remote.ExpectedSha256)
+
+	m, err := s.Meta()
+	require.NoError(t, err)
+	assert.Equal(t, "7509e5bda0c762d2bac7f90d758b5b2263fa01ccbc542ab5e3df163be08e6ca9", m.Sha256Sum())
+
+	// Other query parameters are retained verbatim
+	assert.Equal(t, "remote:///etc/app.conf?b=1&a=%2f", m.ETag())
+}
@@ -0,0 +1,237 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/neuspaces/terraform-provider-system/internal/lib/homedir"
+	"github.com/neuspaces/terraform-provider-system/internal/sshclient"
+	"github.com/pkg/sftp"
+	"io/fs"
+	"net/url"
+	"os"
+	"os/user"
+	"strconv"
+	"time"
+)
+
+const SftpScheme = "sftp"
+
+const ScpScheme = "scp"
+
+const sftpClient = "sftp"
+
+// sftpDefaultIdentityFiles are the private keys which authenticate the user if they exist, equivalent to the default
+// identity files of OpenSSH
+var sftpDefaultIdentityFiles = []string{
+	"~/.ssh/id_ed25519",
+	"~/.ssh/id_ecdsa",
+	"~/.ssh/id_rsa",
+}
+
+// Sftp is a Client which reads files from another ssh host over the SFTP subsystem. Urls have the form
+// sftp://user@host:port/absolute/path. Urls with the scheme scp are read over the SFTP subsystem as well.
+type Sftp struct {
+	// ConnectOptions authenticate the user and verify the host key. The address and the user are set from the url.
+	ConnectOptions []sshclient.ConnectOption
+
+	Timeout time.Duration
+}
+
+var _ Client = &Sftp{}
+
+var ErrSftpClient = &Error{msg: "sftp client error"}
+
+// ErrSftpUrlPassword is returned for urls which contain a password
+var ErrSftpUrlPassword = &Error{msg: "password in url is not supported"}
+
+// NewSftpClient returns a Sftp client. If opts is empty, the user is authenticated by the identities of the ssh agent, if
+// available, and the default identity files in ~/.ssh which are not encrypted, and the host key is verified by
+// ~/.ssh/known_hosts. Urls must not contain a password because urls are not treated as secrets; provide a password by
+// sshclient.Password in opts instead.
+func NewSftpClient(opts ...sshclient.ConnectOption) *Sftp {
+	return &Sftp{
+		ConnectOptions: opts,
+		Timeout:        30 * time.Second,
+	}
+}
+
+func (c *Sftp) Open(u *url.URL) (Source, error) {
+	if u.Host == "" || u.Path == "" {
+		return nil, ErrSftpClient.WithCause(fmt.Errorf("expected an url like %s://user@host/path/to/file", u.Scheme))
+	}
+
+	if _, hasPassword := u.User.Password(); hasPassword {
+		return nil, ErrSftpClient.WithCause(ErrSftpUrlPassword)
+	}
+
+	connectOpts, err := c.connectOptions(u)
+	if err != nil {
+		return nil, ErrSftpClient.WithCause(err)
+	}
+
+	connect, err := sshclient.Prepare(connectOpts...)
+	if err != nil {
+		return nil, ErrSftpClient.WithCause(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.Timeout)
+	defer cancel()
+
+	sshClient := sshclient.New(connect)
+	err = sshClient.Connect(ctx)
+	if err != nil {
+		return nil, ErrSftpClient.WithCause(err)
+	}
+
+	s := &sftpSource{
+		url:       u.String(),
+		sshClient: sshClient,
+	}
+
+	s.sftpClient, err = sftp.NewClient(sshClient.Client)
+	if err != nil {
+		_ = s.Close()
+		return nil, ErrSftpClient.WithCause(err)
+	}
+
+	s.file, err = s.sftpClient.Open(u.Path)
+	if err != nil {
+		_ = s.Close()
+		return nil, ErrSftpClient.WithCause(err)
+	}
+
+	s.fileInfo, err = s.file.Stat()
+	if err != nil {
+		_ = s.Close()
+		return nil, ErrSftpClient.WithCause(err)
+	}
+
+	if !s.fileInfo.Mode().IsRegular() {
+		_ = s.Close()
+		return nil, ErrSftpClient.WithCause(fmt.Errorf("%q is not a regular file", u.Path))
+	}
+
+	return s, nil
+}
+
+// connectOptions returns the sshclient.ConnectOption to connect to the host of u
+func (c *Sftp) connectOptions(u *url.URL) ([]sshclient.ConnectOption, error) {
+	port := 22
+	if u.Port() != "" {
+		var err error
+		port, err = strconv.Atoi(u.Port())
+		if err != nil {
+			return nil, fmt.Errorf("invalid port %q", u.Port())
+		}
+	}
+
+	userName := u.User.Username()
+	if userName == "" {
+		current, err := user.Current()
+		if err != nil {
+			return nil, err
+		}
+		userName = current.Username
+	}
+
+	opts := []sshclient.ConnectOption{
+		sshclient.Addr(sshclient.NewHostPortAddr("tcp", u.Hostname(), uint16(port))),
+		sshclient.Net(sshclient.Dial(sshclient.NewHostPortAddr("tcp", u.Hostname(), uint16(port)), c.Timeout)),
+		sshclient.User(userName),
+		sshclient.Timeout(c.Timeout),
+	}
+
+	if len(c.ConnectOptions) > 0 {
+		opts = append(opts, c.ConnectOptions...)
+	} else {
+		defaultOpts, err := sftpDefaultConnectOptions()
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, defaultOpts...)
+	}
+
+	return opts, nil
+}
+
+// sftpDefaultConnectOptions returns the default authentication methods and host key verification. The identities of
+// the agent and of the identity files are offered by a single public key method. Identities which are unavailable,
+// encrypted or cannot be parsed are skipped.
+func sftpDefaultConnectOptions() ([]sshclient.ConnectOption, error) {
+	var signers []sshclient.SignersFunc
+
+	if os.Getenv("SSH_AUTH_SOCK") != "" {
+		signers = append(signers, sshclient.OptionalSigners(sshclient.AgentSigners()))
+	}
+
+	for _, identityFile := range sftpDefaultIdentityFiles {
+		name, err := homedir.Expand(identityFile)
+		if err != nil {
+			return nil, err
+		}
+
+		privateKey, err := os.ReadFile(name)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		signers = append(signers, sshclient.OptionalSigners(sshclient.PrivateKeySigners(string(privateKey), "")))
+	}
+
+	return []sshclient.ConnectOption{
+		sshclient.Auth(sshclient.PublicKeys(signers...)),
+		sshclient.HostKey(sshclient.KnownHostsFile("~/.ssh/known_hosts")),
+		sshclient.KnownHostKeyAlgorithms(),
+	}, nil
+}
+
+func (c *Sftp) Schemes() []string {
+	return []string{
+		SftpScheme,
+		ScpScheme,
+	}
+}
+
+type sftpSource struct {
+	url string
+
+	sshClient  *sshclient.Client
+	sftpClient *sftp.Client
+	file       *sftp.File
+	fileInfo   fs.FileInfo
+}
+
+var _ Source = &sftpSource{}
+
+func (s *sftpSource) Read(p []byte) (int, error) {
+	return s.file.Read(p)
+}
+
+func (s *sftpSource) Close() error {
+	var errs []error
+
+	if s.file != nil {
+		errs = append(errs, s.file.Close())
+	}
+
+	if s.sftpClient != nil {
+		errs = append(errs, s.sftpClient.Close())
+	}
+
+	errs = append(errs, s.sshClient.Close())
+
+	return errors.Join(errs...)
+}
+
+// Meta returns a weak ETag of the size and the modification time of the file
+func (s *sftpSource) Meta() (Meta, error) {
+	return &meta{
+		client: sftpClient,
+		url:    s.url,
+		size:   s.fileInfo.Size(),
+		etag:   fmt.Sprintf(`W/"%x-%x"`, s.fileInfo.ModTime().Unix(), s.fileInfo.Size()),
+	}, nil
+}
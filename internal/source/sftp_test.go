@@ -0,0 +1,137 @@
+package source_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"fmt"
+	"github.com/neuspaces/terraform-provider-system/internal/source"
+	"github.com/neuspaces/terraform-provider-system/internal/sshclient"
+	"github.com/pkg/sftp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// newTestSftpServer starts an ssh server which provides the SFTP subsystem only
+func newTestSftpServer(t *testing.T) net.Addr {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	signer, err := ssh.NewSignerFromKey(priv)
+	require.NoError(t, err)
+
+	config := &ssh.ServerConfig{
+		NoClientAuth: true,
+	}
+	config.AddHostKey(signer)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = l.Close()
+	})
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+
+			go func() {
+				defer conn.Close()
+
+				sshConn, chans, reqs, err := ssh.NewServerConn(conn, config)
+				if err != nil {
+					return
+				}
+				defer sshConn.Close()
+
+				go ssh.DiscardRequests(reqs)
+
+				for newCh := range chans {
+					ch, chReqs, err := newCh.Accept()
+					if err != nil {
+						continue
+					}
+
+					go func() {
+						defer ch.Close()
+
+						for req := range chReqs {
+							if req.Type != "subsystem" || string(req.Payload[4:]) != "sftp" {
+								_ = req.Reply(false, nil)
+								continue
+							}
+							_ = req.Reply(true, nil)
+
+							server, err := sftp.NewServer(ch)
+							if err != nil {
+								return
+							}
+							_ = server.Serve()
+
+							return
+						}
+					}()
+				}
+			}()
+		}
+	}()
+
+	return l.Addr()
+}
+
+func TestSftp_Open(t *testing.T) {
+	t.Parallel()
+
+	addr := newTestSftpServer(t)
+
+	name := filepath.Join(t.TempDir(), "file")
+	require.NoError(t, os.WriteFile(name, []byte("hello world!"), 0644))
+
+	registry, err := source.NewRegistry(
+		source.WithClient(source.NewSftpClient(sshclient.HostKeyCallback(ssh.InsecureIgnoreHostKey()))),
+	)
+	require.NoError(t, err)
+
+	for _, scheme := range []string{source.SftpScheme, source.ScpScheme} {
+		scheme := scheme
+		t.Run(scheme, func(t *testing.T) {
+			t.Parallel()
+
+			s, err := registry.Open(fmt.Sprintf("%s://test@%s%s", scheme, addr, name))
+			require.NoError(t, err)
+			defer func() {
+				_ = s.Close()
+			}()
+
+			m, err := s.Meta()
+			require.NoError(t, err)
+			assert.Equal(t, int64(12), m.Size())
+			assert.NotEmpty(t, m.ETag())
+
+			content, err := io.ReadAll(s)
+			require.NoError(t, err)
+			assert.Equal(t, "hello world!", string(content))
+		})
+	}
+
+	t.Run("not found", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := registry.Open(fmt.Sprintf("sftp://test@%s%s", addr, name+".missing"))
+		assert.ErrorIs(t, err, source.ErrSftpClient)
+	})
+	t.Run("password in url", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := registry.Open(fmt.Sprintf("sftp://test:secret@%s%s", addr, name))
+		assert.ErrorIs(t, err, source.ErrSftpUrlPassword)
+	})
+}
@@ -0,0 +1,68 @@
+package source
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"text/template"
+)
+
+const TemplateScheme = "template"
+
+const templateClient = "template"
+
+// QueryTemplateVars is the query parameter of a template url which contains the variables of the template as JSON
+// object
+const QueryTemplateVars = "vars"
+
+// Template is a Client which renders a local file as Go text/template. The variables are provided as JSON object in
+// the query parameter QueryTemplateVars like template://./app.conf.tmpl?vars={"port":8080}. The variables are the
+// root of the template, e.g. {{ .port }}.
+type Template struct {
+}
+
+var _ Client = &Template{}
+
+var ErrTemplateClient = &Error{msg: "template client error"}
+
+func NewTemplateClient() *Template {
+	return &Template{}
+}
+
+func (c *Template) Open(u *url.URL) (Source, error) {
+	name := u.Host + u.Path
+
+	text, err := os.ReadFile(name)
+	if err != nil {
+		return nil, ErrTemplateClient.WithCause(err)
+	}
+
+	vars := map[string]interface{}{}
+	if varsJson := u.Query().Get(QueryTemplateVars); varsJson != "" {
+		err = json.Unmarshal([]byte(varsJson), &vars)
+		if err != nil {
+			return nil, ErrTemplateClient.WithCause(fmt.Errorf("query parameter %s must be a JSON object: %w", QueryTemplateVars, err))
+		}
+	}
+
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(string(text))
+	if err != nil {
+		return nil, ErrTemplateClient.WithCause(err)
+	}
+
+	var content bytes.Buffer
+	err = tmpl.Execute(&content, vars)
+	if err != nil {
+		return nil, ErrTemplateClient.WithCause(err)
+	}
+
+	return newContentSource(templateClient, u.String(), content.Bytes()), nil
+}
+
+func (c *Template) Schemes() []string {
+	return []string{
+		TemplateScheme,
+	}
+}
@@ -0,0 +1,114 @@
+package source_test
+
+import (
+	"github.com/neuspaces/terraform-provider-system/internal/source"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTemplate_Open(t *testing.T) {
+	t.Parallel()
+
+	name := filepath.Join(t.TempDir(), "app.conf.tmpl")
+	require.NoError(t, os.WriteFile(name, []byte("listen {{ .port }}\n{{ range .hosts }}host {{ . }}\n{{ end }}"), 0644))
+
+	registry, err := source.NewRegistry(
+		source.WithClient(source.NewTemplateClient()),
+	)
+	require.NoError(t, err)
+
+	templateUrl := func(vars string) string {
+		return "template://" + name + "?" + url.Values{source.QueryTemplateVars: {vars}}.Encode()
+	}
+
+	type testCase struct {
+		Desc          string
+		Url           string
+		ExpectContent string
+		ExpectErr     error
+	}
+
+	tcs := []testCase{
+		{
+			Desc:          "vars",
+			Url:           templateUrl(`{"port":8080,"hosts":["a","b"]}`),
+			ExpectContent: "listen 8080\nhost a\nhost b\n",
+		},
+		{
+			Desc:      "missing var",
+			Url:       templateUrl(`{"hosts":[]}`),
+			ExpectErr: source.ErrTemplateClient,
+		},
+		{
+			Desc:      "invalid vars",
+			Url:       templateUrl(`[1]`),
+			ExpectErr: source.ErrTemplateClient,
+		},
+		{
+			Desc:      "missing file",
+			Url:       "template://" + name + ".missing",
+			ExpectErr: source.ErrTemplateClient,
+		},
+	}
+
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.Desc, func(t *testing.T) {
+			t.Parallel()
+
+			s, err := registry.Open(tc.Url)
+			if tc.ExpectErr != nil {
+				assert.ErrorIs(t, err, tc.ExpectErr)
+				return
+			}
+			require.NoError(t, err)
+			defer func() {
+				_ = s.Close()
+			}()
+
+			content, err := io.ReadAll(s)
+			require.NoError(t, err)
+			assert.Equal(t, tc.ExpectContent, string(content))
+		})
+	}
+}
+
+func TestTemplate_Meta(t *testing.T) {
+	t.Parallel()
+
+	name := filepath.Join(t.TempDir(), "app.conf.tmpl")
+	require.NoError(t, os.WriteFile(name, []byte("listen {{ .port }}"), 0644))
+
+	registry, err := source.NewRegistry(
+		source.WithClient(source.NewTemplateClient()),
+	)
+	require.NoError(t, err)
+
+	sha256sum := func(rawurl string) string {
+		s, err := registry.Open(rawurl)
+		require.NoError(t, err)
+		defer func() {
+			_ = s.Close()
+		}()
+
+		m, err := s.Meta()
+		require.NoError(t, err)
+
+		return m.Sha256Sum()
+	}
+
+	// echo -n 'listen 8080' | sha256sum
+	rendered := sha256sum("template://" + name + "?vars=%7B%22port%22%3A8080%7D")
+	assert.Equal(t, "572abbf5f5e997a59f2d37308880fbeb3aa65ae89b001192cb602c85261cde25", rendered)
+
+	// The checksum of the rendered content changes with the vars and the template
+	assert.NotEqual(t, rendered, sha256sum("template://"+name+"?vars=%7B%22port%22%3A8081%7D"))
+
+	require.NoError(t, os.WriteFile(name, []byte("port {{ .port }}"), 0644))
+	assert.NotEqual(t, rendered, sha256sum("template://"+name+"?vars=%7B%22port%22%3A8080%7D"))
+}
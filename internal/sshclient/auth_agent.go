@@ -1,8 +1,6 @@
 package sshclient
 
 import (
-	"fmt"
-	sshagent "github.com/xanzy/ssh-agent"
 	"golang.org/x/crypto/ssh"
 	"golang.org/x/crypto/ssh/agent"
 	"io"
@@ -10,50 +8,11 @@ import (
 )
 
 func Agent() AuthMethod {
-	return func() ([]ssh.AuthMethod, error) {
-		return []ssh.AuthMethod{
-			ssh.PublicKeysCallback(func() ([]ssh.Signer, error) {
-				return agentConnectFuncSigners(sshagent.New)
-			})}, nil
-	}
+	return PublicKeys(AgentSigners())
 }
 
 func AgentExplicitIdentities(identities ...string) AuthMethod {
-	return func() ([]ssh.AuthMethod, error) {
-		return []ssh.AuthMethod{
-			ssh.PublicKeysCallback(func() ([]ssh.Signer, error) {
-				explicitPublicKeys := map[string]struct{}{}
-
-				// Parse identities
-				for _, identity := range identities {
-					publicKey, _, _, _, err := ssh.ParseAuthorizedKey([]byte(identity))
-					if err != nil {
-						return nil, err
-					}
-					explicitPublicKeys[string(publicKey.Marshal())] = struct{}{}
-				}
-
-				signers, err := agentConnectFuncSigners(sshagent.New)
-				if err != nil {
-					return nil, err
-				}
-
-				// Filter signers by explicit identities
-				var explicitSigners []ssh.Signer
-				for _, signer := range signers {
-					if _, ok := explicitPublicKeys[string(signer.PublicKey().Marshal())]; ok {
-						explicitSigners = append(explicitSigners, signer)
-					}
-				}
-
-				// Require at least one explicit identity which provided the agent
-				if len(explicitSigners) == 0 {
-					return nil, fmt.Errorf("sshclient: agent does not provide any explicit identity")
-				}
-
-				return explicitSigners, nil
-			})}, nil
-	}
+	return PublicKeys(AgentExplicitIdentitiesSigners(identities...))
 }
 
 type agentConnectFunc func() (agent.Agent, net.Conn, error)
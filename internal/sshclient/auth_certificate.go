@@ -6,27 +6,30 @@ import (
 )
 
 func Certificate(cert string, privateKey string) AuthMethod {
+	return CertificateWithPassphrase(cert, privateKey, "")
+}
+
+// CertificateWithPassphrase returns an AuthMethod which authenticates using a client certificate and a private key
+// which is encrypted with a passphrase. The passphrase is ignored if the private key is not encrypted.
+func CertificateWithPassphrase(cert string, privateKey string, passphrase string) AuthMethod {
 	return func() ([]ssh.AuthMethod, error) {
-		certSigner, err := signCertWithPrivateKey(privateKey, cert)
+		// Parse eagerly to fail before connecting
+		certSigner, err := newCertSigner(cert, privateKey, passphrase)
 		if err != nil {
 			return nil, err
 		}
 
 		return []ssh.AuthMethod{
-			certSigner,
+			ssh.PublicKeys(certSigner),
 		}, nil
 	}
 }
 
-// signCertWithPrivateKey returns an ssh.AuthMethod using a client certificate and a private key
-func signCertWithPrivateKey(privateKey string, cert string) (ssh.AuthMethod, error) {
-	rawPk, err := ssh.ParseRawPrivateKey([]byte(privateKey))
+// newCertSigner returns an ssh.Signer of a client certificate and its private key
+func newCertSigner(cert string, privateKey string, passphrase string) (ssh.Signer, error) {
+	signer, err := ParsePrivateKey(privateKey, passphrase)
 	if err != nil {
-		if err.Error() == (&ssh.PassphraseMissingError{}).Error() {
-			return nil, fmt.Errorf("sshclient: failed to read ssh private key: password protected keys are not supported. decrypt the key before use")
-		}
-
-		return nil, fmt.Errorf("sshclient: failed to parse private key %q: %w", privateKey, err)
+		return nil, err
 	}
 
 	parsedCert, _, _, _, err := ssh.ParseAuthorizedKey([]byte(cert))
@@ -34,15 +37,15 @@ func signCertWithPrivateKey(privateKey string, cert string) (ssh.AuthMethod, err
 		return nil, fmt.Errorf("sshclient: failed to parse certificate %q: %w", cert, err)
 	}
 
-	signer, err := ssh.NewSignerFromKey(rawPk)
-	if err != nil {
-		return nil, fmt.Errorf("sshclient: failed to create signer from raw private key %q: %w", rawPk, err)
+	sshCert, isCert := parsedCert.(*ssh.Certificate)
+	if !isCert {
+		return nil, fmt.Errorf("sshclient: %q is not a certificate", cert)
 	}
 
-	certSigner, err := ssh.NewCertSigner(parsedCert.(*ssh.Certificate), signer)
+	certSigner, err := ssh.NewCertSigner(sshCert, signer)
 	if err != nil {
 		return nil, fmt.Errorf("sshclient: failed to create cert signer %q: %w", signer, err)
 	}
 
-	return ssh.PublicKeys(certSigner), nil
+	return certSigner, nil
 }
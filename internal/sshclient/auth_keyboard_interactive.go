@@ -0,0 +1,81 @@
+package sshclient
+
+import (
+	"fmt"
+	"github.com/neuspaces/terraform-provider-system/internal/lib/totp"
+	"golang.org/x/crypto/ssh"
+	"regexp"
+	"time"
+)
+
+// KeyboardInteractivePrompt answers questions of keyboard-interactive authentication which match Pattern.
+type KeyboardInteractivePrompt struct {
+	// Pattern is a regular expression which is matched against the question of the server
+	Pattern string
+
+	// Answer returns the answer to a matching question
+	Answer func() (string, error)
+}
+
+// PromptAnswer answers questions which match pattern with a static answer
+func PromptAnswer(pattern string, answer string) KeyboardInteractivePrompt {
+	return KeyboardInteractivePrompt{
+		Pattern: pattern,
+		Answer: func() (string, error) {
+			return answer, nil
+		},
+	}
+}
+
+// PromptTOTP answers questions which match pattern with a time-based one-time password (RFC 6238) generated from the
+// base32 encoded secret
+func PromptTOTP(pattern string, secret string) KeyboardInteractivePrompt {
+	return KeyboardInteractivePrompt{
+		Pattern: pattern,
+		Answer: func() (string, error) {
+			return totp.Code(secret, time.Now())
+		},
+	}
+}
+
+// KeyboardInteractive returns an AuthMethod for keyboard-interactive authentication. Every question of the server is
+// answered by the first prompt whose pattern matches the question. Authentication fails if a question does not match
+// any prompt.
+func KeyboardInteractive(prompts ...KeyboardInteractivePrompt) AuthMethod {
+	return func() ([]ssh.AuthMethod, error) {
+		patterns := make([]*regexp.Regexp, len(prompts))
+		for i, prompt := range prompts {
+			pattern, err := regexp.Compile(prompt.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("sshclient: invalid keyboard-interactive prompt pattern %q: %w", prompt.Pattern, err)
+			}
+			patterns[i] = pattern
+		}
+
+		challenge := func(user, instruction string, questions []string, echos []bool) ([]string, error) {
+			answers := make([]string, len(questions))
+
+		questionsLoop:
+			for i, question := range questions {
+				for j, pattern := range patterns {
+					if pattern.MatchString(question) {
+						answer, err := prompts[j].Answer()
+						if err != nil {
+							return nil, fmt.Errorf("sshclient: failed to answer keyboard-interactive prompt %q: %w", question, err)
+						}
+						answers[i] = answer
+						continue questionsLoop
+					}
+				}
+
+				return nil, fmt.Errorf("sshclient: no answer for keyboard-interactive prompt %q", question)
+			}
+
+			return answers, nil
+		}
+
+		return []ssh.AuthMethod{
+			ssh.KeyboardInteractive(challenge),
+		}, nil
+	}
+}
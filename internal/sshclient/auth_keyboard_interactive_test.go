@@ -0,0 +1,142 @@
+package sshclient_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/base32"
+	"fmt"
+	"github.com/neuspaces/terraform-provider-system/internal/lib/totp"
+	"github.com/neuspaces/terraform-provider-system/internal/sshclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+	"net"
+	"testing"
+	"time"
+)
+
+// newTestMultiFactorServer starts an ssh server which requires publickey authentication using clientKey followed by
+// keyboard-interactive authentication with a password and a verification code
+func newTestMultiFactorServer(t *testing.T, clientKey ssh.PublicKey, password string, totpSecret string) net.Addr {
+	config := &ssh.ServerConfig{
+		PublicKeyCallback: func(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if !bytes.Equal(key.Marshal(), clientKey.Marshal()) {
+				return nil, fmt.Errorf("unknown public key")
+			}
+
+			return nil, &ssh.PartialSuccessError{
+				Next: ssh.ServerAuthCallbacks{
+					KeyboardInteractiveCallback: func(conn ssh.ConnMetadata, client ssh.KeyboardInteractiveChallenge) (*ssh.Permissions, error) {
+						answers, err := client(conn.User(), "", []string{"Password: ", "Verification code: "}, []bool{false, false})
+						if err != nil {
+							return nil, err
+						}
+
+						code, err := totp.Code(totpSecret, time.Now())
+						if err != nil {
+							return nil, err
+						}
+
+						if len(answers) != 2 || answers[0] != password || answers[1] != code {
+							return nil, fmt.Errorf("wrong answers")
+						}
+
+						return nil, nil
+					},
+				},
+			}
+		},
+	}
+
+	return newTestSshServer(t, config, nil)
+}
+
+func TestKeyboardInteractive(t *testing.T) {
+	t.Parallel()
+
+	clientSigner := newTestSigner(t)
+	totpSecret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+
+	addr := newTestMultiFactorServer(t, clientSigner.PublicKey(), "secret", totpSecret)
+
+	type testCase struct {
+		Desc      string
+		Prompts   []sshclient.KeyboardInteractivePrompt
+		ExpectErr string
+	}
+
+	tcs := []testCase{
+		{
+			Desc: "password and totp",
+			Prompts: []sshclient.KeyboardInteractivePrompt{
+				sshclient.PromptAnswer("(?i)^password", "secret"),
+				sshclient.PromptTOTP("(?i)verification code", totpSecret),
+			},
+		},
+		{
+			Desc: "first matching prompt answers",
+			Prompts: []sshclient.KeyboardInteractivePrompt{
+				sshclient.PromptAnswer("(?i)^password", "secret"),
+				sshclient.PromptTOTP("(?i)verification code", totpSecret),
+				sshclient.PromptAnswer(".*", "wrong"),
+			},
+		},
+		{
+			Desc: "unanswered prompt",
+			Prompts: []sshclient.KeyboardInteractivePrompt{
+				sshclient.PromptAnswer("(?i)^password", "secret"),
+			},
+			ExpectErr: `no answer for keyboard-interactive prompt "Verification code: "`,
+		},
+		{
+			Desc: "wrong answer",
+			Prompts: []sshclient.KeyboardInteractivePrompt{
+				sshclient.PromptAnswer("(?i)^password", "wrong"),
+				sshclient.PromptTOTP("(?i)verification code", totpSecret),
+			},
+			ExpectErr: "unable to authenticate",
+		},
+		{
+			Desc: "invalid pattern",
+			Prompts: []sshclient.KeyboardInteractivePrompt{
+				sshclient.PromptAnswer("(", "secret"),
+			},
+			ExpectErr: "invalid keyboard-interactive prompt pattern",
+		},
+	}
+
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.Desc, func(t *testing.T) {
+			t.Parallel()
+
+			connect, err := sshclient.Prepare(
+				sshclient.Addr(addr),
+				sshclient.Net(sshclient.Dial(addr, 5*time.Second)),
+				sshclient.User("test"),
+				sshclient.HostKeyCallback(ssh.InsecureIgnoreHostKey()),
+				sshclient.Auth(func() ([]ssh.AuthMethod, error) {
+					return []ssh.AuthMethod{ssh.PublicKeys(clientSigner)}, nil
+				}),
+				sshclient.Auth(sshclient.KeyboardInteractive(tc.Prompts...)),
+			)
+			if err != nil {
+				require.NotEmpty(t, tc.ExpectErr, "unexpected error: %v", err)
+				assert.Contains(t, err.Error(), tc.ExpectErr)
+				return
+			}
+
+			client := sshclient.New(connect)
+			err = client.Connect(context.Background())
+
+			if tc.ExpectErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tc.ExpectErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.NoError(t, client.Close())
+		})
+	}
+}
@@ -1,14 +1,22 @@
 package sshclient
 
 import (
+	"crypto/x509"
+	"errors"
 	"fmt"
 	"golang.org/x/crypto/ssh"
 )
 
 // PrivateKey returns an AuthMethod which authenticates using a private key.
 func PrivateKey(privateKey string) AuthMethod {
+	return PrivateKeyWithPassphrase(privateKey, "")
+}
+
+// PrivateKeyWithPassphrase returns an AuthMethod which authenticates using a private key which is encrypted with a
+// passphrase. The passphrase is ignored if the private key is not encrypted.
+func PrivateKeyWithPassphrase(privateKey string, passphrase string) AuthMethod {
 	return func() ([]ssh.AuthMethod, error) {
-		privateKeySigner, err := parsePrivateKey(privateKey)
+		privateKeySigner, err := ParsePrivateKey(privateKey, passphrase)
 		if err != nil {
 			return nil, err
 		}
@@ -19,11 +27,24 @@ func PrivateKey(privateKey string) AuthMethod {
 	}
 }
 
-func parsePrivateKey(privateKey string) (ssh.Signer, error) {
+// ParsePrivateKey returns an ssh.Signer from a pem encoded private key.
+// If the private key is encrypted, the private key is decrypted using passphrase. The passphrase is ignored if the
+// private key is not encrypted.
+func ParsePrivateKey(privateKey string, passphrase string) (ssh.Signer, error) {
 	signer, err := ssh.ParsePrivateKey([]byte(privateKey))
+
+	var passphraseMissingErr *ssh.PassphraseMissingError
+	if errors.As(err, &passphraseMissingErr) && passphrase != "" {
+		signer, err = ssh.ParsePrivateKeyWithPassphrase([]byte(privateKey), []byte(passphrase))
+	}
+
 	if err != nil {
-		if err.Error() == (&ssh.PassphraseMissingError{}).Error() {
-			return nil, fmt.Errorf("sshclient: failed to read ssh private key: password protected keys are not supported. decrypt the key before use")
+		if errors.As(err, &passphraseMissingErr) {
+			return nil, fmt.Errorf("sshclient: failed to read ssh private key: private key is encrypted, passphrase required")
+		}
+
+		if errors.Is(err, x509.IncorrectPasswordError) {
+			return nil, fmt.Errorf("sshclient: failed to read ssh private key: incorrect passphrase")
 		}
 
 		return nil, fmt.Errorf("sshclient: failed to read ssh private key: %w", err)
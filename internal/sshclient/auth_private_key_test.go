@@ -0,0 +1,56 @@
+package sshclient_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"github.com/neuspaces/terraform-provider-system/internal/sshclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+	"testing"
+)
+
+func TestParsePrivateKey(t *testing.T) {
+	t.Parallel()
+
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	block, err := ssh.MarshalPrivateKey(priv, "")
+	require.NoError(t, err)
+	plainKey := string(pem.EncodeToMemory(block))
+
+	block, err = ssh.MarshalPrivateKeyWithPassphrase(priv, "", []byte("secret!"))
+	require.NoError(t, err)
+	encryptedKey := string(pem.EncodeToMemory(block))
+
+	cases := []struct {
+		name        string
+		privateKey  string
+		passphrase  string
+		expectedErr string
+	}{
+		{name: "unencrypted", privateKey: plainKey},
+		{name: "unencrypted with passphrase", privateKey: plainKey, passphrase: "unused"},
+		{name: "encrypted", privateKey: encryptedKey, passphrase: "secret!"},
+		{name: "encrypted without passphrase", privateKey: encryptedKey, expectedErr: "passphrase required"},
+		{name: "encrypted with wrong passphrase", privateKey: encryptedKey, passphrase: "wrong!", expectedErr: "incorrect passphrase"},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			signer, err := sshclient.ParsePrivateKey(tc.privateKey, tc.passphrase)
+			if tc.expectedErr != "" {
+				assert.ErrorContains(t, err, tc.expectedErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, ssh.KeyAlgoED25519, signer.PublicKey().Type())
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package sshclient
+
+import (
+	"fmt"
+	sshagent "github.com/xanzy/ssh-agent"
+	"golang.org/x/crypto/ssh"
+)
+
+// SignersFunc returns the signers of one or more identities
+type SignersFunc func() ([]ssh.Signer, error)
+
+// PublicKeys returns an AuthMethod which offers the identities of all signersFuncs by a single public key method. The
+// ssh client tries each kind of authentication method at most once, so that identities of an agent and of private keys
+// are only tried together if they are offered by the same method.
+func PublicKeys(signersFuncs ...SignersFunc) AuthMethod {
+	return func() ([]ssh.AuthMethod, error) {
+		return []ssh.AuthMethod{
+			ssh.PublicKeysCallback(func() ([]ssh.Signer, error) {
+				var signers []ssh.Signer
+				for _, signersFunc := range signersFuncs {
+					s, err := signersFunc()
+					if err != nil {
+						return nil, err
+					}
+					signers = append(signers, s...)
+				}
+				return signers, nil
+			}),
+		}, nil
+	}
+}
+
+// AgentSigners returns a SignersFunc of the identities of the ssh agent
+func AgentSigners() SignersFunc {
+	return func() ([]ssh.Signer, error) {
+		return agentConnectFuncSigners(sshagent.New)
+	}
+}
+
+// AgentExplicitIdentitiesSigners returns a SignersFunc of the identities of the ssh agent which are contained in
+// identities. identities are public keys in the authorized_keys format. The SignersFunc fails if the agent provides
+// none of the identities.
+func AgentExplicitIdentitiesSigners(identities ...string) SignersFunc {
+	return func() ([]ssh.Signer, error) {
+		explicitPublicKeys := map[string]struct{}{}
+
+		// Parse identities
+		for _, identity := range identities {
+			publicKey, _, _, _, err := ssh.ParseAuthorizedKey([]byte(identity))
+			if err != nil {
+				return nil, err
+			}
+			explicitPublicKeys[string(publicKey.Marshal())] = struct{}{}
+		}
+
+		signers, err := agentConnectFuncSigners(sshagent.New)
+		if err != nil {
+			return nil, err
+		}
+
+		// Filter signers by explicit identities
+		var explicitSigners []ssh.Signer
+		for _, signer := range signers {
+			if _, ok := explicitPublicKeys[string(signer.PublicKey().Marshal())]; ok {
+				explicitSigners = append(explicitSigners, signer)
+			}
+		}
+
+		// Require at least one explicit identity which provided the agent
+		if len(explicitSigners) == 0 {
+			return nil, fmt.Errorf("sshclient: agent does not provide any explicit identity")
+		}
+
+		return explicitSigners, nil
+	}
+}
+
+// CertificateSigners returns a SignersFunc of a client certificate and its private key. The passphrase is ignored if
+// the private key is not encrypted.
+func CertificateSigners(cert string, privateKey string, passphrase string) SignersFunc {
+	return func() ([]ssh.Signer, error) {
+		signer, err := newCertSigner(cert, privateKey, passphrase)
+		if err != nil {
+			return nil, err
+		}
+		return []ssh.Signer{signer}, nil
+	}
+}
+
+// PrivateKeySigners returns a SignersFunc of a private key. The passphrase is ignored if the private key is not
+// encrypted.
+func PrivateKeySigners(privateKey string, passphrase string) SignersFunc {
+	return func() ([]ssh.Signer, error) {
+		signer, err := ParsePrivateKey(privateKey, passphrase)
+		if err != nil {
+			return nil, err
+		}
+		return []ssh.Signer{signer}, nil
+	}
+}
+
+// OptionalSigners returns a SignersFunc which provides no signers instead of failing if signersFunc fails, e.g. for
+// an agent which is not running or for a private key which is encrypted
+func OptionalSigners(signersFunc SignersFunc) SignersFunc {
+	return func() ([]ssh.Signer, error) {
+		signers, err := signersFunc()
+		if err != nil {
+			return nil, nil
+		}
+		return signers, nil
+	}
+}
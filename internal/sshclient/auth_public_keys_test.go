@@ -0,0 +1,140 @@
+package sshclient_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"errors"
+	"github.com/neuspaces/terraform-provider-system/internal/sshclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+	"testing"
+	"time"
+)
+
+func newTestPrivateKey(t *testing.T, passphrase string) (string, ssh.PublicKey) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	var block *pem.Block
+	if passphrase == "" {
+		block, err = ssh.MarshalPrivateKey(priv, "")
+	} else {
+		block, err = ssh.MarshalPrivateKeyWithPassphrase(priv, "", []byte(passphrase))
+	}
+	require.NoError(t, err)
+
+	publicKey, err := ssh.NewPublicKey(pub)
+	require.NoError(t, err)
+
+	return string(pem.EncodeToMemory(block)), publicKey
+}
+
+func TestPublicKeys(t *testing.T) {
+	t.Parallel()
+
+	otherKey, _ := newTestPrivateKey(t, "")
+	encryptedKey, _ := newTestPrivateKey(t, "secret!")
+	authorizedKey, authorizedPublicKey := newTestPrivateKey(t, "")
+
+	// Certificate of otherKey which the server does not trust
+	caKey, _ := newTestPrivateKey(t, "")
+	caSigner, err := sshclient.ParsePrivateKey(caKey, "")
+	require.NoError(t, err)
+	otherSigner, err := sshclient.ParsePrivateKey(otherKey, "")
+	require.NoError(t, err)
+	otherCert := &ssh.Certificate{
+		Key:             otherSigner.PublicKey(),
+		CertType:        ssh.UserCert,
+		ValidPrincipals: []string{"test"},
+		ValidBefore:     ssh.CertTimeInfinity,
+	}
+	require.NoError(t, otherCert.SignCert(rand.Reader, caSigner))
+	otherCertAuthorizedKey := string(ssh.MarshalAuthorizedKey(otherCert))
+
+	addr := newTestSshServer(t, &ssh.ServerConfig{
+		PublicKeyCallback: func(_ ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if bytes.Equal(key.Marshal(), authorizedPublicKey.Marshal()) {
+				return nil, nil
+			}
+			return nil, errors.New("unauthorized")
+		},
+	}, nil)
+
+	type testCase struct {
+		Desc      string
+		Signers   []sshclient.SignersFunc
+		ExpectErr string
+	}
+
+	tcs := []testCase{
+		{
+			// All identities are offered by a single method
+			Desc: "authorized key after other key",
+			Signers: []sshclient.SignersFunc{
+				sshclient.PrivateKeySigners(otherKey, ""),
+				sshclient.PrivateKeySigners(authorizedKey, ""),
+			},
+		},
+		{
+			Desc: "authorized key after rejected certificate",
+			Signers: []sshclient.SignersFunc{
+				sshclient.CertificateSigners(otherCertAuthorizedKey, otherKey, ""),
+				sshclient.PrivateKeySigners(authorizedKey, ""),
+			},
+		},
+		{
+			Desc: "optional encrypted key is skipped",
+			Signers: []sshclient.SignersFunc{
+				sshclient.OptionalSigners(sshclient.PrivateKeySigners(encryptedKey, "")),
+				sshclient.PrivateKeySigners(authorizedKey, ""),
+			},
+		},
+		{
+			Desc: "encrypted key",
+			Signers: []sshclient.SignersFunc{
+				sshclient.PrivateKeySigners(encryptedKey, ""),
+				sshclient.PrivateKeySigners(authorizedKey, ""),
+			},
+			ExpectErr: "passphrase required",
+		},
+		{
+			Desc: "unauthorized key",
+			Signers: []sshclient.SignersFunc{
+				sshclient.PrivateKeySigners(otherKey, ""),
+			},
+			ExpectErr: "unable to authenticate",
+		},
+	}
+
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.Desc, func(t *testing.T) {
+			t.Parallel()
+
+			connect, err := sshclient.Prepare(
+				sshclient.Addr(addr),
+				sshclient.Net(sshclient.Dial(addr, 5*time.Second)),
+				sshclient.User("test"),
+				sshclient.HostKeyCallback(ssh.InsecureIgnoreHostKey()),
+				sshclient.Auth(sshclient.PublicKeys(tc.Signers...)),
+			)
+			require.NoError(t, err)
+
+			client := sshclient.New(connect)
+			err = client.Connect(context.Background())
+
+			if tc.ExpectErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tc.ExpectErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.NoError(t, client.Close())
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"golang.org/x/crypto/ssh"
 	"io"
+	"time"
 )
 
 // Client is high level ssh client which relies on golang.org/x/crypto/ssh
@@ -12,27 +13,64 @@ type Client struct {
 	*ssh.Client
 
 	connectFunc ConnectFunc
+
+	// done is closed when the transport of Client has terminated
+	done chan struct{}
+
+	keepaliveInterval time.Duration
+	keepaliveCountMax int
 }
 
 var _ io.Closer = &Client{}
 
-func New(connectFunc ConnectFunc) *Client {
+type ClientOption func(*Client)
+
+// Keepalive sends a keepalive@openssh.com request every interval. The connection is closed if countMax consecutive
+// requests have not been answered within interval. Keepalive is equivalent to ServerAliveInterval and
+// ServerAliveCountMax of OpenSSH. An interval of 0 disables keepalive requests.
+func Keepalive(interval time.Duration, countMax int) ClientOption {
+	return func(c *Client) {
+		c.keepaliveInterval = interval
+		c.keepaliveCountMax = countMax
+	}
+}
+
+func New(connectFunc ConnectFunc, opts ...ClientOption) *Client {
 	c := &Client{
 		connectFunc: connectFunc,
 	}
 
+	for _, opt := range opts {
+		opt(c)
+	}
+
 	return c
 }
 
 // Connected ensures that an ssh connection is established.
+// Connected reconnects if the transport of a previously established connection has terminated.
 func (c *Client) Connected(ctx context.Context) error {
-	if c.Client == nil {
+	if !c.Alive() {
 		return c.Connect(ctx)
 	}
 
 	return nil
 }
 
+// Alive returns true if an ssh connection is established and its transport has not terminated
+func (c *Client) Alive() bool {
+	if c.Client == nil {
+		return false
+	}
+
+	select {
+	case <-c.done:
+		return false
+	default:
+		return true
+	}
+}
+
 // Connect establishes an ssh connection.
 func (c *Client) Connect(ctx context.Context) error {
 	var err error
@@ -43,10 +81,7 @@ func (c *Client) Connect(ctx context.Context) error {
 	}
 
 	// Reset client
-	if c.Client != nil {
-		_ = c.Close()
-		c.Client = nil
-	}
+	_ = c.Disconnect()
 
 	// Connect
 	sshConn, chans, reqs, err := c.connectFunc(ctx)
@@ -57,12 +92,82 @@ func (c *Client) Connect(ctx context.Context) error {
 	// Create ssh client
 	c.Client = ssh.NewClient(sshConn, chans, reqs)
 
+	// Detect termination of the transport
+	done := make(chan struct{})
+	go func(client *ssh.Client) {
+		_ = client.Wait()
+		close(done)
+	}(c.Client)
+	c.done = done
+
+	if c.keepaliveInterval > 0 {
+		go keepalive(c.Client, c.keepaliveInterval, c.keepaliveCountMax, done)
+	}
+
 	return nil
 }
 
+// Disconnect closes the ssh connection. A subsequent call of Connected establishes a new connection.
+func (c *Client) Disconnect() error {
+	if c.Client == nil {
+		return nil
+	}
+
+	err := c.Client.Close()
+	c.Client = nil
+
+	return err
+}
+
 func (c *Client) Close() error {
 	if c.Client != nil {
 		return c.Client.Close()
 	}
 	return nil
 }
+
+const keepaliveRequest = "keepalive@openssh.com"
+
+// keepalive sends keepalive requests until done is closed. keepalive closes the client if countMax consecutive requests
+// have not been answered within interval or if a request fails.
+func keepalive(client *ssh.Client, interval time.Duration, countMax int, done <-chan struct{}) {
+	if countMax < 1 {
+		countMax = 1
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	missed := 0
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+
+		replied := make(chan error, 1)
+		go func() {
+			// Any reply, including a failure reply, proves that the remote is alive
+			_, _, err := client.SendRequest(keepaliveRequest, true, nil)
+			replied <- err
+		}()
+
+		select {
+		case <-done:
+			return
+		case err := <-replied:
+			if err != nil {
+				_ = client.Close()
+				return
+			}
+			missed = 0
+		case <-time.After(interval):
+			missed++
+			if missed >= countMax {
+				_ = client.Close()
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package sshclient_test
+
+import (
+	"context"
+	"errors"
+	"github.com/neuspaces/terraform-provider-system/internal/sshclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestSshServer starts an ssh server with config and a new host key. handle is invoked for every established
+// connection and may be nil. The connection is closed when handle returns.
+func newTestSshServer(t *testing.T, config *ssh.ServerConfig, handle func(conn *ssh.ServerConn)) net.Addr {
+	config.AddHostKey(newTestSigner(t))
+
+	return newTestServer(t, func(conn net.Conn) {
+		sshConn, chans, reqs, err := ssh.NewServerConn(conn, config)
+		if err != nil {
+			return
+		}
+		defer sshConn.Close()
+
+		go ssh.DiscardRequests(reqs)
+		go func() {
+			for ch := range chans {
+				_ = ch.Reject(ssh.Prohibited, "no channels")
+			}
+		}()
+
+		if handle != nil {
+			handle(sshConn)
+			return
+		}
+
+		_ = sshConn.Wait()
+	})
+}
+
+func newTestClient(t *testing.T, netConnect sshclient.NetConnectFunc, addr net.Addr, opts ...sshclient.ClientOption) *sshclient.Client {
+	connect, err := sshclient.Prepare(
+		sshclient.Addr(addr),
+		sshclient.Net(netConnect),
+		sshclient.User("test"),
+		sshclient.HostKeyCallback(ssh.InsecureIgnoreHostKey()),
+		sshclient.Auth(sshclient.Password("test")),
+	)
+	require.NoError(t, err)
+
+	client := sshclient.New(connect, opts...)
+	t.Cleanup(func() {
+		_ = client.Close()
+	})
+
+	return client
+}
+
+func noClientAuthConfig() *ssh.ServerConfig {
+	return &ssh.ServerConfig{
+		NoClientAuth: true,
+	}
+}
+
+func TestClient_Connected(t *testing.T) {
+	t.Parallel()
+
+	var connections atomic.Int32
+	drop := make(chan struct{})
+
+	addr := newTestSshServer(t, noClientAuthConfig(), func(conn *ssh.ServerConn) {
+		if connections.Add(1) == 1 {
+			// Drop the first connection
+			<-drop
+			return
+		}
+		_ = conn.Wait()
+	})
+
+	client := newTestClient(t, sshclient.Dial(addr, 5*time.Second), addr)
+
+	require.NoError(t, client.Connected(context.Background()))
+	assert.True(t, client.Alive())
+
+	close(drop)
+	assert.Eventually(t, func() bool {
+		return !client.Alive()
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// Reconnect
+	require.NoError(t, client.Connected(context.Background()))
+	assert.True(t, client.Alive())
+	assert.Equal(t, int32(2), connections.Load())
+}
+
+// blackholeConn is a net.Conn which stops delivering data in both directions after blackhole has been called
+type blackholeConn struct {
+	net.Conn
+
+	once   sync.Once
+	closed chan struct{}
+	active atomic.Bool
+}
+
+func (c *blackholeConn) blackhole() {
+	c.active.Store(true)
+}
+
+func (c *blackholeConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	if c.active.Load() {
+		<-c.closed
+		return 0, net.ErrClosed
+	}
+	return n, err
+}
+
+func (c *blackholeConn) Write(b []byte) (int, error) {
+	if c.active.Load() {
+		return len(b), nil
+	}
+	return c.Conn.Write(b)
+}
+
+func (c *blackholeConn) Close() error {
+	c.once.Do(func() {
+		close(c.closed)
+	})
+	return c.Conn.Close()
+}
+
+func TestClient_Keepalive(t *testing.T) {
+	t.Parallel()
+
+	addr := newTestSshServer(t, noClientAuthConfig(), nil)
+
+	var conn *blackholeConn
+	netConnect := func(ctx context.Context) (net.Conn, error) {
+		c, err := sshclient.Dial(addr, 5*time.Second)(ctx)
+		if err != nil {
+			return nil, err
+		}
+		conn = &blackholeConn{Conn: c, closed: make(chan struct{})}
+		return conn, nil
+	}
+
+	client := newTestClient(t, netConnect, addr, sshclient.Keepalive(50*time.Millisecond, 2))
+
+	require.NoError(t, client.Connected(context.Background()))
+
+	// Keepalive requests are answered
+	time.Sleep(200 * time.Millisecond)
+	assert.True(t, client.Alive())
+
+	// Keepalive requests are not answered
+	conn.blackhole()
+	assert.Eventually(t, func() bool {
+		return !client.Alive()
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestCircuitBreak(t *testing.T) {
+	t.Parallel()
+
+	type testCase struct {
+		Desc        string
+		Err         error
+		ExpectCalls int
+	}
+
+	tcs := []testCase{
+		{Desc: "auth error trips", Err: errors.New("ssh: handshake failed: ssh: unable to authenticate, attempted methods [none password], no supported methods remain"), ExpectCalls: 1},
+		{Desc: "network error does not trip", Err: &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}, ExpectCalls: 3},
+	}
+
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.Desc, func(t *testing.T) {
+			t.Parallel()
+
+			calls := 0
+			connect := sshclient.CircuitBreak()(func(ctx context.Context) (ssh.Conn, <-chan ssh.NewChannel, <-chan *ssh.Request, error) {
+				calls++
+				return nil, nil, nil, tc.Err
+			})
+
+			for i := 0; i < 3; i++ {
+				_, _, _, err := connect(context.Background())
+				assert.Equal(t, tc.Err, err)
+			}
+
+			assert.Equal(t, tc.ExpectCalls, calls)
+		})
+	}
+}
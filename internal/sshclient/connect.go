@@ -2,9 +2,13 @@ package sshclient
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"github.com/sethvargo/go-retry"
 	"golang.org/x/crypto/ssh"
 	"net"
+	"strings"
+	"sync"
 )
 
 type ConnectFunc func(ctx context.Context) (ssh.Conn, <-chan ssh.NewChannel, <-chan *ssh.Request, error)
@@ -52,21 +56,31 @@ func ConnectCustom(netConnectFunc NetConnectFunc, addr net.Addr, clientConfig *s
 }
 
 // CircuitBreak wraps a ConnectFunc and ensures that the ConnectFunc will not be called again when a previous
-// call returned an error. If the ConnectFunc has returned an error previously, CircuitBreak will not call it
-// again and return the same error.
+// call returned an authentication error. If the ConnectFunc has returned an authentication error previously,
+// CircuitBreak will not call it again and return the same error. Other errors, e.g. network errors, do not trip the
+// circuit breaker because a later attempt may succeed.
 func CircuitBreak() ConnectMiddleware {
 	return func(next ConnectFunc) ConnectFunc {
 		var connectErr error
+		var connectErrM sync.Mutex
 
 		return func(ctx context.Context) (ssh.Conn, <-chan ssh.NewChannel, <-chan *ssh.Request, error) {
-			if connectErr != nil {
-				return nil, nil, nil, connectErr
+			connectErrM.Lock()
+			err := connectErr
+			connectErrM.Unlock()
+
+			if err != nil {
+				return nil, nil, nil, err
 			}
 
 			conn, chans, reqs, err := next(ctx)
 
 			if err != nil {
-				connectErr = err
+				if IsAuthError(err) {
+					connectErrM.Lock()
+					connectErr = err
+					connectErrM.Unlock()
+				}
 				return nil, nil, nil, err
 			}
 
@@ -75,6 +89,49 @@ func CircuitBreak() ConnectMiddleware {
 	}
 }
 
+// IsAuthError returns true if err indicates that the authentication with the remote ssh server has failed
+func IsAuthError(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "ssh: unable to authenticate")
+}
+
+// ConnectError is an error of a ConnectFunc wrapped with Describe
+type ConnectError struct {
+	Description string
+	Err         error
+}
+
+func (e *ConnectError) Error() string {
+	return fmt.Sprintf("sshclient: %s: %v", e.Description, e.Err)
+}
+
+func (e *ConnectError) Unwrap() error {
+	return e.Err
+}
+
+// Describe wraps a ConnectFunc and returns errors as ConnectError with the provided description.
+// Errors which are already a ConnectError are returned unchanged. Describe allows to identify the failing hop when
+// ConnectFunc are chained: the description of the innermost failing ConnectFunc is retained.
+func Describe(description string) ConnectMiddleware {
+	return func(next ConnectFunc) ConnectFunc {
+		return func(ctx context.Context) (ssh.Conn, <-chan ssh.NewChannel, <-chan *ssh.Request, error) {
+			conn, chans, reqs, err := next(ctx)
+			if err != nil {
+				var connectErr *ConnectError
+				if errors.As(err, &connectErr) {
+					return nil, nil, nil, err
+				}
+
+				return nil, nil, nil, &ConnectError{
+					Description: description,
+					Err:         err,
+				}
+			}
+
+			return conn, chans, reqs, nil
+		}
+	}
+}
+
 // Retry wraps a NetConnectFunc and attempts retries according to the provided retry.Backoff
 func Retry(backoff retry.Backoff) ConnectMiddleware {
 	return func(next ConnectFunc) ConnectFunc {
@@ -89,13 +146,12 @@ func Retry(backoff retry.Backoff) ConnectMiddleware {
 				conn, chans, reqs, err = next(ctx)
 
 				if err != nil {
-					switch err.(type) {
-					case *net.OpError:
+					var opErr *net.OpError
+					if errors.As(err, &opErr) {
 						return retry.RetryableError(err)
 					}
 
-					switch err.Error() {
-					case "ssh: handshake failed: EOF":
+					if strings.HasSuffix(err.Error(), "ssh: handshake failed: EOF") {
 						return retry.RetryableError(err)
 					}
 
@@ -13,6 +13,8 @@ type connectArgs struct {
 	netConnectFunc NetConnectFunc
 	addr           net.Addr
 	clientConfig   *ssh.ClientConfig
+
+	knownHostKeyAlgorithms bool
 }
 
 func processConnectOpts(opts []ConnectOption) (*connectArgs, error) {
@@ -29,6 +31,11 @@ func processConnectOpts(opts []ConnectOption) (*connectArgs, error) {
 		}
 	}
 
+	// Restrict host key algorithms to keys known for the remote
+	if args.knownHostKeyAlgorithms && len(args.clientConfig.HostKeyAlgorithms) == 0 {
+		args.clientConfig.HostKeyAlgorithms = knownHostKeyAlgorithms(args.clientConfig.HostKeyCallback, args.addr)
+	}
+
 	return args, nil
 }
 
@@ -70,6 +77,16 @@ func HostKey(hostKeyVerifier HostKeyVerifier) ConnectOption {
 	}
 }
 
+// KnownHostKeyAlgorithms restricts the negotiated host key algorithms to the types of the keys which are known for the
+// remote in known_hosts. KnownHostKeyAlgorithms ensures that a remote with multiple host keys presents a key which can be
+// verified. KnownHostKeyAlgorithms has no effect if the host key callback is not backed by known_hosts.
+func KnownHostKeyAlgorithms() ConnectOption {
+	return func(c *connectArgs) error {
+		c.knownHostKeyAlgorithms = true
+		return nil
+	}
+}
+
 func HostKeyCallback(callback ssh.HostKeyCallback) ConnectOption {
 	return func(c *connectArgs) error {
 		c.clientConfig.HostKeyCallback = callback
@@ -0,0 +1,107 @@
+package sshclient
+
+import (
+	"bytes"
+	"fmt"
+	"golang.org/x/crypto/ssh"
+	"net"
+	"time"
+)
+
+type HostCertificateOption func(*hostCertificateArgs) error
+
+type hostCertificateArgs struct {
+	revokedSerials map[uint64]struct{}
+	fallback       HostKeyVerifier
+	clock          func() time.Time
+}
+
+// HostCertificateRevokedSerials rejects host certificates with one of the provided serial numbers.
+func HostCertificateRevokedSerials(serials ...uint64) HostCertificateOption {
+	return func(a *hostCertificateArgs) error {
+		for _, serial := range serials {
+			a.revokedSerials[serial] = struct{}{}
+		}
+		return nil
+	}
+}
+
+// HostCertificateFallback verifies host keys which are not certificates using the provided HostKeyVerifier.
+// Without fallback, host keys which are not certificates are rejected.
+func HostCertificateFallback(fallback HostKeyVerifier) HostCertificateOption {
+	return func(a *hostCertificateArgs) error {
+		a.fallback = fallback
+		return nil
+	}
+}
+
+// HostCertificateClock sets the clock which is used to check the validity window of host certificates.
+// Defaults to time.Now.
+func HostCertificateClock(clock func() time.Time) HostCertificateOption {
+	return func(a *hostCertificateArgs) error {
+		a.clock = clock
+		return nil
+	}
+}
+
+// HostCertificate returns a HostKeyVerifier which accepts host certificates signed by one of the provided certificate
+// authorities. caPublicKeys are expected in the OpenSSH `authorized_keys` format.
+// HostCertificate uses ssh.CertChecker which verifies that the host of the remote address is a principal of the
+// certificate, that the current time is within the validity window of the certificate and that the certificate
+// is not revoked.
+func HostCertificate(caPublicKeys []string, opts ...HostCertificateOption) HostKeyVerifier {
+	return func() (ssh.HostKeyCallback, error) {
+		args := &hostCertificateArgs{
+			revokedSerials: map[uint64]struct{}{},
+		}
+
+		for _, opt := range opts {
+			if err := opt(args); err != nil {
+				return nil, err
+			}
+		}
+
+		if len(caPublicKeys) == 0 {
+			return nil, fmt.Errorf("sshclient: expected at least one host certificate authority")
+		}
+
+		var authorities []ssh.PublicKey
+		for _, caPublicKey := range caPublicKeys {
+			pk, _, _, _, err := ssh.ParseAuthorizedKey([]byte(caPublicKey))
+			if err != nil {
+				return nil, fmt.Errorf("sshclient: failed to parse host certificate authority %q: %w", caPublicKey, err)
+			}
+			authorities = append(authorities, pk)
+		}
+
+		certChecker := &ssh.CertChecker{
+			IsHostAuthority: func(auth ssh.PublicKey, address string) bool {
+				for _, authority := range authorities {
+					if bytes.Equal(authority.Marshal(), auth.Marshal()) {
+						return true
+					}
+				}
+				return false
+			},
+			IsRevoked: func(cert *ssh.Certificate) bool {
+				_, revoked := args.revokedSerials[cert.Serial]
+				return revoked
+			},
+			Clock: args.clock,
+		}
+
+		if args.fallback != nil {
+			fallback, err := args.fallback()
+			if err != nil {
+				return nil, err
+			}
+			certChecker.HostKeyFallback = fallback
+		} else {
+			certChecker.HostKeyFallback = func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+				return fmt.Errorf("sshclient: host %s did not present a host certificate", hostname)
+			}
+		}
+
+		return certChecker.CheckHostKey, nil
+	}
+}
@@ -0,0 +1,112 @@
+package sshclient_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"github.com/neuspaces/terraform-provider-system/internal/sshclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+	"testing"
+	"time"
+)
+
+func newTestSigner(t *testing.T) ssh.Signer {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	signer, err := ssh.NewSignerFromKey(priv)
+	require.NoError(t, err)
+
+	return signer
+}
+
+func newTestHostCertificate(t *testing.T, ca ssh.Signer, serial uint64, principals []string, validAfter time.Time, validBefore time.Time) *ssh.Certificate {
+	cert := &ssh.Certificate{
+		Key:             newTestPublicKey(t),
+		Serial:          serial,
+		CertType:        ssh.HostCert,
+		ValidPrincipals: principals,
+		ValidAfter:      uint64(validAfter.Unix()),
+		ValidBefore:     uint64(validBefore.Unix()),
+	}
+	require.NoError(t, cert.SignCert(rand.Reader, ca))
+
+	return cert
+}
+
+func TestHostCertificate(t *testing.T) {
+	t.Parallel()
+
+	ca := newTestSigner(t)
+	otherCa := newTestSigner(t)
+	caPublicKey := string(ssh.MarshalAuthorizedKey(ca.PublicKey()))
+
+	now := time.Now()
+	validAfter := now.Add(-1 * time.Hour)
+	validBefore := now.Add(1 * time.Hour)
+
+	addr := sshclient.NewHostPortAddr(sshclient.Tcp, "host.example.com", 22)
+
+	plainKey := newTestPublicKey(t)
+
+	type testCase struct {
+		Desc      string
+		Opts      []sshclient.HostCertificateOption
+		Key       ssh.PublicKey
+		ExpectErr string
+	}
+
+	tcs := []testCase{
+		{
+			Desc: "valid certificate",
+			Key:  newTestHostCertificate(t, ca, 1, []string{"host.example.com"}, validAfter, validBefore),
+		},
+		{
+			Desc:      "principal mismatch",
+			Key:       newTestHostCertificate(t, ca, 1, []string{"other.example.com"}, validAfter, validBefore),
+			ExpectErr: "not in the set of valid principals",
+		},
+		{
+			Desc:      "expired certificate",
+			Key:       newTestHostCertificate(t, ca, 1, []string{"host.example.com"}, now.Add(-2*time.Hour), now.Add(-1*time.Hour)),
+			ExpectErr: "cert has expired",
+		},
+		{
+			Desc:      "revoked serial",
+			Opts:      []sshclient.HostCertificateOption{sshclient.HostCertificateRevokedSerials(7)},
+			Key:       newTestHostCertificate(t, ca, 7, []string{"host.example.com"}, validAfter, validBefore),
+			ExpectErr: "certificate serial 7 revoked",
+		},
+		{
+			Desc:      "unknown authority",
+			Key:       newTestHostCertificate(t, otherCa, 1, []string{"host.example.com"}, validAfter, validBefore),
+			ExpectErr: "no authorities for hostname",
+		},
+		{
+			Desc:      "plain key without fallback",
+			Key:       plainKey,
+			ExpectErr: "did not present a host certificate",
+		},
+		{
+			Desc: "plain key with fallback",
+			Opts: []sshclient.HostCertificateOption{sshclient.HostCertificateFallback(sshclient.StaticHostKey(string(ssh.MarshalAuthorizedKey(plainKey))))},
+			Key:  plainKey,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.Desc, func(t *testing.T) {
+			callback, err := sshclient.HostCertificate([]string{caPublicKey}, tc.Opts...)()
+			require.NoError(t, err)
+
+			err = callback(addr.String(), addr, tc.Key)
+
+			if tc.ExpectErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tc.ExpectErr)
+			}
+		})
+	}
+}
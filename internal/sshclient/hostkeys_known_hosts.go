@@ -0,0 +1,176 @@
+package sshclient
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"github.com/neuspaces/terraform-provider-system/internal/lib/homedir"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+	"net"
+	"os"
+	"strings"
+)
+
+// KnownHosts returns a HostKeyVerifier which verifies host keys against OpenSSH known_hosts entries.
+// knownHosts is either the path to a known_hosts file or the content of a known_hosts file.
+// The value is treated as content if it spans multiple lines or if it is a single valid known_hosts entry.
+func KnownHosts(knownHosts string) HostKeyVerifier {
+	if isKnownHostsContent(knownHosts) {
+		return KnownHostsContent(knownHosts)
+	}
+
+	return KnownHostsFile(knownHosts)
+}
+
+// KnownHostsFile returns a HostKeyVerifier which verifies host keys against one or more files in the OpenSSH
+// known_hosts format. A leading `~` in a path is expanded to the home directory of the current user.
+// Hashed hostnames, `[host]:port` patterns, multiple keys per host and the markers `@cert-authority` and `@revoked`
+// are supported.
+func KnownHostsFile(paths ...string) HostKeyVerifier {
+	return func() (ssh.HostKeyCallback, error) {
+		var expandedPaths []string
+		for _, path := range paths {
+			expandedPath, err := homedir.Expand(path)
+			if err != nil {
+				return nil, fmt.Errorf("sshclient: failed to expand known_hosts path %q: %w", path, err)
+			}
+			expandedPaths = append(expandedPaths, expandedPath)
+		}
+
+		callback, err := knownhosts.New(expandedPaths...)
+		if err != nil {
+			return nil, fmt.Errorf("sshclient: failed to read known_hosts: %w", err)
+		}
+
+		return knownHostsCallback(callback), nil
+	}
+}
+
+// KnownHostsContent returns a HostKeyVerifier which verifies host keys against the provided content in the OpenSSH
+// known_hosts format. Refer to KnownHostsFile for supported features.
+func KnownHostsContent(content string) HostKeyVerifier {
+	return func() (ssh.HostKeyCallback, error) {
+		// knownhosts.New only reads from files; the content is staged in a temporary file which is removed after parsing
+		f, err := os.CreateTemp("", "known_hosts")
+		if err != nil {
+			return nil, fmt.Errorf("sshclient: failed to stage known_hosts: %w", err)
+		}
+		defer func() {
+			_ = os.Remove(f.Name())
+		}()
+
+		_, err = f.WriteString(content)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return nil, fmt.Errorf("sshclient: failed to stage known_hosts: %w", err)
+		}
+
+		callback, err := knownhosts.New(f.Name())
+		if err != nil {
+			// Hide the name of the temporary file from the error
+			return nil, fmt.Errorf("sshclient: failed to parse known_hosts: %s", strings.ReplaceAll(err.Error(), f.Name(), "known_hosts"))
+		}
+
+		return knownHostsCallback(callback), nil
+	}
+}
+
+// knownHostsCallback wraps an ssh.HostKeyCallback returned by knownhosts.New and returns descriptive errors.
+// The original errors of the knownhosts package remain accessible using errors.As.
+func knownHostsCallback(callback ssh.HostKeyCallback) ssh.HostKeyCallback {
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		err := callback(hostname, remote, key)
+		if err == nil {
+			return nil
+		}
+
+		var revokedErr *knownhosts.RevokedError
+		if errors.As(err, &revokedErr) {
+			return fmt.Errorf("sshclient: host key of %s is revoked: %w", hostname, err)
+		}
+
+		var keyErr *knownhosts.KeyError
+		if errors.As(err, &keyErr) {
+			if len(keyErr.Want) == 0 {
+				return fmt.Errorf("sshclient: host %s is unknown in known_hosts: %w", hostname, err)
+			}
+			return fmt.Errorf("sshclient: host key mismatch for %s: %w", hostname, err)
+		}
+
+		return err
+	}
+}
+
+// isKnownHostsContent returns true if s is the content of a known_hosts file rather than a path to a known_hosts file
+func isKnownHostsContent(s string) bool {
+	s = strings.TrimSpace(s)
+
+	if s == "" || strings.Contains(s, "\n") || strings.HasPrefix(s, "#") {
+		return true
+	}
+
+	_, _, _, _, _, err := ssh.ParseKnownHosts([]byte(s))
+	return err == nil
+}
+
+// knownHostKeyAlgorithms returns the host key algorithms of the keys which are known for addr by a known_hosts
+// ssh.HostKeyCallback. The returned algorithms are ordered by preference.
+// knownHostKeyAlgorithms returns nil if callback is not backed by known_hosts or if no keys are known for addr.
+// Restricting the host key algorithms ensures that the remote presents a key which is present in known_hosts in case
+// the remote has multiple host keys of different types.
+func knownHostKeyAlgorithms(callback ssh.HostKeyCallback, addr net.Addr) []string {
+	if callback == nil || addr == nil {
+		return nil
+	}
+
+	// Probe the callback using a key which is never present in known_hosts
+	probeKey, err := ssh.NewPublicKey(ed25519.NewKeyFromSeed(make([]byte, ed25519.SeedSize)).Public())
+	if err != nil {
+		return nil
+	}
+
+	var keyErr *knownhosts.KeyError
+	if err := callback(addr.String(), addr, probeKey); !errors.As(err, &keyErr) || len(keyErr.Want) == 0 {
+		return nil
+	}
+
+	knownKeyTypes := map[string]struct{}{}
+	for _, knownKey := range keyErr.Want {
+		knownKeyTypes[knownKey.Key.Type()] = struct{}{}
+	}
+
+	var algorithms []string
+	for _, algorithm := range hostKeyAlgorithmsPreference {
+		if _, ok := knownKeyTypes[hostKeyAlgorithmKeyType(algorithm)]; ok {
+			algorithms = append(algorithms, algorithm)
+		}
+	}
+
+	return algorithms
+}
+
+// hostKeyAlgorithmsPreference is the order of preference of host key algorithms
+var hostKeyAlgorithmsPreference = []string{
+	ssh.KeyAlgoED25519,
+	ssh.KeyAlgoECDSA256,
+	ssh.KeyAlgoECDSA384,
+	ssh.KeyAlgoECDSA521,
+	ssh.KeyAlgoSKED25519,
+	ssh.KeyAlgoSKECDSA256,
+	ssh.KeyAlgoRSASHA512,
+	ssh.KeyAlgoRSASHA256,
+	ssh.KeyAlgoRSA,
+	ssh.KeyAlgoDSA,
+}
+
+// hostKeyAlgorithmKeyType returns the public key type of a host key algorithm
+func hostKeyAlgorithmKeyType(algorithm string) string {
+	switch algorithm {
+	case ssh.KeyAlgoRSASHA512, ssh.KeyAlgoRSASHA256:
+		return ssh.KeyAlgoRSA
+	}
+	return algorithm
+}
@@ -0,0 +1,97 @@
+package sshclient_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"github.com/neuspaces/terraform-provider-system/internal/sshclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newTestPublicKey(t *testing.T) ssh.PublicKey {
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	sshPub, err := ssh.NewPublicKey(pub)
+	require.NoError(t, err)
+
+	return sshPub
+}
+
+func TestKnownHosts(t *testing.T) {
+	t.Parallel()
+
+	hostKey := newTestPublicKey(t)
+	otherKey := newTestPublicKey(t)
+	revokedKey := newTestPublicKey(t)
+
+	addr := sshclient.NewHostPortAddr(sshclient.Tcp, "10.0.0.1", 22)
+	addrPort := sshclient.NewHostPortAddr(sshclient.Tcp, "10.0.0.1", 2222)
+	addrHashed := sshclient.NewHostPortAddr(sshclient.Tcp, "example.com", 22)
+
+	knownHostsContent := strings.Join([]string{
+		"# comment",
+		knownhosts.Line([]string{"10.0.0.1"}, hostKey),
+		knownhosts.Line([]string{"[10.0.0.1]:2222"}, otherKey),
+		knownhosts.Line([]string{knownhosts.HashHostname("example.com")}, hostKey),
+		"@revoked * " + strings.TrimSpace(string(ssh.MarshalAuthorizedKey(revokedKey))),
+	}, "\n")
+
+	type testCase struct {
+		Desc      string
+		Addr      string
+		Key       ssh.PublicKey
+		ExpectErr string
+	}
+
+	tcs := []testCase{
+		{Desc: "known host", Addr: addr.String(), Key: hostKey},
+		{Desc: "host with port", Addr: addrPort.String(), Key: otherKey},
+		{Desc: "hashed host", Addr: addrHashed.String(), Key: hostKey},
+		{Desc: "key mismatch", Addr: addr.String(), Key: otherKey, ExpectErr: "host key mismatch"},
+		{Desc: "key mismatch with port", Addr: addrPort.String(), Key: hostKey, ExpectErr: "host key mismatch"},
+		{Desc: "unknown host", Addr: "10.0.0.2:22", Key: hostKey, ExpectErr: "is unknown in known_hosts"},
+		{Desc: "revoked key", Addr: addr.String(), Key: revokedKey, ExpectErr: "is revoked"},
+	}
+
+	knownHostsFile := filepath.Join(t.TempDir(), "known_hosts")
+	require.NoError(t, os.WriteFile(knownHostsFile, []byte(knownHostsContent), 0600))
+
+	verifiers := map[string]sshclient.HostKeyVerifier{
+		"content": sshclient.KnownHosts(knownHostsContent),
+		"file":    sshclient.KnownHosts(knownHostsFile),
+	}
+
+	for verifierDesc, verifier := range verifiers {
+		callback, err := verifier()
+		require.NoError(t, err)
+
+		for _, tc := range tcs {
+			t.Run(verifierDesc+"/"+tc.Desc, func(t *testing.T) {
+				err := callback(tc.Addr, addr, tc.Key)
+
+				if tc.ExpectErr == "" {
+					assert.NoError(t, err)
+				} else {
+					assert.ErrorContains(t, err, tc.ExpectErr)
+				}
+			})
+		}
+	}
+}
+
+func TestKnownHosts_Invalid(t *testing.T) {
+	t.Parallel()
+
+	_, err := sshclient.KnownHosts("10.0.0.1 ssh-ed25519 invalid\n")()
+	assert.Error(t, err)
+
+	_, err = sshclient.KnownHosts(filepath.Join(t.TempDir(), "missing"))()
+	assert.Error(t, err)
+}
@@ -2,8 +2,10 @@ package sshclient
 
 import (
 	"context"
+	"errors"
 	"github.com/sethvargo/go-retry"
 	"net"
+	"strings"
 )
 
 type NetConnectFunc func(context.Context) (net.Conn, error)
@@ -44,13 +46,12 @@ func NetRetry(backoff retry.Backoff) NetConnectMiddleware {
 				var err error
 				conn, err = next(ctx)
 				if err != nil {
-					switch err.(type) {
-					case *net.OpError:
+					var opErr *net.OpError
+					if errors.As(err, &opErr) {
 						return retry.RetryableError(err)
 					}
 
-					switch err.Error() {
-					case "ssh: handshake failed: EOF":
+					if strings.HasSuffix(err.Error(), "ssh: handshake failed: EOF") {
 						return retry.RetryableError(err)
 					}
 
@@ -66,3 +67,26 @@ func NetRetry(backoff retry.Backoff) NetConnectMiddleware {
 		}
 	}
 }
+
+// NetDescribe wraps a NetConnectFunc and returns errors as ConnectError with the provided description.
+// Errors which are already a ConnectError are returned unchanged. See Describe.
+func NetDescribe(description string) NetConnectMiddleware {
+	return func(next NetConnectFunc) NetConnectFunc {
+		return func(ctx context.Context) (net.Conn, error) {
+			conn, err := next(ctx)
+			if err != nil {
+				var connectErr *ConnectError
+				if errors.As(err, &connectErr) {
+					return nil, err
+				}
+
+				return nil, &ConnectError{
+					Description: description,
+					Err:         err,
+				}
+			}
+
+			return conn, nil
+		}
+	}
+}
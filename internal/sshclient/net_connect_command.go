@@ -0,0 +1,250 @@
+package sshclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// commandStderrLimit is the maximum number of bytes of stderr of a proxy command which are retained for error messages
+const commandStderrLimit = 4096
+
+// commandExitGracePeriod is the duration to wait for a proxy command to exit after it has closed stdout
+const commandExitGracePeriod = 1 * time.Second
+
+// Command returns a NetConnectFunc which starts the local command and uses its stdin and stdout as connection to the
+// remote addr. The command is equivalent to ProxyCommand of OpenSSH and is executed using /bin/sh.
+// The tokens %h, %p and %r in the command are replaced with host and port of addr and the remote user. A literal %
+// is expressed as %%.
+// Closing the returned net.Conn stops the command.
+func Command(command string, addr net.Addr, user string) NetConnectFunc {
+	return func(ctx context.Context) (net.Conn, error) {
+		expanded, err := ExpandCommandTokens(command, addr, user)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		return startCommandConn(expanded, addr)
+	}
+}
+
+// ExpandCommandTokens replaces the tokens %h (host), %p (port), %r (remote user) and %% in command.
+func ExpandCommandTokens(command string, addr net.Addr, user string) (string, error) {
+	host, port, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return "", fmt.Errorf("sshclient: invalid address %s: %w", addr, err)
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(command); i++ {
+		if command[i] != '%' {
+			b.WriteByte(command[i])
+			continue
+		}
+
+		if i+1 >= len(command) {
+			return "", fmt.Errorf("sshclient: invalid token at end of command")
+		}
+
+		i++
+		switch command[i] {
+		case 'h':
+			b.WriteString(host)
+		case 'p':
+			b.WriteString(port)
+		case 'r':
+			b.WriteString(user)
+		case '%':
+			b.WriteByte('%')
+		default:
+			return "", fmt.Errorf("sshclient: unsupported token %%%c in command", command[i])
+		}
+	}
+
+	return b.String(), nil
+}
+
+func startCommandConn(command string, addr net.Addr) (net.Conn, error) {
+	// os.Pipe is used instead of exec.Cmd pipes because *os.File supports deadlines
+	stdinR, stdinW, err := os.Pipe()
+	if err != nil {
+		return nil, fmt.Errorf("sshclient: failed to create pipe: %w", err)
+	}
+
+	stdoutR, stdoutW, err := os.Pipe()
+	if err != nil {
+		_ = stdinR.Close()
+		_ = stdinW.Close()
+		return nil, fmt.Errorf("sshclient: failed to create pipe: %w", err)
+	}
+
+	stderr := &commandStderr{}
+
+	cmd := exec.Command("/bin/sh", "-c", command)
+	cmd.Stdin = stdinR
+	cmd.Stdout = stdoutW
+	cmd.Stderr = stderr
+	// Bound waiting for stderr if descendants of the command keep it open after the command has been stopped
+	cmd.WaitDelay = commandExitGracePeriod
+
+	err = cmd.Start()
+
+	// The command holds its own copies of the pipe ends
+	_ = stdinR.Close()
+	_ = stdoutW.Close()
+
+	if err != nil {
+		_ = stdinW.Close()
+		_ = stdoutR.Close()
+		return nil, fmt.Errorf("sshclient: failed to start proxy command: %w", err)
+	}
+
+	c := &commandConn{
+		cmd:    cmd,
+		stdin:  stdinW,
+		stdout: stdoutR,
+		stderr: stderr,
+		addr:   addr,
+		done:   make(chan struct{}),
+	}
+
+	go func() {
+		c.waitErr = cmd.Wait()
+		close(c.done)
+	}()
+
+	return c, nil
+}
+
+// commandConn is a net.Conn using stdin and stdout of a command
+type commandConn struct {
+	cmd    *exec.Cmd
+	stdin  *os.File
+	stdout *os.File
+	stderr *commandStderr
+	addr   net.Addr
+
+	// done is closed when the command has exited. waitErr must not be accessed before.
+	done    chan struct{}
+	waitErr error
+
+	closeOnce sync.Once
+	closeErr  error
+}
+
+var _ net.Conn = &commandConn{}
+
+func (c *commandConn) Read(b []byte) (int, error) {
+	n, err := c.stdout.Read(b)
+	if errors.Is(err, io.EOF) {
+		// Report the exit status and stderr if the command has failed
+		select {
+		case <-c.done:
+			if c.waitErr != nil {
+				return n, c.exitError()
+			}
+		case <-time.After(commandExitGracePeriod):
+		}
+	}
+	return n, err
+}
+
+func (c *commandConn) Write(b []byte) (int, error) {
+	return c.stdin.Write(b)
+}
+
+// Close closes stdin and stdout and stops the command if it has not exited yet
+func (c *commandConn) Close() error {
+	c.closeOnce.Do(func() {
+		stdinErr := c.stdin.Close()
+		stdoutErr := c.stdout.Close()
+
+		select {
+		case <-c.done:
+		default:
+			_ = c.cmd.Process.Kill()
+			<-c.done
+		}
+
+		c.closeErr = errors.Join(stdinErr, stdoutErr)
+	})
+
+	return c.closeErr
+}
+
+func (c *commandConn) exitError() error {
+	if msg := strings.TrimSpace(c.stderr.String()); msg != "" {
+		return fmt.Errorf("sshclient: proxy command failed: %w: %s", c.waitErr, msg)
+	}
+	return fmt.Errorf("sshclient: proxy command failed: %w", c.waitErr)
+}
+
+func (c *commandConn) LocalAddr() net.Addr {
+	return commandAddr{}
+}
+
+func (c *commandConn) RemoteAddr() net.Addr {
+	return c.addr
+}
+
+func (c *commandConn) SetDeadline(t time.Time) error {
+	return errors.Join(c.stdin.SetDeadline(t), c.stdout.SetDeadline(t))
+}
+
+func (c *commandConn) SetReadDeadline(t time.Time) error {
+	return c.stdout.SetReadDeadline(t)
+}
+
+func (c *commandConn) SetWriteDeadline(t time.Time) error {
+	return c.stdin.SetWriteDeadline(t)
+}
+
+// commandAddr is the local net.Addr of a commandConn
+type commandAddr struct{}
+
+func (commandAddr) Network() string {
+	return "command"
+}
+
+func (commandAddr) String() string {
+	return "command"
+}
+
+// commandStderr retains the first commandStderrLimit bytes written to stderr of a command and discards the rest
+type commandStderr struct {
+	mu  sync.Mutex
+	buf strings.Builder
+}
+
+func (w *commandStderr) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if remaining := commandStderrLimit - w.buf.Len(); remaining > 0 {
+		if len(p) > remaining {
+			w.buf.Write(p[:remaining])
+		} else {
+			w.buf.Write(p)
+		}
+	}
+
+	return len(p), nil
+}
+
+func (w *commandStderr) String() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.buf.String()
+}
@@ -0,0 +1,99 @@
+package sshclient_test
+
+import (
+	"context"
+	"github.com/neuspaces/terraform-provider-system/internal/sshclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestExpandCommandTokens(t *testing.T) {
+	t.Parallel()
+
+	addr := sshclient.NewHostPortAddr(sshclient.Tcp, "remote.example.com", 2222)
+
+	type testCase struct {
+		Desc      string
+		Command   string
+		Expect    string
+		ExpectErr string
+	}
+
+	tcs := []testCase{
+		{Desc: "no tokens", Command: "nc proxy 22", Expect: "nc proxy 22"},
+		{Desc: "host and port", Command: "nc %h %p", Expect: "nc remote.example.com 2222"},
+		{Desc: "user", Command: "connect --user=%r %h", Expect: "connect --user=root remote.example.com"},
+		{Desc: "literal percent", Command: "echo 100%% %h", Expect: "echo 100% remote.example.com"},
+		{Desc: "unsupported token", Command: "nc %x", ExpectErr: "unsupported token %x"},
+		{Desc: "trailing percent", Command: "nc %", ExpectErr: "invalid token at end of command"},
+	}
+
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.Desc, func(t *testing.T) {
+			t.Parallel()
+
+			actual, err := sshclient.ExpandCommandTokens(tc.Command, addr, "root")
+
+			if tc.ExpectErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tc.ExpectErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.Expect, actual)
+		})
+	}
+}
+
+func TestCommand(t *testing.T) {
+	t.Parallel()
+
+	addr := sshclient.NewHostPortAddr(sshclient.Tcp, "remote.example.com", 22)
+
+	t.Run("stdin and stdout", func(t *testing.T) {
+		t.Parallel()
+
+		conn, err := sshclient.Command("cat", addr, "root")(context.Background())
+		require.NoError(t, err)
+		defer conn.Close()
+
+		assertEcho(t, conn)
+	})
+
+	t.Run("close stops command", func(t *testing.T) {
+		t.Parallel()
+
+		conn, err := sshclient.Command("sleep 60", addr, "root")(context.Background())
+		require.NoError(t, err)
+
+		closed := make(chan error)
+		go func() {
+			closed <- conn.Close()
+		}()
+
+		select {
+		case err := <-closed:
+			assert.NoError(t, err)
+		case <-time.After(10 * time.Second):
+			t.Error("command was not stopped")
+		}
+	})
+
+	t.Run("failing command", func(t *testing.T) {
+		t.Parallel()
+
+		conn, err := sshclient.Command("echo 'cannot reach %h' >&2; exit 3", addr, "root")(context.Background())
+		require.NoError(t, err)
+		defer conn.Close()
+
+		_, err = io.ReadAll(conn)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "exit status 3")
+		assert.Contains(t, err.Error(), "cannot reach remote.example.com")
+	})
+}
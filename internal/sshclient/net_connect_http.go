@@ -0,0 +1,102 @@
+package sshclient
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// HttpConnect returns a NetConnectFunc which connects to the remote addr via an HTTP proxy server using the
+// HTTP CONNECT method. proxyConnect establishes the connection to the proxy server, e.g. using Dial.
+// Use ProxyTLS to connect to the proxy server using TLS.
+func HttpConnect(proxyConnect NetConnectFunc, proxyAddr net.Addr, addr net.Addr, opts ...ProxyServerOption) NetConnectFunc {
+	return func(ctx context.Context) (net.Conn, error) {
+		args, err := processProxyServerOpts(opts)
+		if err != nil {
+			return nil, err
+		}
+
+		conn, err := proxyConnect(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		conn, err = httpConnect(ctx, conn, args, addr)
+		if err != nil {
+			_ = conn.Close()
+			return nil, fmt.Errorf("sshclient: failed to connect to %s via http proxy %s: %w", addr, proxyAddr, err)
+		}
+
+		return conn, nil
+	}
+}
+
+// httpConnect requests a tunnel to addr on an established connection to an HTTP proxy server.
+// The returned net.Conn must be used instead of conn. The returned net.Conn is never nil.
+func httpConnect(ctx context.Context, conn net.Conn, args *proxyServerArgs, addr net.Addr) (net.Conn, error) {
+	// Respect deadline of the context during the handshake
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+		defer func() {
+			_ = conn.SetDeadline(time.Time{})
+		}()
+	}
+
+	if args.tlsConfig != nil {
+		tlsConn := tls.Client(conn, args.tlsConfig)
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			return conn, err
+		}
+		conn = tlsConn
+	}
+
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: addr.String()},
+		Host:   addr.String(),
+		Header: http.Header{},
+	}
+
+	if args.user != "" {
+		credentials := base64.StdEncoding.EncodeToString([]byte(args.user + ":" + args.password))
+		req.Header.Set("Proxy-Authorization", "Basic "+credentials)
+	}
+
+	if err := req.Write(conn); err != nil {
+		return conn, err
+	}
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		return conn, err
+	}
+	_ = resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return conn, fmt.Errorf("unexpected response status %q", resp.Status)
+	}
+
+	if br.Buffered() > 0 {
+		// Data of the tunnel has already been read into the buffer
+		return &bufferedConn{Conn: conn, r: br}, nil
+	}
+
+	return conn, nil
+}
+
+// bufferedConn is a net.Conn which reads from a bufio.Reader
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *bufferedConn) Read(b []byte) (int, error) {
+	return c.r.Read(b)
+}
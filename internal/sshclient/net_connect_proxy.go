@@ -2,10 +2,14 @@ package sshclient
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"net"
 )
 
-// Proxy returns a NetConnectFunc which connects to the remote via a proxy Client
+// Proxy returns a NetConnectFunc which connects to the remote via a proxy Client.
+// Proxy clients can be chained by using a NetConnectFunc returned by Proxy to connect another proxy Client.
+// Closing the returned net.Conn closes the proxy Client and thereby every preceding proxy Client in the chain.
 func Proxy(proxy *Client, addr net.Addr) NetConnectFunc {
 	return func(ctx context.Context) (net.Conn, error) {
 		// Connect to proxy
@@ -17,11 +21,9 @@ func Proxy(proxy *Client, addr net.Addr) NetConnectFunc {
 		// Connect to remote via proxy
 		conn, err := proxy.Dial(addr.Network(), addr.String())
 		if err != nil {
-			if err := proxy.Close(); err != nil {
-				return nil, err
-			}
+			_ = proxy.Close()
 
-			return nil, err
+			return nil, fmt.Errorf("sshclient: failed to connect to %s via proxy %s: %w", addr, proxy.RemoteAddr(), err)
 		}
 
 		return &proxyConn{
@@ -36,9 +38,11 @@ type proxyConn struct {
 	Proxy *Client
 }
 
+// Close closes the connection through the proxy and the proxy Client.
+// The proxy Client is closed even if closing the connection fails.
 func (c *proxyConn) Close() error {
-	if err := c.Conn.Close(); err != nil {
-		return err
-	}
-	return c.Proxy.Close()
+	connErr := c.Conn.Close()
+	proxyErr := c.Proxy.Close()
+
+	return errors.Join(connErr, proxyErr)
 }
@@ -0,0 +1,106 @@
+package sshclient
+
+import (
+	"crypto/tls"
+	"fmt"
+	"golang.org/x/net/http/httpproxy"
+	"net"
+	"net/url"
+	"os"
+)
+
+type ProxyServerOption func(*proxyServerArgs) error
+
+type proxyServerArgs struct {
+	user     string
+	password string
+
+	tlsConfig *tls.Config
+}
+
+func processProxyServerOpts(opts []ProxyServerOption) (*proxyServerArgs, error) {
+	args := &proxyServerArgs{}
+
+	for _, opt := range opts {
+		err := opt(args)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return args, nil
+}
+
+// ProxyCredentials authenticates with the proxy server using user and password.
+func ProxyCredentials(user string, password string) ProxyServerOption {
+	return func(a *proxyServerArgs) error {
+		a.user = user
+		a.password = password
+		return nil
+	}
+}
+
+// ProxyTLS establishes a TLS connection to the proxy server before the proxy protocol is used.
+// ProxyTLS applies to HttpConnect only.
+func ProxyTLS(tlsConfig *tls.Config) ProxyServerOption {
+	return func(a *proxyServerArgs) error {
+		a.tlsConfig = tlsConfig
+		return nil
+	}
+}
+
+// Environment variables which define proxy servers
+const (
+	EnvAllProxy   = "ALL_PROXY"
+	EnvHttpsProxy = "HTTPS_PROXY"
+	EnvNoProxy    = "NO_PROXY"
+)
+
+// ProxyUrlFromEnvironment returns the url of a proxy server which is defined in the first set environment variable of
+// names. The lower case variant of each name is considered as well.
+// ProxyUrlFromEnvironment returns nil if none of the environment variables is set or if addr is excluded from using a
+// proxy server by the environment variable NO_PROXY. Consistent with net/http, connections to localhost and loopback
+// addresses never use a proxy server.
+func ProxyUrlFromEnvironment(addr net.Addr, names ...string) (*url.URL, error) {
+	var proxy string
+	for _, name := range names {
+		if val := getEnvAny(name); val != "" {
+			proxy = val
+			break
+		}
+	}
+
+	if proxy == "" {
+		return nil, nil
+	}
+
+	cfg := &httpproxy.Config{
+		HTTPSProxy: proxy,
+		NoProxy:    getEnvAny(EnvNoProxy),
+	}
+
+	proxyUrl, err := cfg.ProxyFunc()(&url.URL{Scheme: "https", Host: addr.String()})
+	if err != nil {
+		return nil, fmt.Errorf("sshclient: invalid proxy url in environment: %w", err)
+	}
+
+	return proxyUrl, nil
+}
+
+// getEnvAny returns the value of the environment variable name or its lower case variant
+func getEnvAny(name string) string {
+	if val := os.Getenv(name); val != "" {
+		return val
+	}
+	return os.Getenv(lowerAscii(name))
+}
+
+func lowerAscii(s string) string {
+	b := []byte(s)
+	for i, c := range b {
+		if 'A' <= c && c <= 'Z' {
+			b[i] = c + ('a' - 'A')
+		}
+	}
+	return string(b)
+}
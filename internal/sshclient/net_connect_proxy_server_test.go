@@ -0,0 +1,303 @@
+package sshclient_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"github.com/neuspaces/terraform-provider-system/internal/sshclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// newTestEchoServer starts a tcp server which echoes all received data
+func newTestEchoServer(t *testing.T) net.Addr {
+	return newTestServer(t, func(conn net.Conn) {
+		_, _ = io.Copy(conn, conn)
+	})
+}
+
+func newTestServer(t *testing.T, handle func(conn net.Conn)) net.Addr {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = l.Close()
+	})
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				handle(conn)
+			}()
+		}
+	}()
+
+	return l.Addr()
+}
+
+// newTestHttpProxy starts an HTTP proxy server which supports the CONNECT method only
+func newTestHttpProxy(t *testing.T, proxyAuthorization string) net.Addr {
+	return newTestServer(t, func(conn net.Conn) {
+		br := bufio.NewReader(conn)
+		req, err := http.ReadRequest(br)
+		if err != nil {
+			return
+		}
+
+		if req.Method != http.MethodConnect {
+			_, _ = io.WriteString(conn, "HTTP/1.1 405 Method Not Allowed\r\n\r\n")
+			return
+		}
+
+		if req.Header.Get("Proxy-Authorization") != proxyAuthorization {
+			_, _ = io.WriteString(conn, "HTTP/1.1 407 Proxy Authentication Required\r\n\r\n")
+			return
+		}
+
+		target, err := net.Dial("tcp", req.Host)
+		if err != nil {
+			_, _ = io.WriteString(conn, "HTTP/1.1 502 Bad Gateway\r\n\r\n")
+			return
+		}
+		defer target.Close()
+
+		_, _ = io.WriteString(conn, "HTTP/1.1 200 Connection established\r\n\r\n")
+
+		go func() {
+			_, _ = io.Copy(target, br)
+		}()
+		_, _ = io.Copy(conn, target)
+	})
+}
+
+// newTestSocks5Proxy starts a minimal SOCKS5 proxy server which supports the CONNECT command only
+func newTestSocks5Proxy(t *testing.T, user string, password string) net.Addr {
+	return newTestServer(t, func(conn net.Conn) {
+		buf := make([]byte, 512)
+
+		// Greeting
+		if _, err := io.ReadFull(conn, buf[:2]); err != nil {
+			return
+		}
+		if _, err := io.ReadFull(conn, buf[:buf[1]]); err != nil {
+			return
+		}
+
+		if user == "" {
+			_, _ = conn.Write([]byte{0x05, 0x00})
+		} else {
+			_, _ = conn.Write([]byte{0x05, 0x02})
+
+			// Username/password authentication
+			if _, err := io.ReadFull(conn, buf[:2]); err != nil {
+				return
+			}
+			u := make([]byte, buf[1])
+			if _, err := io.ReadFull(conn, u); err != nil {
+				return
+			}
+			if _, err := io.ReadFull(conn, buf[:1]); err != nil {
+				return
+			}
+			p := make([]byte, buf[0])
+			if _, err := io.ReadFull(conn, p); err != nil {
+				return
+			}
+
+			if string(u) != user || string(p) != password {
+				_, _ = conn.Write([]byte{0x01, 0x01})
+				return
+			}
+			_, _ = conn.Write([]byte{0x01, 0x00})
+		}
+
+		// Request
+		if _, err := io.ReadFull(conn, buf[:4]); err != nil {
+			return
+		}
+
+		var host string
+		switch buf[3] {
+		case 0x01:
+			if _, err := io.ReadFull(conn, buf[:4]); err != nil {
+				return
+			}
+			host = net.IP(buf[:4]).String()
+		case 0x03:
+			if _, err := io.ReadFull(conn, buf[:1]); err != nil {
+				return
+			}
+			name := make([]byte, buf[0])
+			if _, err := io.ReadFull(conn, name); err != nil {
+				return
+			}
+			host = string(name)
+		default:
+			return
+		}
+
+		if _, err := io.ReadFull(conn, buf[:2]); err != nil {
+			return
+		}
+		port := binary.BigEndian.Uint16(buf[:2])
+
+		target, err := net.Dial("tcp", net.JoinHostPort(host, strconv.Itoa(int(port))))
+		if err != nil {
+			_, _ = conn.Write([]byte{0x05, 0x05, 0x00, 0x01, 0, 0, 0, 0, 0, 0})
+			return
+		}
+		defer target.Close()
+
+		_, _ = conn.Write([]byte{0x05, 0x00, 0x00, 0x01, 0, 0, 0, 0, 0, 0})
+
+		go func() {
+			_, _ = io.Copy(target, conn)
+		}()
+		_, _ = io.Copy(conn, target)
+	})
+}
+
+func assertEcho(t *testing.T, conn net.Conn) {
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	_, err := conn.Write([]byte("ping"))
+	require.NoError(t, err)
+
+	buf := make([]byte, 4)
+	_, err = io.ReadFull(conn, buf)
+	require.NoError(t, err)
+	assert.Equal(t, "ping", string(buf))
+}
+
+func TestHttpConnect(t *testing.T) {
+	t.Parallel()
+
+	echoAddr := newTestEchoServer(t)
+
+	type testCase struct {
+		Desc               string
+		ProxyAuthorization string
+		Opts               []sshclient.ProxyServerOption
+		ExpectErr          string
+	}
+
+	tcs := []testCase{
+		{Desc: "without authentication"},
+		{Desc: "with authentication", ProxyAuthorization: "Basic dXNlcjpwYXNz", Opts: []sshclient.ProxyServerOption{sshclient.ProxyCredentials("user", "pass")}},
+		{Desc: "missing authentication", ProxyAuthorization: "Basic dXNlcjpwYXNz", ExpectErr: "407 Proxy Authentication Required"},
+		{Desc: "wrong authentication", ProxyAuthorization: "Basic dXNlcjpwYXNz", Opts: []sshclient.ProxyServerOption{sshclient.ProxyCredentials("user", "wrong")}, ExpectErr: "407 Proxy Authentication Required"},
+	}
+
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.Desc, func(t *testing.T) {
+			t.Parallel()
+
+			proxyAddr := newTestHttpProxy(t, tc.ProxyAuthorization)
+
+			netConnect := sshclient.HttpConnect(sshclient.Dial(proxyAddr, 5*time.Second), proxyAddr, echoAddr, tc.Opts...)
+			conn, err := netConnect(context.Background())
+
+			if tc.ExpectErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tc.ExpectErr)
+				return
+			}
+
+			require.NoError(t, err)
+			defer conn.Close()
+
+			assertEcho(t, conn)
+		})
+	}
+}
+
+func TestSocks5(t *testing.T) {
+	t.Parallel()
+
+	echoAddr := newTestEchoServer(t)
+
+	type testCase struct {
+		Desc      string
+		User      string
+		Password  string
+		Opts      []sshclient.ProxyServerOption
+		ExpectErr string
+	}
+
+	tcs := []testCase{
+		{Desc: "without authentication"},
+		{Desc: "with authentication", User: "user", Password: "pass", Opts: []sshclient.ProxyServerOption{sshclient.ProxyCredentials("user", "pass")}},
+		{Desc: "wrong authentication", User: "user", Password: "pass", Opts: []sshclient.ProxyServerOption{sshclient.ProxyCredentials("user", "wrong")}, ExpectErr: "failed to connect to"},
+	}
+
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.Desc, func(t *testing.T) {
+			t.Parallel()
+
+			proxyAddr := newTestSocks5Proxy(t, tc.User, tc.Password)
+
+			netConnect := sshclient.Socks5(sshclient.Dial(proxyAddr, 5*time.Second), proxyAddr, echoAddr, tc.Opts...)
+			conn, err := netConnect(context.Background())
+
+			if tc.ExpectErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tc.ExpectErr)
+				return
+			}
+
+			require.NoError(t, err)
+			defer conn.Close()
+
+			assertEcho(t, conn)
+		})
+	}
+}
+
+func TestProxyUrlFromEnvironment(t *testing.T) {
+	addr := sshclient.NewHostPortAddr(sshclient.Tcp, "remote.example.com", 22)
+
+	type testCase struct {
+		Desc      string
+		Env       map[string]string
+		ExpectUrl string
+	}
+
+	tcs := []testCase{
+		{Desc: "unset", Env: map[string]string{}},
+		{Desc: "upper case", Env: map[string]string{"ALL_PROXY": "socks5://proxy:1080"}, ExpectUrl: "socks5://proxy:1080"},
+		{Desc: "lower case", Env: map[string]string{"all_proxy": "socks5://proxy:1080"}, ExpectUrl: "socks5://proxy:1080"},
+		{Desc: "no proxy", Env: map[string]string{"ALL_PROXY": "socks5://proxy:1080", "NO_PROXY": ".example.com"}},
+		{Desc: "no proxy other domain", Env: map[string]string{"ALL_PROXY": "socks5://proxy:1080", "NO_PROXY": ".example.org"}, ExpectUrl: "socks5://proxy:1080"},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.Desc, func(t *testing.T) {
+			for _, name := range []string{"ALL_PROXY", "all_proxy", "NO_PROXY", "no_proxy"} {
+				t.Setenv(name, tc.Env[name])
+			}
+
+			u, err := sshclient.ProxyUrlFromEnvironment(addr, sshclient.EnvAllProxy)
+			require.NoError(t, err)
+
+			if tc.ExpectUrl == "" {
+				assert.Nil(t, u)
+				return
+			}
+
+			require.NotNil(t, u)
+			assert.Equal(t, tc.ExpectUrl, u.String())
+		})
+	}
+}
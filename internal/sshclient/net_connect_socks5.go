@@ -0,0 +1,54 @@
+package sshclient
+
+import (
+	"context"
+	"fmt"
+	"golang.org/x/net/proxy"
+	"net"
+)
+
+// Socks5 returns a NetConnectFunc which connects to the remote addr via a SOCKS5 proxy server.
+// proxyConnect establishes the connection to the proxy server, e.g. using Dial.
+// The host of addr is resolved by the proxy server.
+func Socks5(proxyConnect NetConnectFunc, proxyAddr net.Addr, addr net.Addr, opts ...ProxyServerOption) NetConnectFunc {
+	return func(ctx context.Context) (net.Conn, error) {
+		args, err := processProxyServerOpts(opts)
+		if err != nil {
+			return nil, err
+		}
+
+		var auth *proxy.Auth
+		if args.user != "" {
+			auth = &proxy.Auth{
+				User:     args.user,
+				Password: args.password,
+			}
+		}
+
+		dialer, err := proxy.SOCKS5(proxyAddr.Network(), proxyAddr.String(), auth, netConnectDialer(proxyConnect))
+		if err != nil {
+			return nil, fmt.Errorf("sshclient: failed to configure socks5 proxy %s: %w", proxyAddr, err)
+		}
+
+		conn, err := dialer.(proxy.ContextDialer).DialContext(ctx, addr.Network(), addr.String())
+		if err != nil {
+			return nil, fmt.Errorf("sshclient: failed to connect to %s via socks5 proxy %s: %w", addr, proxyAddr, err)
+		}
+
+		return conn, nil
+	}
+}
+
+// netConnectDialer implements proxy.Dialer and proxy.ContextDialer using a NetConnectFunc.
+// The network and address arguments are ignored because the NetConnectFunc connects to a predefined address.
+type netConnectDialer NetConnectFunc
+
+var _ proxy.ContextDialer = netConnectDialer(nil)
+
+func (d netConnectDialer) Dial(network, addr string) (net.Conn, error) {
+	return d.DialContext(context.Background(), network, addr)
+}
+
+func (d netConnectDialer) DialContext(ctx context.Context, _, _ string) (net.Conn, error) {
+	return d(ctx)
+}
@@ -29,3 +29,20 @@ type WriteFile interface {
 	io.Writer
 	io.Closer
 }
+
+// DirectFS is implemented by a System which may access the file system without executing commands.
+// Files which are accessed directly are not subject to the command middleware of the System such as privilege
+// escalation.
+type DirectFS interface {
+	// DirectFS returns true if ReadFS, WriteFS and StatFS access the file system without executing commands
+	DirectFS(ctx context.Context) bool
+}
+
+// IsDirectFS returns true if s accesses the file system without executing commands
+func IsDirectFS(ctx context.Context, s System) bool {
+	if d, ok := s.(DirectFS); ok {
+		return d.DirectFS(ctx)
+	}
+
+	return false
+}
@@ -0,0 +1,254 @@
+package system
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/alessio/shellescape"
+	"github.com/neuspaces/terraform-provider-system/internal/cmd"
+	"github.com/neuspaces/terraform-provider-system/internal/lib/stat"
+	"io"
+	"io/fs"
+	"strings"
+)
+
+// shellFS is a FileSystem which accesses files by executing shell commands
+type shellFS struct {
+	e Executor
+}
+
+var _ FileSystem = &shellFS{}
+
+// ShellFS returns a FileSystem which reads, writes and stats files by executing the commands `cat`, `tail` and `stat`
+// using e. The commands are subject to the command middleware of e.
+func ShellFS(e Executor) FileSystem {
+	return &shellFS{
+		e: e,
+	}
+}
+
+// Open returns a fs.File which implements io.Seeker.
+// Open fetches fs.FileInfo immediately when invoked. The content is read by a command which is started on the first
+// Read.
+func (s *shellFS) Open(ctx context.Context, name string) (fs.File, error) {
+	fileInfo, err := s.Stat(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	file := &shellReadFile{
+		ctx:      ctx,
+		e:        s.e,
+		name:     name,
+		fileInfo: fileInfo,
+	}
+
+	return file, nil
+}
+
+// Create returns a WriteFile which writes to the standard input of a `cat` command. The permissions of the file are
+// set to the permissions of fileInfo unless they are 0. Errors of the command are returned by Close.
+func (s *shellFS) Create(ctx context.Context, fileInfo fs.FileInfo) (WriteFile, error) {
+	name := shellescape.Quote(fileInfo.Name())
+
+	command := `cat - > ` + name
+	if perm := fileInfo.Mode().Perm(); perm != 0 {
+		command += fmt.Sprintf(` && chmod %o %s`, perm, name)
+	}
+
+	pipeReader, pipeWriter := io.Pipe()
+
+	f := &shellWriteFile{
+		pipeWriter: pipeWriter,
+		done:       make(chan struct{}),
+	}
+
+	go func() {
+		defer close(f.done)
+
+		stderr := &bytes.Buffer{}
+		res, err := s.e.Execute(ctx, cmd.NewCommand(command, cmd.Stdin(pipeReader), cmd.Stderr(stderr)))
+		if err == nil && res.ExitCode() != 0 {
+			err = fmt.Errorf("failed to create %q: exit code %d: %s", fileInfo.Name(), res.ExitCode(), strings.TrimSpace(stderr.String()))
+		}
+		f.err = err
+
+		// Unblock Write if the command has terminated before the standard input has been consumed
+		if err == nil {
+			err = io.ErrClosedPipe
+		}
+		_ = pipeReader.CloseWithError(err)
+	}()
+
+	return f, nil
+}
+
+func (s *shellFS) Stat(ctx context.Context, name string) (fs.FileInfo, error) {
+	statOut := &bytes.Buffer{}
+	// Use an explicit format because the number of fields of `stat -t` differs between versions
+	statCmd := cmd.NewCommand(fmt.Sprintf(`stat -c '%s' %s`, stat.FormatTerseGnu, shellescape.Quote(name)), cmd.Stdout(statOut))
+
+	statCmdResult, err := s.e.Execute(ctx, statCmd)
+	if err != nil {
+		return nil, err
+	}
+
+	if statCmdResult.ExitCode() != 0 {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+	}
+
+	fileInfo, err := stat.ParseTerseFormat(statOut.Bytes())
+	if err != nil {
+		return nil, err
+	}
+
+	return fileInfo.ToFsFileInfo(), nil
+}
+
+// shellReadFile implements fs.File and io.Seeker for a file which is read by a `cat` or `tail` command
+type shellReadFile struct {
+	ctx      context.Context
+	e        Executor
+	name     string
+	fileInfo fs.FileInfo
+
+	// offset is the offset of the next Read
+	offset int64
+
+	// r reads from the standard output of the command which is started on the first Read after Open or Seek
+	r io.ReadCloser
+}
+
+var _ fs.File = &shellReadFile{}
+var _ io.Seeker = &shellReadFile{}
+
+func (f *shellReadFile) Stat() (fs.FileInfo, error) {
+	return f.fileInfo, nil
+}
+
+func (f *shellReadFile) Read(b []byte) (int, error) {
+	if f.r == nil {
+		f.r = newShellFileReader(f.ctx, f.e, f.name, f.offset)
+	}
+
+	n, err := f.r.Read(b)
+	f.offset += int64(n)
+
+	return n, err
+}
+
+// Seek sets the offset of the next Read. The command which reads the content is restarted if the offset changes.
+func (f *shellReadFile) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		offset += f.offset
+	case io.SeekEnd:
+		offset += f.fileInfo.Size()
+	}
+
+	if offset < 0 {
+		return 0, &fs.PathError{Op: "seek", Path: f.name, Err: fs.ErrInvalid}
+	}
+
+	if offset != f.offset && f.r != nil {
+		_ = f.r.Close()
+		f.r = nil
+	}
+
+	f.offset = offset
+
+	return offset, nil
+}
+
+func (f *shellReadFile) Close() error {
+	if f.r != nil {
+		return f.r.Close()
+	}
+
+	return nil
+}
+
+// shellFileReader is an io.ReadCloser which reads from the standard output of a command
+type shellFileReader struct {
+	*io.PipeReader
+
+	cancel context.CancelFunc
+}
+
+// newShellFileReader returns an io.ReadCloser which reads a file from offset. Close terminates the command if the file
+// has not been read completely.
+func newShellFileReader(ctx context.Context, e Executor, name string, offset int64) io.ReadCloser {
+	ctx, cancel := context.WithCancel(ctx)
+
+	command := `cat ` + shellescape.Quote(name)
+	if offset > 0 {
+		command = fmt.Sprintf(`tail -c +%d %s`, offset+1, shellescape.Quote(name))
+	}
+
+	// Create pipe: pipe reader is returned to the caller; pipe writer captures stdout
+	pipeReader, pipeWriter := io.Pipe()
+	readCmd := cmd.NewCommand(command, cmd.Stdout(pipeWriter))
+
+	go func() {
+		res, err := e.Execute(ctx, readCmd)
+		if err != nil {
+			// Error will be returned by Read of the PipeReader
+			_ = pipeWriter.CloseWithError(err)
+			return
+		}
+
+		if rc := res.ExitCode(); rc != 0 {
+			// Error will be returned by Read of the PipeReader
+			_ = pipeWriter.CloseWithError(fmt.Errorf("non-zero exit code: %d", rc))
+			return
+		}
+
+		// Read completed
+		_ = pipeWriter.Close()
+	}()
+
+	return &shellFileReader{
+		PipeReader: pipeReader,
+		cancel:     cancel,
+	}
+}
+
+func (r *shellFileReader) Close() error {
+	r.cancel()
+	return r.PipeReader.Close()
+}
+
+// shellWriteFile implements WriteFile for a file which is written by a `cat` command
+type shellWriteFile struct {
+	pipeWriter *io.PipeWriter
+
+	// done is closed when the command has terminated
+	done chan struct{}
+
+	// err is the error of the command; err must not be read before done is closed
+	err error
+}
+
+var _ WriteFile = &shellWriteFile{}
+
+func (f *shellWriteFile) Write(b []byte) (int, error) {
+	n, err := f.pipeWriter.Write(b)
+	if errors.Is(err, io.ErrClosedPipe) {
+		// The command has terminated prematurely
+		<-f.done
+		if f.err != nil {
+			return n, f.err
+		}
+	}
+
+	return n, err
+}
+
+// Close completes the standard input of the command and waits for the command to terminate
+func (f *shellWriteFile) Close() error {
+	_ = f.pipeWriter.Close()
+	<-f.done
+
+	return f.err
+}
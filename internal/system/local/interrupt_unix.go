@@ -0,0 +1,20 @@
+//go:build !windows
+
+package local
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// interruptOnCancel configures execCmd to send an interrupt (SIGINT) to the process group of the command when the
+// context of execCmd is cancelled. The process group includes the children of the shell.
+func interruptOnCancel(execCmd *exec.Cmd) {
+	execCmd.SysProcAttr = &syscall.SysProcAttr{
+		Setpgid: true,
+	}
+
+	execCmd.Cancel = func() error {
+		return syscall.Kill(-execCmd.Process.Pid, syscall.SIGINT)
+	}
+}
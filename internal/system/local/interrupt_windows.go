@@ -0,0 +1,12 @@
+//go:build windows
+
+package local
+
+import (
+	"os/exec"
+)
+
+// interruptOnCancel retains the default behaviour of execCmd which kills the process when the context of execCmd is
+// cancelled because interrupts are not supported on windows
+func interruptOnCancel(execCmd *exec.Cmd) {
+}
@@ -4,19 +4,43 @@ import (
 	"context"
 	"github.com/neuspaces/terraform-provider-system/internal/cmd"
 	"github.com/neuspaces/terraform-provider-system/internal/system"
+	"io"
 	"io/fs"
 	"os"
 	"os/exec"
+	"time"
 )
 
 type System struct {
+	cmdM cmd.Middleware
 }
 
 // System implements system.System
 var _ system.System = &System{}
 
-func NewSystem() system.System {
-	return &System{}
+type SystemOption func(*System) error
+
+func CommandMiddleware(m cmd.Middleware) SystemOption {
+	return func(s *System) error {
+		s.cmdM = m
+		return nil
+	}
+}
+
+func NewSystem(opts ...SystemOption) (*System, error) {
+	var err error
+
+	s := &System{}
+
+	// Apply options
+	for _, opt := range opts {
+		err = opt(s)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
 }
 
 func (s *System) Open(ctx context.Context, name string) (fs.File, error) {
@@ -35,15 +59,56 @@ func (s *System) Stat(ctx context.Context, name string) (fs.FileInfo, error) {
 	return os.Stat(name)
 }
 
+// interruptWaitDelay is the duration after which a command is killed if it has not exited after an interrupt
+const interruptWaitDelay = 10 * time.Second
+
 func (s *System) Execute(ctx context.Context, c cmd.Command) (cmd.Result, error) {
+	// Apply command middleware
+	c = cmd.Apply(c, s.cmdM)
+
+	result, err := s.execute(ctx, c)
+
+	return cmd.Finish(c, result, err)
+}
+
+func (s *System) execute(ctx context.Context, c cmd.Command) (cmd.Result, error) {
 	command := c.Command()
 	execCmd := exec.CommandContext(ctx, "sh", "-c", command)
 
-	execCmd.Stdin = c.Stdin()
+	// Send interrupt (SIGINT) when context is cancelled equivalent to ssh.System
+	interruptOnCancel(execCmd)
+	execCmd.WaitDelay = interruptWaitDelay
+
 	execCmd.Stdout = c.Stdout()
 	execCmd.Stderr = c.Stderr()
 
-	err := execCmd.Run()
+	// Copy the standard input without waiting for the copy to complete after the command has exited.
+	// The standard input may block until the command is finished.
+	var stdin io.WriteCloser
+	if c.Stdin() != nil {
+		var err error
+		stdin, err = execCmd.StdinPipe()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err := execCmd.Start()
+	if err != nil {
+		return nil, err
+	}
+
+	if stdin != nil {
+		go func() {
+			_, _ = io.Copy(stdin, c.Stdin())
+			_ = stdin.Close()
+		}()
+	}
+
+	err = execCmd.Wait()
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return nil, ctxErr
+	}
 	if err != nil {
 		if exitErr, isExitErr := err.(*exec.ExitError); isExitErr {
 			return cmd.NewResult(exitErr.ExitCode()), nil
@@ -8,10 +8,12 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"testing"
+	"time"
 )
 
 func TestSystem(t *testing.T) {
-	s := local.NewSystem()
+	s, err := local.NewSystem()
+	require.NoError(t, err)
 
 	stdout := &bytes.Buffer{}
 	cmd := cmd.NewCommand(`whoami`, cmd.Stdout(stdout))
@@ -26,3 +28,28 @@ func TestSystem(t *testing.T) {
 	stdoutBytes := stdout.Bytes()
 	assert.NotEmpty(t, stdoutBytes)
 }
+
+func TestSystem_CommandMiddleware(t *testing.T) {
+	s, err := local.NewSystem(local.CommandMiddleware(cmd.ShMiddleware()))
+	require.NoError(t, err)
+
+	stdout := &bytes.Buffer{}
+	c := cmd.NewCommand(`echo "$0"; exit 3`, cmd.Stdout(stdout))
+
+	result, err := s.Execute(context.Background(), c)
+	require.NoError(t, err)
+
+	assert.Equal(t, 3, result.ExitCode())
+	assert.Equal(t, "/bin/sh\n", stdout.String())
+}
+
+func TestSystem_Cancel(t *testing.T) {
+	s, err := local.NewSystem()
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	_, err = s.Execute(ctx, cmd.NewCommand(`sleep 10`))
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
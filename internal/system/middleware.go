@@ -0,0 +1,39 @@
+package system
+
+import (
+	"context"
+	"github.com/neuspaces/terraform-provider-system/internal/cmd"
+	"io/fs"
+)
+
+// middlewareSystem is a System which executes all commands using a Middleware
+type middlewareSystem struct {
+	System
+
+	m cmd.Middleware
+}
+
+// WithCommandMiddleware returns a System which executes all commands using m instead of the command middleware of s.
+// Files are accessed by executing shell commands using m.
+func WithCommandMiddleware(s System, m cmd.Middleware) System {
+	return &middlewareSystem{
+		System: s,
+		m:      m,
+	}
+}
+
+func (s *middlewareSystem) Execute(ctx context.Context, c cmd.Command) (cmd.Result, error) {
+	return s.System.Execute(ctx, cmd.WithMiddleware(c, s.m))
+}
+
+func (s *middlewareSystem) Open(ctx context.Context, name string) (fs.File, error) {
+	return ShellFS(s).Open(ctx, name)
+}
+
+func (s *middlewareSystem) Create(ctx context.Context, fileInfo fs.FileInfo) (WriteFile, error) {
+	return ShellFS(s).Create(ctx, fileInfo)
+}
+
+func (s *middlewareSystem) Stat(ctx context.Context, name string) (fs.FileInfo, error) {
+	return ShellFS(s).Stat(ctx, name)
+}
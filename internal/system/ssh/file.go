@@ -2,59 +2,112 @@ package ssh
 
 import (
 	"context"
-	"fmt"
-	"github.com/neuspaces/terraform-provider-system/internal/cmd"
+	"github.com/neuspaces/terraform-provider-system/internal/lib/stat"
 	"github.com/neuspaces/terraform-provider-system/internal/system"
-	"io"
+	"github.com/pkg/sftp"
 	"io/fs"
+	"os"
+	"time"
 )
 
-// readFile implements fs.File for a known fs.FileInfo and an io.ReadCloser
-type readFile struct {
-	fileInfo   fs.FileInfo
-	readCloser io.ReadCloser
+// sftpFS is a system.FileSystem which accesses files over the SFTP subsystem
+type sftpFS struct {
+	client *sftp.Client
 }
 
-var _ fs.File = &readFile{}
+var _ system.FileSystem = &sftpFS{}
 
-func (f *readFile) Stat() (fs.FileInfo, error) {
-	return f.fileInfo, nil
-}
+// Open returns a fs.File which implements io.Seeker and io.ReaderAt
+func (f *sftpFS) Open(ctx context.Context, name string) (fs.File, error) {
+	file, err := f.client.Open(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
 
-func (f *readFile) Read(b []byte) (int, error) {
-	return f.readCloser.Read(b)
+	return newSftpFile(ctx, file), nil
 }
 
-func (f *readFile) Close() error {
-	return f.readCloser.Close()
-}
+// Create creates or truncates a file. The permissions of the file are set to the permissions of fileInfo unless they
+// are 0.
+func (f *sftpFS) Create(ctx context.Context, fileInfo fs.FileInfo) (system.WriteFile, error) {
+	name := fileInfo.Name()
 
-// newCatFileReader returns an io.ReadCloser which reads a file from a System using the `cat` command
-func newCatFileReader(ctx context.Context, s system.System, name string) io.ReadCloser {
-	// Create pipe: pipe reader is returned to the caller; pipe writer captures stdout
-	pipeReader, pipeWriter := io.Pipe()
-	catCmd := cmd.NewCommand(fmt.Sprintf(`cat '%s'`, name), cmd.Stdout(pipeWriter))
+	file, err := f.client.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC)
+	if err != nil {
+		return nil, &fs.PathError{Op: "create", Path: name, Err: err}
+	}
 
-	go func() {
-		res, err := s.Execute(ctx, catCmd)
+	if perm := fileInfo.Mode().Perm(); perm != 0 {
+		err = file.Chmod(perm)
 		if err != nil {
-			// Error will be returned by Read of the PipeReader
-			_ = pipeWriter.CloseWithError(err)
-			return
+			_ = file.Close()
+			return nil, &fs.PathError{Op: "chmod", Path: name, Err: err}
 		}
+	}
 
-		if rc := res.ExitCode(); rc != 0 {
-			// Error will be returned by Read of the PipeReader
-			_ = pipeWriter.CloseWithError(fmt.Errorf("non-zero exit code: %d", rc))
-			return
-		}
+	return newSftpFile(ctx, file), nil
+}
+
+func (f *sftpFS) Stat(ctx context.Context, name string) (fs.FileInfo, error) {
+	fileInfo, err := f.client.Stat(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: err}
+	}
+
+	return &sftpFileInfo{FileInfo: fileInfo}, nil
+}
+
+// sftpFile is a file which is accessed over the SFTP subsystem. sftpFile is closed when the context is cancelled.
+type sftpFile struct {
+	*sftp.File
+
+	stop func() bool
+}
 
-		// Read completed
-		_ = pipeWriter.Close()
-	}()
+var _ fs.File = &sftpFile{}
+var _ system.WriteFile = &sftpFile{}
+
+func newSftpFile(ctx context.Context, file *sftp.File) *sftpFile {
+	return &sftpFile{
+		File: file,
+		stop: context.AfterFunc(ctx, func() {
+			_ = file.Close()
+		}),
+	}
+}
+
+func (f *sftpFile) Stat() (fs.FileInfo, error) {
+	fileInfo, err := f.File.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	return &sftpFileInfo{FileInfo: fileInfo}, nil
+}
+
+func (f *sftpFile) Close() error {
+	f.stop()
+	return f.File.Close()
+}
+
+// sftpFileInfo is a fs.FileInfo of the SFTP subsystem whose Sys returns a *stat.Stat equivalent to the system.ShellFS
+type sftpFileInfo struct {
+	fs.FileInfo
+}
 
-	// TODO implement a lazy reader; i.e. start reading on first read call; cat reader
-	// TODO close if context is cancelled in separate goroutine; actually this should be the responsibility of Execute to close the Stdout
+func (fi *sftpFileInfo) Sys() interface{} {
+	fileStat, ok := fi.FileInfo.Sys().(*sftp.FileStat)
+	if !ok {
+		return nil
+	}
 
-	return pipeReader
+	return &stat.Stat{
+		Mode:         stat.FileMode(fileStat.Mode),
+		Name:         fi.Name(),
+		Uid:          int(fileStat.UID),
+		Gid:          int(fileStat.GID),
+		Size:         int64(fileStat.Size),
+		AccessTime:   time.Unix(int64(fileStat.Atime), 0),
+		ModifiedTime: time.Unix(int64(fileStat.Mtime), 0),
+	}
 }
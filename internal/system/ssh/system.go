@@ -1,16 +1,17 @@
 package ssh
 
 import (
-	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"github.com/neuspaces/terraform-provider-system/internal/cmd"
-	"github.com/neuspaces/terraform-provider-system/internal/lib/stat"
 	"github.com/neuspaces/terraform-provider-system/internal/sshclient"
 	"github.com/neuspaces/terraform-provider-system/internal/system"
+	"github.com/pkg/sftp"
 	"golang.org/x/crypto/ssh"
 	"golang.org/x/sync/semaphore"
 	"io/fs"
+	"strings"
 	"sync"
 )
 
@@ -21,6 +22,15 @@ type System struct {
 	cmdM cmd.Middleware
 
 	sessions *semaphore.Weighted
+
+	// sftpEnabled enables file access over the SFTP subsystem
+	sftpEnabled bool
+
+	// sftpClient is the client of the SFTP subsystem on the connection sftpConn
+	sftpClient      *sftp.Client
+	sftpConn        *ssh.Client
+	sftpUnsupported bool
+	sftpM           sync.Mutex
 }
 
 // System implements system.System
@@ -51,6 +61,17 @@ func CommandMiddleware(m cmd.Middleware) SystemOption {
 	}
 }
 
+// Sftp is a SystemOption which enables file access over the SFTP subsystem. If SFTP is disabled or the remote does not
+// support the SFTP subsystem, files are accessed by executing shell commands. Files which are accessed over SFTP are
+// not subject to the command middleware. The SFTP subsystem occupies one ssh session for the lifetime of a connection
+// which is not limited by Sessions.
+func Sftp(enabled bool) SystemOption {
+	return func(s *System) error {
+		s.sftpEnabled = enabled
+		return nil
+	}
+}
+
 func NewSystem(sshClient *sshclient.Client, opts ...SystemOption) (*System, error) {
 	var err error
 
@@ -71,6 +92,13 @@ func NewSystem(sshClient *sshclient.Client, opts ...SystemOption) (*System, erro
 
 // Close closes the underlying ssh client
 func (s *System) Close() error {
+	s.sftpM.Lock()
+	if s.sftpClient != nil {
+		_ = s.sftpClient.Close()
+		s.sftpClient = nil
+	}
+	s.sftpM.Unlock()
+
 	s.sshClientM.Lock()
 	defer s.sshClientM.Unlock()
 
@@ -81,47 +109,163 @@ func (s *System) Close() error {
 	return nil
 }
 
-// Open returns a fs.File
-// Open fetches fs.FileInfo immediately when invoked
+// Open returns a fs.File which implements io.Seeker
 func (s *System) Open(ctx context.Context, name string) (fs.File, error) {
-	// Retrieve file info
-	fileInfo, err := s.Stat(ctx, name)
+	fsys, err := s.fileSystem(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	// Return readFile with file info and reader based on `cat` command
-	file := &readFile{
-		fileInfo:   fileInfo,
-		readCloser: newCatFileReader(ctx, s, name),
-	}
-
-	return file, nil
+	return fsys.Open(ctx, name)
 }
 
 func (s *System) Create(ctx context.Context, fileInfo fs.FileInfo) (system.WriteFile, error) {
-	return nil, fmt.Errorf("create is not supported by ssh.System")
+	fsys, err := s.fileSystem(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return fsys.Create(ctx, fileInfo)
 }
 
 func (s *System) Stat(ctx context.Context, name string) (fs.FileInfo, error) {
-	statOut := &bytes.Buffer{}
-	statCmd := cmd.NewCommand(fmt.Sprintf(`stat -t '%s'`, name), cmd.Stdout(statOut))
+	fsys, err := s.fileSystem(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return fsys.Stat(ctx, name)
+}
 
-	statCmdResult, err := s.Execute(ctx, statCmd)
+// DirectFS returns true if files are accessed over the SFTP subsystem
+func (s *System) DirectFS(ctx context.Context) bool {
+	fsys, err := s.fileSystem(ctx)
 	if err != nil {
+		return false
+	}
+
+	_, isSftp := fsys.(*sftpFS)
+
+	return isSftp
+}
+
+// fileSystem returns a system.FileSystem which accesses files over the SFTP subsystem. fileSystem returns a
+// system.FileSystem which executes shell commands if SFTP is disabled or not supported by the remote.
+func (s *System) fileSystem(ctx context.Context) (system.FileSystem, error) {
+	if !s.sftpEnabled {
+		return system.ShellFS(s), nil
+	}
+
+	client, err := s.connectedSftpClient(ctx)
+	if errors.Is(err, errSftpUnsupported) {
+		return system.ShellFS(s), nil
+	} else if err != nil {
 		return nil, err
 	}
 
-	if statCmdResult.ExitCode() != 0 {
-		return nil, fs.ErrNotExist
+	return &sftpFS{client: client}, nil
+}
+
+var errSftpUnsupported = errors.New("ssh.System: sftp subsystem is not supported by the remote")
+
+// connectedSftpClient returns the client of the SFTP subsystem on an established connection. A new client is started
+// if the connection has been reestablished. connectedSftpClient returns errSftpUnsupported if the remote does not
+// support the SFTP subsystem.
+func (s *System) connectedSftpClient(ctx context.Context) (*sftp.Client, error) {
+	s.sftpM.Lock()
+	defer s.sftpM.Unlock()
+
+	if s.sftpUnsupported {
+		return nil, errSftpUnsupported
+	}
+
+	for attempt := 0; ; attempt++ {
+		client, err := s.connectedClient(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		if s.sftpClient != nil && s.sftpConn == client {
+			return s.sftpClient, nil
+		}
+
+		if s.sftpClient != nil {
+			// The connection of the client has been replaced
+			_ = s.sftpClient.Close()
+			s.sftpClient = nil
+		}
+
+		sftpClient, err := sftp.NewClient(client)
+		if err == nil {
+			s.sftpClient = sftpClient
+			s.sftpConn = client
+			return sftpClient, nil
+		}
+
+		var openChannelErr *ssh.OpenChannelError
+		if errors.As(err, &openChannelErr) {
+			// The remote rejected the session
+			return nil, err
+		}
+
+		if strings.Contains(err.Error(), "subsystem request failed") || attempt > 0 {
+			// The remote rejected the subsystem or reconnecting did not help
+			s.sftpUnsupported = true
+			return nil, errSftpUnsupported
+		}
+
+		// The transport is dead
+		s.disconnect(client)
 	}
+}
 
-	fileInfo, err := stat.ParseTerseFormat(statOut.Bytes())
+// connectedClient returns the ssh client of an established connection. connectedClient reconnects if the transport of
+// the connection has terminated.
+func (s *System) connectedClient(ctx context.Context) (*ssh.Client, error) {
+	s.sshClientM.Lock()
+	defer s.sshClientM.Unlock()
+
+	err := s.sshClient.Connected(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	return fileInfo.ToFsFileInfo(), nil
+	return s.sshClient.Client, nil
+}
+
+// disconnect closes the connection of client unless the connection has already been replaced
+func (s *System) disconnect(client *ssh.Client) {
+	s.sshClientM.Lock()
+	defer s.sshClientM.Unlock()
+
+	if s.sshClient.Client == client {
+		_ = s.sshClient.Disconnect()
+	}
+}
+
+// newSession opens a session on the ssh connection. If the session cannot be opened because the transport of the
+// connection is dead, newSession reconnects once and retries. Retrying is safe because no command has been started.
+func (s *System) newSession(ctx context.Context) (*ssh.Session, error) {
+	for attempt := 0; ; attempt++ {
+		client, err := s.connectedClient(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		sess, err := client.NewSession()
+		if err == nil {
+			return sess, nil
+		}
+
+		var openChannelErr *ssh.OpenChannelError
+		if errors.As(err, &openChannelErr) || attempt > 0 {
+			// The remote rejected the session or reconnecting did not help
+			return nil, err
+		}
+
+		// The transport is dead
+		s.disconnect(client)
+	}
 }
 
 func (s *System) Execute(ctx context.Context, c cmd.Command) (cmd.Result, error) {
@@ -136,20 +280,16 @@ func (s *System) Execute(ctx context.Context, c cmd.Command) (cmd.Result, error)
 	}
 
 	// Apply command middleware
-	if s.cmdM != nil {
-		c = s.cmdM(c)
-	}
+	c = cmd.Apply(c, s.cmdM)
 
-	// Ensure connection
-	s.sshClientM.Lock()
-	err = s.sshClient.Connected(ctx)
-	s.sshClientM.Unlock()
-	if err != nil {
-		return nil, err
-	}
+	result, err := s.execute(ctx, c)
+
+	return cmd.Finish(c, result, err)
+}
 
+func (s *System) execute(ctx context.Context, c cmd.Command) (cmd.Result, error) {
 	// Create session
-	sess, err := s.sshClient.NewSession()
+	sess, err := s.newSession(ctx)
 	if err != nil {
 		return nil, err
 	}
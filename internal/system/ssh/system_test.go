@@ -0,0 +1,235 @@
+package ssh_test
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/binary"
+	"github.com/neuspaces/terraform-provider-system/internal/lib/stat"
+	"github.com/neuspaces/terraform-provider-system/internal/sshclient"
+	"github.com/neuspaces/terraform-provider-system/internal/system"
+	systemssh "github.com/neuspaces/terraform-provider-system/internal/system/ssh"
+	"github.com/pkg/sftp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+	"io"
+	"io/fs"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newTestSshServer starts an ssh server which executes commands using the local shell. The server provides the SFTP
+// subsystem if sftpSubsystem is true.
+func newTestSshServer(t *testing.T, sftpSubsystem bool) net.Addr {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	signer, err := ssh.NewSignerFromKey(priv)
+	require.NoError(t, err)
+
+	config := &ssh.ServerConfig{
+		NoClientAuth: true,
+	}
+	config.AddHostKey(signer)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = l.Close()
+	})
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+
+			go func() {
+				defer conn.Close()
+
+				sshConn, chans, reqs, err := ssh.NewServerConn(conn, config)
+				if err != nil {
+					return
+				}
+				defer sshConn.Close()
+
+				go ssh.DiscardRequests(reqs)
+
+				for newCh := range chans {
+					if newCh.ChannelType() != "session" {
+						_ = newCh.Reject(ssh.UnknownChannelType, "session channels only")
+						continue
+					}
+
+					ch, chReqs, err := newCh.Accept()
+					if err != nil {
+						continue
+					}
+
+					go handleTestSession(ch, chReqs, sftpSubsystem)
+				}
+			}()
+		}
+	}()
+
+	return l.Addr()
+}
+
+func handleTestSession(ch ssh.Channel, reqs <-chan *ssh.Request, sftpSubsystem bool) {
+	defer ch.Close()
+
+	for req := range reqs {
+		switch req.Type {
+		case "exec":
+			command := string(req.Payload[4:])
+			_ = req.Reply(true, nil)
+
+			execCmd := exec.Command("sh", "-c", command)
+			execCmd.Stdin = ch
+			execCmd.Stdout = ch
+			execCmd.Stderr = ch.Stderr()
+
+			exitStatus := 0
+			if err := execCmd.Run(); err != nil {
+				exitStatus = 255
+				if exitErr, ok := err.(*exec.ExitError); ok {
+					exitStatus = exitErr.ExitCode()
+				}
+			}
+
+			status := make([]byte, 4)
+			binary.BigEndian.PutUint32(status, uint32(exitStatus))
+			_, _ = ch.SendRequest("exit-status", false, status)
+
+			return
+		case "subsystem":
+			if !sftpSubsystem || string(req.Payload[4:]) != "sftp" {
+				_ = req.Reply(false, nil)
+				continue
+			}
+			_ = req.Reply(true, nil)
+
+			server, err := sftp.NewServer(ch)
+			if err != nil {
+				return
+			}
+			_ = server.Serve()
+
+			return
+		default:
+			if req.WantReply {
+				_ = req.Reply(false, nil)
+			}
+		}
+	}
+}
+
+func newTestSystem(t *testing.T, addr net.Addr, opts ...systemssh.SystemOption) *systemssh.System {
+	connect, err := sshclient.Prepare(
+		sshclient.Addr(addr),
+		sshclient.Net(sshclient.Dial(addr, 5*time.Second)),
+		sshclient.User("test"),
+		sshclient.HostKeyCallback(ssh.InsecureIgnoreHostKey()),
+	)
+	require.NoError(t, err)
+
+	s, err := systemssh.NewSystem(sshclient.New(connect), opts...)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = s.Close()
+	})
+
+	return s
+}
+
+func TestSystem_FileSystem(t *testing.T) {
+	t.Parallel()
+
+	type testCase struct {
+		Desc           string
+		SftpSubsystem  bool
+		Opts           []systemssh.SystemOption
+		ExpectDirectFS bool
+	}
+
+	tcs := []testCase{
+		{
+			Desc:           "sftp",
+			SftpSubsystem:  true,
+			Opts:           []systemssh.SystemOption{systemssh.Sftp(true)},
+			ExpectDirectFS: true,
+		},
+		{
+			Desc:           "sftp disabled",
+			SftpSubsystem:  true,
+			Opts:           []systemssh.SystemOption{systemssh.Sftp(false)},
+			ExpectDirectFS: false,
+		},
+		{
+			Desc:           "sftp not supported by the remote",
+			SftpSubsystem:  false,
+			Opts:           []systemssh.SystemOption{systemssh.Sftp(true)},
+			ExpectDirectFS: false,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.Desc, func(t *testing.T) {
+			t.Parallel()
+
+			s := newTestSystem(t, newTestSshServer(t, tc.SftpSubsystem), tc.Opts...)
+			ctx := context.Background()
+
+			assert.Equal(t, tc.ExpectDirectFS, system.IsDirectFS(ctx, s))
+
+			name := filepath.Join(t.TempDir(), "file")
+
+			// Stat of a missing file
+			_, err := s.Stat(ctx, name)
+			assert.ErrorIs(t, err, fs.ErrNotExist)
+
+			// Create
+			w, err := s.Create(ctx, (&stat.Stat{Name: name, Mode: 0o640}).ToFsFileInfo())
+			require.NoError(t, err)
+			_, err = io.WriteString(w, "0123456789")
+			require.NoError(t, err)
+			require.NoError(t, w.Close())
+
+			localFileInfo, err := os.Stat(name)
+			require.NoError(t, err)
+			assert.Equal(t, fs.FileMode(0o640), localFileInfo.Mode().Perm())
+
+			// Stat
+			fileInfo, err := s.Stat(ctx, name)
+			require.NoError(t, err)
+			assert.Equal(t, int64(10), fileInfo.Size())
+			assert.True(t, fileInfo.Mode().IsRegular())
+			assert.Equal(t, fs.FileMode(0o640), fileInfo.Mode().Perm())
+			require.IsType(t, &stat.Stat{}, fileInfo.Sys())
+			assert.Equal(t, os.Getuid(), fileInfo.Sys().(*stat.Stat).Uid)
+
+			// Open
+			f, err := s.Open(ctx, name)
+			require.NoError(t, err)
+			content, err := io.ReadAll(f)
+			require.NoError(t, err)
+			assert.Equal(t, "0123456789", string(content))
+
+			// Partial read
+			seeker, ok := f.(io.Seeker)
+			require.True(t, ok)
+			_, err = seeker.Seek(4, io.SeekStart)
+			require.NoError(t, err)
+			content, err = io.ReadAll(io.LimitReader(f, 3))
+			require.NoError(t, err)
+			assert.Equal(t, "456", string(content))
+			require.NoError(t, f.Close())
+		})
+	}
+}
@@ -1,16 +1,20 @@
 package validate
 
 import (
+	"errors"
 	"fmt"
 	"github.com/hashicorp/go-cty/cty"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/neuspaces/terraform-provider-system/internal/lib/totp"
 	"github.com/neuspaces/terraform-provider-system/internal/sshclient"
 	"golang.org/x/crypto/ssh"
 )
 
 // PrivateKey validates if the value can be parsed using ssh.ParseRawPrivateKey.
-// Supported private keys are unencrypted pem encoded RSA (PKCS#1), PKCS#8, DSA (OpenSSL), and ECDSA private keys.
+// Supported private keys are pem encoded RSA (PKCS#1), PKCS#8, DSA (OpenSSL), ECDSA and OpenSSH private keys.
+// Encrypted private keys are accepted because the passphrase is provided separately; use PrivateKeyPassphrase to
+// validate that a passphrase decrypts the private key.
 func PrivateKey() schema.SchemaValidateDiagFunc {
 	return func(val interface{}, path cty.Path) diag.Diagnostics {
 		strVal, diagErr := expectString(val, path)
@@ -20,23 +24,50 @@ func PrivateKey() schema.SchemaValidateDiagFunc {
 
 		_, err := ssh.ParsePrivateKey([]byte(strVal))
 		if err != nil {
-			if err.Error() == (&ssh.PassphraseMissingError{}).Error() {
-				return []diag.Diagnostic{
-					{
-						Severity:      diag.Error,
-						Summary:       fmt.Sprintf("password protected private key is not supported"),
-						AttributePath: path,
-					},
-				}
-			} else {
-				return []diag.Diagnostic{
-					{
-						Severity:      diag.Error,
-						Summary:       fmt.Sprintf("invalid private key format"),
-						Detail:        err.Error(),
-						AttributePath: path,
-					},
-				}
+			var passphraseMissingErr *ssh.PassphraseMissingError
+			if errors.As(err, &passphraseMissingErr) {
+				// Encrypted private key
+				return nil
+			}
+
+			return []diag.Diagnostic{
+				{
+					Severity:      diag.Error,
+					Summary:       fmt.Sprintf("invalid private key format"),
+					Detail:        err.Error(),
+					AttributePath: path,
+				},
+			}
+		}
+
+		return nil
+	}
+}
+
+// PrivateKeyPassphrase returns an error if the private key cannot be parsed using the passphrase.
+// If the passphrase is empty, the private key must not be encrypted. The passphrase is ignored if the private key is not
+// encrypted.
+func PrivateKeyPassphrase(privateKey string, passphrase string) error {
+	_, err := sshclient.ParsePrivateKey(privateKey, passphrase)
+	return err
+}
+
+// TotpSecret validates if the value is a base32 encoded secret of time-based one-time passwords
+func TotpSecret() schema.SchemaValidateDiagFunc {
+	return func(val interface{}, path cty.Path) diag.Diagnostics {
+		strVal, diagErr := expectString(val, path)
+		if diagErr != nil {
+			return diagErr
+		}
+
+		if _, err := totp.DecodeSecret(strVal); err != nil {
+			return []diag.Diagnostic{
+				{
+					Severity:      diag.Error,
+					Summary:       "invalid totp secret",
+					Detail:        err.Error(),
+					AttributePath: path,
+				},
 			}
 		}
 
@@ -93,3 +124,27 @@ func AuthorizedKey() schema.SchemaValidateDiagFunc {
 		return nil
 	}
 }
+
+// KnownHosts validates if the value is either a readable known_hosts file or valid known_hosts content.
+func KnownHosts() schema.SchemaValidateDiagFunc {
+	return func(val interface{}, path cty.Path) diag.Diagnostics {
+		strVal, diagErr := expectString(val, path)
+		if diagErr != nil {
+			return diagErr
+		}
+
+		_, err := sshclient.KnownHosts(strVal)()
+		if err != nil {
+			return []diag.Diagnostic{
+				{
+					Severity:      diag.Error,
+					Summary:       "invalid known_hosts",
+					Detail:        err.Error(),
+					AttributePath: path,
+				},
+			}
+		}
+
+		return nil
+	}
+}
@@ -52,3 +52,56 @@ func expectString(val interface{}, path cty.Path) (string, diag.Diagnostics) {
 
 	return strVal, nil
 }
+
+// Regexp validates if the value is a valid regular expression
+func Regexp() schema.SchemaValidateDiagFunc {
+	return func(val interface{}, path cty.Path) diag.Diagnostics {
+		strVal, diagErr := expectString(val, path)
+		if diagErr != nil {
+			return diagErr
+		}
+
+		if _, err := regexp.Compile(strVal); err != nil {
+			return []diag.Diagnostic{
+				{
+					Severity:      diag.Error,
+					Summary:       "invalid regular expression",
+					Detail:        err.Error(),
+					AttributePath: path,
+				},
+			}
+		}
+
+		return nil
+	}
+}
+
+// RegexpMapKeys validates if all keys of a map value are valid regular expressions
+func RegexpMapKeys() schema.SchemaValidateDiagFunc {
+	return func(val interface{}, path cty.Path) diag.Diagnostics {
+		mapVal, isMap := val.(map[string]interface{})
+		if !isMap {
+			return []diag.Diagnostic{
+				{
+					Severity:      diag.Error,
+					Summary:       "expected type map",
+					AttributePath: path,
+				},
+			}
+		}
+
+		var diags diag.Diagnostics
+		for key := range mapVal {
+			if _, err := regexp.Compile(key); err != nil {
+				diags = append(diags, diag.Diagnostic{
+					Severity:      diag.Error,
+					Summary:       "invalid regular expression",
+					Detail:        fmt.Sprintf("key %q: %s", key, err),
+					AttributePath: path,
+				})
+			}
+		}
+
+		return diags
+	}
+}